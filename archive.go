@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+// archiveSuffix precedes the date in the names of archive files, which
+// sit next to the tasks file like tasks.archive-2024-06-01.
+const archiveSuffix = ".archive-"
+
+// archivePath returns the archive file the tasks file at path is
+// archived to on the day of now.
+func archivePath(path string, now time.Time) string {
+	return path + archiveSuffix + now.Format(dateLayout)
+}
+
+// archiveTasks handles --archive: it moves every task into the dated
+// archive file, appending if there already is one for today, and empties
+// the list, writing both in one transaction.
+func archiveTasks(now time.Time) error {
+	if len(tasklist.Tasks) == 0 {
+		return fmt.Errorf("nothing to archive")
+	}
+	path := archivePath(taskFilePath, now)
+	archive, err := readTaskList(path)
+	if err != nil {
+		return err
+	}
+	archive.Tasks = append(archive.Tasks, tasklist.Tasks...)
+	tx := newTransaction(journalPath(taskFilePath))
+	if err := archive.stageTo(tx, path, false); err != nil {
+		tx.abort()
+		return err
+	}
+	n := len(tasklist.Tasks)
+	tasklist.Tasks = nil
+	if err := tasklist.commit(tx, true); err != nil {
+		return err
+	}
+	fmt.Printf("archived %d tasks to %s\n", n, filepath.Base(path))
+	return nil
+}
+
+// archiveNames returns the names of the tasks file's archives, oldest
+// first.
+func archiveNames() ([]string, error) {
+	files, err := ioutil.ReadDir(filepath.Dir(taskFilePath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	prefix := filepath.Base(taskFilePath) + archiveSuffix
+	names := make([]string, 0)
+	for _, file := range files {
+		if !file.IsDir() && strings.HasPrefix(file.Name(), prefix) {
+			names = append(names, file.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// importBatchSize is how many tasks --import adds to the tasks file
+// with each write.
+const importBatchSize = 1000
+
+// importArchive handles --import: it appends the tasks of an archive
+// file to the list and removes the archive. A bare name as printed by
+// --archives is looked up next to the tasks file. The archive is read
+// line by line and the tasks written in batches, with progress reported
+// after each. If a line can't be read, the batches before it are kept
+// and the error says which line to resume from; resumeFrom skips the
+// lines before it.
+func importArchive(name string, resumeFrom int) error {
+	path := core.ExpandHome(name)
+	if !strings.ContainsRune(name, filepath.Separator) {
+		path = filepath.Join(filepath.Dir(taskFilePath), name)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	size := int64(0)
+	if info, err := file.Stat(); err == nil {
+		size = info.Size()
+	}
+	reader := bufio.NewReader(file)
+	read := int64(0)
+	imported, pending := 0, 0
+	batchStart := resumeFrom
+	if batchStart < 1 {
+		batchStart = 1
+	}
+	flush := func(next int) error {
+		if pending == 0 {
+			return nil
+		}
+		if err := tasklist.write(true); err != nil {
+			return err
+		}
+		imported += pending
+		pending, batchStart = 0, next
+		return nil
+	}
+	stopped := func(n int, err error) error {
+		return fmt.Errorf("line %d: %v\nimported %d tasks, resume with t --import %s --resume-from %d", n, err, imported, name, n)
+	}
+	for n := 1; ; n++ {
+		line, readErr := reader.ReadString('\n')
+		read += int64(len(line))
+		if readErr != nil && readErr != io.EOF {
+			if err := flush(n); err != nil {
+				return stopped(batchStart, err)
+			}
+			return stopped(n, readErr)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if n >= resumeFrom && line != "" && !strings.HasPrefix(line, core.IncludeDirective) {
+			task := &Task{}
+			if err := task.UnmarshalText([]byte(line)); err != nil {
+				if err := flush(n); err != nil {
+					return stopped(batchStart, err)
+				}
+				return stopped(n, err)
+			}
+			tasklist.Tasks = append(tasklist.Tasks, task)
+			pending++
+		}
+		if readErr == io.EOF {
+			if err := flush(n + 1); err != nil {
+				return stopped(batchStart, err)
+			}
+			break
+		}
+		if pending == importBatchSize {
+			if err := flush(n + 1); err != nil {
+				return stopped(batchStart, err)
+			}
+			if size > 0 {
+				fmt.Fprintf(os.Stderr, "imported %d tasks (%d%%)\n", imported, read*100/size)
+			} else {
+				fmt.Fprintf(os.Stderr, "imported %d tasks\n", imported)
+			}
+		}
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	fmt.Printf("imported %d tasks from %s\n", imported, filepath.Base(path))
+	return nil
+}
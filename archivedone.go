@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+// defaultArchiveAge is how long ago the tasks --archive-done moves were
+// finished, unless --older-than says otherwise.
+const defaultArchiveAge = 30 * 24 * time.Hour
+
+// defaultArchiveDir is where --archive-done keeps the archives of the
+// done file, unless archive_dir in the config says otherwise.
+const defaultArchiveDir = "~/.t/archive"
+
+// monthArchivePath returns the archive in dir for the tasks finished in
+// the month of t, like 2024-06.txt.
+func monthArchivePath(dir string, t time.Time) string {
+	return filepath.Join(dir, t.Local().Format("2006-01")+".txt")
+}
+
+// stageArchive stages moving the tasks of a done list finished before
+// cutoff to the monthly archives in dir in tx, appending to those there
+// are, and drops them from the list. Tasks without a finishing time
+// stay. It returns how many tasks it moved, and to which archives.
+func (t *TaskList) stageArchive(tx *transaction, cutoff time.Time, dir string) (int, []string, error) {
+	months := make(map[string][]*Task)
+	kept := make([]*Task, 0, len(t.Tasks))
+	for _, task := range t.Tasks {
+		if task.DoneAt.IsZero() || !task.DoneAt.Before(cutoff) {
+			kept = append(kept, task)
+			continue
+		}
+		path := monthArchivePath(dir, task.DoneAt)
+		months[path] = append(months[path], task)
+	}
+	paths := make([]string, 0, len(months))
+	for path := range months {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		archive, err := readStaged(tx, path)
+		if err != nil {
+			return 0, nil, err
+		}
+		archive.Tasks = append(archive.Tasks, months[path]...)
+		if err := archive.stageTo(tx, path, false); err != nil {
+			return 0, nil, err
+		}
+	}
+	archived := len(t.Tasks) - len(kept)
+	t.Tasks = kept
+	return archived, paths, nil
+}
+
+// ArchiveOlderThan moves the tasks of a done list finished before cutoff
+// to the monthly archives in dir, and returns how many it moved. It
+// writes the archives on their own and leaves writing the list to the
+// caller; --archive-done writes both at once instead.
+func (t *TaskList) ArchiveOlderThan(cutoff time.Time, dir string) (int, error) {
+	tx := newTransaction(filepath.Join(dir, ".journal"))
+	archived, _, err := t.stageArchive(tx, cutoff, dir)
+	if err != nil {
+		tx.abort()
+		return 0, err
+	}
+	return archived, tx.commit()
+}
+
+// archiveDone handles --archive-done: it moves the tasks finished before
+// cutoff from the done file at path and its segments to the monthly
+// archives in dir, removing segments left empty, all in one
+// transaction, and returns how many tasks it moved into how many
+// archives.
+func archiveDone(path string, cutoff time.Time, dir string) (int, int, error) {
+	segments, err := filepath.Glob(path + ".[0-9][0-9][0-9][0-9]-Q[1-4]")
+	if err != nil {
+		return 0, 0, err
+	}
+	sort.Strings(segments)
+	tx := newTransaction(journalPath(path))
+	archived := 0
+	archives := make(map[string]bool)
+	for _, name := range append(segments, path) {
+		text, err := ioutil.ReadFile(name)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			tx.abort()
+			return 0, 0, err
+		}
+		// Rewriting the file would drop its broken lines unseen.
+		done, skipped, err := parseDone(text)
+		if err == nil && skipped > 0 {
+			err = fmt.Errorf("%s has %d unparseable lines, run t --check --done --fix first", name, skipped)
+		}
+		if err != nil {
+			tx.abort()
+			return 0, 0, err
+		}
+		n, paths, err := done.stageArchive(tx, cutoff, dir)
+		if err == nil && n > 0 {
+			err = done.stageTo(tx, name, name != path)
+		}
+		if err != nil {
+			tx.abort()
+			return 0, 0, err
+		}
+		archived += n
+		for _, archive := range paths {
+			archives[archive] = true
+		}
+	}
+	if err := tx.commit(); err != nil {
+		return 0, 0, err
+	}
+	return archived, len(archives), nil
+}
+
+// getArchiveDir returns the directory --archive-done keeps archives in,
+// archive_dir from the config or defaultArchiveDir.
+func getArchiveDir(config Config) (string, error) {
+	if value, ok := config.Get("archive_dir"); ok {
+		return expandPath(value, "archive_dir")
+	}
+	return core.ExpandHome(defaultArchiveDir), nil
+}
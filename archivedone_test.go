@@ -0,0 +1,79 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestArchiveOlderThan(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "archive")
+	done := &TaskList{}
+	done.UnmarshalText([]byte("may | done:2024-05-10T09:00:00Z\n" +
+		"june | done:2024-06-02T09:00:00Z\n" +
+		"legacy\n" +
+		"late june | done:2024-06-20T09:00:00Z\n" +
+		"recent | done:2024-07-09T09:00:00Z"))
+	os.MkdirAll(dir, 0700)
+	ioutil.WriteFile(filepath.Join(dir, "2024-06.txt"), []byte("earlier | done:2024-06-01T09:00:00Z"), 0644)
+	cutoff := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	archived, err := done.ArchiveOlderThan(cutoff, dir)
+	if err != nil || archived != 3 {
+		t.Fatalf("Expected 3 tasks archived, got %d (%v)", archived, err)
+	}
+	if len(done.Tasks) != 2 || done.Tasks[0].Description != "legacy" || done.Tasks[1].Description != "recent" {
+		t.Fatalf("Expected the untimed and recent tasks kept, got %v", done.Tasks)
+	}
+	for name, expected := range map[string]string{
+		"2024-05.txt": "may | done:2024-05-10T09:00:00Z",
+		"2024-06.txt": "earlier | done:2024-06-01T09:00:00Z\njune | done:2024-06-02T09:00:00Z\nlate june | done:2024-06-20T09:00:00Z",
+	} {
+		if text, _ := ioutil.ReadFile(filepath.Join(dir, name)); string(text) != expected {
+			t.Errorf("Expected %s to be '%s', got '%s'", name, expected, text)
+		}
+	}
+	if archived, err := done.ArchiveOlderThan(cutoff, dir); err != nil || archived != 0 {
+		t.Fatalf("Expected nothing left to archive, got %d (%v)", archived, err)
+	}
+}
+
+func TestCliArchiveDone(t *testing.T) {
+	withCliSetup(t, func() {
+		home := t.TempDir()
+		os.MkdirAll(filepath.Join(home, "t"), 0700)
+		ioutil.WriteFile(filepath.Join(home, "t", "config"), []byte("archive_dir = "+filepath.Join(home, "archive")), 0644)
+		old := time.Now().AddDate(0, 0, -60).UTC().Format(time.RFC3339)
+		older := time.Now().AddDate(0, 0, -120).UTC().Format(time.RFC3339)
+		recent := time.Now().AddDate(0, 0, -10).UTC().Format(time.RFC3339)
+		ioutil.WriteFile("/tmp/tasks.done", []byte("a | done:"+older+"\nb | done:"+old+"\nc | done:"+recent), 0644)
+		env := append(os.Environ(), "XDG_CONFIG_HOME="+home)
+		cmd := exec.Command(tBinary, "--archive-done", "--older-than", "90d")
+		cmd.Env = env
+		out, err := cmd.Output()
+		if err != nil || string(out) != "archived 1 tasks into 1 files\n" {
+			t.Fatalf("Expected one task archived, got '%s' (%v)", out, err)
+		}
+		cmd = exec.Command(tBinary, "--archive-done")
+		cmd.Env = env
+		if out, err = cmd.Output(); err != nil || string(out) != "archived 1 tasks into 1 files\n" {
+			t.Fatalf("Expected the default of 30 days, got '%s' (%v)", out, err)
+		}
+		text, _ := ioutil.ReadFile("/tmp/tasks.done")
+		if !strings.HasPrefix(string(text), "c | ") || strings.Contains(string(text), "\n") {
+			t.Fatalf("Expected only the recent task left, got '%s'", text)
+		}
+		names, _ := filepath.Glob(filepath.Join(home, "archive", "*.txt"))
+		if len(names) != 2 {
+			t.Fatalf("Expected two archives in archive_dir, got %v", names)
+		}
+		cmd = exec.Command(tBinary, "--archive-done", "--older-than", "soon")
+		cmd.Env = env
+		if err := cmd.Run(); err == nil {
+			t.Fatal("Expected an invalid --older-than to fail")
+		}
+	})
+}
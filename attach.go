@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+// attachFile handles --attach: it adds the paths in args to the task.
+func attachFile(target string, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("Usage: t --attach <id> <path>...")
+	}
+	taskId, err := tasklist.resolveId(target)
+	if err != nil {
+		return err
+	}
+	task, err := tasklist.Get(taskId)
+	if err != nil {
+		return err
+	}
+	for _, path := range args {
+		task.Attachments = append(task.Attachments, core.ExpandHome(path))
+	}
+	return tasklist.write(true)
+}
+
+// openAttached handles --open-attachment: it opens an attachment of the
+// task with the system's default application. Tasks with several
+// attachments need the attachment's number as well.
+func openAttached(target string, args []string) error {
+	taskId, err := tasklist.resolveId(target)
+	if err != nil {
+		return err
+	}
+	task, err := tasklist.Get(taskId)
+	if err != nil {
+		return err
+	}
+	n := 0
+	switch {
+	case len(task.Attachments) == 0:
+		return fmt.Errorf("task %d has no attachments", displayId(taskId))
+	case len(args) == 1:
+		n, err = strconv.Atoi(args[0])
+		if err != nil || n < 0 || n >= len(task.Attachments) {
+			return fmt.Errorf("no attachment %s on task %d", args[0], displayId(taskId))
+		}
+	case len(task.Attachments) > 1:
+		msg := fmt.Sprintf("task %d has several attachments, pick one:", displayId(taskId))
+		for i, path := range task.Attachments {
+			msg += fmt.Sprintf("\n  t --open-attachment %d %d  # %s", displayId(taskId), i, path)
+		}
+		return fmt.Errorf("%s", msg)
+	}
+	path := task.Attachments[n]
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("can't open attachment: %v", err)
+	}
+	return openerCommand(path).Start()
+}
+
+// openerCommand returns the command opening path with the system's
+// default application.
+func openerCommand(path string) *exec.Cmd {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", path)
+	case "windows":
+		return exec.Command("cmd", "/c", "start", "", path)
+	default:
+		return exec.Command("xdg-open", path)
+	}
+}
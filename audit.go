@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+// auditSize is the number of events the audit log keeps, across all
+// tasks.
+const auditSize = 1000
+
+// auditEvent is something that happened to a task, keyed by its stable
+// id.
+type auditEvent struct {
+	Time  time.Time `json:"time"`
+	Id    string    `json:"id"`
+	Event string    `json:"event"`
+}
+
+// auditPath returns the path of the audit log of a tasks file.
+func auditPath(path string) string {
+	return path + ".log"
+}
+
+// taskEvents compares the tasks before and after a change and describes
+// what happened to each of them. Tasks that are gone were finished if
+// their id is in finished, and removed otherwise.
+func taskEvents(before, after *TaskList, finished map[string]bool) []auditEvent {
+	events := make([]auditEvent, 0)
+	add := func(id string, format string, args ...interface{}) {
+		events = append(events, auditEvent{Id: id, Event: fmt.Sprintf(format, args...)})
+	}
+	old := make(map[string]*Task)
+	for _, task := range before.Tasks {
+		old[task.Id] = task
+	}
+	for _, task := range after.Tasks {
+		was, ok := old[task.Id]
+		if !ok {
+			add(task.Id, "created: %s", task.Description)
+			continue
+		}
+		delete(old, task.Id)
+		if was.Description != task.Description {
+			add(task.Id, "edited: %s → %s", was.Description, task.Description)
+		}
+		if storedPriority(was) != storedPriority(task) {
+			add(task.Id, "priority: P%d → P%d", storedPriority(was), storedPriority(task))
+		}
+		if !was.DueAt.Equal(task.DueAt) {
+			add(task.Id, "due: %s → %s", formatDate(was.DueAt), formatDate(task.DueAt))
+		}
+		if !was.SnoozedUntil.Equal(task.SnoozedUntil) {
+			if task.SnoozedUntil.IsZero() {
+				add(task.Id, "no longer deferred")
+			} else {
+				add(task.Id, "deferred until %s", formatDate(task.SnoozedUntil))
+			}
+		}
+	}
+	for _, task := range before.Tasks {
+		if _, gone := old[task.Id]; !gone {
+			continue
+		}
+		if finished[task.Id] {
+			add(task.Id, "finished")
+		} else {
+			add(task.Id, "removed")
+		}
+	}
+	return events
+}
+
+// formatDate renders a date of the audit log, "none" if there is none.
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return "none"
+	}
+	return t.Format(dateLayout)
+}
+
+// loadAudit reads the audit log of a tasks file, skipping lines it
+// can't make sense of.
+func loadAudit(path string) []auditEvent {
+	events := make([]auditEvent, 0)
+	file, err := os.Open(auditPath(path))
+	if err != nil {
+		return events
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event auditEvent
+		if json.Unmarshal(scanner.Bytes(), &event) == nil {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// recordAudit appends what a write of the tasks file at path did to
+// each task to its audit log, dropping the oldest events beyond
+// auditSize. Like the undo history, failing to do so is only reported.
+func recordAudit(path string, before, after string, finished map[string]bool) {
+	oldList, newList := &TaskList{}, &TaskList{}
+	if oldList.UnmarshalText([]byte(before)) != nil || newList.UnmarshalText([]byte(after)) != nil {
+		return
+	}
+	events := taskEvents(oldList, newList, finished)
+	if len(events) == 0 {
+		return
+	}
+	now := time.Now()
+	for i := range events {
+		events[i].Time = now
+	}
+	events = append(loadAudit(path), events...)
+	if len(events) > auditSize {
+		events = events[len(events)-auditSize:]
+	}
+	lines := make([]string, 0, len(events))
+	for _, event := range events {
+		line, _ := json.Marshal(event)
+		lines = append(lines, string(line))
+	}
+	text := strings.Join(lines, "\n") + "\n"
+	if err := ioutil.WriteFile(auditPath(path), []byte(text), 0600); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: can't save audit log: %v\n", err)
+	}
+}
+
+// printLog handles --log: it lists everything recorded about a task,
+// oldest first.
+func printLog(target string) error {
+	taskId, err := tasklist.resolveId(target)
+	if err != nil {
+		return err
+	}
+	task, err := tasklist.Get(taskId)
+	if err != nil {
+		return err
+	}
+	for _, event := range loadAudit(taskFilePath) {
+		if event.Id == task.Id {
+			fmt.Printf("%s  %s\n", event.Time.Local().Format("2006-01-02 15:04:05"), event.Event)
+		}
+	}
+	return nil
+}
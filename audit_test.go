@@ -0,0 +1,37 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTaskEvents(t *testing.T) {
+	before := &TaskList{}
+	before.UnmarshalText([]byte("pay rent\nwater plants\ncall bob\nread book"))
+	after := &TaskList{}
+	after.UnmarshalText([]byte("pay rent\nwater plants\ncall bob"))
+	after.Tasks[0].Description = "pay the rent"
+	after.Tasks[0].Priority = 2
+	after.Tasks[1].SnoozedUntil = time.Date(2024, 6, 3, 0, 0, 0, 0, time.Local)
+	after.Tasks[1].DueAt = time.Date(2024, 6, 5, 0, 0, 0, 0, time.Local)
+	after.Finish(2)
+	after.Add("new task")
+
+	events := make([]string, 0)
+	for _, event := range taskEvents(before, after, after.Finished) {
+		events = append(events, event.Event)
+	}
+	expected := []string{
+		"edited: pay rent → pay the rent",
+		"priority: P5 → P2",
+		"due: none → 2024-06-05",
+		"deferred until 2024-06-03",
+		"created: new task",
+		"finished",
+		"removed",
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Fatalf("Expected events %q, got %q", expected, events)
+	}
+}
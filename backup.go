@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+)
+
+// defaultBackups is how many backups of the tasks file are kept unless
+// T_BACKUPS says otherwise.
+const defaultBackups = 3
+
+// backupPath returns backup n of the tasks file at path, like tasks.1
+// for the one before the last write.
+func backupPath(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
+
+// backupDepth returns how many backups are kept, T_BACKUPS or
+// defaultBackups; 0 keeps none.
+func backupDepth() (int, error) {
+	value := os.Getenv("T_BACKUPS")
+	if value == "" {
+		return defaultBackups, nil
+	}
+	depth, err := strconv.Atoi(value)
+	if err != nil || depth < 0 {
+		return 0, fmt.Errorf("invalid T_BACKUPS %q", value)
+	}
+	return depth, nil
+}
+
+// stageBackups stages keeping before, what the tasks file at path held
+// until now, as backup 1 in tx, moving the backups there are up by one
+// and dropping those beyond depth. An empty file isn't worth a backup.
+func stageBackups(tx *transaction, path string, before []byte, depth int) error {
+	if depth == 0 || len(before) == 0 {
+		return nil
+	}
+	for n := depth - 1; n >= 1; n-- {
+		text, err := ioutil.ReadFile(backupPath(path, n))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := tx.write(backupPath(path, n+1), text, 0644); err != nil {
+			return err
+		}
+	}
+	return tx.write(backupPath(path, 1), before, 0644)
+}
+
+// backupNumber returns the backup --restore names, for a name that is
+// a number.
+func backupNumber(name string) (int, bool) {
+	n, err := strconv.Atoi(name)
+	return n, err == nil && n >= 1 && fmt.Sprint(n) == name
+}
+
+// restoreBackup handles --restore with a number: it prints how backup n
+// of the tasks file at path differs from it and, if ok says so,
+// replaces the file with it. The file as it was becomes backup 1, so
+// that another --restore 2 takes the restore back, and --undo does too.
+func restoreBackup(path string, n int, ok func(question string) bool) error {
+	text, err := ioutil.ReadFile(backupPath(path, n))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("no backup %d of %s", n, path)
+	}
+	if err != nil {
+		return err
+	}
+	before, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	current, backup := &TaskList{}, &TaskList{}
+	if err := current.UnmarshalText(before); err != nil {
+		return err
+	}
+	if err := backup.UnmarshalText(text); err != nil {
+		return fmt.Errorf("backup %d: %v", n, err)
+	}
+	lines := diffTasks(current, backup)
+	if len(lines) == 0 {
+		fmt.Printf("backup %d is the same as the tasks file\n", n)
+		return nil
+	}
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+	if !ok(fmt.Sprintf("Restore backup %d from %s?", n, modTime(backupPath(path, n)))) {
+		return fmt.Errorf("the tasks file wasn't changed")
+	}
+	depth, err := backupDepth()
+	if err != nil {
+		return err
+	}
+	tx := newTransaction(journalPath(path))
+	if err := stageBackups(tx, path, before, depth); err != nil {
+		tx.abort()
+		return err
+	}
+	if err := tx.write(path, text, 0644); err != nil {
+		tx.abort()
+		return err
+	}
+	stageHistory(tx, path, string(before), string(text))
+	return tx.commit()
+}
+
+// modTime returns when the file at path was last written, for saying
+// which backup is which.
+func modTime(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "an unknown time"
+	}
+	return info.ModTime().Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStageBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks")
+	for _, before := range []string{"", "one", "two", "three", "four"} {
+		tx := newTransaction(journalPath(path))
+		if err := stageBackups(tx, path, []byte(before), 3); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for n, expected := range map[int]string{1: "four", 2: "three", 3: "two"} {
+		if text, _ := ioutil.ReadFile(backupPath(path, n)); string(text) != expected {
+			t.Errorf("Expected backup %d to be '%s', got '%s'", n, expected, text)
+		}
+	}
+	if _, err := os.Stat(backupPath(path, 4)); !os.IsNotExist(err) {
+		t.Fatalf("Expected no backup beyond the depth, got %v", err)
+	}
+}
+
+func TestBackupNumber(t *testing.T) {
+	for name, expected := range map[string]bool{"1": true, "12": true, "0": false, "01": false, "-1": false, "monday": false} {
+		if _, ok := backupNumber(name); ok != expected {
+			t.Errorf("Expected %q to be a backup number: %v", name, expected)
+		}
+	}
+}
+
+func TestCliBackups(t *testing.T) {
+	withCliSetup(t, func() {
+		exec.Command(tBinary, "pay rent").Run()
+		exec.Command(tBinary, "call bob").Run()
+		exec.Command(tBinary, "-f", "0").Run()
+		exec.Command(tBinary, "--count").Run()
+		backup1, _ := ioutil.ReadFile(backupPath("/tmp/tasks", 1))
+		backup2, _ := ioutil.ReadFile(backupPath("/tmp/tasks", 2))
+		if !strings.HasPrefix(string(backup1), "pay rent") || !strings.Contains(string(backup1), "\ncall bob") {
+			t.Fatalf("Expected backup 1 to hold both tasks, got '%s'", backup1)
+		}
+		if !strings.HasPrefix(string(backup2), "pay rent") || strings.Contains(string(backup2), "call bob") {
+			t.Fatalf("Expected backup 2 to hold the first task, got '%s'", backup2)
+		}
+		if _, err := os.Stat(backupPath("/tmp/tasks", 3)); !os.IsNotExist(err) {
+			t.Fatal("Expected no backup of the missing tasks file")
+		}
+		if err := exec.Command(tBinary, "--restore", "2").Run(); err == nil {
+			t.Fatal("Expected --restore to ask before restoring when not on a terminal")
+		}
+		out, err := exec.Command(tBinary, "--restore", "2", "-y").Output()
+		if err != nil || !strings.HasPrefix(string(out), "- call bob | ") || !strings.Contains(string(out), "+ pay rent | ") {
+			t.Fatalf("Expected a preview of the restore, got '%s' (%v)", out, err)
+		}
+		if out, _ := exec.Command(tBinary).Output(); string(out) != "0 - pay rent\n" {
+			t.Fatalf("Expected backup 2 restored, got '%s'", out)
+		}
+		if text, _ := ioutil.ReadFile(backupPath("/tmp/tasks", 1)); !strings.HasPrefix(string(text), "call bob") {
+			t.Fatalf("Expected the restored-over file kept as backup 1, got '%s'", text)
+		}
+		if err := exec.Command(tBinary, "--restore", "4", "-y").Run(); err == nil {
+			t.Fatal("Expected a missing backup to fail")
+		}
+		cmd := exec.Command(tBinary, "-e", "0", "pay the rent")
+		cmd.Env = append(cmd.Environ(), "T_BACKUPS=0")
+		cmd.Run()
+		if text, _ := ioutil.ReadFile(backupPath("/tmp/tasks", 1)); !strings.HasPrefix(string(text), "call bob") {
+			t.Fatalf("Expected T_BACKUPS=0 to keep no backup, got '%s'", text)
+		}
+	})
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+// bookmark is a link of a bookmarks export, with the names of the
+// folders it is in, outermost first.
+type bookmark struct {
+	title   string
+	url     string
+	folders []string
+}
+
+var (
+	// markupTag matches an HTML tag, opening or closing, by its name.
+	markupTag = regexp.MustCompile(`<(/?)([a-zA-Z][a-zA-Z0-9]*)([^>]*)>`)
+	// hrefAttr matches the href attribute of a tag, quoted or not.
+	hrefAttr = regexp.MustCompile(`(?i)\bhref\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	// anyTag matches the tags left in the text of a link or folder name.
+	anyTag = regexp.MustCompile(`<[^>]*>`)
+)
+
+// asciiLower lowers the ASCII letters of s only, so that offsets into
+// it are offsets into s.
+func asciiLower(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
+
+// markupText returns the text of s up to the closing tag close, or up
+// to the next entry if it isn't closed, unescaped and with its spaces
+// collapsed. lower is s with asciiLower applied.
+func markupText(s, lower, close string) string {
+	end := len(s)
+	for _, stop := range []string{close, "<dt", "<dl", "</dl", "<h3"} {
+		if i := strings.Index(lower, stop); i != -1 && i < end {
+			end = i
+		}
+	}
+	text := html.UnescapeString(anyTag.ReplaceAllString(s[:end], " "))
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// parseBookmarks returns the links of a bookmarks export in the Netscape
+// format browsers write. Rather than expecting valid HTML, which real
+// exports aren't, it goes by the tags that matter: each H3 names the
+// folder the DL after it holds, and each A with an href is a link.
+// Unclosed DT and P tags, any case and stray markup are fine; links
+// that aren't web pages, like javascript: bookmarklets or Firefox's
+// place: queries, are left out.
+func parseBookmarks(data []byte) []bookmark {
+	text := string(data)
+	lower := asciiLower(text)
+	marks := make([]bookmark, 0)
+	folders := make([]string, 0)
+	pending := ""
+	for _, m := range markupTag.FindAllStringSubmatchIndex(text, -1) {
+		closing := m[3] > m[2]
+		name := lower[m[4]:m[5]]
+		switch {
+		case name == "h3" && !closing:
+			pending = markupText(text[m[1]:], lower[m[1]:], "</h3")
+		case name == "dl" && !closing:
+			folders = append(folders, pending)
+			pending = ""
+		case name == "dl" && closing:
+			if len(folders) > 0 {
+				folders = folders[:len(folders)-1]
+			}
+		case name == "a" && !closing:
+			href := hrefAttr.FindStringSubmatch(text[m[6]:m[7]])
+			if href == nil {
+				continue
+			}
+			url := strings.TrimSpace(html.UnescapeString(href[1] + href[2] + href[3]))
+			if !strings.Contains(url, "://") {
+				continue
+			}
+			mark := bookmark{title: markupText(text[m[1]:], lower[m[1]:], "</a"), url: url}
+			for _, folder := range folders {
+				if folder != "" {
+					mark.folders = append(mark.folders, folder)
+				}
+			}
+			marks = append(marks, mark)
+		}
+	}
+	return marks
+}
+
+// inFolder reports whether the bookmark is in the named folder, or in a
+// folder within it, ignoring case.
+func (b bookmark) inFolder(name string) bool {
+	for _, folder := range b.folders {
+		if strings.EqualFold(folder, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// bookmarkTask returns the description of the task a bookmark becomes,
+// like "read: Title https://example.com +reading".
+func bookmarkTask(b bookmark) string {
+	if b.title == "" || b.title == b.url {
+		return "read: " + b.url + " +reading"
+	}
+	return "read: " + b.title + " " + b.url + " +reading"
+}
+
+// importBookmarks handles --import-bookmarks: it adds a task for every
+// bookmark of the export at name, or only for those in folder, and
+// reports how many it added and skipped. Bookmarks whose URL is in a
+// task already, open or done, are skipped, so importing the same export
+// again only adds what is new.
+func importBookmarks(name, folder string, out io.Writer) error {
+	data, err := ioutil.ReadFile(core.ExpandHome(name))
+	if err != nil {
+		return err
+	}
+	marks := parseBookmarks(data)
+	if folder != "" {
+		kept := make([]bookmark, 0, len(marks))
+		for _, mark := range marks {
+			if mark.inFolder(folder) {
+				kept = append(kept, mark)
+			}
+		}
+		if len(kept) == 0 {
+			return fmt.Errorf("no bookmarks in a folder named %q in %s", folder, name)
+		}
+		marks = kept
+	}
+	done, err := readDoneHistory(donePath(taskFilePath))
+	if err != nil {
+		return err
+	}
+	known := append(append([]*Task(nil), tasklist.Tasks...), done.Tasks...)
+	seen := func(url string) bool {
+		for _, task := range known {
+			if hasWord(task, url) {
+				return true
+			}
+		}
+		return false
+	}
+	added, skipped := 0, 0
+	for _, mark := range marks {
+		if seen(mark.url) {
+			skipped++
+			continue
+		}
+		task, err := tasklist.Add(bookmarkTask(mark))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: skipping %s: %v\n", mark.url, err)
+			continue
+		}
+		known = append(known, task)
+		added++
+	}
+	if added > 0 {
+		if err := tasklist.write(true); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(out, "imported %d bookmarks, skipped %d already on the list or done\n", added, skipped)
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestParseBookmarksChrome(t *testing.T) {
+	data, _ := ioutil.ReadFile("testdata/bookmarks-chrome.html")
+	marks := parseBookmarks(data)
+	expected := []bookmark{
+		{"Mail", "https://mail.example.com/", []string{"Bookmarks bar"}},
+		{"Errors are values - The Go Programming Language", "https://go.dev/blog/errors-are-values", []string{"Bookmarks bar", "Read Later"}},
+		{"Tom & Jerry's guide", "https://example.com/a?x=1&y=2", []string{"Bookmarks bar", "Read Later"}},
+		{"", "https://research.example.org/paper.pdf", []string{"Bookmarks bar", "Read Later", "Papers"}},
+		{"News", "https://news.example.com/", []string{"Other bookmarks"}},
+	}
+	checkBookmarks(t, marks, expected)
+}
+
+func TestParseBookmarksFirefox(t *testing.T) {
+	data, _ := ioutil.ReadFile("testdata/bookmarks-firefox.html")
+	marks := parseBookmarks(data)
+	expected := []bookmark{
+		{"A post spanning lines", "https://blog.example.net/post", []string{"read later"}},
+		{"single quotes", "https://example.com/single", []string{"read later"}},
+		{"unquoted", "https://example.com/unquoted", []string{"read later"}},
+		{"Errors are values", "https://go.dev/blog/errors-are-values", []string{"Other Bookmarks"}},
+	}
+	checkBookmarks(t, marks, expected)
+}
+
+func checkBookmarks(t *testing.T, marks, expected []bookmark) {
+	t.Helper()
+	if len(marks) != len(expected) {
+		t.Fatalf("Expected %d bookmarks, got %d: %v", len(expected), len(marks), marks)
+	}
+	for i, mark := range marks {
+		want := expected[i]
+		if mark.title != want.title || mark.url != want.url || strings.Join(mark.folders, "/") != strings.Join(want.folders, "/") {
+			t.Errorf("Bookmark %d: expected %v, got %v", i, want, mark)
+		}
+	}
+}
+
+func TestCliImportBookmarks(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("skim https://go.dev/blog/errors-are-values"), 0644)
+		out, err := exec.Command(tBinary, "--import-bookmarks", "testdata/bookmarks-chrome.html", "--folder", "read later").CombinedOutput()
+		if err != nil || string(out) != "imported 2 bookmarks, skipped 1 already on the list or done\n" {
+			t.Fatalf("Expected 2 imported and 1 skipped, got '%s' (%v)", out, err)
+		}
+		text, _ := ioutil.ReadFile("/tmp/tasks")
+		for _, expected := range []string{
+			"read: Tom & Jerry's guide https://example.com/a?x=1&y=2 +reading",
+			"read: https://research.example.org/paper.pdf +reading",
+		} {
+			if !strings.Contains(string(text), expected) {
+				t.Errorf("Expected '%s' in '%s'", expected, text)
+			}
+		}
+		out, _ = exec.Command(tBinary, "--import-bookmarks", "testdata/bookmarks-chrome.html", "--folder", "Read Later").CombinedOutput()
+		if string(out) != "imported 0 bookmarks, skipped 3 already on the list or done\n" {
+			t.Fatalf("Expected nothing new the second time, got '%s'", out)
+		}
+		if err := exec.Command(tBinary, "--import-bookmarks", "testdata/bookmarks-chrome.html", "--folder", "Nope").Run(); err == nil {
+			t.Fatal("Expected an unknown folder to fail")
+		}
+	})
+}
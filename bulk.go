@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+// Descriptions are escaped on a line of --bulk so that one with a line
+// break stays on its line.
+var bulkEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`)
+var bulkUnescaper = strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\r`, "\r")
+
+// bulkText returns the list as --bulk opens it: a line per task, hidden
+// ones too, with the prefix of its stable id, a colon and its
+// description.
+func (t *TaskList) bulkText() string {
+	prefixes := t.idPrefixes()
+	var text strings.Builder
+	for _, task := range t.Tasks {
+		fmt.Fprintf(&text, "%s: %s\n", prefixes[task], bulkEscaper.Replace(task.Description))
+	}
+	return text.String()
+}
+
+// parseBulk reads the lines of --bulk as saved, against the list they
+// were written from: a line starting with the id prefix of one of its
+// tasks and a colon is that task, and any other line a new task, with
+// no id. Blank lines are skipped. A line starting with what looks like
+// an id but isn't one of a task is an error, so that a mistyped id
+// isn't taken for a new task.
+func (t *TaskList) parseBulk(text string) (*TaskList, error) {
+	edited := &TaskList{TaskList: core.TaskList{Tasks: make([]*Task, 0)}}
+	lines := strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n")
+	for n, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		task := &Task{Description: bulkUnescaper.Replace(line)}
+		if colon := strings.Index(line, ":"); colon != -1 && isIdPrefix(line[:colon]) {
+			taskId, err := t.resolvePrefix(line[:colon])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", n+1, err)
+			}
+			task.Id = t.Tasks[taskId].Id
+			task.Description = bulkUnescaper.Replace(strings.TrimPrefix(line[colon+1:], " "))
+		}
+		edited.Tasks = append(edited.Tasks, task)
+	}
+	return edited, nil
+}
+
+// Reconcile makes the list what edited says it should be, as parseBulk
+// reads it: tasks of the list edited leaves out are finished, those
+// whose description changed are edited, and tasks without an id are
+// added. The list then has the order of edited, followed by the
+// recurring tasks finishing brought back. It returns the finished
+// tasks and how many were edited and added. Nothing changes if any of
+// it is invalid, like a task that isn't on the list or is given twice,
+// or an empty description.
+func (t *TaskList) Reconcile(edited *TaskList) ([]*Task, int, int, error) {
+	prefixes := t.idPrefixes()
+	seen := make(map[string]bool)
+	for n, task := range edited.Tasks {
+		name := fmt.Sprintf("new task %d", n+1)
+		if task.Id != "" {
+			taskId := t.IndexOf(task.Id)
+			if taskId == -1 {
+				return nil, 0, 0, fmt.Errorf("no task with id %s, the tasks weren't changed", task.Id)
+			}
+			name = "task " + prefixes[t.Tasks[taskId]]
+			if seen[task.Id] {
+				return nil, 0, 0, fmt.Errorf("%s is there twice, the tasks weren't changed", name)
+			}
+		}
+		seen[task.Id] = true
+		description := task.Description
+		if !t.Raw {
+			description = core.NormalizeText(description)
+		}
+		if _, err := core.LimitDescription(description, t.Truncate); err != nil {
+			return nil, 0, 0, fmt.Errorf("%s: %v, the tasks weren't changed", name, err)
+		}
+	}
+	finished := make([]*Task, 0)
+	for _, task := range append([]*Task(nil), t.Tasks...) {
+		if !seen[task.Id] {
+			done, err := t.Finish(t.IndexOf(task.Id))
+			if err != nil {
+				return nil, 0, 0, err
+			}
+			finished = append(finished, done)
+		}
+	}
+	edits, adds := 0, 0
+	order := make([]*Task, 0, len(edited.Tasks))
+	placed := make(map[*Task]bool)
+	for _, line := range edited.Tasks {
+		if line.Id == "" {
+			task, err := t.Add(line.Description)
+			if err != nil {
+				return nil, 0, 0, err
+			}
+			order = append(order, task)
+			placed[task] = true
+			adds++
+			continue
+		}
+		taskId := t.IndexOf(line.Id)
+		if t.Tasks[taskId].Description != line.Description {
+			if err := t.Edit(taskId, line.Description); err != nil {
+				return nil, 0, 0, err
+			}
+			edits++
+		}
+		order = append(order, t.Tasks[taskId])
+		placed[t.Tasks[taskId]] = true
+	}
+	for _, task := range t.Tasks {
+		if !placed[task] {
+			order = append(order, task)
+		}
+	}
+	t.Tasks = order
+	return finished, edits, adds, nil
+}
+
+// bulkEdit handles --bulk: it opens the list in the editor, as
+// bulkText writes it, and reconciles the list with what was saved,
+// writing it and the finished tasks at once. An editor that fails or
+// a line that can't be read leave the list as it was.
+func bulkEdit(now time.Time) error {
+	limit, err := doneLimit()
+	if err != nil {
+		return err
+	}
+	file, err := ioutil.TempFile("", "t-bulk-*.txt")
+	if err != nil {
+		return err
+	}
+	_, err = file.WriteString(tasklist.bulkText())
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	// What was saved is reconciled with the list it was made from, which
+	// would finish the tasks another t added meanwhile.
+	changed, err := runEditor(editorCommand(file.Name()))
+	if err == nil && changed {
+		return fmt.Errorf("the tasks changed while they were being edited, they weren't changed; the edit is kept in %s", file.Name())
+	}
+	defer os.Remove(file.Name())
+	if err != nil {
+		return fmt.Errorf("%v, the tasks weren't changed", err)
+	}
+	text, err := ioutil.ReadFile(file.Name())
+	if err != nil {
+		return err
+	}
+	edited, err := tasklist.parseBulk(string(text))
+	if err != nil {
+		return fmt.Errorf("%v, the tasks weren't changed", err)
+	}
+	before, _ := tasklist.MarshalText()
+	finished, edits, adds, err := tasklist.Reconcile(edited)
+	if err != nil {
+		return err
+	}
+	if after, _ := tasklist.MarshalText(); string(after) == string(before) && len(finished) == 0 {
+		return nil
+	}
+	if len(finished) > 0 {
+		err = tasklist.writeDone(finished, now, limit)
+	} else {
+		err = tasklist.write(true)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("finished %d, edited %d, added %d\n", len(finished), edits, adds)
+	return nil
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+// bulkFixture returns a list of three tasks and the prefixes --bulk
+// shows them with.
+func bulkFixture() (*TaskList, map[string]string) {
+	list := &TaskList{}
+	for _, description := range []string{"pay rent", "call bob", "water the plants"} {
+		list.Add(description)
+	}
+	prefixes := make(map[string]string)
+	for task, prefix := range list.idPrefixes() {
+		prefixes[task.Description] = prefix
+	}
+	return list, prefixes
+}
+
+func descriptions(list *TaskList) string {
+	all := make([]string, 0, len(list.Tasks))
+	for _, task := range list.Tasks {
+		all = append(all, task.Description)
+	}
+	return strings.Join(all, ", ")
+}
+
+func TestBulkTextRoundTrip(t *testing.T) {
+	list, _ := bulkFixture()
+	list.Tasks[2].Description = "water the plants\nand the garden \\ shed"
+	edited, err := list.parseBulk(list.bulkText())
+	if err != nil {
+		t.Fatal(err)
+	}
+	finished, edits, adds, err := list.Reconcile(edited)
+	if err != nil || len(finished) != 0 || edits != 0 || adds != 0 {
+		t.Fatalf("Expected the unchanged text to change nothing, got %v, %d, %d (%v)", finished, edits, adds, err)
+	}
+	if got := descriptions(list); got != "pay rent, call bob, water the plants\nand the garden \\ shed" {
+		t.Fatalf("Expected the list as it was, got '%s'", got)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	cases := []struct {
+		name     string
+		text     func(p map[string]string) string
+		expected string
+		finished string
+		edits    int
+		adds     int
+	}{
+		{"edit", func(p map[string]string) string {
+			return p["pay rent"] + ": pay the rent\n" + p["call bob"] + ": call bob\n" + p["water the plants"] + ": water the plants\n"
+		}, "pay the rent, call bob, water the plants", "", 1, 0},
+		{"finish", func(p map[string]string) string {
+			return p["pay rent"] + ": pay rent\n\n" + p["water the plants"] + ": water the plants\n"
+		}, "pay rent, water the plants", "call bob", 0, 0},
+		{"add", func(p map[string]string) string {
+			return p["pay rent"] + ": pay rent\nbuy milk\n" + p["call bob"] + ": call bob\n" + p["water the plants"] + ": water the plants\nnote: later\n"
+		}, "pay rent, buy milk, call bob, water the plants, note: later", "", 0, 2},
+		{"reorder", func(p map[string]string) string {
+			return p["water the plants"] + ": water the plants\n" + p["pay rent"] + ": pay rent\n" + p["call bob"] + ": call bob\n"
+		}, "water the plants, pay rent, call bob", "", 0, 0},
+		{"all at once", func(p map[string]string) string {
+			return "buy milk\n" + p["water the plants"] + ": water the roses\n"
+		}, "buy milk, water the roses", "pay rent, call bob", 1, 1},
+		{"everything finished", func(p map[string]string) string {
+			return ""
+		}, "", "pay rent, call bob, water the plants", 0, 0},
+	}
+	for _, c := range cases {
+		list, prefixes := bulkFixture()
+		ids := make(map[string]string)
+		for _, task := range list.Tasks {
+			ids[task.Description] = task.Id
+		}
+		edited, err := list.parseBulk(c.text(prefixes))
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		finished, edits, adds, err := list.Reconcile(edited)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if got := descriptions(list); got != c.expected {
+			t.Errorf("%s: expected '%s', got '%s'", c.name, c.expected, got)
+		}
+		if got := descriptions(&TaskList{TaskList: core.TaskList{Tasks: finished}}); got != c.finished {
+			t.Errorf("%s: expected '%s' finished, got '%s'", c.name, c.finished, got)
+		}
+		if edits != c.edits || adds != c.adds {
+			t.Errorf("%s: expected %d edits and %d adds, got %d and %d", c.name, c.edits, c.adds, edits, adds)
+		}
+		for _, task := range list.Tasks {
+			if id, ok := ids[task.Description]; ok && task.Id != id {
+				t.Errorf("%s: expected %s to keep its id", c.name, task.Description)
+			}
+		}
+	}
+}
+
+func TestReconcileRecurring(t *testing.T) {
+	list, prefixes := bulkFixture()
+	list.Tasks[0].RecurEvery = 7 * everyUnit
+	edited, _ := list.parseBulk(prefixes["water the plants"] + ": water the plants\n")
+	finished, _, _, err := list.Reconcile(edited)
+	if err != nil || len(finished) != 2 {
+		t.Fatalf("Expected two tasks finished, got %v (%v)", finished, err)
+	}
+	if got := descriptions(list); got != "water the plants, pay rent" {
+		t.Fatalf("Expected the recurring task back at the end, got '%s'", got)
+	}
+}
+
+func TestReconcileRefuses(t *testing.T) {
+	cases := map[string]func(p map[string]string) string{
+		"twice": func(p map[string]string) string {
+			return p["pay rent"] + ": pay rent\n" + p["pay rent"] + ": pay rent again\n"
+		},
+		"empty": func(p map[string]string) string {
+			return p["pay rent"] + ":  \n"
+		},
+	}
+	for name, text := range cases {
+		list, prefixes := bulkFixture()
+		edited, err := list.parseBulk(text(prefixes))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if _, _, _, err := list.Reconcile(edited); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+		if got := descriptions(list); got != "pay rent, call bob, water the plants" {
+			t.Errorf("%s: expected the list unchanged, got '%s'", name, got)
+		}
+	}
+	list, _ := bulkFixture()
+	if _, err := list.parseBulk("ffff: who\n"); err == nil || !strings.HasPrefix(err.Error(), "line 1: ") {
+		t.Fatalf("Expected an unknown id to be an error, got %v", err)
+	}
+	if _, _, _, err := list.Reconcile(&TaskList{TaskList: core.TaskList{Tasks: []*Task{{Id: "ffff", Description: "who"}}}}); err == nil {
+		t.Fatal("Expected an id not on the list to be refused")
+	}
+}
+
+func TestCliBulk(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("pay rent\ncall bob\nwater the plants\n"), 0644)
+		editor := filepath.Join(t.TempDir(), "editor")
+		ioutil.WriteFile(editor, []byte("#!/bin/sh\nsed -i -e /bob/d -e 's/rent/the rent/' -e '$abuy milk' \"$1\"\n"), 0755)
+		cmd := exec.Command(tBinary, "--bulk")
+		cmd.Env = append(cmd.Environ(), "VISUAL=", "EDITOR="+editor)
+		out, err := cmd.CombinedOutput()
+		if err != nil || string(out) != "finished 1, edited 1, added 1\n" {
+			t.Fatalf("Expected the edits applied, got '%s' (%v)", out, err)
+		}
+		if out, _ := exec.Command(tBinary).Output(); string(out) != "0 - pay the rent\n1 - water the plants\n2 - buy milk\n" {
+			t.Fatalf("Expected the reconciled list, got '%s'", out)
+		}
+		if text, _ := ioutil.ReadFile("/tmp/tasks.done"); !strings.HasPrefix(string(text), "call bob | ") {
+			t.Fatalf("Expected the removed task finished, got '%s'", text)
+		}
+		before, _ := ioutil.ReadFile("/tmp/tasks")
+		for _, editor := range []string{"false", "sed -i s/^../ffff/"} {
+			cmd := exec.Command(tBinary, "--bulk")
+			cmd.Env = append(cmd.Environ(), "VISUAL=", "EDITOR="+editor)
+			if out, err := cmd.CombinedOutput(); err == nil || !strings.Contains(string(out), "weren't changed") {
+				t.Fatalf("Expected %s to leave the tasks, got '%s' (%v)", editor, out, err)
+			}
+			if after, _ := ioutil.ReadFile("/tmp/tasks"); string(after) != string(before) {
+				t.Fatalf("Expected %s to leave the tasks file, got '%s'", editor, after)
+			}
+		}
+	})
+}
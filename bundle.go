@@ -0,0 +1,220 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// bundleRoots are where the files of a bundle go on this machine, by
+// their role, wherever the environment and the flags put them.
+type bundleRoots struct {
+	tasks  string
+	done   string
+	config string
+	lists  string
+}
+
+// currentBundleRoots returns the roots of this run: the tasks file, its
+// done file, t's config directory and the directory of named lists.
+func currentBundleRoots() (bundleRoots, error) {
+	config, err := configDir()
+	if err != nil {
+		return bundleRoots{}, err
+	}
+	lists, err := getTaskDir()
+	if err != nil {
+		return bundleRoots{}, err
+	}
+	return bundleRoots{tasks: taskFilePath, done: donePath(taskFilePath), config: config, lists: lists}, nil
+}
+
+// bundleConfigFiles are the files of the config directory that go into
+// a bundle.
+var bundleConfigFiles = []string{"config", "schedule", "schedule.last"}
+
+var (
+	// doneSegment matches the names of the done file's segments in a
+	// bundle, like done.2024-Q1.
+	doneSegment = regexp.MustCompile(`^done\.[0-9]{4}-Q[1-4]$`)
+	// doneSuffix matches the names of the done files of named lists and
+	// their segments, like work.done and work.done.2024-Q1.
+	doneSuffix = regexp.MustCompile(`\.done(\.[0-9]{4}-Q[1-4])?$`)
+)
+
+// path returns where the file named name in a bundle goes: tasks for the
+// tasks file, done and its segments like done.2024-Q1 for the done
+// file, config/ for the config directory and lists/ for named lists.
+// Names that could land anywhere else are refused.
+func (r bundleRoots) path(name string) (string, error) {
+	role, file := name, ""
+	if i := strings.Index(name, "/"); i != -1 {
+		role, file = name[:i], name[i+1:]
+	}
+	switch {
+	case name == "tasks":
+		return r.tasks, nil
+	case name == "done" || doneSegment.MatchString(name):
+		return r.done + strings.TrimPrefix(name, "done"), nil
+	case role == "config":
+		for _, known := range bundleConfigFiles {
+			if file == known {
+				return filepath.Join(r.config, file), nil
+			}
+		}
+	case role == "lists" && file != "" && !strings.ContainsAny(file, `/\`) && !strings.HasPrefix(file, "."):
+		return filepath.Join(r.lists, file), nil
+	}
+	return "", fmt.Errorf("unknown file %q in bundle", name)
+}
+
+// bundleFile is a file of a bundle, by its name there and its path on
+// this machine.
+type bundleFile struct {
+	name string
+	path string
+}
+
+// bundleFiles returns the files that exist of those a bundle holds: the
+// tasks file, its done file with its segments, the config and schedule,
+// and the named lists with their done files.
+func (r bundleRoots) bundleFiles() ([]bundleFile, error) {
+	files := make([]bundleFile, 0)
+	add := func(name, path string) {
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			files = append(files, bundleFile{name, path})
+		}
+	}
+	add("tasks", r.tasks)
+	segments, err := filepath.Glob(r.done + ".[0-9][0-9][0-9][0-9]-Q[1-4]")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(segments)
+	for _, segment := range segments {
+		add("done"+strings.TrimPrefix(segment, r.done), segment)
+	}
+	add("done", r.done)
+	for _, file := range bundleConfigFiles {
+		add("config/"+file, filepath.Join(r.config, file))
+	}
+	entries, err := ioutil.ReadDir(r.lists)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.Contains(name, ".") || doneSuffix.MatchString(name) && !strings.HasPrefix(name, ".") {
+			add("lists/"+name, filepath.Join(r.lists, name))
+		}
+	}
+	return files, nil
+}
+
+// writeBundle writes the files as a gzipped tar archive to w.
+func writeBundle(w io.Writer, files []bundleFile) error {
+	zw := gzip.NewWriter(w)
+	tw := tar.NewWriter(zw)
+	for _, file := range files {
+		text, err := ioutil.ReadFile(file.path)
+		if err != nil {
+			return err
+		}
+		info, err := os.Stat(file.path)
+		if err != nil {
+			return err
+		}
+		header := &tar.Header{Name: file.name, Mode: int64(info.Mode().Perm()), Size: int64(len(text)), ModTime: info.ModTime()}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tw.Write(text); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// bundle writes the files of r to a bundle at path and returns how many
+// it holds.
+func bundle(path string, r bundleRoots) (int, error) {
+	files, err := r.bundleFiles()
+	if err != nil {
+		return 0, err
+	}
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return 0, err
+	}
+	if err := writeBundle(out, files); err != nil {
+		out.Close()
+		return 0, err
+	}
+	return len(files), out.Close()
+}
+
+// unbundle restores the files of the bundle read from in to where they
+// go under r and returns their paths. Unless force is set, it refuses
+// to overwrite files, before writing any.
+func unbundle(in io.Reader, r bundleRoots, force bool) ([]string, error) {
+	zr, err := gzip.NewReader(in)
+	if err != nil {
+		return nil, fmt.Errorf("not a bundle: %v", err)
+	}
+	type restored struct {
+		path string
+		mode os.FileMode
+		text []byte
+	}
+	files := make([]restored, 0)
+	existing := make([]string, 0)
+	tr := tar.NewReader(zr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("not a bundle: %v", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			return nil, fmt.Errorf("unknown file %q in bundle", header.Name)
+		}
+		path, err := r.path(header.Name)
+		if err != nil {
+			return nil, err
+		}
+		text, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(path); err == nil {
+			existing = append(existing, path)
+		}
+		files = append(files, restored{path, os.FileMode(header.Mode).Perm() | 0600, text})
+	}
+	if len(existing) > 0 && !force {
+		return nil, fmt.Errorf("%s already exists, --force overwrites it", strings.Join(existing, ", "))
+	}
+	paths := make([]string, 0, len(files))
+	for _, file := range files {
+		if err := os.MkdirAll(filepath.Dir(file.path), 0700); err != nil {
+			return paths, err
+		}
+		if err := ioutil.WriteFile(file.path, file.text, file.mode); err != nil {
+			return paths, err
+		}
+		paths = append(paths, file.path)
+	}
+	return paths, nil
+}
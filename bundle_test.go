@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBundlePath(t *testing.T) {
+	r := bundleRoots{tasks: "/a/tasks", done: "/b/done", config: "/c/t", lists: "/d/.t"}
+	cases := map[string]string{
+		"tasks":           "/a/tasks",
+		"done":            "/b/done",
+		"done.2024-Q1":    "/b/done.2024-Q1",
+		"config/config":   "/c/t/config",
+		"config/schedule": "/c/t/schedule",
+		"lists/work":      "/d/.t/work",
+		"lists/work.done": "/d/.t/work.done",
+	}
+	for name, expected := range cases {
+		if path, err := r.path(name); err != nil || path != filepath.FromSlash(expected) {
+			t.Errorf("%s: expected %s, got %s (%v)", name, expected, path, err)
+		}
+	}
+	for _, name := range []string{"../tasks", "/etc/passwd", "done.x", "config/../../x", "config/other", "lists/../x", "lists/..", "lists/"} {
+		if _, err := r.path(name); err == nil {
+			t.Errorf("Expected %s to be refused", name)
+		}
+	}
+}
+
+// bundleEnv returns an environment that keeps all of t's files under
+// dir, the tasks file and the done file set through the environment.
+func bundleEnv(dir string) []string {
+	return append(os.Environ(),
+		"T_TASKS_FILE="+filepath.Join(dir, "home", "tasks"),
+		"T_DONE_FILE="+filepath.Join(dir, "sync", "finished"),
+		"T_TASKS_DIR="+filepath.Join(dir, "lists"),
+		"XDG_CONFIG_HOME="+filepath.Join(dir, "config"))
+}
+
+func runWithEnv(t *testing.T, env []string, args ...string) string {
+	cmd := exec.Command(tBinary, args...)
+	cmd.Env = env
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("t %s: %v: %s", strings.Join(args, " "), err, out)
+	}
+	return string(out)
+}
+
+func TestCliBundleRoundTrip(t *testing.T) {
+	old, fresh := t.TempDir(), t.TempDir()
+	oldEnv, freshEnv := bundleEnv(old), bundleEnv(fresh)
+	os.MkdirAll(filepath.Join(old, "home"), 0700)
+	os.MkdirAll(filepath.Join(old, "sync"), 0700)
+	os.MkdirAll(filepath.Join(old, "config", "t"), 0700)
+	ioutil.WriteFile(filepath.Join(old, "config", "t", "config"), []byte("index_base = 1\n"), 0644)
+	runWithEnv(t, oldEnv, "pay rent")
+	runWithEnv(t, oldEnv, "call bob")
+	runWithEnv(t, oldEnv, "-f", "1")
+	runWithEnv(t, oldEnv, "-l", "work", "write report")
+	commands := [][]string{{}, {"-D"}, {"-l", "work"}}
+	expected := make([]string, 0)
+	for _, args := range commands {
+		expected = append(expected, runWithEnv(t, oldEnv, args...))
+	}
+	archive := filepath.Join(t.TempDir(), "out.tar.gz")
+	if out := runWithEnv(t, oldEnv, "--bundle", archive); out != "bundled 4 files\n" {
+		t.Fatalf("Expected 4 files bundled, got '%s'", out)
+	}
+
+	os.MkdirAll(filepath.Join(fresh, "home"), 0700)
+	out := runWithEnv(t, freshEnv, "--unbundle", archive)
+	if !strings.Contains(out, "restored "+filepath.Join(fresh, "sync", "finished")) {
+		t.Fatalf("Expected the done file restored to T_DONE_FILE, got '%s'", out)
+	}
+	for i, args := range commands {
+		if out := runWithEnv(t, freshEnv, args...); out != expected[i] {
+			t.Errorf("t %s: expected '%s' as before, got '%s'", strings.Join(args, " "), expected[i], out)
+		}
+	}
+
+	cmd := exec.Command(tBinary, "--unbundle", archive)
+	cmd.Env = freshEnv
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err == nil || !strings.Contains(stderr.String(), "--force") {
+		t.Fatalf("Expected existing files not to be overwritten, got '%s' (%v)", stderr.String(), err)
+	}
+	runWithEnv(t, freshEnv, "-f", "1")
+	runWithEnv(t, freshEnv, "--unbundle", archive, "--force")
+	if out := runWithEnv(t, freshEnv); out != expected[0] {
+		t.Fatalf("Expected --force to restore the tasks, got '%s'", out)
+	}
+}
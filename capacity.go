@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// capacityLargest is how many of the largest tasks --capacity lists.
+const capacityLargest = 5
+
+// capacityCheck is what --capacity found: the work due by a date against
+// the time there is for it until then.
+type capacityCheck struct {
+	by        time.Time
+	weekdays  int
+	daily     time.Duration
+	committed time.Duration
+	// largest are the ids of the estimated tasks due by the date, the
+	// largest first, and unestimated those of the tasks without an est:.
+	largest     []int
+	unestimated []int
+}
+
+// available is the time there is for the work, daily on each weekday.
+func (c capacityCheck) available() time.Duration {
+	return time.Duration(c.weekdays) * c.daily
+}
+
+// over is how much more work is due than there is time for, negative if
+// there is time to spare.
+func (c capacityCheck) over() time.Duration {
+	return c.committed - c.available()
+}
+
+// countWeekdays returns the number of weekdays from today up to and
+// including the given day.
+func countWeekdays(now time.Time, by time.Time) int {
+	n := 0
+	for day := startOfDay(now); !day.After(by); day = day.AddDate(0, 0, 1) {
+		if day.Weekday() != time.Saturday && day.Weekday() != time.Sunday {
+			n++
+		}
+	}
+	return n
+}
+
+// checkCapacity sums up the est: estimates of the tasks due on or before
+// by, overdue ones included, against daily on each weekday until then.
+func (t *TaskList) checkCapacity(by time.Time, now time.Time, daily time.Duration) capacityCheck {
+	check := capacityCheck{
+		by:          by,
+		weekdays:    countWeekdays(now, by),
+		daily:       daily,
+		largest:     make([]int, 0),
+		unestimated: make([]int, 0),
+	}
+	for taskId, task := range t.Tasks {
+		if task.DueAt.IsZero() || task.DueAt.After(by) {
+			continue
+		}
+		estimate, ok := estimate(task)
+		if !ok {
+			check.unestimated = append(check.unestimated, taskId)
+			continue
+		}
+		check.committed += estimate
+		check.largest = append(check.largest, taskId)
+	}
+	sort.SliceStable(check.largest, func(i, j int) bool {
+		a, _ := estimate(t.Tasks[check.largest[i]])
+		b, _ := estimate(t.Tasks[check.largest[j]])
+		return a > b
+	})
+	if len(check.largest) > capacityLargest {
+		check.largest = check.largest[:capacityLargest]
+	}
+	return check
+}
+
+// printCapacity tells whether the work due fits, then lists the largest
+// tasks and those that weren't counted for want of an estimate.
+func (t *TaskList) printCapacity(w io.Writer, check capacityCheck, opts formatOptions) {
+	fmt.Fprintf(w, "due by %s: %s of work, %s available (%d weekdays at %s)\n", displayDate(check.by),
+		formatEstimate(check.committed), formatEstimate(check.available()), check.weekdays, formatEstimate(check.daily))
+	if over := check.over(); over > 0 {
+		fmt.Fprintf(w, "over-committed by %s\n", formatEstimate(over))
+	} else {
+		fmt.Fprintf(w, "it fits, with %s to spare\n", formatEstimate(-over))
+	}
+	if len(check.largest) > 0 {
+		fmt.Fprintln(w, "largest:")
+		for _, taskId := range check.largest {
+			fmt.Fprintln(w, "  "+formatTask(taskId, t.Tasks[taskId], opts))
+		}
+	}
+	if len(check.unestimated) > 0 {
+		fmt.Fprintf(w, "not counted, %d without an est: estimate:\n", len(check.unestimated))
+		for _, taskId := range check.unestimated {
+			fmt.Fprintln(w, "  "+formatTask(taskId, t.Tasks[taskId], opts))
+		}
+	}
+}
+
+// jsonCapacity is what --capacity --json prints. Durations are in
+// minutes.
+type jsonCapacity struct {
+	By            string     `json:"by"`
+	Weekdays      int        `json:"weekdays"`
+	DailyCapacity int        `json:"daily_capacity"`
+	Available     int        `json:"available"`
+	Committed     int        `json:"committed"`
+	Over          int        `json:"over"`
+	OverCommitted bool       `json:"over_committed"`
+	Largest       []jsonTask `json:"largest"`
+	Unestimated   []jsonTask `json:"unestimated"`
+}
+
+// writeCapacityJSON writes the check to w as JSON on one line.
+func (t *TaskList) writeCapacityJSON(w io.Writer, check capacityCheck) error {
+	minutes := func(d time.Duration) int { return int(d.Round(time.Minute) / time.Minute) }
+	return json.NewEncoder(w).Encode(jsonCapacity{
+		By:            check.by.Format(dateLayout),
+		Weekdays:      check.weekdays,
+		DailyCapacity: minutes(check.daily),
+		Available:     minutes(check.available()),
+		Committed:     minutes(check.committed),
+		Over:          minutes(check.over()),
+		OverCommitted: check.over() > 0,
+		Largest:       t.jsonTasks(check.largest, ""),
+		Unestimated:   t.jsonTasks(check.unestimated, ""),
+	})
+}
+
+// dailyCapacity reads the daily_capacity setting, which is plan_budget
+// unless set.
+func dailyCapacity(config Config) (time.Duration, error) {
+	value, ok := config.Get("daily_capacity")
+	if !ok {
+		return planBudget(config)
+	}
+	capacity, err := time.ParseDuration(value)
+	if err != nil || capacity <= 0 {
+		return 0, fmt.Errorf("invalid daily_capacity = %s, expected e.g. 4h or 6h30m", value)
+	}
+	return capacity, nil
+}
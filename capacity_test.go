@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCountWeekdays(t *testing.T) {
+	wednesday := time.Date(2024, 6, 12, 9, 0, 0, 0, time.UTC)
+	for by, expected := range map[string]int{"2024-06-12": 1, "2024-06-14": 3, "2024-06-16": 3, "2024-06-17": 4, "2024-06-11": 0} {
+		day, _ := time.Parse(dateLayout, by)
+		if n := countWeekdays(wednesday, day); n != expected {
+			t.Fatalf("Expected %d weekdays up to %s, got %d", expected, by, n)
+		}
+	}
+}
+
+func TestCheckCapacity(t *testing.T) {
+	list := &TaskList{}
+	list.UnmarshalText([]byte(strings.Join([]string{
+		"write spec est:3h | due:2024-06-13",
+		"no date est:8h",
+		"review est:2h | due:2024-06-14",
+		"call bank | due:2024-06-12",
+		"overdue est:4h30m | due:2024-06-01",
+		"later est:9h | due:2024-06-20",
+		"ship est:5h | due:2024-06-14",
+	}, "\n")))
+	now := time.Date(2024, 6, 12, 9, 0, 0, 0, time.UTC)
+	by, _ := time.Parse(dateLayout, "2024-06-14")
+	check := list.checkCapacity(by, now, 4*time.Hour)
+	if check.committed != 14*time.Hour+30*time.Minute || check.available() != 12*time.Hour || check.over() != 2*time.Hour+30*time.Minute {
+		t.Fatalf("Expected 14h30m against 12h, got %v against %v", check.committed, check.available())
+	}
+	if !reflect.DeepEqual(check.largest, []int{6, 4, 0, 2}) || !reflect.DeepEqual(check.unestimated, []int{3}) {
+		t.Fatalf("Unexpected tasks: largest %v, unestimated %v", check.largest, check.unestimated)
+	}
+
+	var out bytes.Buffer
+	list.printCapacity(&out, check, formatOptions{now: now, plain: true})
+	if !strings.HasPrefix(out.String(), "due by 2024-06-14: 14h30m of work, 12h available (3 weekdays at 4h)\nover-committed by 2h30m\nlargest:\n  6 - ship") ||
+		!strings.HasSuffix(out.String(), "not counted, 1 without an est: estimate:\n  3 - call bank (due 2024-06-12)\n") {
+		t.Fatalf("Unexpected report:\n%s", out.String())
+	}
+
+	check = list.checkCapacity(by, now, 6*time.Hour)
+	out.Reset()
+	list.printCapacity(&out, check, formatOptions{now: now, plain: true})
+	if !strings.Contains(out.String(), "it fits, with 3h30m to spare\n") {
+		t.Fatalf("Expected time to spare, got:\n%s", out.String())
+	}
+}
+
+func TestDailyCapacity(t *testing.T) {
+	for _, c := range []struct {
+		values   map[string]string
+		expected time.Duration
+	}{
+		{map[string]string{}, 4 * time.Hour},
+		{map[string]string{"plan_budget": "5h"}, 5 * time.Hour},
+		{map[string]string{"plan_budget": "5h", "daily_capacity": "7h30m"}, 7*time.Hour + 30*time.Minute},
+	} {
+		capacity, err := dailyCapacity(Config{values: c.values})
+		if err != nil || capacity != c.expected {
+			t.Fatalf("Expected %v for %v, got %v (%v)", c.expected, c.values, capacity, err)
+		}
+	}
+	if _, err := dailyCapacity(Config{values: map[string]string{"daily_capacity": "lots"}}); err == nil {
+		t.Fatal("Expected an invalid daily_capacity to be refused")
+	}
+}
+
+func TestCliCapacityJSON(t *testing.T) {
+	withCliSetup(t, func() {
+		exec.Command(tBinary, "--due", "today", "write spec est:3h").Run()
+		exec.Command(tBinary, "--due", "today", "call bank").Run()
+		out, err := exec.Command(tBinary, "--capacity", "today", "--json").Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var check jsonCapacity
+		if err := json.Unmarshal(out, &check); err != nil {
+			t.Fatalf("Expected JSON, got '%s' (%v)", out, err)
+		}
+		if check.Committed != 180 || len(check.Largest) != 1 || len(check.Unestimated) != 1 || check.Unestimated[0].Text != "call bank" {
+			t.Fatalf("Unexpected check: %+v", check)
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+// colorStrike is the escape sequence for struck-through text.
+const colorStrike = "\x1b[9m"
+
+// seenPath returns the path of the file --changed keeps the last seen
+// snapshot of a tasks file in.
+func seenPath(path string) string {
+	return path + ".seen"
+}
+
+// seenState is the tasks file as --changed last showed it, with its
+// hash to tell quickly whether anything changed since.
+type seenState struct {
+	Hash  string `json:"hash"`
+	Tasks string `json:"tasks"`
+}
+
+// formatChange renders a change for --changed: "+ " and the listing of
+// a new task, "~ " and that of a changed one, and "- " and the struck
+// through description of a task that is gone.
+func formatChange(t *TaskList, change taskChange, opts formatOptions) string {
+	if change.after == nil {
+		description := lineBreaks.Replace(change.before.Description)
+		if opts.color {
+			description = colorStrike + description + colorReset
+		}
+		return "- " + description
+	}
+	taskId := t.IndexOf(change.after.Id)
+	if change.before == nil {
+		return "+ " + formatTask(taskId, t.Tasks[taskId], opts)
+	}
+	return "~ " + formatTask(taskId, t.Tasks[taskId], opts)
+}
+
+// changedTasks handles --changed: it writes to w how the list of the
+// tasks file at path changed since it was last looked at this way, all
+// of it the first time, and records it as seen.
+func changedTasks(w io.Writer, t *TaskList, path string, opts formatOptions) error {
+	text, err := t.MarshalText()
+	if err != nil {
+		return err
+	}
+	hash := core.Hash(string(text))
+	var seen seenState
+	if previous, err := ioutil.ReadFile(seenPath(path)); err == nil {
+		if err := json.Unmarshal(previous, &seen); err != nil {
+			return fmt.Errorf("invalid %s: %v", seenPath(path), err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if seen.Hash == hash {
+		return nil
+	}
+	before, now := &TaskList{}, &TaskList{}
+	if err := before.UnmarshalText([]byte(seen.Tasks)); err != nil {
+		return err
+	}
+	// Only the tasks of the file itself are compared; those of included
+	// files aren't in its text.
+	if err := now.UnmarshalText(text); err != nil {
+		return err
+	}
+	for _, change := range compareTasks(before, now) {
+		fmt.Fprintln(w, formatChange(t, change, opts))
+	}
+	state, err := json.Marshal(seenState{hash, string(text)})
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(seenPath(path), state, 0600)
+}
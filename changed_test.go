@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestChangedTasks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks")
+	opts := formatOptions{now: time.Now()}
+	tasklist := &TaskList{}
+	tasklist.Add("pay rent")
+	tasklist.Add("call bob")
+	tasklist.Add("fix deck")
+	var out bytes.Buffer
+	steps := []struct {
+		change   func()
+		expected string
+	}{
+		{func() {}, "+ 0 - pay rent\n+ 1 - call bob\n+ 2 - fix deck\n"},
+		{func() {}, ""},
+		{func() {
+			tasklist.Finish(0)
+			tasklist.Edit(0, "call bob back")
+			tasklist.Add("buy milk")
+		}, "- pay rent\n~ 0 - call bob back\n+ 2 - buy milk\n"},
+		{func() {}, ""},
+	}
+	for i, step := range steps {
+		step.change()
+		out.Reset()
+		if err := changedTasks(&out, tasklist, path, opts); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != step.expected {
+			t.Fatalf("Step %d: expected '%s', got '%s'", i, step.expected, out.String())
+		}
+	}
+}
+
+func TestFormatChangeStrike(t *testing.T) {
+	change := taskChange{before: &Task{Description: "pay rent"}}
+	if line := formatChange(&TaskList{}, change, formatOptions{color: true}); line != "- "+colorStrike+"pay rent"+colorReset {
+		t.Fatalf("Expected the gone task struck through, got %q", line)
+	}
+}
+
+func TestCliChangedStateRemovedWithList(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("pay rent"), 0644)
+		out, err := exec.Command(tBinary, "--changed").Output()
+		if err != nil || string(out) != "+ 0 - pay rent\n" {
+			t.Fatalf("Expected the task to be new, got '%s' (%v)", out, err)
+		}
+		if _, err := os.Stat("/tmp/tasks.seen"); err != nil {
+			t.Fatal("Expected the seen state to be recorded")
+		}
+		if err := exec.Command(tBinary, "-d", "0").Run(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat("/tmp/tasks.seen"); !os.IsNotExist(err) {
+			t.Fatal("Expected the seen state to go with the list")
+		}
+	})
+}
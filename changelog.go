@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// changelogMisc groups the finished tasks without a project or a tag.
+const changelogMisc = "(misc)"
+
+// defaultChangelog is the window of a bare --changelog.
+const defaultChangelog = "7d"
+
+// changelogGroup is the tasks of a project or tag finished in the
+// window, in the order they were finished.
+type changelogGroup struct {
+	name  string
+	tasks []*Task
+}
+
+// changelogKey returns what a finished task is grouped under: its proj:
+// project, otherwise its first +tag or #tag, and the word of the
+// description it came from.
+func changelogKey(task *Task) (string, string) {
+	for _, word := range strings.Fields(task.Description) {
+		if strings.HasPrefix(word, "proj:") && len(word) > len("proj:") {
+			return word[len("proj:"):], word
+		}
+	}
+	for _, word := range strings.Fields(task.Description) {
+		if isTag(word) && word[0] != '@' {
+			return strings.ToLower(word[1:]), word
+		}
+	}
+	return changelogMisc, ""
+}
+
+// changelog groups the tasks finished from since up to until by project
+// or tag, the largest group first.
+func changelog(done []*Task, since, until time.Time) []changelogGroup {
+	groups := make(map[string]*changelogGroup)
+	for _, task := range done {
+		if task.DoneAt.Before(since) || !task.DoneAt.Before(until) {
+			continue
+		}
+		key, _ := changelogKey(task)
+		group, ok := groups[key]
+		if !ok {
+			group = &changelogGroup{name: key}
+			groups[key] = group
+		}
+		group.tasks = append(group.tasks, task)
+	}
+	list := make([]changelogGroup, 0, len(groups))
+	for _, group := range groups {
+		sort.SliceStable(group.tasks, func(i, j int) bool {
+			return group.tasks[i].DoneAt.Before(group.tasks[j].DoneAt)
+		})
+		list = append(list, *group)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if len(list[i].tasks) != len(list[j].tasks) {
+			return len(list[i].tasks) > len(list[j].tasks)
+		}
+		if (list[i].name == changelogMisc) != (list[j].name == changelogMisc) {
+			return list[j].name == changelogMisc
+		}
+		return list[i].name < list[j].name
+	})
+	return list
+}
+
+// writeChangelog writes the groups as Markdown, a line per group like
+// "**website**: fixed login redirect (Tue), updated footer (Wed)". Days
+// are weekdays for a window of a week at most, and dates otherwise.
+func writeChangelog(w io.Writer, groups []changelogGroup, since, until time.Time) {
+	day := "Mon"
+	if until.Sub(since) > 7*24*time.Hour {
+		day = "Jan 2"
+	}
+	for _, group := range groups {
+		entries := make([]string, 0, len(group.tasks))
+		for _, task := range group.tasks {
+			_, word := changelogKey(task)
+			description := task.Description
+			if word != "" {
+				description = strings.Join(strings.Fields(strings.Replace(" "+description+" ", " "+word+" ", " ", 1)), " ")
+			}
+			entries = append(entries, fmt.Sprintf("%s (%s)", description, task.DoneAt.Local().Format(day)))
+		}
+		fmt.Fprintf(w, "**%s**: %s\n", group.name, strings.Join(entries, ", "))
+	}
+}
+
+// parseUntil parses the day given to --until, and returns the end of
+// it.
+func parseUntil(s string, loc *time.Location) (time.Time, error) {
+	until, err := parseDate(s, loc)
+	if err == errNotADate {
+		return time.Time{}, fmt.Errorf("invalid --until %q, expected a date (%s)", s, dateForms())
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return until.AddDate(0, 0, 1), nil
+}
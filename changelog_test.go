@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChangelog(t *testing.T) {
+	done := &TaskList{}
+	done.UnmarshalText([]byte(strings.Join([]string{
+		"updated footer +website | done:2024-06-05T10:00:00Z",
+		"fixed login redirect +website | done:2024-06-04T10:00:00Z",
+		"renewed certificate proj:infra +website | done:2024-06-06T10:00:00Z",
+		"called the bank @phone | done:2024-06-03T10:00:00Z",
+		"too early +website | done:2024-05-20T10:00:00Z",
+		"too late +website | done:2024-06-08T10:00:00Z",
+		"no time +website",
+	}, "\n")))
+	since := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2024, 6, 8, 0, 0, 0, 0, time.UTC)
+	groups := changelog(done.Tasks, since, until)
+	if len(groups) != 3 || groups[0].name != "website" || groups[1].name != "infra" || groups[2].name != changelogMisc {
+		t.Fatalf("Expected website, infra and misc, got %v", groups)
+	}
+	if len(groups[0].tasks) != 2 || groups[0].tasks[0].Description != "fixed login redirect +website" {
+		t.Fatalf("Expected the website tasks in the order they were finished, got %v", groups[0].tasks)
+	}
+
+	var out bytes.Buffer
+	defer func(saved *time.Location) { time.Local = saved }(time.Local)
+	time.Local = time.UTC
+	writeChangelog(&out, groups, since, until)
+	expected := "**website**: fixed login redirect (Tue), updated footer (Wed)\n" +
+		"**infra**: renewed certificate +website (Thu)\n" +
+		"**(misc)**: called the bank @phone (Mon)\n"
+	if out.String() != expected {
+		t.Fatalf("Expected\n%s\ngot\n%s", expected, out.String())
+	}
+	out.Reset()
+	writeChangelog(&out, groups[2:], since, until.AddDate(0, 0, 7))
+	if out.String() != "**(misc)**: called the bank @phone (Jun 3)\n" {
+		t.Fatalf("Expected dates for a window over a week, got %s", out.String())
+	}
+}
+
+func TestParseUntil(t *testing.T) {
+	until, err := parseUntil("2024-06-07", time.UTC)
+	if err != nil || !until.Equal(time.Date(2024, 6, 8, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Expected the end of June 7, got %v (%v)", until, err)
+	}
+	if _, err := parseUntil("7d", time.UTC); err == nil {
+		t.Fatal("Expected a duration to be refused")
+	}
+}
+
+func TestCliChangelog(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks.done", []byte(strings.Join([]string{
+			"fixed login redirect +website | done:2024-06-04T10:00:00Z",
+			"called the bank | done:2024-06-03T10:00:00Z",
+			"later +website | done:2024-06-10T10:00:00Z",
+		}, "\n")), 0644)
+		cmd := exec.Command(tBinary, "--changelog", "--since", "2024-06-01", "--until", "2024-06-07")
+		cmd.Env = append(cmd.Environ(), "TZ=UTC")
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := "**website**: fixed login redirect (Tue)\n**(misc)**: called the bank (Mon)\n"
+		if string(out) != expected {
+			t.Fatalf("Expected '%s', got '%s'", expected, out)
+		}
+		if out, _ := exec.Command(tBinary, "--changelog", "7d").Output(); string(out) != "" {
+			t.Fatalf("Expected nothing finished in the last 7 days, got '%s'", out)
+		}
+		for _, args := range [][]string{{"--changelog", "7d", "--since", "2024-06-01"}, {"--until", "2024-06-07"}} {
+			err := exec.Command(tBinary, args...).Run()
+			if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 2 {
+				t.Fatalf("Expected t %v to exit with 2, got %v", args, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,435 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+// parseDescriptionLimit reads the description_limit setting.
+func parseDescriptionLimit(config Config) (int, error) {
+	value, ok := config.Get("description_limit")
+	if !ok {
+		return core.MaxDescriptionLength, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 {
+		return 0, fmt.Errorf("invalid description_limit = %s, expected a number of bytes", value)
+	}
+	return limit, nil
+}
+
+// dateKeys are the metadata keys holding dates.
+var dateKeys = map[string]bool{"due": true, "snooze": true}
+
+// Problem is something suspicious t --check found in a tasks file.
+type Problem struct {
+	Line    int
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("line %d: %s", p.Line, p.Message)
+}
+
+// checkTasks parses the text of a tasks file line by line, more strictly
+// than UnmarshalText does, and returns everything suspicious in it.
+func checkTasks(text []byte) []Problem {
+	problems := make([]Problem, 0)
+	seen := make(map[string]int)
+	for i, line := range strings.Split(string(text), "\n") {
+		n := i + 1
+		if line == "" {
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			problems = append(problems, Problem{n, "line of only whitespace, skipped"})
+			continue
+		}
+		if !utf8.ValidString(line) {
+			problems = append(problems, Problem{n, "invalid UTF-8"})
+			continue
+		}
+		if marker := conflictMarker(line); marker != "" {
+			problems = append(problems, Problem{n, "merge conflict marker " + marker})
+			continue
+		}
+		if strings.IndexFunc(line, isControl) != -1 {
+			problems = append(problems, Problem{n, "control characters"})
+		}
+		if len(line) > core.DescriptionLimit {
+			problems = append(problems, Problem{n, fmt.Sprintf("line is %d bytes long", len(line))})
+		}
+		if sep := strings.LastIndex(line, core.MetaSeparator); sep != -1 {
+			if _, ok := core.ParseMeta(line[sep+len(core.MetaSeparator):]); !ok && looksLikeMeta(line[sep:]) {
+				problems = append(problems, Problem{n, "unparseable metadata, the whole line is read as the description"})
+			}
+		}
+		task := Task{}
+		if err := task.UnmarshalText([]byte(line)); err != nil {
+			problems = append(problems, Problem{n, err.Error()})
+			continue
+		}
+		// A tombstone may share its id with a task added again since.
+		if !task.DeletedAt.IsZero() {
+			continue
+		}
+		if first, ok := seen[task.Id]; ok {
+			problems = append(problems, Problem{n, fmt.Sprintf("duplicate id %s, same as line %d", task.Id, first)})
+		} else {
+			seen[task.Id] = n
+		}
+	}
+	return problems
+}
+
+// looksLikeMeta reports whether any word of text is a key:value token.
+func looksLikeMeta(text string) bool {
+	for _, field := range strings.Fields(text) {
+		if sep := strings.Index(field, ":"); sep > 0 && sep < len(field)-1 {
+			return true
+		}
+	}
+	return false
+}
+
+// fixTasks applies the repairs t --check --fix knows to be safe:
+// replacing invalid UTF-8 and stripping control characters, splitting
+// merge conflicts into the tasks of both sides, dropping lines that are
+// exact duplicates of an earlier one, rewriting sloppy dates like
+// due:2024-6-1 to YYYY-MM-DD and dropping the metadata values that still
+// can't be read, like due:friday, which checkFile keeps in corruptPath
+// with the rest of the file as it was. It returns the repaired text and
+// the number of fixes.
+func fixTasks(text []byte) ([]byte, int) {
+	fixes := 0
+	lines := strings.Split(string(text), "\n")
+	for i, line := range lines {
+		if cleaned := cleanLine(line); cleaned != line {
+			lines[i] = cleaned
+			fixes++
+		}
+	}
+	lines, n := splitConflicts(lines)
+	fixes += n
+	lines, n = dropDuplicates(lines)
+	fixes += n
+	for i, line := range lines {
+		sep := strings.LastIndex(line, core.MetaSeparator)
+		if sep == -1 {
+			continue
+		}
+		if _, ok := core.ParseMeta(line[sep+len(core.MetaSeparator):]); !ok {
+			continue
+		}
+		fields := strings.Fields(line[sep+len(core.MetaSeparator):])
+		kept := make([]string, 0, len(fields))
+		changed := false
+		for _, field := range fields {
+			colon := strings.Index(field, ":")
+			key, value := field[:colon], field[colon+1:]
+			if fixed, ok := normalizeDate(value); dateKeys[key] && ok && fixed != value {
+				value = fixed
+				changed = true
+				fixes++
+			}
+			if (&Task{}).SetMeta([][2]string{{key, core.UnescapeMetaValue(value)}}) != nil {
+				changed = true
+				fixes++
+				continue
+			}
+			kept = append(kept, key+":"+value)
+		}
+		if !changed {
+			continue
+		}
+		if len(kept) == 0 {
+			lines[i] = line[:sep]
+		} else {
+			lines[i] = line[:sep+len(core.MetaSeparator)] + strings.Join(kept, " ")
+		}
+	}
+	return []byte(strings.Join(lines, "\n")), fixes
+}
+
+// isControl reports whether r is a control character that has no place
+// in the tasks file. Tabs do, in descriptions added with --raw.
+func isControl(r rune) bool {
+	return r != '\t' && unicode.IsControl(r)
+}
+
+// cleanLine replaces the invalid UTF-8 in a line with U+FFFD and strips
+// its control characters.
+func cleanLine(line string) string {
+	line = strings.ToValidUTF8(line, string(utf8.RuneError))
+	if strings.IndexFunc(line, isControl) == -1 {
+		return line
+	}
+	return strings.Map(func(r rune) rune {
+		if isControl(r) {
+			return -1
+		}
+		return r
+	}, line)
+}
+
+// The markers of a merge conflict, as git and other tools leave them in
+// a tasks file both sides of a sync changed: ours come after the start,
+// the common base, with diff3, after conflictBase, and theirs after the
+// split.
+const (
+	conflictStart = "<<<<<<<"
+	conflictBase  = "|||||||"
+	conflictSplit = "======="
+	conflictEnd   = ">>>>>>>"
+)
+
+// conflictTag is added to the tasks --check --fix takes out of a merge
+// conflict, so they can be looked at.
+const conflictTag = "#conflict"
+
+// conflictMarker returns the merge conflict marker line is, or "".
+func conflictMarker(line string) string {
+	for _, marker := range []string{conflictStart, conflictBase, conflictSplit, conflictEnd} {
+		if line == marker || strings.HasPrefix(line, marker+" ") {
+			return marker
+		}
+	}
+	return ""
+}
+
+// splitConflicts replaces each merge conflict in lines with the tasks of
+// both sides, in order, those only on one side tagged with conflictTag.
+// A task only on one side that a tombstone anywhere in lines says was
+// deleted after its last edit is dropped; one edited after is kept, and
+// its tombstones dropped instead. The base of a diff3 conflict is
+// dropped, and a conflict without an end left as it is. It returns the
+// lines and the number of conflicts.
+func splitConflicts(lines []string) ([]string, int) {
+	deleted := deletionTimes(lines)
+	revived := make(map[string]bool)
+	out := make([]string, 0, len(lines))
+	conflicts := 0
+	for i := 0; i < len(lines); i++ {
+		if conflictMarker(lines[i]) != conflictStart {
+			out = append(out, lines[i])
+			continue
+		}
+		end := i + 1
+		for end < len(lines) && conflictMarker(lines[end]) != conflictEnd {
+			end++
+		}
+		if end == len(lines) {
+			out = append(out, lines[i:]...)
+			break
+		}
+		sides := [2][]string{}
+		side, base := 0, false
+		for _, line := range lines[i+1 : end] {
+			switch conflictMarker(line) {
+			case conflictBase:
+				base = true
+			case conflictSplit:
+				side, base = 1, false
+			default:
+				if !base && strings.TrimSpace(line) != "" {
+					sides[side] = append(sides[side], line)
+				}
+			}
+		}
+		for n, lines := range sides {
+			for _, line := range lines {
+				task := Task{}
+				parsed := task.UnmarshalText([]byte(line)) == nil
+				switch {
+				case containsWord(sides[1-n], line):
+					if n == 0 {
+						// The same on both sides, so not in conflict.
+						out = append(out, line)
+					}
+				case parsed && !task.DeletedAt.IsZero():
+					// Tombstones aren't listed, there is nothing to look at.
+					out = append(out, line)
+				case parsed && !deleted[task.Id].IsZero() && !lastEdit(&task).After(deleted[task.Id]):
+					// Deleted on the other side after it was last edited.
+				default:
+					if parsed && !deleted[task.Id].IsZero() {
+						revived[task.Id] = true
+					}
+					out = append(out, tagConflict(line))
+				}
+			}
+		}
+		conflicts++
+		i = end
+	}
+	if len(revived) == 0 {
+		return out, conflicts
+	}
+	kept := make([]string, 0, len(out))
+	for _, line := range out {
+		task := Task{}
+		if task.UnmarshalText([]byte(line)) == nil && !task.DeletedAt.IsZero() && revived[task.Id] {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return kept, conflicts
+}
+
+// deletionTimes returns when the tasks of the tombstones in lines were
+// deleted, the latest for each stable id.
+func deletionTimes(lines []string) map[string]time.Time {
+	deleted := make(map[string]time.Time)
+	for _, line := range lines {
+		task := Task{}
+		if task.UnmarshalText([]byte(line)) != nil || task.DeletedAt.IsZero() {
+			continue
+		}
+		if task.DeletedAt.After(deleted[task.Id]) {
+			deleted[task.Id] = task.DeletedAt
+		}
+	}
+	return deleted
+}
+
+// lastEdit returns when a task was last edited, or added if it never
+// was.
+func lastEdit(task *Task) time.Time {
+	if !task.EditedAt.IsZero() {
+		return task.EditedAt
+	}
+	return task.CreatedAt
+}
+
+// tagConflict adds conflictTag to the description of the task on line,
+// keeping its stable id.
+func tagConflict(line string) string {
+	task := Task{}
+	if err := task.UnmarshalText([]byte(line)); err != nil {
+		return line
+	}
+	if !containsWord(strings.Fields(task.Description), conflictTag) {
+		task.Description += " " + conflictTag
+	}
+	text, err := task.MarshalText()
+	if err != nil {
+		return line
+	}
+	return string(text)
+}
+
+// dropDuplicates drops the lines that are the same as an earlier one,
+// and returns the others and how many it dropped.
+func dropDuplicates(lines []string) ([]string, int) {
+	out := make([]string, 0, len(lines))
+	seen := make(map[string]bool)
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" && seen[line] {
+			continue
+		}
+		seen[line] = true
+		out = append(out, line)
+	}
+	return out, len(lines) - len(out)
+}
+
+// normalizeDate parses a date written less strictly than t writes it,
+// like 2024-6-1 or 2024/06/01, and returns it as YYYY-MM-DD.
+func normalizeDate(s string) (string, bool) {
+	for _, layout := range []string{"2006-1-2", "2006/1/2"} {
+		if date, err := time.Parse(layout, s); err == nil {
+			return date.Format(dateLayout), true
+		}
+	}
+	return "", false
+}
+
+// corruptPath returns the file t --check --done --fix moves the broken
+// lines of the done file at path and its segments to, for a look by
+// hand, and t --check --fix keeps the tasks file at path in as it was
+// before it repaired it.
+func corruptPath(path string) string {
+	return path + ".corrupt"
+}
+
+// checkDone handles --check --done: it reports the broken lines of the
+// done file at path and of its segments, and with fix moves them to
+// corruptPath in one transaction, after confirming. It returns the exit
+// status, 1 if broken lines are left.
+func checkDone(path string, fix bool, yes bool) int {
+	segments, err := filepath.Glob(path + ".[0-9][0-9][0-9][0-9]-Q[1-4]")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	sort.Strings(segments)
+	broken := make([]string, 0)
+	cleaned := make(map[string][]byte)
+	names := append(segments, path)
+	for _, name := range names {
+		text, err := ioutil.ReadFile(name)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		lines := strings.Split(string(text), "\n")
+		kept := make([]string, 0, len(lines))
+		for i, line := range lines {
+			if !brokenDoneLine(line) {
+				kept = append(kept, line)
+				continue
+			}
+			fmt.Printf("%s: %s\n", name, Problem{i + 1, "unparseable, cut short?"})
+			broken = append(broken, line)
+		}
+		if len(kept) < len(lines) {
+			cleaned[name] = []byte(strings.Join(kept, "\n"))
+		}
+	}
+	if len(broken) == 0 {
+		return 0
+	}
+	quarantine := corruptPath(path)
+	if !fix || (!yes && !confirm(fmt.Sprintf("Move %d lines to %s?", len(broken), quarantine))) {
+		return 1
+	}
+	text, err := ioutil.ReadFile(quarantine)
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	for _, line := range broken {
+		text = append(text, line+"\n"...)
+	}
+	tx := newTransaction(journalPath(path))
+	err = tx.write(quarantine, text, 0600)
+	for _, name := range names {
+		if text, ok := cleaned[name]; ok && err == nil {
+			err = tx.write(name, text, 0644)
+		}
+	}
+	if err != nil {
+		tx.abort()
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if err := tx.commit(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	fmt.Printf("moved %d lines to %s\n", len(broken), quarantine)
+	return 0
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+func TestCheckTasks(t *testing.T) {
+	text := strings.Join([]string{
+		"fine",
+		"pay rent | due:2024-6-1",
+		"fine",
+		"compare a | b",
+		"broken | due:2024-06-01 oops",
+		strings.Repeat("x", core.MaxDescriptionLength+1),
+		"  \t",
+	}, "\n")
+	problems := checkTasks([]byte(text))
+	expected := []string{
+		`line 2: invalid due date "2024-6-1"`,
+		"line 3: duplicate id " + core.Hash("fine") + ", same as line 1",
+		"line 5: unparseable metadata, the whole line is read as the description",
+		"line 6: line is 10241 bytes long",
+		"line 7: line of only whitespace, skipped",
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("Expected problems %v, got %v", expected, problems)
+	}
+	for i := range expected {
+		if problems[i].String() != expected[i] {
+			t.Fatalf("Expected problem '%s', got '%s'", expected[i], problems[i])
+		}
+	}
+}
+
+func TestFixTasks(t *testing.T) {
+	text := "pay rent | due:2024-6-1 owner:me\nfoo | snooze:2024/07/02\nbar | due:2024-06-01\nShip it | due:friday\nx | owner:me created:yesterday"
+	fixed, fixes := fixTasks([]byte(text))
+	expected := "pay rent | due:2024-06-01 owner:me\nfoo | snooze:2024-07-02\nbar | due:2024-06-01\nShip it\nx | owner:me"
+	if string(fixed) != expected {
+		t.Fatalf("Expected fixed text '%s', got '%s'", expected, fixed)
+	}
+	if fixes != 4 {
+		t.Fatalf("Expected 4 fixes, got %d", fixes)
+	}
+	if problems := checkTasks(fixed); len(problems) != 0 {
+		t.Fatalf("Expected fixed text to check clean, got %v", problems)
+	}
+}
+
+// conflicted is a tasks file a sync left with a merge conflict, a
+// duplicate, a stray control character and a byte that isn't UTF-8.
+var conflicted = strings.Join([]string{
+	"pay rent",
+	"<<<<<<< HEAD",
+	"call bob",
+	"water the plants | due:2024-06-01",
+	"||||||| base",
+	"call bob",
+	"=======",
+	"call bob",
+	"water the plants | due:2024-06-02",
+	">>>>>>> theirs",
+	"pay rent",
+	"buy milk\x07",
+	"caf\xe9",
+}, "\n")
+
+func TestCheckCorruption(t *testing.T) {
+	expected := []string{
+		"line 2: merge conflict marker <<<<<<<",
+		"line 5: merge conflict marker |||||||",
+		"line 6: duplicate id " + core.Hash("call bob") + ", same as line 3",
+		"line 7: merge conflict marker =======",
+		"line 8: duplicate id " + core.Hash("call bob") + ", same as line 3",
+		"line 9: duplicate id " + core.Hash("water the plants") + ", same as line 4",
+		"line 10: merge conflict marker >>>>>>>",
+		"line 11: duplicate id " + core.Hash("pay rent") + ", same as line 1",
+		"line 12: control characters",
+		"line 13: invalid UTF-8",
+	}
+	problems := checkTasks([]byte(conflicted))
+	if len(problems) != len(expected) {
+		t.Fatalf("Expected problems %v, got %v", expected, problems)
+	}
+	for i := range expected {
+		if problems[i].String() != expected[i] {
+			t.Fatalf("Expected problem '%s', got '%s'", expected[i], problems[i])
+		}
+	}
+}
+
+func TestFixCorruption(t *testing.T) {
+	fixed, fixes := fixTasks([]byte(conflicted))
+	id := "id:" + core.Hash("water the plants")
+	expected := strings.Join([]string{
+		"pay rent",
+		"call bob",
+		"water the plants #conflict | " + id + " due:2024-06-01",
+		"water the plants #conflict | " + id + " due:2024-06-02",
+		"buy milk",
+		"caf\uFFFD",
+	}, "\n")
+	if string(fixed) != expected {
+		t.Fatalf("Expected fixed text '%s', got '%s'", expected, fixed)
+	}
+	// Two cleaned lines, a conflict and a duplicate.
+	if fixes != 4 {
+		t.Fatalf("Expected 4 fixes, got %d", fixes)
+	}
+	// The two sides keep the task's id, left for a look by hand.
+	if problems := checkTasks(fixed); len(problems) != 1 || !strings.Contains(problems[0].String(), "duplicate id") {
+		t.Fatalf("Expected only the tasks of the conflict to share an id, got %v", problems)
+	}
+	unterminated := "pay rent\n<<<<<<< HEAD\ncall bob"
+	if fixed, fixes := fixTasks([]byte(unterminated)); string(fixed) != unterminated || fixes != 0 {
+		t.Fatalf("Expected a conflict without an end left alone, got '%s'", fixed)
+	}
+}
+
+func TestSplitConflictsTombstones(t *testing.T) {
+	created := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	line := func(task Task) string {
+		text, _ := task.MarshalText()
+		return string(text)
+	}
+	// Edited on one side, finished on the other, the tombstone of which
+	// a merge appends to the end of the file.
+	merge := func(edited, deleted time.Time) []string {
+		return []string{
+			"pay rent",
+			"<<<<<<< HEAD",
+			line(Task{Description: "call bob at 5", Id: core.Hash("call bob"), CreatedAt: created, EditedAt: edited}),
+			"=======",
+			">>>>>>> theirs",
+			line(Task{Description: "call bob", Id: core.Hash("call bob"), CreatedAt: created, DeletedAt: deleted}),
+		}
+	}
+	early, late := created.Add(time.Hour), created.Add(2*time.Hour)
+	lines, n := splitConflicts(merge(early, late))
+	if n != 1 || len(lines) != 2 || lines[0] != "pay rent" || !strings.Contains(lines[1], "deleted:") {
+		t.Fatalf("Expected a task finished after its edit to stay finished, got %q", lines)
+	}
+	lines, n = splitConflicts(merge(late, early))
+	if n != 1 || len(lines) != 2 || !strings.HasPrefix(lines[1], "call bob at 5 "+conflictTag+" | ") {
+		t.Fatalf("Expected a task edited after it was finished to be kept and its tombstone dropped, got %q", lines)
+	}
+}
+
+func TestCliFsck(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte(conflicted), 0644)
+		if err := exec.Command(tBinary).Run(); err == nil {
+			t.Fatal("Expected a file that isn't UTF-8 refused")
+		}
+		out, err := exec.Command(tBinary, "--fsck").Output()
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 || !strings.Contains(string(out), "line 13: invalid UTF-8") {
+			t.Fatalf("Expected the problems and exit 1, got '%s' (%v)", out, err)
+		}
+		if out, err := exec.Command(tBinary, "--fsck", "--fix", "-y").Output(); err == nil || !strings.HasPrefix(string(out), "applied 4 fixes\n") {
+			t.Fatalf("Expected the fixes applied and the shared id left, got '%s' (%v)", out, err)
+		}
+		if backup, _ := ioutil.ReadFile("/tmp/tasks.corrupt"); string(backup) != conflicted {
+			t.Fatalf("Expected the original kept, got '%s'", backup)
+		}
+		out, err = exec.Command(tBinary, "-g", "conflict").Output()
+		if err != nil || string(out) != "2 - water the plants #conflict (due 2024-06-01)\n3 - water the plants #conflict (due 2024-06-02)\n" {
+			t.Fatalf("Expected the tasks of both sides tagged, got '%s' (%v)", out, err)
+		}
+		ioutil.WriteFile("/tmp/tasks", []byte("pay rent\n"), 0644)
+		if err := exec.Command(tBinary, "--fsck").Run(); err != nil {
+			t.Fatalf("Expected a clean file to pass, got %v", err)
+		}
+	})
+}
+
+func TestDescriptionLimit(t *testing.T) {
+	defer func(limit int) { core.DescriptionLimit = limit }(core.DescriptionLimit)
+	core.DescriptionLimit = 10
+	list := &TaskList{}
+	if _, err := list.Add("a bit too long"); err == nil || !strings.Contains(err.Error(), "14 bytes") {
+		t.Fatalf("Expected an over-limit task to be refused with its size, got %v", err)
+	}
+	if len(list.Tasks) != 0 {
+		t.Fatal("Expected nothing to be added")
+	}
+	list.Add("short")
+	if err := list.Edit(0, "a bit too long"); err == nil || list.Tasks[0].Description != "short" {
+		t.Fatalf("Expected an over-limit edit to be refused, got %v", err)
+	}
+	list.Truncate = true
+	if task, err := list.Add("a bit too long"); err != nil || task.Description != "a bit t…" {
+		t.Fatalf("Expected the task to be truncated, got %v", err)
+	}
+
+	loaded := &TaskList{}
+	if err := loaded.UnmarshalText([]byte("a bit too long | due:2024-06-01")); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Tasks[0].Description != "a bit t…" || loaded.Tasks[0].DueAt.IsZero() {
+		t.Fatalf("Expected a long line to be cut but keep its metadata, got %q", loaded.Tasks[0].Description)
+	}
+}
+
+func TestBlankLines(t *testing.T) {
+	for text, expected := range map[string]string{
+		"":                   "",
+		"\n":                 "",
+		"   ":                "",
+		"foo\n  \nbar\n\t\n": "foo bar",
+	} {
+		tasklist := TaskList{}
+		if err := tasklist.UnmarshalText([]byte(text)); err != nil {
+			t.Fatal(err)
+		}
+		if got := joinDescriptions(tasklist.Tasks); got != expected {
+			t.Fatalf("Expected %q to hold '%s', got '%s'", text, expected, got)
+		}
+	}
+	tasklist := TaskList{TaskList: core.TaskList{Raw: true}}
+	if _, err := tasklist.Add("  \t "); err != core.ErrEmptyDescription {
+		t.Fatalf("Expected a blank task to be refused, got %v", err)
+	}
+	tasklist.Add("foo")
+	if err := tasklist.Edit(0, " "); err != core.ErrEmptyDescription {
+		t.Fatalf("Expected a blank edit to be refused, got %v", err)
+	}
+	tasklist.Tasks = append(tasklist.Tasks, &Task{Description: "   ", Id: core.Hash("   ")})
+	if text, _ := tasklist.MarshalText(); strings.Contains(string(text), "\n") {
+		t.Fatalf("Expected no blank line to be written, got %q", text)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+// defaultCheckpointLimit is how many checkpoints are kept unless the
+// checkpoint_limit setting says otherwise.
+const defaultCheckpointLimit = 20
+
+// checkpointDir returns the directory the checkpoints of the tasks file
+// at path are kept in, like tasks.checkpoints.
+func checkpointDir(path string) string {
+	return path + ".checkpoints"
+}
+
+// checkpointLimit returns how many checkpoints are kept, the
+// checkpoint_limit setting or defaultCheckpointLimit.
+func checkpointLimit(config Config) (int, error) {
+	value, ok := config.Get("checkpoint_limit")
+	if !ok {
+		return defaultCheckpointLimit, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 {
+		return 0, fmt.Errorf("invalid checkpoint_limit = %s, expected a positive number", value)
+	}
+	return limit, nil
+}
+
+// checkpointPrefix names a checkpoint for --restore, for the ones named
+// by a number, which --restore otherwise takes for a backup's.
+const checkpointPrefix = "checkpoint:"
+
+// checkpointPath returns the file of a named checkpoint, refusing names
+// that would end up outside of the checkpoint directory.
+func checkpointPath(path string, name string) (string, error) {
+	if name == "" || strings.HasPrefix(name, ".") || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid checkpoint name %q", name)
+	}
+	return filepath.Join(checkpointDir(path), name), nil
+}
+
+// checkpoints returns the checkpoints of the tasks file at path, oldest
+// first.
+func checkpoints(path string) ([]os.FileInfo, error) {
+	files, err := ioutil.ReadDir(checkpointDir(path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	saved := make([]os.FileInfo, 0, len(files))
+	for _, file := range files {
+		if !file.IsDir() && !strings.HasPrefix(file.Name(), ".") {
+			saved = append(saved, file)
+		}
+	}
+	sort.SliceStable(saved, func(i, j int) bool {
+		return saved[i].ModTime().Before(saved[j].ModTime())
+	})
+	return saved, nil
+}
+
+// saveCheckpoint handles --checkpoint: it saves the tasks file at path
+// as it is now under a name, replacing an earlier checkpoint of that
+// name, and drops the oldest checkpoints beyond limit. Numbers name
+// backups, so a checkpoint can't be saved under one; those saved before
+// are still restored with checkpointPrefix.
+func saveCheckpoint(path string, name string, limit int) error {
+	file, err := checkpointPath(path, name)
+	if err != nil {
+		return err
+	}
+	if strings.Trim(name, "0123456789") == "" {
+		return fmt.Errorf("invalid checkpoint name %q, numbers name backups, like --restore 2", name)
+	}
+	text, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.MkdirAll(checkpointDir(path), 0700); err != nil {
+		return err
+	}
+	if err := core.WriteAtomic(file, text); err != nil {
+		return fmt.Errorf("can't save checkpoint %s: %v", name, err)
+	}
+	saved, err := checkpoints(path)
+	if err != nil {
+		return err
+	}
+	for len(saved) > limit {
+		if err := os.Remove(filepath.Join(checkpointDir(path), saved[0].Name())); err != nil {
+			return err
+		}
+		saved = saved[1:]
+	}
+	return nil
+}
+
+// restoreCheckpoint handles --restore: it saves the tasks file at path
+// as a checkpoint of its own, then replaces it with the named
+// checkpoint in one step. It returns the name the previous state was
+// saved under.
+func restoreCheckpoint(path string, name string, limit int, now time.Time) (string, error) {
+	file, err := checkpointPath(path, name)
+	if err != nil {
+		return "", err
+	}
+	text, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("no checkpoint named %s, see t --checkpoints", name)
+	}
+	if err != nil {
+		return "", err
+	}
+	before, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	previous := "before-restore-" + now.Format("20060102-150405")
+	if err := saveCheckpoint(path, previous, limit); err != nil {
+		return "", err
+	}
+	tx := newTransaction(journalPath(path))
+	if err := tx.write(path, text, 0644); err != nil {
+		return "", err
+	}
+	stageHistory(tx, path, string(before), string(text))
+	if err := tx.commit(); err != nil {
+		return "", err
+	}
+	return previous, nil
+}
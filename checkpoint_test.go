@@ -0,0 +1,100 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckpointPrune(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks")
+	ioutil.WriteFile(path, []byte("foo"), 0644)
+	for i, name := range []string{"a", "b", "c"} {
+		if err := saveCheckpoint(path, name, 2); err != nil {
+			t.Fatal(err)
+		}
+		// Checkpoints are ordered by modification time.
+		modTime := time.Now().Add(time.Duration(i-10) * time.Minute)
+		os.Chtimes(filepath.Join(checkpointDir(path), name), modTime, modTime)
+	}
+	saved, err := checkpoints(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved) != 2 || saved[0].Name() != "b" || saved[1].Name() != "c" {
+		t.Fatalf("Expected checkpoints b and c to be kept, got %v", saved)
+	}
+}
+
+func TestCheckpointName(t *testing.T) {
+	for _, name := range []string{"", "../tasks", ".hidden", `a\b`} {
+		if _, err := checkpointPath("tasks", name); err == nil {
+			t.Fatalf("Expected checkpoint name %q to be rejected", name)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "tasks")
+	for _, name := range []string{"2", "0", "007"} {
+		if err := saveCheckpoint(path, name, 5); err == nil {
+			t.Fatalf("Expected saving a checkpoint named %q to be refused", name)
+		}
+		if _, err := checkpointPath(path, name); err != nil {
+			t.Fatalf("Expected a checkpoint named %q before to be found, got %v", name, err)
+		}
+	}
+	if err := saveCheckpoint(path, "2024-06-01", 5); err != nil {
+		t.Fatalf("Expected a date to be a checkpoint name, got %v", err)
+	}
+}
+
+func TestCliCheckpointRestore(t *testing.T) {
+	withCliSetup(t, func() {
+		defer os.RemoveAll(checkpointDir("/tmp/tasks"))
+		ioutil.WriteFile("/tmp/tasks", []byte("foo\nbar"), 0644)
+		if err := exec.Command(tBinary, "--checkpoint", "pre-cleanup").Run(); err != nil {
+			t.Fatal(err)
+		}
+		ioutil.WriteFile("/tmp/tasks", []byte("baz"), 0644)
+		if err := exec.Command(tBinary, "--restore", "pre-cleanup").Run(); err != nil {
+			t.Fatal(err)
+		}
+		text, _ := ioutil.ReadFile("/tmp/tasks")
+		if string(text) != "foo\nbar" {
+			t.Fatalf("Expected the checkpoint to be restored, got '%s'", text)
+		}
+		saved, _ := checkpoints("/tmp/tasks")
+		if len(saved) != 2 {
+			t.Fatalf("Expected the previous tasks to be saved too, got %d checkpoints", len(saved))
+		}
+		previous, _ := ioutil.ReadFile(filepath.Join(checkpointDir("/tmp/tasks"), saved[1].Name()))
+		if string(previous) != "baz" {
+			t.Fatalf("Expected the previous tasks to be 'baz', got '%s'", previous)
+		}
+		if err := exec.Command(tBinary, "--restore", "missing").Run(); err == nil {
+			t.Fatal("Expected restoring a missing checkpoint to fail")
+		}
+	})
+}
+
+func TestCliRestoreNumberedCheckpoint(t *testing.T) {
+	withCliSetup(t, func() {
+		defer os.RemoveAll(checkpointDir("/tmp/tasks"))
+		// Saved before numbers were refused as checkpoint names.
+		os.MkdirAll(checkpointDir("/tmp/tasks"), 0700)
+		ioutil.WriteFile(filepath.Join(checkpointDir("/tmp/tasks"), "2024"), []byte("foo"), 0644)
+		ioutil.WriteFile("/tmp/tasks", []byte("bar"), 0644)
+		if err := exec.Command(tBinary, "--checkpoint", "2025").Run(); err == nil {
+			t.Fatal("Expected a checkpoint named by a number to be refused")
+		}
+		out, err := exec.Command(tBinary, "--restore", "checkpoint:2024").Output()
+		if err != nil || !strings.HasPrefix(string(out), "restored 2024, ") {
+			t.Fatalf("Expected checkpoint 2024 to be restored, got '%s' (%v)", out, err)
+		}
+		if text, _ := ioutil.ReadFile("/tmp/tasks"); string(text) != "foo" {
+			t.Fatalf("Expected the checkpoint's tasks, got '%s'", text)
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// clipboard is where --yank copies to, so that tests can check what
+// would be copied without a display server.
+type clipboard interface {
+	Copy(text string) error
+}
+
+// commandClipboard copies by piping the text into a command like pbcopy.
+type commandClipboard struct {
+	name string
+	args []string
+}
+
+func (c commandClipboard) Copy(text string) error {
+	cmd := exec.Command(c.name, c.args...)
+	cmd.Stdin = strings.NewReader(text)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s failed: %v %s", c.name, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// clipboardCommands are the clipboard commands t knows, in the order
+// they are tried. wl-copy only works in a Wayland session.
+var clipboardCommands = []commandClipboard{
+	{name: "pbcopy"},
+	{name: "wl-copy"},
+	{name: "xclip", args: []string{"-selection", "clipboard"}},
+	{name: "xsel", args: []string{"--clipboard", "--input"}},
+	{name: "clip.exe"},
+}
+
+// systemClipboard returns the first clipboard command that is installed.
+func systemClipboard() (clipboard, error) {
+	for _, c := range clipboardCommands {
+		if c.name == "wl-copy" && os.Getenv("WAYLAND_DISPLAY") == "" {
+			continue
+		}
+		if _, err := exec.LookPath(c.name); err == nil {
+			return c, nil
+		}
+	}
+	return nil, errors.New("no clipboard command found, install xclip, xsel or wl-clipboard")
+}
+
+// yankTask handles --yank: it copies the description of a task to the
+// clipboard, and with show also prints it.
+func yankTask(target string, c clipboard, show bool) error {
+	taskId, err := tasklist.resolveId(target)
+	if err != nil {
+		return err
+	}
+	task, err := tasklist.Get(taskId)
+	if err != nil {
+		return err
+	}
+	if err := c.Copy(task.Description); err != nil {
+		return err
+	}
+	if show {
+		fmt.Println(task.Description)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"testing"
+)
+
+// fakeClipboard remembers what was copied.
+type fakeClipboard struct {
+	text string
+}
+
+func (c *fakeClipboard) Copy(text string) error {
+	c.text = text
+	return nil
+}
+
+func TestYankTask(t *testing.T) {
+	defer func(saved *TaskList) { tasklist = saved }(tasklist)
+	tasklist = &TaskList{}
+	tasklist.Add("call $MOM 'now'")
+	tasklist.Add("second")
+	c := &fakeClipboard{}
+	if err := yankTask("0", c, false); err != nil {
+		t.Fatal(err)
+	}
+	if c.text != "call $MOM 'now'" {
+		t.Fatalf("Expected the raw description to be copied, got %q", c.text)
+	}
+	if err := yankTask("5", c, false); err == nil {
+		t.Fatal("Expected yanking a missing task to fail")
+	}
+}
+
+func TestCliShowWithoutId(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("foo"), 0644)
+		if err := exec.Command(tBinary, "--show").Run(); err == nil {
+			t.Fatal("Expected --show without an id to fail")
+		}
+	})
+}
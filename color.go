@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+const (
+	colorReset = "\x1b[0m"
+	colorBold  = "\x1b[1m"
+	colorDim   = "\x1b[2m"
+)
+
+// colorNames are the basic terminal colors by name.
+var colorNames = map[string]int{
+	"black": 0, "red": 1, "green": 2, "yellow": 3,
+	"blue": 4, "magenta": 5, "cyan": 6, "white": 7,
+}
+
+// overdueColor is what overdue tasks are shown in, whatever their tags.
+var overdueColor = colorCode(colorNames["red"])
+
+// tagColor is what tags and contexts without a color of their own are
+// shown in.
+var tagColor = colorCode(colorNames["cyan"])
+
+// urgentPriority is the lowest effective priority that is shown in red
+// and bold.
+const urgentPriority = 2
+
+// colorCode returns the escape sequence that switches to one of the 256
+// terminal colors.
+func colorCode(n int) string {
+	if n < 8 {
+		return fmt.Sprintf("\x1b[%dm", 30+n)
+	}
+	return fmt.Sprintf("\x1b[38;5;%dm", n)
+}
+
+// parseColor parses a color name like red or a 256-color code like 208.
+func parseColor(s string) (string, error) {
+	if n, ok := colorNames[strings.ToLower(s)]; ok {
+		return colorCode(n), nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || n > 255 {
+		return "", fmt.Errorf("invalid color %q, expected a name like red or a number from 0 to 255", s)
+	}
+	return colorCode(n), nil
+}
+
+// tagColors reads the color.<tag> settings, like color.+urgent = red,
+// into escape sequences keyed by the lowercased tag.
+func tagColors(config Config) (map[string]string, error) {
+	colors := make(map[string]string)
+	for tag, name := range config.withPrefix("color.") {
+		code, err := parseColor(name)
+		if err != nil {
+			return colors, fmt.Errorf("color.%s: %v", tag, err)
+		}
+		colors[strings.ToLower(tag)] = code
+	}
+	return colors, nil
+}
+
+// colorSetting decides from --color whether listings are colored:
+// always, never, or auto, the default, for colors on a terminal that
+// shows them unless --plain or NO_COLOR turn them off.
+func colorSetting(mode string, plain bool) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto", "":
+		return !plain && useColor(), nil
+	}
+	return false, fmt.Errorf("invalid --color=%s, expected always, never or auto", mode)
+}
+
+// useColor reports whether output goes to a terminal that shows colors.
+func useColor() bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0 && terminalColors(os.Stdout)
+}
+
+// lineColor returns the escape sequence a listed task is shown in, or
+// "" for none. Hidden tasks --all shows are dim. Overdue tasks are
+// always shown as such, and urgent ones in red and bold; otherwise the
+// first +tag or @context of the description with a color decides.
+func lineColor(task *Task, opts formatOptions) string {
+	if core.Snoozed(task, opts.now) {
+		return colorDim
+	}
+	overdue := !task.DueAt.IsZero() && daysBetween(opts.now, task.DueAt) < 0
+	if effectivePriority(task, opts.now) <= urgentPriority {
+		return colorBold + overdueColor
+	}
+	if overdue {
+		return overdueColor
+	}
+	for _, word := range strings.Fields(task.Description) {
+		if len(word) > 1 && (word[0] == '+' || word[0] == '@') {
+			if code, ok := opts.tagColors[strings.ToLower(word)]; ok {
+				return code
+			}
+		}
+	}
+	return ""
+}
+
+// paint shows s in the color of code within a line shown in base, which
+// is switched back to after it.
+func paint(code, s, base string) string {
+	return code + s + colorReset + base
+}
+
+// paintTags shows the +tags, #tags and @contexts of a description in
+// their color, or tagColor for those without one, within a line shown
+// in base.
+func paintTags(description string, opts formatOptions, base string) string {
+	words := strings.Split(description, " ")
+	for i, word := range words {
+		if !isTag(word) {
+			continue
+		}
+		code, ok := opts.tagColors[strings.ToLower(word)]
+		if !ok {
+			code = tagColor
+		}
+		words[i] = paint(code, word, base)
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestParseColor(t *testing.T) {
+	cases := map[string]string{"red": "\x1b[31m", "Blue": "\x1b[34m", "7": "\x1b[37m", "208": "\x1b[38;5;208m"}
+	for name, expected := range cases {
+		if code, err := parseColor(name); err != nil || code != expected {
+			t.Fatalf("parseColor(%s): expected %q, got %q (%v)", name, expected, code, err)
+		}
+	}
+	for _, name := range []string{"pink", "256", "-1"} {
+		if _, err := parseColor(name); err == nil {
+			t.Fatalf("Expected %s to be rejected", name)
+		}
+	}
+}
+
+func TestLineColor(t *testing.T) {
+	config := Config{values: map[string]string{"color.+urgent": "red", "color.@home": "blue"}}
+	colors, err := tagColors(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.Local)
+	opts := formatOptions{color: true, tagColors: colors, now: now}
+	cases := []struct {
+		task     Task
+		expected string
+	}{
+		{Task{Description: "fix deck @home"}, "\x1b[34m"},
+		{Task{Description: "fix deck @Home +urgent"}, "\x1b[34m"},
+		{Task{Description: "fix deck +other"}, ""},
+		{Task{Description: "fix deck @home", DueAt: now.AddDate(0, 0, -1)}, overdueColor},
+		{Task{Description: "fix deck @home", Priority: 2}, colorBold + overdueColor},
+		{Task{Description: "fix deck", Priority: 3}, ""},
+	}
+	for _, c := range cases {
+		if code := lineColor(&c.task, opts); code != c.expected {
+			t.Fatalf("%s: expected %q, got %q", c.task.Description, c.expected, code)
+		}
+	}
+	expected := "\x1b[34m" + colorDim + "0" + colorReset + "\x1b[34m - fix deck \x1b[34m@home" + colorReset + "\x1b[34m" + colorReset
+	if line := formatTask(0, &cases[0].task, opts); line != expected {
+		t.Fatalf("Expected the line to be colored, got %q", line)
+	}
+}
+
+func TestFormatTaskColor(t *testing.T) {
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.Local)
+	urgent := Task{Description: "pay rent +home", Priority: 1}
+	cases := []struct {
+		task     Task
+		color    bool
+		expected string
+	}{
+		{Task{Description: "pay rent +home"}, false, "0 - pay rent +home"},
+		{urgent, false, "0 - pay rent +home (P1)"},
+		{Task{Description: "pay rent +home"}, true, colorDim + "0" + colorReset + " - pay rent " + tagColor + "+home" + colorReset},
+		{Task{Description: "pay rent"}, true, colorDim + "0" + colorReset + " - pay rent"},
+		{urgent, true, colorBold + overdueColor + colorDim + "0" + colorReset + colorBold + overdueColor + " - pay rent " +
+			tagColor + "+home" + colorReset + colorBold + overdueColor + " (P1)" + colorReset},
+	}
+	for _, c := range cases {
+		if line := formatTask(0, &c.task, formatOptions{color: c.color, now: now}); line != c.expected {
+			t.Errorf("%s with color %v: expected %q, got %q", c.task.Description, c.color, c.expected, line)
+		}
+	}
+}
+
+func TestColorSetting(t *testing.T) {
+	// Tests don't run on a terminal, so auto never turns colors on.
+	cases := []struct {
+		mode     string
+		plain    bool
+		expected bool
+	}{
+		{"always", false, true},
+		{"always", true, true},
+		{"never", false, false},
+		{"auto", false, false},
+		{"", false, false},
+	}
+	for _, c := range cases {
+		if color, err := colorSetting(c.mode, c.plain); err != nil || color != c.expected {
+			t.Errorf("colorSetting(%q, %v): expected %v, got %v (%v)", c.mode, c.plain, c.expected, color, err)
+		}
+	}
+	if _, err := colorSetting("sometimes", false); err == nil {
+		t.Error("Expected --color=sometimes to be rejected")
+	}
+}
+
+func TestCliColor(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("pay rent +home"), 0644)
+		out, err := exec.Command(tBinary, "--color=always").Output()
+		if err != nil || string(out) != colorDim+"0"+colorReset+" - pay rent "+tagColor+"+home"+colorReset+"\n" {
+			t.Fatalf("Expected colors even when piped, got %q (%v)", out, err)
+		}
+		if out, err := exec.Command(tBinary, "--color=never").Output(); err != nil || string(out) != "0 - pay rent +home\n" {
+			t.Fatalf("Expected no colors, got %q (%v)", out, err)
+		}
+		err = exec.Command(tBinary, "--color=sometimes").Run()
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 2 {
+			t.Fatalf("Expected an invalid --color to exit with 2, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// completionFlags returns the flags as they are typed, -x for the
+// one-letter ones and --name for the rest, and separately those that
+// take a task id, which are the ones whose usage mentions "task #".
+func completionFlags() ([]string, []string) {
+	names := make([]string, 0)
+	idNames := make([]string, 0)
+	flag.VisitAll(func(f *flag.Flag) {
+		name := "--" + f.Name
+		if len(f.Name) == 1 {
+			name = "-" + f.Name
+		}
+		names = append(names, name)
+		if strings.Contains(f.Usage, "task #") {
+			idNames = append(idNames, name)
+		}
+	})
+	sort.Strings(names)
+	sort.Strings(idNames)
+	return names, idNames
+}
+
+const bashCompletion = `# bash completion for t, from t --completion bash
+_t() {
+    local cur prev list i
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    prev="${COMP_WORDS[COMP_CWORD-1]}"
+    for ((i = 1; i < COMP_CWORD - 1; i++)); do
+        case "${COMP_WORDS[i]}" in
+            -l|--list) list="${COMP_WORDS[i+1]}" ;;
+        esac
+    done
+    case "$prev" in
+        %s)
+            COMPREPLY=($(compgen -W "$(t ${list:+-l "$list"} --ids 2>/dev/null)" -- "$cur"))
+            return ;;
+    esac
+    if [[ "$cur" == -* ]]; then
+        COMPREPLY=($(compgen -W "%s" -- "$cur"))
+    fi
+}
+complete -o default -F _t t
+`
+
+const zshCompletion = `#compdef t
+# zsh completion for t, from t --completion zsh
+_t() {
+    local list i
+    for ((i = 2; i < CURRENT - 1; i++)); do
+        case "${words[i]}" in
+            -l|--list) list="${words[i+1]}" ;;
+        esac
+    done
+    case "${words[CURRENT-1]}" in
+        %s)
+            compadd -- ${(f)"$(t ${list:+-l "$list"} --ids 2>/dev/null)"}
+            return ;;
+    esac
+    if [[ "${words[CURRENT]}" == -* ]]; then
+        compadd -- %s
+    else
+        _files
+    fi
+}
+compdef _t t
+`
+
+// completionScript returns the script that makes shell complete t's
+// flags and, after a flag that takes a task id, the ids of the tasks,
+// which it gets from t --ids.
+func completionScript(shell string) (string, error) {
+	names, idNames := completionFlags()
+	switch shell {
+	case "bash":
+		return fmt.Sprintf(bashCompletion, strings.Join(idNames, "|"), strings.Join(names, " ")), nil
+	case "zsh":
+		return fmt.Sprintf(zshCompletion, strings.Join(idNames, "|"), strings.Join(names, " ")), nil
+	}
+	return "", fmt.Errorf("invalid shell %q for --completion, use bash or zsh", shell)
+}
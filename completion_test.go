@@ -0,0 +1,102 @@
+package main
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// definedFlags returns the names of the flags t.go defines.
+func definedFlags(t *testing.T) []string {
+	source, err := ioutil.ReadFile("t.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	definition := regexp.MustCompile(`flag\.(?:String|Bool|Int|Int64|Var|StringVar|BoolVar)\((?:[^,()"]+, )?"([^"]+)"`)
+	names := make([]string, 0)
+	for _, m := range definition.FindAllStringSubmatch(string(source), -1) {
+		names = append(names, m[1])
+	}
+	return names
+}
+
+func TestCliCompletionHasEveryFlag(t *testing.T) {
+	names := definedFlags(t)
+	if len(names) < 100 {
+		t.Fatalf("Expected to find the flags in t.go, got %v", names)
+	}
+	for _, shell := range []string{"bash", "zsh"} {
+		out, err := exec.Command(tBinary, "--completion", shell).Output()
+		if err != nil {
+			t.Fatalf("Expected a %s script, got %v", shell, err)
+		}
+		words := strings.FieldsFunc(string(out), func(r rune) bool {
+			return r == ' ' || r == '|' || r == '"' || r == '\n' || r == ')'
+		})
+		for _, name := range names {
+			typed := "--" + name
+			if len(name) == 1 {
+				typed = "-" + name
+			}
+			found := false
+			for _, word := range words {
+				if word == typed {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("Expected %s in the %s script", typed, shell)
+			}
+		}
+	}
+}
+
+func TestCliCompletionIdFlags(t *testing.T) {
+	out, err := exec.Command(tBinary, "--completion", "bash").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "|-d|-e|-f)") || !strings.Contains(string(out), "t ${list:+-l \"$list\"} --ids") {
+		t.Fatalf("Expected -f, -e and -d to complete task ids, got '%s'", out)
+	}
+}
+
+func TestCliCompletionInvalidShell(t *testing.T) {
+	err := exec.Command(tBinary, "--completion", "fish").Run()
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 2 {
+		t.Fatalf("Expected an unknown shell to be a usage error, got %v", err)
+	}
+}
+
+func TestCliIds(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("pay rent\ncall bob\nbuy milk +home"), 0644)
+		for _, c := range []struct {
+			args     []string
+			expected string
+		}{
+			{[]string{"--ids"}, "0\n1\n2\n"},
+			{[]string{"-g", "home", "--ids"}, "2\n"},
+		} {
+			out, err := exec.Command(tBinary, c.args...).Output()
+			if err != nil || string(out) != c.expected {
+				t.Errorf("Expected '%s' from %v, got '%s' (%v)", c.expected, c.args, out, err)
+			}
+		}
+	})
+}
+
+func TestCliIdsOfList(t *testing.T) {
+	withCliSetup(t, func() {
+		dir := t.TempDir()
+		ioutil.WriteFile(dir+"/work", []byte("write report\nreview"), 0644)
+		cmd := exec.Command(tBinary, "-l", "work", "--ids")
+		cmd.Env = append(cmd.Environ(), "T_TASKS_DIR="+dir)
+		out, err := cmd.Output()
+		if err != nil || string(out) != "0\n1\n" {
+			t.Fatalf("Expected the ids of the work list, got '%s' (%v)", out, err)
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Config holds the settings of the config file. Keys in a [section]
+// are prefixed with the section's name, so "format" under [list.work]
+// is list.work.format.
+type Config struct {
+	values map[string]string
+}
+
+// configDir returns t's directory in the user's config directory, like
+// ~/.config/t.
+func configDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "t"), nil
+}
+
+// loadConfig reads the config file at path: key = value lines, with
+// [section] headers and # comments. A missing file is an empty config.
+// Malformed lines are skipped and reported in the returned error, along
+// with the config made of the other lines.
+func loadConfig(path string) (Config, error) {
+	config := Config{values: make(map[string]string)}
+	text, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return config, nil
+		}
+		return config, err
+	}
+	problems := make([]string, 0)
+	section := ""
+	scanner := bufio.NewScanner(strings.NewReader(string(text)))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			section = strings.TrimSpace(line[1 : len(line)-1])
+		default:
+			eq := strings.Index(line, "=")
+			if eq < 1 || strings.TrimSpace(line[:eq]) == "" {
+				problems = append(problems, fmt.Sprintf("%s:%d: expected key = value", path, n))
+				continue
+			}
+			key := strings.TrimSpace(line[:eq])
+			if section != "" {
+				key = section + "." + key
+			}
+			config.values[key] = strings.Trim(strings.TrimSpace(line[eq+1:]), `"`)
+		}
+	}
+	if len(problems) > 0 {
+		return config, fmt.Errorf("%s", strings.Join(problems, "\n"))
+	}
+	return config, scanner.Err()
+}
+
+// Get returns the value of a setting.
+func (c Config) Get(key string) (string, bool) {
+	value, ok := c.values[key]
+	return value, ok
+}
+
+// listSetting returns a setting for the named list, "" for the default
+// list: the one under [list.<name>], or else the one outside of any
+// section.
+func (c Config) listSetting(list, key string) (string, bool) {
+	if list != "" {
+		if value, ok := c.values["list."+list+"."+key]; ok {
+			return value, true
+		}
+	}
+	return c.Get(key)
+}
+
+// withPrefix returns the settings whose keys start with prefix, keyed
+// by the rest of the key.
+func (c Config) withPrefix(prefix string) map[string]string {
+	values := make(map[string]string)
+	for key, value := range c.values {
+		if strings.HasPrefix(key, prefix) {
+			values[key[len(prefix):]] = value
+		}
+	}
+	return values
+}
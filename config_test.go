@@ -0,0 +1,96 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	ioutil.WriteFile(path, []byte("# colors\ncolor.+urgent = red\n\n[list.work]\nformat = \"long\"\nbroken line\n"), 0644)
+	config, err := loadConfig(path)
+	if err == nil || err.Error() != path+":6: expected key = value" {
+		t.Fatalf("Expected the malformed line to be reported, got %v", err)
+	}
+	cases := map[string]string{"color.+urgent": "red", "list.work.format": "long"}
+	for key, expected := range cases {
+		if value, _ := config.Get(key); value != expected {
+			t.Fatalf("Expected %s to be '%s', got '%s'", key, expected, value)
+		}
+	}
+
+	config, err = loadConfig(filepath.Join(t.TempDir(), "missing"))
+	if err != nil || len(config.values) != 0 {
+		t.Fatalf("Expected a missing config file to be an empty config, got %v (%v)", config.values, err)
+	}
+}
+
+func TestListSetting(t *testing.T) {
+	config := Config{values: map[string]string{"format": "long", "color": "never", "list.work.format": "csv"}}
+	cases := []struct {
+		list, key string
+		expected  string
+		ok        bool
+	}{
+		{"", "format", "long", true},
+		{"work", "format", "csv", true},
+		{"work", "color", "never", true},
+		{"home", "format", "long", true},
+		{"work", "taskdir", "", false},
+	}
+	for _, c := range cases {
+		if value, ok := config.listSetting(c.list, c.key); value != c.expected || ok != c.ok {
+			t.Errorf("Expected %s of list %q to be '%s' (%v), got '%s' (%v)", c.key, c.list, c.expected, c.ok, value, ok)
+		}
+	}
+}
+
+func TestCliConfigPrecedence(t *testing.T) {
+	home := t.TempDir()
+	lists, envLists := filepath.Join(home, "lists"), filepath.Join(home, "env-lists")
+	os.MkdirAll(filepath.Join(home, "t"), 0700)
+	os.MkdirAll(lists, 0700)
+	os.MkdirAll(envLists, 0700)
+	ioutil.WriteFile(filepath.Join(home, "t", "config"), []byte("format = simple\ncolor = always\ntaskdir = "+lists+
+		"\ndone_file = "+filepath.Join(home, "done")+"\n[list.work]\nformat = {{.Description}}\ncolor = never\n"), 0644)
+	ioutil.WriteFile(filepath.Join(lists, "work"), []byte("review"), 0644)
+	ioutil.WriteFile(filepath.Join(envLists, "work"), []byte("from the environment"), 0644)
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("pay rent (P2)"), 0644)
+		env := append(os.Environ(), "XDG_CONFIG_HOME="+home, "T_FORMAT=env {{.ID}}", "T_TASKS_DIR="+envLists, "T_DONE_FILE="+filepath.Join(home, "env-done"))
+		cases := []struct {
+			args     []string
+			expected string
+		}{
+			{[]string{}, "0 - pay rent (P2)\n"},
+			{[]string{"--format", "{{.ID}}: {{.Description}}"}, "0: pay rent (P2)\n"},
+			{[]string{"-l", "work"}, "review\n"},
+			{[]string{"-l", "work", "--format", "simple"}, "0 - review\n"},
+		}
+		for _, c := range cases {
+			cmd := exec.Command(tBinary, c.args...)
+			cmd.Env = env
+			if out, err := cmd.Output(); err != nil || string(out) != c.expected {
+				t.Errorf("Expected t %v to print %q, got %q (%v)", c.args, c.expected, out, err)
+			}
+		}
+		for _, args := range [][]string{{"--format", ""}, {"--format", "", "--color", "never"}} {
+			cmd := exec.Command(tBinary, args...)
+			cmd.Env = env
+			out, _ := cmd.Output()
+			if colored := strings.Contains(string(out), "\x1b["); colored != (len(args) == 2) {
+				t.Errorf("Expected t %v to be colored: %v, got %q", args, len(args) == 2, out)
+			}
+		}
+		cmd := exec.Command(tBinary, "-f", "0")
+		cmd.Env = env
+		cmd.Run()
+		if _, err := os.Stat(filepath.Join(home, "done")); err != nil {
+			t.Fatalf("Expected done_file to win over T_DONE_FILE, got %v", err)
+		}
+	})
+}
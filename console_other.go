@@ -0,0 +1,12 @@
+//go:build !windows
+
+package main
+
+import "os"
+
+// terminalColors reports whether the terminal f writes to shows colors,
+// which all but a dumb or unknown one do.
+func terminalColors(f *os.File) bool {
+	term := os.Getenv("TERM")
+	return term != "" && term != "dumb"
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestTerminalColors(t *testing.T) {
+	for term, expected := range map[string]bool{"xterm-256color": true, "dumb": false, "": false} {
+		t.Setenv("TERM", term)
+		if colors := terminalColors(os.Stdout); colors != expected {
+			t.Fatalf("terminalColors with TERM=%q: expected %v, got %v", term, expected, colors)
+		}
+	}
+}
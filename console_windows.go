@@ -0,0 +1,57 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// enableVirtualTerminalProcessing is the console mode in which Windows
+// consoles act on escape sequences like colors. Consoles older than
+// Windows 10 don't have it.
+const enableVirtualTerminalProcessing = 0x0004
+
+var (
+	kernel32                   = syscall.NewLazyDLL("kernel32.dll")
+	setConsoleMode             = kernel32.NewProc("SetConsoleMode")
+	getConsoleScreenBufferInfo = kernel32.NewProc("GetConsoleScreenBufferInfo")
+)
+
+// terminalColors reports whether the console f writes to shows colors,
+// turning on its handling of escape sequences if need be. On a console
+// that can't, colors are left out rather than printed as garbage. TERM
+// is rarely set on Windows, so it isn't asked.
+func terminalColors(f *os.File) bool {
+	handle := syscall.Handle(f.Fd())
+	var mode uint32
+	if err := syscall.GetConsoleMode(handle, &mode); err != nil {
+		return false
+	}
+	if mode&enableVirtualTerminalProcessing != 0 {
+		return true
+	}
+	ok, _, _ := setConsoleMode.Call(uintptr(handle), uintptr(mode|enableVirtualTerminalProcessing))
+	return ok != 0
+}
+
+// consoleScreenBufferInfo is CONSOLE_SCREEN_BUFFER_INFO, of which only
+// the visible window is of interest.
+type consoleScreenBufferInfo struct {
+	size, cursorPosition     [2]int16
+	attributes               uint16
+	left, top, right, bottom int16
+	maximumWindowSize        [2]int16
+}
+
+// terminalWidth returns the number of columns of the console window f
+// writes to, or 0 if it isn't a console.
+func terminalWidth(f *os.File) int {
+	var info consoleScreenBufferInfo
+	ok, _, _ := getConsoleScreenBufferInfo.Call(f.Fd(), uintptr(unsafe.Pointer(&info)))
+	if ok == 0 {
+		return 0
+	}
+	return int(info.right-info.left) + 1
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+// fuzzyDistance is the largest edit distance between two normalized
+// descriptions that --dedupe --fuzzy still treats as duplicates.
+const fuzzyDistance = 2
+
+// Duplicates groups tasks whose normalized descriptions are identical, or
+// with fuzzy set merely close to each other. Each group lists task ids in
+// list order, so the first id is the oldest task of the group. Tasks
+// without duplicates are not part of any group.
+func (t *TaskList) Duplicates(fuzzy bool) [][]int {
+	groups := make([][]int, 0)
+	normalized := make([]string, len(t.Tasks))
+	grouped := make([]bool, len(t.Tasks))
+	for i, task := range t.Tasks {
+		normalized[i] = normalizeDescription(task.Description)
+	}
+	for i := range t.Tasks {
+		if grouped[i] {
+			continue
+		}
+		group := []int{i}
+		for j := i + 1; j < len(t.Tasks); j++ {
+			if grouped[j] {
+				continue
+			}
+			if normalized[i] == normalized[j] ||
+				(fuzzy && editDistance(normalized[i], normalized[j]) <= fuzzyDistance) {
+				group = append(group, j)
+				grouped[j] = true
+			}
+		}
+		if len(group) > 1 {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// Dedupe takes the tasks whose descriptions are identical to that of an
+// earlier task, but for spaces around them, off the list, keeping the
+// first and the order of the rest. Unlike Duplicates it tells case
+// apart. It returns how many tasks it removed.
+func (t *TaskList) Dedupe() int {
+	seen := make(map[string]bool)
+	kept := make([]*Task, 0, len(t.Tasks))
+	removed := make([]*Task, 0)
+	for _, task := range t.Tasks {
+		description := strings.TrimSpace(task.Description)
+		if seen[description] {
+			removed = append(removed, task)
+			continue
+		}
+		seen[description] = true
+		kept = append(kept, task)
+	}
+	t.Tasks = kept
+	for _, task := range removed {
+		t.Bury(task)
+		// Tasks read from a file may share an id with the one kept.
+		if t.IndexOf(task.Id) == -1 {
+			t.Unlink(task.Id)
+		}
+	}
+	return len(removed)
+}
+
+// hasDescription reports whether a task of the list has the description
+// a task added with it would get, but for spaces around it.
+func (t *TaskList) hasDescription(description string) bool {
+	if !t.Raw {
+		description = core.NormalizeText(description)
+	}
+	description = strings.TrimSpace(description)
+	for _, task := range t.Tasks {
+		if strings.TrimSpace(task.Description) == description {
+			return true
+		}
+	}
+	return false
+}
+
+// noDupSetting reads the no_dup setting, which makes adds skip the
+// descriptions already on the list as --no-dup does.
+func noDupSetting(config Config) (bool, error) {
+	value, ok := config.Get("no_dup")
+	if !ok {
+		return false, nil
+	}
+	switch value {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid no_dup = %s, expected true or false", value)
+}
+
+// normalizeDescription trims, case-folds and collapses whitespace so that
+// descriptions differing only in those respects compare equal.
+func normalizeDescription(description string) string {
+	return strings.Join(strings.Fields(strings.ToLower(description)), " ")
+}
+
+// editDistance returns the Levenshtein distance between a and b in runes.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDuplicates(t *testing.T) {
+	tasklist := TaskList{}
+	tasklist.Add("Buy milk")
+	tasklist.Add("call mom")
+	tasklist.Add("  buy   MILK ")
+	tasklist.Add("buy milk")
+	tasklist.Add("Call mum")
+
+	groups := tasklist.Duplicates(false)
+	expected := [][]int{{0, 2, 3}}
+	if !reflect.DeepEqual(groups, expected) {
+		t.Fatalf("Expected duplicate groups %v, got %v", expected, groups)
+	}
+
+	groups = tasklist.Duplicates(true)
+	expected = [][]int{{0, 2, 3}, {1, 4}}
+	if !reflect.DeepEqual(groups, expected) {
+		t.Fatalf("Expected fuzzy duplicate groups %v, got %v", expected, groups)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		distance int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"café", "cafe", 1},
+	}
+	for _, c := range cases {
+		if d := editDistance(c.a, c.b); d != c.distance {
+			t.Fatalf("editDistance(%q, %q): expected %d, got %d", c.a, c.b, c.distance, d)
+		}
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	tasklist := TaskList{}
+	for _, description := range []string{"buy milk", "call mom", "Buy milk", "buy milk", "fix deck", "call mom"} {
+		tasklist.Add(description)
+	}
+	tasklist.Tasks[3].Description = " buy milk "
+	if n := tasklist.Dedupe(); n != 2 {
+		t.Fatalf("Expected 2 duplicates removed, got %d", n)
+	}
+	if joined := joinDescriptions(tasklist.Tasks); joined != "buy milk call mom Buy milk fix deck" {
+		t.Fatalf("Expected the first of each kept in order, case apart, got '%s'", joined)
+	}
+	if n := tasklist.Dedupe(); n != 0 {
+		t.Fatalf("Expected nothing left to remove, got %d", n)
+	}
+}
+
+func TestHasDescription(t *testing.T) {
+	tasklist := TaskList{}
+	tasklist.Add("buy milk")
+	for description, expected := range map[string]bool{"buy milk": true, "  buy milk ": true, "Buy milk": false, "buy": false} {
+		if has := tasklist.hasDescription(description); has != expected {
+			t.Errorf("%q: expected %v, got %v", description, expected, has)
+		}
+	}
+}
+
+func TestCliNoDup(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("buy milk"), 0644)
+		out, err := exec.Command(tBinary, "--no-dup", "buy", "milk").Output()
+		if err != nil || string(out) != "already on list: buy milk\n" {
+			t.Fatalf("Expected 'already on list' and exit status 0, got '%s' (%v)", out, err)
+		}
+		exec.Command(tBinary, "--no-dup", "--multi", "buy milk", "Buy milk").Run()
+		if out, _ := exec.Command(tBinary).Output(); string(out) != "0 - buy milk\n1 - Buy milk\n" {
+			t.Fatalf("Expected only the new description added, got '%s'", out)
+		}
+
+		dir := t.TempDir()
+		os.Mkdir(filepath.Join(dir, "t"), 0700)
+		ioutil.WriteFile(filepath.Join(dir, "t", "config"), []byte("no_dup = true\n"), 0644)
+		cmd := exec.Command(tBinary, "buy milk")
+		cmd.Env = append(os.Environ(), "XDG_CONFIG_HOME="+dir)
+		if out, err := cmd.Output(); err != nil || string(out) != "already on list: buy milk\n" {
+			t.Fatalf("Expected no_dup = true to work like --no-dup, got '%s' (%v)", out, err)
+		}
+	})
+}
+
+func TestCliDedupeExact(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("foo\nFoo\nfoo \nbar"), 0644)
+		out, err := exec.Command(tBinary, "--dedupe", "--exact").Output()
+		if err != nil || string(out) != "removed 1 duplicates\n" {
+			t.Fatalf("Expected 1 duplicate removed, got '%s' (%v)", out, err)
+		}
+		if out, _ := exec.Command(tBinary).Output(); string(out) != "0 - foo\n1 - Foo\n2 - bar\n" {
+			t.Fatalf("Expected the order kept and case told apart, got '%s'", out)
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// addDefaults are what tasks added to a list get unless the command
+// line says otherwise.
+type addDefaults struct {
+	tags     []string
+	priority int
+}
+
+// listDefaults reads the defaults of the tasks added to the named list,
+// "" for the default list: tags and priority under [list.<name>], each
+// falling back to the same setting under [defaults].
+func listDefaults(config Config, list string) (addDefaults, error) {
+	defaults := addDefaults{}
+	sections := []string{"defaults."}
+	if list != "" {
+		sections = append([]string{"list." + list + "."}, sections...)
+	}
+	for _, section := range sections {
+		if value, ok := config.Get(section + "tags"); ok && defaults.tags == nil {
+			tags := strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+			for _, tag := range tags {
+				if !isTag(tag) {
+					return defaults, fmt.Errorf("invalid %stags = %s, expected +tags or @contexts like +work, @office", section, value)
+				}
+			}
+			defaults.tags = tags
+		}
+		if value, ok := config.Get(section + "priority"); ok && defaults.priority == 0 {
+			priority, err := parsePriority(value)
+			if err != nil {
+				return defaults, fmt.Errorf("invalid %spriority = %s, expected 1 to 9", section, value)
+			}
+			defaults.priority = priority
+		}
+	}
+	return defaults, nil
+}
+
+// withTags returns the description with the default tags it doesn't
+// have yet added at the end.
+func (d addDefaults) withTags(description string) string {
+	task := Task{Description: description}
+	for _, tag := range d.tags {
+		if !hasWord(&task, tag) {
+			description += " " + tag
+		}
+	}
+	return description
+}
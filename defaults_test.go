@@ -0,0 +1,82 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestListDefaults(t *testing.T) {
+	config := Config{values: map[string]string{
+		"defaults.tags":        "+inbox",
+		"defaults.priority":    "6",
+		"list.work.tags":       "+work, @office",
+		"list.work.priority":   "3",
+		"list.home.priority":   "4",
+		"list.broken.tags":     "work",
+		"list.broken.priority": "1",
+	}}
+	cases := map[string]addDefaults{
+		"":     {[]string{"+inbox"}, 6},
+		"work": {[]string{"+work", "@office"}, 3},
+		"home": {[]string{"+inbox"}, 4},
+		"misc": {[]string{"+inbox"}, 6},
+	}
+	for list, expected := range cases {
+		if defaults, err := listDefaults(config, list); err != nil || !reflect.DeepEqual(defaults, expected) {
+			t.Errorf("%q: expected %v, got %v (%v)", list, expected, defaults, err)
+		}
+	}
+	if _, err := listDefaults(config, "broken"); err == nil {
+		t.Error("Expected a tag without + or @ to be rejected")
+	}
+	if defaults, _ := listDefaults(Config{values: map[string]string{}}, "work"); defaults.tags != nil || defaults.priority != 0 {
+		t.Errorf("Expected no defaults without settings, got %v", defaults)
+	}
+}
+
+func TestWithTags(t *testing.T) {
+	defaults := addDefaults{tags: []string{"+work", "@office"}}
+	if description := defaults.withTags("write report +Work"); description != "write report +Work @office" {
+		t.Fatalf("Expected only the missing tag added, got '%s'", description)
+	}
+}
+
+func TestCliListDefaults(t *testing.T) {
+	withCliSetup(t, func() {
+		dir := t.TempDir()
+		os.Mkdir(filepath.Join(dir, "t"), 0700)
+		ioutil.WriteFile(filepath.Join(dir, "t", "config"), []byte("[defaults]\npriority = 6\n[list.work]\ntags = +work\npriority = 3\n"), 0644)
+		env := append(os.Environ(), "XDG_CONFIG_HOME="+dir, "T_TASKS_DIR="+filepath.Join(dir, "lists"))
+		run := func(args ...string) string {
+			cmd := exec.Command(tBinary, args...)
+			cmd.Env = env
+			out, err := cmd.Output()
+			if err != nil {
+				t.Fatalf("t %v: %v", args, err)
+			}
+			return string(out)
+		}
+		os.Mkdir(filepath.Join(dir, "lists"), 0700)
+		ioutil.WriteFile(filepath.Join(dir, "lists", "work"), []byte("old task"), 0644)
+		if out := run("-l", "work", "write report"); out != "added 1 - write report +work (P3)\n" {
+			t.Fatalf("Expected the list's defaults shown, got '%s'", out)
+		}
+		if out := run("-l", "work", "-p", "1", "fix build +work"); !strings.HasPrefix(out, "added: fix build +work (id 2, ") {
+			t.Fatalf("Expected no defaults applied with -p and the tag given, got '%s'", out)
+		}
+		if out := run("buy milk"); out != "added 0 - buy milk (P6)\n" {
+			t.Fatalf("Expected the global default priority, got '%s'", out)
+		}
+		if out := run("-l", "work"); out != "2 - fix build +work (P1)\n1 - write report +work (P3)\n0 - old task\n" {
+			t.Fatalf("Expected the old task left alone, got '%s'", out)
+		}
+		if out := run("-q", "-l", "work", "tidy up"); out != "" {
+			t.Fatalf("Expected -q to leave out a task with defaults too, got '%s'", out)
+		}
+	})
+}
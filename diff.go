@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// taskChange is a task that differs between two lists: before is nil
+// for a task only in the second and after for one only in the first.
+type taskChange struct {
+	before *Task
+	after  *Task
+}
+
+// compareTasks returns how list b differs from list a: the tasks only
+// in a, those in both that changed, in the order of a, then the tasks
+// only in b. Tasks are matched by stable id, then by exact description.
+func compareTasks(a, b *TaskList) []taskChange {
+	matched := make([]int, len(a.Tasks))
+	used := make([]bool, len(b.Tasks))
+	for i, task := range a.Tasks {
+		matched[i] = -1
+		if j := b.IndexOf(task.Id); j != -1 && !used[j] {
+			matched[i], used[j] = j, true
+		}
+	}
+	for i, task := range a.Tasks {
+		if matched[i] != -1 {
+			continue
+		}
+		for j, other := range b.Tasks {
+			if !used[j] && other.Description == task.Description {
+				matched[i], used[j] = j, true
+				break
+			}
+		}
+	}
+	changes := make([]taskChange, 0)
+	for i, task := range a.Tasks {
+		if matched[i] == -1 {
+			changes = append(changes, taskChange{before: task})
+			continue
+		}
+		line, _ := task.MarshalText()
+		otherLine, _ := b.Tasks[matched[i]].MarshalText()
+		if string(line) != string(otherLine) {
+			changes = append(changes, taskChange{task, b.Tasks[matched[i]]})
+		}
+	}
+	for j, task := range b.Tasks {
+		if !used[j] {
+			changes = append(changes, taskChange{after: task})
+		}
+	}
+	return changes
+}
+
+// diffTasks describes how two lists differ, one line per task: "- "
+// for tasks only in a, "+ " for tasks only in b and "~ " for tasks in
+// both that changed, with their lines in a and b.
+func diffTasks(a, b *TaskList) []string {
+	lines := make([]string, 0)
+	for _, change := range compareTasks(a, b) {
+		switch {
+		case change.after == nil:
+			before, _ := change.before.MarshalText()
+			lines = append(lines, "- "+string(before))
+		case change.before == nil:
+			after, _ := change.after.MarshalText()
+			lines = append(lines, "+ "+string(after))
+		default:
+			before, _ := change.before.MarshalText()
+			after, _ := change.after.MarshalText()
+			lines = append(lines, fmt.Sprintf("~ %s → %s", before, after))
+		}
+	}
+	return lines
+}
+
+// diffFiles handles --diff: it prints how the tasks file at other
+// differs from the list, or with done how their done files differ. It
+// returns the exit status: 0 if they are the same, 1 if they differ and
+// 2 if they can't be compared.
+func diffFiles(other string, done bool) int {
+	path, err := expandPath(other, "--diff")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	list := tasklist
+	if done {
+		path = donePath(path)
+		if list, err = readDoneList(donePath(taskFilePath)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+	if _, err := os.Stat(path); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	otherList, err := readTaskList(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	lines := diffTasks(list, otherList)
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+	if len(lines) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"reflect"
+	"testing"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+func TestDiffTasks(t *testing.T) {
+	a, b := &TaskList{}, &TaskList{}
+	a.UnmarshalText([]byte("same\nedit me\nonly here\ndue later"))
+	b.UnmarshalText([]byte("same\nedited | id:" + core.Hash("edit me") + "\ndue later | due:2024-06-01\nonly there"))
+	expected := []string{
+		"~ edit me → edited | id:" + core.Hash("edit me"),
+		"- only here",
+		"~ due later → due later | due:2024-06-01",
+		"+ only there",
+	}
+	if lines := diffTasks(a, b); !reflect.DeepEqual(lines, expected) {
+		t.Fatalf("Expected %q, got %q", expected, lines)
+	}
+	if lines := diffTasks(a, a); len(lines) != 0 {
+		t.Fatalf("Expected a list not to differ from itself, got %q", lines)
+	}
+}
+
+func TestCliDiff(t *testing.T) {
+	withCliSetup(t, func() {
+		defer os.Remove("/tmp/tasks-other")
+		ioutil.WriteFile("/tmp/tasks", []byte("foo\nbar"), 0644)
+		ioutil.WriteFile("/tmp/tasks-other", []byte("foo\nbar"), 0644)
+		if err := exec.Command(tBinary, "--diff", "/tmp/tasks-other").Run(); err != nil {
+			t.Fatalf("Expected identical files to exit with 0, got %v", err)
+		}
+		ioutil.WriteFile("/tmp/tasks-other", []byte("foo"), 0644)
+		out, err := exec.Command(tBinary, "--diff", "/tmp/tasks-other").Output()
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+			t.Fatalf("Expected differing files to exit with 1, got %v", err)
+		}
+		if string(out) != "- bar\n" {
+			t.Fatalf("Expected output to be '- bar\n', got '%s'", out)
+		}
+	})
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// clockSlack is how far in the future a stored timestamp may be before
+// the clock looks wrong.
+const clockSlack = time.Hour
+
+// doctorCheck is one check of t --doctor. hint says how to fix it when
+// it fails.
+type doctorCheck struct {
+	name string
+	hint string
+	run  func() error
+}
+
+// doctorChecks are the checks of t --doctor for the tasks file at path
+// and the config file at configPath.
+func doctorChecks(path string, configPath string, now time.Time) []doctorCheck {
+	return []doctorCheck{
+		{"tasks file is writable", "fix the permissions of " + path + ", or use another file with T_TASKS_FILE or --file", func() error {
+			file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+			if os.IsNotExist(err) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			return file.Close()
+		}},
+		{"tasks directory is writable", "fix the permissions of " + filepath.Dir(path) + ", t keeps the undo history and other files next to the tasks file", func() error {
+			return checkWritableDir(filepath.Dir(path))
+		}},
+		{"tasks file is valid", "run t --check to see the problems and t --check --fix to repair them", func() error {
+			text, err := ioutil.ReadFile(path)
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			if problems := checkTasks(text); len(problems) > 0 {
+				return fmt.Errorf("%d problems, the first on %s", len(problems), problems[0])
+			}
+			return nil
+		}},
+		{"done file is readable", "fix the permissions of " + donePath(path) + ", or run t --check --done --fix", func() error {
+			text, err := ioutil.ReadFile(donePath(path))
+			if os.IsNotExist(err) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			_, skipped, err := parseDone(text)
+			if err == nil && skipped > 0 {
+				err = fmt.Errorf("%d unparseable lines", skipped)
+			}
+			return err
+		}},
+		{"environment is valid", "fix or unset the variable", checkEnvironment},
+		{"config is valid", "fix the setting in " + configPath, func() error {
+			return checkConfig(configPath)
+		}},
+		{"clock is sane", "check the system clock and time zone", func() error {
+			return checkClock(path, now)
+		}},
+	}
+}
+
+// checkWritableDir checks that a file can be created in dir, or if dir
+// doesn't exist yet, that it can be created.
+func checkWritableDir(dir string) error {
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return fmt.Errorf("no existing directory above %s", dir)
+		}
+		dir = parent
+	}
+	file, err := ioutil.TempFile(dir, ".t-doctor")
+	if err != nil {
+		return err
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
+
+// checkEnvironment checks the environment variables t reads.
+func checkEnvironment() error {
+	if _, err := parseAging(os.Getenv("T_PRIORITY_AGING")); err != nil {
+		return err
+	}
+	if _, err := doneLimit(); err != nil {
+		return err
+	}
+	if _, err := getDoneFilePath("", Config{}); err != nil {
+		return err
+	}
+	if value := os.Getenv("T_INDEX_BASE"); value != "" {
+		if _, err := parseIndexBase(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkConfig loads the config file and the settings t reads from it.
+func checkConfig(path string) error {
+	config, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+	if _, err := tagColors(config); err != nil {
+		return err
+	}
+	if _, err := wipLimits(config); err != nil {
+		return err
+	}
+	if _, err := checkpointLimit(config); err != nil {
+		return err
+	}
+	if _, err := parseDescriptionLimit(config); err != nil {
+		return err
+	}
+	if _, _, err := tombstoneSettings(config); err != nil {
+		return err
+	}
+	if _, err := nagInterval(config); err != nil {
+		return err
+	}
+	if _, err := argGuardSettings(config); err != nil {
+		return err
+	}
+	if _, err := noDupSetting(config); err != nil {
+		return err
+	}
+	if _, err := listDefaults(config, ""); err != nil {
+		return err
+	}
+	if err := checkOutputSettings(config, ""); err != nil {
+		return err
+	}
+	for key := range config.withPrefix("list.") {
+		if i := strings.LastIndex(key, "."); i != -1 {
+			if _, err := listDefaults(config, key[:i]); err != nil {
+				return err
+			}
+			if err := checkOutputSettings(config, key[:i]); err != nil {
+				return err
+			}
+		}
+	}
+	for _, key := range []string{"taskdir", "done_file"} {
+		if value, ok := config.Get(key); ok {
+			if _, err := expandPath(value, key); err != nil {
+				return err
+			}
+		}
+	}
+	if value, ok := config.Get("index_base"); ok {
+		if _, err := parseIndexBase(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkOutputSettings checks the format and color the config sets for
+// the named list, "" for the default list.
+func checkOutputSettings(config Config, list string) error {
+	if value, ok := config.listSetting(list, "format"); ok {
+		if _, err := parseFormat(value); err != nil {
+			return fmt.Errorf("format = %s: %v", value, err)
+		}
+	}
+	if value, ok := config.listSetting(list, "color"); ok {
+		if _, err := colorSetting(value, false); err != nil {
+			return fmt.Errorf("invalid color = %s, expected always, never or auto", value)
+		}
+	}
+	return nil
+}
+
+// checkClock checks that none of the times stored in the tasks file and
+// its done file is in the future, which happens when the clock is or
+// was wrong.
+func checkClock(path string, now time.Time) error {
+	latest := time.Time{}
+	for _, file := range []string{path, donePath(path)} {
+		list, err := readTaskList(file)
+		if err != nil {
+			continue
+		}
+		for _, task := range list.Tasks {
+			for _, t := range []time.Time{task.CreatedAt, task.DoneAt} {
+				if t.After(latest) {
+					latest = t
+				}
+			}
+		}
+	}
+	if latest.After(now.Add(clockSlack)) {
+		return fmt.Errorf("a task was stored at %s, but it is %s now",
+			latest.Local().Format(time.RFC3339), now.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// runDoctor handles --doctor: it runs every check, prints whether it
+// passed and how to fix it if not, and returns the exit status, 1 if any
+// check failed.
+func runDoctor(path string, configPath string, now time.Time) int {
+	status := 0
+	for _, check := range doctorChecks(path, configPath, now) {
+		if err := check.run(); err != nil {
+			fmt.Printf("FAIL  %s: %v\n      %s\n", check.name, err, check.hint)
+			status = 1
+		} else {
+			fmt.Printf("ok    %s\n", check.name)
+		}
+	}
+	return status
+}
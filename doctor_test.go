@@ -0,0 +1,56 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckClock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks")
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+	ioutil.WriteFile(path, []byte("foo | created:2024-06-30T12:30:00Z"), 0644)
+	if err := checkClock(path, now); err != nil {
+		t.Fatalf("Expected half an hour ahead to pass, got %v", err)
+	}
+	ioutil.WriteFile(donePath(path), []byte("bar | done:2025-01-01T00:00:00Z"), 0644)
+	if err := checkClock(path, now); err == nil {
+		t.Fatal("Expected a task finished next year to fail")
+	}
+}
+
+func TestCheckWritableDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := checkWritableDir(filepath.Join(dir, "not", "yet")); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 0 {
+		t.Fatalf("Expected the check to leave nothing behind, got %d files", len(files))
+	}
+}
+
+func TestCliDoctor(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("foo"), 0644)
+		cmd := exec.Command(tBinary, "--doctor")
+		cmd.Env = append(os.Environ(), "XDG_CONFIG_HOME="+t.TempDir())
+		if out, err := cmd.Output(); err != nil {
+			t.Fatalf("Expected every check to pass, got %v:\n%s", err, out)
+		}
+		ioutil.WriteFile("/tmp/tasks", []byte("foo | due:2024-13-45"), 0644)
+		cmd = exec.Command(tBinary, "--doctor")
+		cmd.Env = append(os.Environ(), "XDG_CONFIG_HOME="+t.TempDir())
+		out, err := cmd.Output()
+		if err == nil {
+			t.Fatal("Expected an invalid tasks file to fail")
+		}
+		if !strings.Contains(string(out), "FAIL  tasks file is valid") || !strings.Contains(string(out), "t --check") {
+			t.Fatalf("Expected the failed check and its hint, got\n%s", out)
+		}
+	})
+}
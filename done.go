@@ -0,0 +1,362 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+// defaultDoneLimit is how many finished tasks the done file holds before
+// older ones are rolled over into quarterly segments.
+const defaultDoneLimit = 1000
+
+// doneFilePath is the done file set with --done-file or T_DONE_FILE,
+// which the default tasks file uses instead of its own.
+var doneFilePath string
+
+// donePath returns the done file finished tasks of the tasks file at
+// path are kept in.
+func donePath(path string) string {
+	if doneFilePath != "" && path == taskFilePath {
+		return doneFilePath
+	}
+	return path + ".done"
+}
+
+// getDoneFilePath returns the done file set with --done-file, given as
+// file, done_file in the config or T_DONE_FILE, and "" if there is none.
+func getDoneFilePath(file string, config Config) (string, error) {
+	if file != "" {
+		return expandPath(file, "--done-file")
+	}
+	if path, ok := config.Get("done_file"); ok {
+		return expandPath(path, "done_file")
+	}
+	if path := os.Getenv("T_DONE_FILE"); path != "" {
+		return expandPath(path, "T_DONE_FILE")
+	}
+	return "", nil
+}
+
+// brokenDoneLine reports whether a line of a done file can't be the line
+// t wrote for a task, like one cut short by a crash or a sync: its
+// metadata doesn't parse, or is invalid. t escapes the " | " of a
+// description in the done file, so what follows an unescaped one is
+// always metadata there.
+func brokenDoneLine(line string) bool {
+	if strings.TrimSpace(line) == "" {
+		return false
+	}
+	task := Task{}
+	if err := task.UnmarshalText([]byte(line)); err != nil {
+		return true
+	}
+	if sep := strings.LastIndex(line, core.MetaSeparator); sep != -1 {
+		if _, ok := core.ParseMeta(line[sep+len(core.MetaSeparator):]); !ok && looksLikeMeta(line[sep:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDone reads the text of a done file, or of a segment of it,
+// skipping the lines brokenDoneLine finds, and returns how many it
+// skipped.
+func parseDone(text []byte) (*TaskList, int, error) {
+	lines := strings.Split(string(text), "\n")
+	kept := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if !brokenDoneLine(line) {
+			kept = append(kept, line)
+		}
+	}
+	list := &TaskList{}
+	return list, len(lines) - len(kept), list.UnmarshalText([]byte(strings.Join(kept, "\n")))
+}
+
+// readDoneList reads the done file, or a segment of it, at path like
+// readTaskList, except that broken lines are skipped rather than failing
+// the read, with a warning saying how to deal with them. A missing file
+// is an empty list.
+func readDoneList(path string) (*TaskList, error) {
+	text, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &TaskList{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	list, skipped, err := parseDone(text)
+	if skipped > 0 {
+		warnBrokenDone(skipped)
+	}
+	return list, err
+}
+
+// brokenDoneWarned is set once the broken lines of the done file were
+// warned about, so that a run reading it twice warns once.
+var brokenDoneWarned bool
+
+func warnBrokenDone(skipped int) {
+	if brokenDoneWarned {
+		return
+	}
+	brokenDoneWarned = true
+	fmt.Fprintf(os.Stderr, "done file: %d unparseable lines skipped, run t --check --done\n", skipped)
+}
+
+// readDoneHistory reads every task finished so far: those in the
+// segments of the done file at path, oldest first, then those in the
+// done file itself.
+func readDoneHistory(path string) (*TaskList, error) {
+	segments, err := filepath.Glob(path + ".[0-9][0-9][0-9][0-9]-Q[1-4]")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(segments)
+	done := &TaskList{}
+	for _, name := range append(segments, path) {
+		segment, err := readDoneList(name)
+		if err != nil {
+			return nil, err
+		}
+		done.Tasks = append(done.Tasks, segment.Tasks...)
+	}
+	return done, nil
+}
+
+// doneSegmentPath returns the segment of the done file that tasks
+// finished in the quarter of t are rolled over into, like
+// tasks.done.2024-Q1.
+func doneSegmentPath(path string, t time.Time) string {
+	t = t.Local()
+	return fmt.Sprintf("%s.%d-Q%d", path, t.Year(), (int(t.Month())+2)/3)
+}
+
+// doneLimit returns the rollover threshold, T_DONE_LIMIT or
+// defaultDoneLimit.
+func doneLimit() (int, error) {
+	value := os.Getenv("T_DONE_LIMIT")
+	if value == "" {
+		return defaultDoneLimit, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 {
+		return 0, fmt.Errorf("invalid T_DONE_LIMIT %q", value)
+	}
+	return limit, nil
+}
+
+// recordDone appends a finished task to the done file, rolling the file
+// over once it holds more than limit tasks.
+func recordDone(path string, task *Task, now time.Time, limit int) error {
+	tx := newTransaction(journalPath(path))
+	if err := stageDone(tx, path, []*Task{task}, now, limit); err != nil {
+		tx.abort()
+		return err
+	}
+	return tx.commit()
+}
+
+// stageDone stages appending the finished tasks, finished at now, to the
+// done file at path in tx, rolling the file over once it holds more than
+// limit tasks. Subtasks follow the tasks they were under, each on a line
+// of its own.
+func stageDone(tx *transaction, path string, tasks []*Task, now time.Time, limit int) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	text, err := tx.read(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, task := range withChildren(tasks) {
+		task.DoneAt = now.Truncate(time.Second)
+		line, err := task.MarshalText()
+		if err != nil {
+			return err
+		}
+		if len(text) > 0 {
+			text = append(text, '\n')
+		}
+		text = append(text, line...)
+	}
+	if err := tx.write(path, text, 0644); err != nil {
+		return err
+	}
+	// Rolling over is housekeeping; the tasks are recorded without it.
+	if err := stageRollOver(tx, path, now, limit); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: not rolling over %s: %v\n", path, err)
+	}
+	return nil
+}
+
+// writeDone writes the list like write does, and records the finished
+// tasks in its done file in the same transaction.
+func (t *TaskList) writeDone(finished []*Task, now time.Time, limit int) error {
+	tx := newTransaction(journalPath(taskFilePath))
+	if err := stageDone(tx, donePath(taskFilePath), finished, now, limit); err != nil {
+		tx.abort()
+		return err
+	}
+	return t.commit(tx, true)
+}
+
+// readStaged reads the task list in the file at path as tx leaves it. A
+// missing file is an empty list.
+func readStaged(tx *transaction, path string) (*TaskList, error) {
+	list := &TaskList{}
+	text, err := tx.read(path)
+	if os.IsNotExist(err) {
+		return list, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return list, list.UnmarshalText(text)
+}
+
+// stageRollOver stages moving the tasks finished before the current
+// quarter from the done file at path into their quarterly segments in
+// tx, once the file holds more than limit tasks, keeping the done file
+// itself short. A done file with broken lines isn't rolled over, which
+// would drop them before t --check --done --fix put them aside.
+func stageRollOver(tx *transaction, path string, now time.Time, limit int) error {
+	text, err := tx.read(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	done, skipped, err := parseDone(text)
+	if err != nil || skipped > 0 || len(done.Tasks) <= limit {
+		return err
+	}
+	current := doneSegmentPath(path, now)
+	moved := make(map[string][]*Task)
+	kept := make([]*Task, 0)
+	for _, task := range done.Tasks {
+		segment := doneSegmentPath(path, task.DoneAt)
+		if task.DoneAt.IsZero() || segment == current {
+			kept = append(kept, task)
+		} else {
+			moved[segment] = append(moved[segment], task)
+		}
+	}
+	if len(moved) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(moved))
+	for name := range moved {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	// Every segment is read before any is staged, so that one that
+	// can't be read leaves tx as it was.
+	segments := make([]*TaskList, len(names))
+	for i, name := range names {
+		if segments[i], err = readStaged(tx, name); err != nil {
+			return err
+		}
+	}
+	for i, name := range names {
+		segments[i].Tasks = append(segments[i].Tasks, moved[name]...)
+		if err := segments[i].stageTo(tx, name, false); err != nil {
+			return err
+		}
+	}
+	done.Tasks = kept
+	return done.stageTo(tx, path, false)
+}
+
+// readDoneSince reads the tasks finished since a moment: those in the
+// segments of the quarters from then on, oldest first, and those in the
+// done file at path. It doesn't filter the tasks themselves.
+func readDoneSince(path string, since, now time.Time) (*TaskList, error) {
+	done := &TaskList{}
+	since = since.Local()
+	quarter := time.Date(since.Year(), since.Month()-(since.Month()-1)%3, 1, 0, 0, 0, 0, time.Local)
+	for !quarter.After(now) {
+		segment, err := readDoneList(doneSegmentPath(path, quarter))
+		if err != nil {
+			return nil, err
+		}
+		done.Tasks = append(done.Tasks, segment.Tasks...)
+		quarter = quarter.AddDate(0, 3, 0)
+	}
+	recent, err := readDoneList(path)
+	if err != nil {
+		return nil, err
+	}
+	done.Tasks = append(done.Tasks, recent.Tasks...)
+	return done, nil
+}
+
+// PurgeOlderThan drops the tasks of a done list finished more than age
+// ago and returns how many it dropped. Tasks without a finishing time
+// are kept, since their age isn't known.
+func (t *TaskList) PurgeOlderThan(age time.Duration) int {
+	cutoff := time.Now().Add(-age)
+	kept := make([]*Task, 0, len(t.Tasks))
+	for _, task := range t.Tasks {
+		if task.DoneAt.IsZero() || !task.DoneAt.Before(cutoff) {
+			kept = append(kept, task)
+		}
+	}
+	purged := len(t.Tasks) - len(kept)
+	t.Tasks = kept
+	return purged
+}
+
+// purgeDone handles --purge-done: it drops the tasks finished more than
+// age ago from the done file at path and its segments, removing the
+// segments left empty, all in one transaction, and returns how many it
+// dropped.
+func purgeDone(path string, age time.Duration) (int, error) {
+	segments, err := filepath.Glob(path + ".[0-9][0-9][0-9][0-9]-Q[1-4]")
+	if err != nil {
+		return 0, err
+	}
+	tx := newTransaction(journalPath(path))
+	purged := 0
+	for _, name := range append(segments, path) {
+		text, err := ioutil.ReadFile(name)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			tx.abort()
+			return 0, err
+		}
+		// Rewriting the file would drop its broken lines unseen.
+		done, skipped, err := parseDone(text)
+		if err == nil && skipped > 0 {
+			err = fmt.Errorf("%s has %d unparseable lines, run t --check --done --fix first", name, skipped)
+		}
+		if err != nil {
+			tx.abort()
+			return 0, err
+		}
+		n := done.PurgeOlderThan(age)
+		if n == 0 {
+			continue
+		}
+		if err := done.stageTo(tx, name, name != path); err != nil {
+			tx.abort()
+			return 0, err
+		}
+		purged += n
+	}
+	if err := tx.commit(); err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
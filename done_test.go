@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+func TestDoneRollover(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.done")
+	ioutil.WriteFile(path, []byte("a | done:2024-01-10T09:00:00Z\nb | done:2024-04-02T09:00:00Z\nlegacy"), 0644)
+	now := time.Date(2024, 7, 1, 12, 0, 0, 0, time.Local)
+
+	if err := recordDone(path, &Task{Description: "c"}, now, 4); err != nil {
+		t.Fatal(err)
+	}
+	done, _ := readTaskList(path)
+	if len(done.Tasks) != 4 {
+		t.Fatalf("Expected no rollover below the limit, got %d tasks", len(done.Tasks))
+	}
+
+	if err := recordDone(path, &Task{Description: "d"}, now, 4); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		path:              "legacy\nc | done:" + now.UTC().Format(time.RFC3339) + "\nd | done:" + now.UTC().Format(time.RFC3339),
+		path + ".2024-Q1": "a | done:2024-01-10T09:00:00Z",
+		path + ".2024-Q2": "b | done:2024-04-02T09:00:00Z",
+	}
+	for file, contents := range expected {
+		text, _ := ioutil.ReadFile(file)
+		if string(text) != contents {
+			t.Fatalf("Expected %s to be '%s', got '%s'", filepath.Base(file), contents, text)
+		}
+	}
+}
+
+func TestDoneSegmentPath(t *testing.T) {
+	cases := map[time.Month]string{1: "Q1", 3: "Q1", 4: "Q2", 9: "Q3", 12: "Q4"}
+	for month, quarter := range cases {
+		path := doneSegmentPath("tasks.done", time.Date(2024, month, 15, 0, 0, 0, 0, time.Local))
+		if path != "tasks.done.2024-"+quarter {
+			t.Fatalf("Expected month %d to be in %s, got %s", month, quarter, path)
+		}
+	}
+}
+
+func TestReadDoneSince(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.done")
+	ioutil.WriteFile(path+".2023-Q4", []byte("too old | done:2023-12-10T09:00:00Z"), 0644)
+	ioutil.WriteFile(path+".2024-Q1", []byte("a | done:2024-01-10T09:00:00Z\nb | done:2024-03-02T09:00:00Z"), 0644)
+	ioutil.WriteFile(path, []byte("c | done:2024-07-01T09:00:00Z"), 0644)
+	since := time.Date(2024, 2, 1, 0, 0, 0, 0, time.Local)
+	now := time.Date(2024, 7, 2, 12, 0, 0, 0, time.Local)
+
+	done, err := readDoneSince(path, since, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks, _ := done.doneSince(since)
+	descriptions := make([]string, 0)
+	for _, task := range tasks {
+		descriptions = append(descriptions, task.Description)
+	}
+	if strings.Join(descriptions, " ") != "b c" {
+		t.Fatalf("Expected b and c to be finished since February, got %v", descriptions)
+	}
+}
+
+func TestReadDoneHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.done")
+	ioutil.WriteFile(path+".2024-Q1", []byte("b | done:2024-01-10T09:00:00Z"), 0644)
+	ioutil.WriteFile(path+".2023-Q4", []byte("a | done:2023-12-10T09:00:00Z"), 0644)
+	ioutil.WriteFile(path+".bak", []byte("not a segment"), 0644)
+	ioutil.WriteFile(path, []byte("c | done:2024-07-01T09:00:00Z"), 0644)
+
+	done, err := readDoneHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	descriptions := make([]string, 0)
+	for _, task := range done.Tasks {
+		descriptions = append(descriptions, task.Description)
+	}
+	if strings.Join(descriptions, " ") != "a b c" {
+		t.Fatalf("Expected every finished task, oldest first, got %v", descriptions)
+	}
+}
+
+func TestPurgeOlderThan(t *testing.T) {
+	now := time.Now()
+	done := &TaskList{TaskList: core.TaskList{Tasks: []*Task{
+		{Description: "old", DoneAt: now.AddDate(0, 0, -40)},
+		{Description: "recent", DoneAt: now.AddDate(0, 0, -2)},
+		{Description: "legacy"},
+		{Description: "older", DoneAt: now.AddDate(-1, 0, 0)},
+	}}}
+	if n := done.PurgeOlderThan(30 * 24 * time.Hour); n != 2 {
+		t.Fatalf("Expected 2 tasks dropped, got %d", n)
+	}
+	if joinDescriptions(done.Tasks) != "recent legacy" {
+		t.Fatalf("Expected recent and legacy to be kept, got %s", joinDescriptions(done.Tasks))
+	}
+}
+
+func TestPurgeDone(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.done")
+	recent := time.Now().AddDate(0, 0, -1).UTC().Format(time.RFC3339)
+	ioutil.WriteFile(path+".2023-Q4", []byte("a | done:2023-12-10T09:00:00Z"), 0644)
+	ioutil.WriteFile(path, []byte("b | done:2024-01-10T09:00:00Z\nc | done:"+recent), 0644)
+	purged, err := purgeDone(path, 720*time.Hour)
+	if err != nil || purged != 2 {
+		t.Fatalf("Expected 2 tasks dropped, got %d (%v)", purged, err)
+	}
+	if _, err := os.Stat(path + ".2023-Q4"); !os.IsNotExist(err) {
+		t.Fatal("Expected the emptied segment to be removed")
+	}
+	if text, _ := ioutil.ReadFile(path); string(text) != "c | done:"+recent {
+		t.Fatalf("Expected only c to be left, got '%s'", text)
+	}
+}
+
+func TestCliDoneFile(t *testing.T) {
+	withCliSetup(t, func() {
+		path := filepath.Join(t.TempDir(), "finished")
+		ioutil.WriteFile("/tmp/tasks", []byte("water plants\npay rent\nfix bike"), 0644)
+		env := append(os.Environ(), "T_DONE_FILE="+path)
+		for _, args := range [][]string{{"-f", "0"}, {"-f", "0"}, {"--done-file", path + ".other", "-f", "0"}} {
+			cmd := exec.Command(tBinary, args...)
+			cmd.Env = env
+			if err := cmd.Run(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		cmd := exec.Command(tBinary, "-D")
+		cmd.Env = env
+		out, _ := cmd.Output()
+		today := time.Now().Format(dateLayout)
+		expected := today + " - water plants\n" + today + " - pay rent\n"
+		if string(out) != expected {
+			t.Fatalf("Expected output to be '%s', got '%s'", expected, out)
+		}
+		cmd = exec.Command(tBinary, "-D", "--done-file", path+".other", "bike")
+		out, _ = cmd.Output()
+		if string(out) != today+" - fix bike\n" {
+			t.Fatalf("Expected --done-file to override T_DONE_FILE, got '%s'", out)
+		}
+		if _, err := os.Stat("/tmp/tasks.done"); err == nil {
+			t.Fatal("Expected nothing in the default done file")
+		}
+	})
+}
+
+// brokenDone is a done file two of whose lines were cut short.
+const brokenDone = "pay rent | done:2024-06-01T09:00:00Z\n" +
+	"call bob | done:2024-06-0\n" +
+	"buy milk | done:2024-06-02T09:00:00Z\n" +
+	"water plants | priority:2 due:2024-0"
+
+func TestParseDoneSkipsBrokenLines(t *testing.T) {
+	done, skipped, err := parseDone([]byte(brokenDone))
+	if err != nil || skipped != 2 {
+		t.Fatalf("Expected 2 lines skipped, got %d (%v)", skipped, err)
+	}
+	if len(done.Tasks) != 2 || done.Tasks[1].Description != "buy milk" {
+		t.Fatalf("Expected the two good tasks, got %v", done.Tasks)
+	}
+	for _, line := range []string{"unknown", `a \| b | done:2024-06-01T09:00:00Z`, "ratio 1 | 2"} {
+		if brokenDoneLine(line) {
+			t.Errorf("Expected '%s' to be read", line)
+		}
+	}
+}
+
+func TestCliBrokenDoneFile(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("fix bike"), 0644)
+		ioutil.WriteFile("/tmp/tasks.done", []byte(brokenDone), 0644)
+		cmd := exec.Command(tBinary, "-D")
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil || string(out) != "2024-06-01 - pay rent\n2024-06-02 - buy milk\n" {
+			t.Fatalf("Expected the readable tasks, got '%s' (%v)", out, err)
+		}
+		if !strings.Contains(stderr.String(), "2 unparseable lines skipped, run t --check --done") {
+			t.Fatalf("Expected the skipped lines reported, got '%s'", stderr.String())
+		}
+		if err := exec.Command(tBinary, "-f", "0").Run(); err != nil {
+			t.Fatalf("Expected finishing to work anyway, got %v", err)
+		}
+		text, _ := ioutil.ReadFile("/tmp/tasks.done")
+		if !strings.HasPrefix(string(text), brokenDone+"\nfix bike | done:") {
+			t.Fatalf("Expected the task appended and the broken lines kept, got '%s'", text)
+		}
+	})
+}
+
+func TestCliCheckDoneFix(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks.done", []byte(brokenDone), 0644)
+		out, err := exec.Command(tBinary, "--check", "--done").Output()
+		expected := "/tmp/tasks.done: line 2: unparseable, cut short?\n/tmp/tasks.done: line 4: unparseable, cut short?\n"
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 || string(out) != expected {
+			t.Fatalf("Expected the broken lines reported, got '%s' (%v)", out, err)
+		}
+		out, err = exec.Command(tBinary, "--check", "--done", "--fix", "-y").Output()
+		if err != nil || !strings.HasSuffix(string(out), "moved 2 lines to /tmp/tasks.done.corrupt\n") {
+			t.Fatalf("Expected the broken lines moved, got '%s' (%v)", out, err)
+		}
+		text, _ := ioutil.ReadFile("/tmp/tasks.done")
+		if string(text) != "pay rent | done:2024-06-01T09:00:00Z\nbuy milk | done:2024-06-02T09:00:00Z" {
+			t.Fatalf("Expected only the good lines left, got '%s'", text)
+		}
+		text, _ = ioutil.ReadFile("/tmp/tasks.done.corrupt")
+		if string(text) != "call bob | done:2024-06-0\nwater plants | priority:2 due:2024-0\n" {
+			t.Fatalf("Expected the broken lines put aside, got '%s'", text)
+		}
+		if err := exec.Command(tBinary, "--check", "--done").Run(); err != nil {
+			t.Fatalf("Expected the done file to check clean, got %v", err)
+		}
+	})
+}
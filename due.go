@@ -0,0 +1,217 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+// dateLayout is the layout dates are stored in, and the one of JSON,
+// CSV and --plain output.
+const dateLayout = core.DateLayout
+
+// dateFormat is the layout of dates in listings, and one accepted on the
+// command line besides YYYY-MM-DD, set by the dateformat setting.
+var dateFormat = dateLayout
+
+// numericDate matches dates like 03/04/2024 or 3.4.24, which can't be
+// read without knowing whether the day or the month comes first.
+var numericDate = regexp.MustCompile(`^\d{1,2}[./-]\d{1,2}[./-]\d{2,4}$`)
+
+// parseDateFormat reads the dateformat setting, a Go layout like
+// 02.01.2006, which has to have a day, a month and a year.
+func parseDateFormat(config Config) (string, error) {
+	value, ok := config.Get("dateformat")
+	if !ok {
+		return dateLayout, nil
+	}
+	reference := time.Date(2006, time.January, 2, 0, 0, 0, 0, time.UTC)
+	parsed, err := time.Parse(value, reference.Format(value))
+	if err != nil || !parsed.Equal(reference) {
+		return dateLayout, fmt.Errorf("invalid dateformat = %s, expected a layout like 02.01.2006", value)
+	}
+	return value, nil
+}
+
+// displayDate renders a date for listings, in dateFormat.
+func displayDate(date time.Time) string {
+	return date.Format(dateFormat)
+}
+
+// errNotADate is returned by parseDate for what isn't a date at all, for
+// the caller to say what else it would have taken.
+var errNotADate = errors.New("not a date")
+
+// parseDate parses a date given on the command line, YYYY-MM-DD or in
+// dateFormat. Without a dateformat, dates like 03/04/2024 are an error
+// rather than a guess at which of the numbers is the day.
+func parseDate(s string, loc *time.Location) (time.Time, error) {
+	if date, err := time.ParseInLocation(dateLayout, s, loc); err == nil {
+		return date, nil
+	}
+	if dateFormat != dateLayout {
+		if date, err := time.ParseInLocation(dateFormat, s, loc); err == nil {
+			return date, nil
+		}
+	} else if numericDate.MatchString(s) {
+		return time.Time{}, fmt.Errorf("ambiguous date %q, use YYYY-MM-DD or set the order of day and month with e.g. dateformat = 02.01.2006 in the config", s)
+	}
+	return time.Time{}, errNotADate
+}
+
+// dateForms describes the dates parseDate takes, for error messages.
+func dateForms() string {
+	if dateFormat != dateLayout {
+		return dateFormat + ", YYYY-MM-DD"
+	}
+	return "YYYY-MM-DD"
+}
+
+// parseDue parses a due date as given on the command line: a date as
+// parseDate takes it, the keywords today and tomorrow, or an offset from
+// today like +3d.
+func parseDue(s string, now time.Time) (time.Time, error) {
+	switch s {
+	case "today":
+		return startOfDay(now), nil
+	case "tomorrow":
+		return startOfDay(now).AddDate(0, 0, 1), nil
+	}
+	if strings.HasPrefix(s, "+") {
+		days, err := parseOffset(s)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return startOfDay(now).AddDate(0, 0, days), nil
+	}
+	due, err := parseDate(s, now.Location())
+	if err == errNotADate {
+		return time.Time{}, fmt.Errorf("invalid date %q, expected %s, today, tomorrow or +Nd", s, dateForms())
+	}
+	return due, err
+}
+
+// parseOffset parses a relative offset in days like +2d, -1d or +1w and
+// returns the number of days it stands for.
+func parseOffset(s string) (int, error) {
+	invalid := fmt.Errorf("invalid offset %q, expected e.g. +2d or +1w", s)
+	if len(s) < 3 || (s[0] != '+' && s[0] != '-') {
+		return 0, invalid
+	}
+	unit := 1
+	switch s[len(s)-1] {
+	case 'd':
+	case 'w':
+		unit = 7
+	default:
+		return 0, invalid
+	}
+	n, err := strconv.Atoi(s[1 : len(s)-1])
+	if err != nil || n < 0 {
+		return 0, invalid
+	}
+	if s[0] == '-' {
+		n = -n
+	}
+	return n * unit, nil
+}
+
+// parseDays parses a number of days like 14d or 2w.
+func parseDays(s string) (int, error) {
+	return parseOffset("+" + s)
+}
+
+// parseDuration parses a length of time given on the command line or in
+// the config: a number of days like 30, days or weeks like 30d or 2w,
+// or a Go duration like 36h.
+func parseDuration(s string) (time.Duration, error) {
+	if days, err := strconv.Atoi(s); err == nil && days >= 0 {
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	if days, err := parseDays(s); err == nil {
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q, expected e.g. 30, 30d, 2w or 36h", s)
+	}
+	return d, nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	return core.StartOfDay(t)
+}
+
+// relativeDueDays is how many days away a due date may be to still be
+// shown relative to today.
+const relativeDueDays = 14
+
+// dueSoonDays is how many days ahead --due-soon looks.
+const dueSoonDays = 7
+
+// dueSoonIds returns those of the given task ids whose tasks are due
+// within dueSoonDays of now, or overdue.
+func (t *TaskList) dueSoonIds(ids []int, now time.Time) []int {
+	soon := make([]int, 0)
+	for _, taskId := range ids {
+		task := t.Tasks[taskId]
+		if !task.DueAt.IsZero() && daysBetween(now, task.DueAt) <= dueSoonDays {
+			soon = append(soon, taskId)
+		}
+	}
+	return soon
+}
+
+// daysBetween returns the number of calendar days from a to b, as
+// package tasklist counts them for recurring tasks.
+func daysBetween(a, b time.Time) int {
+	return core.DaysBetween(a, b)
+}
+
+// formatDue renders a due date relative to now, like "tomorrow" or
+// "in 3 days", or as the date itself when it is further away.
+func formatDue(due time.Time, now time.Time) string {
+	days := daysBetween(now, due)
+	switch {
+	case days == 0:
+		return "today"
+	case days == 1:
+		return "tomorrow"
+	case days == -1:
+		return "yesterday"
+	case days > 1 && days <= relativeDueDays:
+		return fmt.Sprintf("in %d days", days)
+	case days < -1 && days >= -relativeDueDays:
+		return fmt.Sprintf("%d days ago", -days)
+	}
+	return displayDate(due)
+}
+
+// formatAge renders how long ago something happened in the largest unit
+// that fits, rounding down: 59m, 1h, 6d, 1w, 52w and >1y past a year.
+func formatAge(age time.Duration) string {
+	const (
+		day  = 24 * time.Hour
+		week = 7 * day
+		year = 365 * day
+	)
+	switch {
+	case age < time.Hour:
+		if age < 0 {
+			age = 0
+		}
+		return fmt.Sprintf("%dm", age/time.Minute)
+	case age < day:
+		return fmt.Sprintf("%dh", age/time.Hour)
+	case age < week:
+		return fmt.Sprintf("%dd", age/day)
+	case age < year:
+		return fmt.Sprintf("%dw", age/week)
+	}
+	return ">1y"
+}
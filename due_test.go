@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDue(t *testing.T) {
+	now := time.Date(2024, 5, 30, 18, 30, 0, 0, time.Local)
+	cases := map[string]string{
+		"today":      "2024-05-30",
+		"tomorrow":   "2024-05-31",
+		"+3d":        "2024-06-02",
+		"+1w":        "2024-06-06",
+		"2024-07-01": "2024-07-01",
+	}
+	for in, expected := range cases {
+		due, err := parseDue(in, now)
+		if err != nil {
+			t.Fatalf("parseDue(%q) failed: %v", in, err)
+		}
+		if due.Format(dateLayout) != expected {
+			t.Fatalf("parseDue(%q): expected %s, got %s", in, expected, due.Format(dateLayout))
+		}
+	}
+	for _, in := range []string{"", "next week", "2024-13-01", "+d", "3d"} {
+		if _, err := parseDue(in, now); err == nil {
+			t.Fatalf("expected parseDue(%q) to fail", in)
+		}
+	}
+}
+
+func TestParseDueDateFormat(t *testing.T) {
+	now := time.Date(2024, 5, 30, 18, 30, 0, 0, time.Local)
+	if _, err := parseDue("03/04/2024", now); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Fatalf("Expected 03/04/2024 to be ambiguous without a dateformat, got %v", err)
+	}
+	defer func() { dateFormat = dateLayout }()
+	dateFormat = "02.01.2006"
+	for in, expected := range map[string]string{"03.04.2024": "2024-04-03", "2024-04-03": "2024-04-03"} {
+		due, err := parseDue(in, now)
+		if err != nil || due.Format(dateLayout) != expected {
+			t.Fatalf("parseDue(%q): expected %s, got %v (%v)", in, expected, due, err)
+		}
+	}
+	if _, err := parseDue("04/03/2024", now); err == nil {
+		t.Fatal("Expected a date in neither format to fail")
+	}
+	due := time.Date(2024, 8, 1, 0, 0, 0, 0, time.Local)
+	if got := formatDue(due, now); got != "01.08.2024" {
+		t.Fatalf("Expected far due dates in the dateformat, got %s", got)
+	}
+}
+
+func TestParseDateFormat(t *testing.T) {
+	for value, valid := range map[string]bool{"02.01.2006": true, "01/02/2006": true, "Jan 2": false, "dd.mm.yyyy": false} {
+		_, err := parseDateFormat(Config{values: map[string]string{"dateformat": value}})
+		if (err == nil) != valid {
+			t.Fatalf("parseDateFormat(%q): expected valid to be %v, got %v", value, valid, err)
+		}
+	}
+}
+
+func TestParseOffset(t *testing.T) {
+	cases := map[string]int{"+2d": 2, "-1d": -1, "+1w": 7, "+10d": 10}
+	for in, expected := range cases {
+		days, err := parseOffset(in)
+		if err != nil {
+			t.Fatalf("parseOffset(%q) failed: %v", in, err)
+		}
+		if days != expected {
+			t.Fatalf("parseOffset(%q): expected %d, got %d", in, expected, days)
+		}
+	}
+	for _, in := range []string{"2d", "+2", "+xd", "+-2d"} {
+		if _, err := parseOffset(in); err == nil {
+			t.Fatalf("expected parseOffset(%q) to fail", in)
+		}
+	}
+}
+
+func TestDaysBetween(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no time zone data")
+	}
+	cases := []struct {
+		a, b time.Time
+		days int
+	}{
+		{time.Date(2024, 6, 1, 23, 59, 0, 0, berlin), time.Date(2024, 6, 2, 0, 0, 0, 0, berlin), 1},
+		{time.Date(2024, 6, 2, 0, 1, 0, 0, berlin), time.Date(2024, 6, 2, 0, 0, 0, 0, berlin), 0},
+		{time.Date(2024, 3, 30, 12, 0, 0, 0, berlin), time.Date(2024, 4, 1, 0, 0, 0, 0, berlin), 2},
+		{time.Date(2024, 10, 28, 0, 30, 0, 0, berlin), time.Date(2024, 10, 26, 0, 0, 0, 0, berlin), -2},
+	}
+	for _, c := range cases {
+		if days := daysBetween(c.a, c.b); days != c.days {
+			t.Fatalf("daysBetween(%v, %v): expected %d, got %d", c.a, c.b, c.days, days)
+		}
+	}
+}
+
+func TestFormatDue(t *testing.T) {
+	now := time.Date(2024, 6, 10, 23, 30, 0, 0, time.Local)
+	cases := map[string]string{
+		"2024-06-10": "today",
+		"2024-06-11": "tomorrow",
+		"2024-06-09": "yesterday",
+		"2024-06-13": "in 3 days",
+		"2024-06-08": "2 days ago",
+		"2024-06-24": "in 14 days",
+		"2024-06-25": "2024-06-25",
+		"2024-05-27": "14 days ago",
+		"2024-05-26": "2024-05-26",
+	}
+	for date, expected := range cases {
+		due, _ := time.ParseInLocation(dateLayout, date, time.Local)
+		if formatted := formatDue(due, now); formatted != expected {
+			t.Fatalf("formatDue(%s): expected %s, got %s", date, expected, formatted)
+		}
+	}
+}
+
+func TestFormatAge(t *testing.T) {
+	cases := map[time.Duration]string{
+		-time.Minute:                  "0m",
+		30 * time.Second:              "0m",
+		59 * time.Minute:              "59m",
+		time.Hour:                     "1h",
+		23*time.Hour + 59*time.Minute: "23h",
+		24 * time.Hour:                "1d",
+		6*24*time.Hour + 23*time.Hour: "6d",
+		7 * 24 * time.Hour:            "1w",
+		364 * 24 * time.Hour:          "52w",
+		365 * 24 * time.Hour:          ">1y",
+	}
+	for age, expected := range cases {
+		if formatted := formatAge(age); formatted != expected {
+			t.Fatalf("formatAge(%v): expected %s, got %s", age, expected, formatted)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	cases := map[string]time.Duration{
+		"30":  30 * 24 * time.Hour,
+		"30d": 30 * 24 * time.Hour,
+		"2w":  14 * 24 * time.Hour,
+		"36h": 36 * time.Hour,
+	}
+	for s, expected := range cases {
+		if d, err := parseDuration(s); err != nil || d != expected {
+			t.Fatalf("parseDuration(%s): expected %v, got %v (%v)", s, expected, d, err)
+		}
+	}
+	for _, s := range []string{"", "soon", "-3", "-1h"} {
+		if _, err := parseDuration(s); err == nil {
+			t.Fatalf("Expected %q to be rejected", s)
+		}
+	}
+}
+
+func TestDueSoonIds(t *testing.T) {
+	now := time.Date(2024, 6, 10, 15, 0, 0, 0, time.Local)
+	tasklist := &TaskList{}
+	for _, c := range []struct {
+		description string
+		days        int
+	}{{"file taxes", 3}, {"renew passport", 30}, {"call dentist", -2}, {"read book", 0}, {"water lawn", 7}, {"fix bike", 8}} {
+		task, _ := tasklist.Add(c.description)
+		if c.description != "read book" {
+			task.DueAt = startOfDay(now).AddDate(0, 0, c.days)
+		}
+	}
+	ids := tasklist.dueSoonIds([]int{0, 1, 2, 3, 4, 5}, now)
+	if fmt.Sprint(ids) != "[0 2 4]" {
+		t.Fatalf("Expected the tasks due within a week or overdue, got %v", ids)
+	}
+}
+
+func TestCliDueSoon(t *testing.T) {
+	withCliSetup(t, func() {
+		for _, args := range [][]string{{"no date"}, {"--due", "+10d", "later"}, {"--due", "+3d", "file taxes"}, {"--due", "tomorrow", "call dentist"}} {
+			if err := exec.Command(tBinary, args...).Run(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		out, err := exec.Command(tBinary, "--due-soon", "--plain").Output()
+		expected := fmt.Sprintf("3 - call dentist (due %s)\n2 - file taxes (due %s)\n",
+			time.Now().AddDate(0, 0, 1).Format(dateLayout), time.Now().AddDate(0, 0, 3).Format(dateLayout))
+		if err != nil || string(out) != expected {
+			t.Fatalf("Expected '%s', got '%s' (%v)", expected, out, err)
+		}
+	})
+}
+
+func TestCliDueInvalid(t *testing.T) {
+	withCliSetup(t, func() {
+		err := exec.Command(tBinary, "--due", "someday", "file taxes").Run()
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 2 {
+			t.Fatalf("Expected a usage error, got %v", err)
+		}
+		if _, err := os.Stat("/tmp/tasks"); !os.IsNotExist(err) {
+			t.Fatal("Expected nothing added")
+		}
+	})
+}
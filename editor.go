@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+// editorCommand returns the command editing path in the user's editor:
+// $VISUAL or $EDITOR, which may have arguments like "code --wait", and
+// otherwise notepad on Windows, where neither is usually set, or vi.
+func editorCommand(path string) *exec.Cmd {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if fields := strings.Fields(os.Getenv(name)); len(fields) > 0 {
+			return exec.Command(fields[0], append(fields[1:], path)...)
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return exec.Command("notepad", path)
+	}
+	return exec.Command("vi", path)
+}
+
+// runEditor runs cmd, an editor, on t's terminal. It doesn't hold the
+// lock on the tasks file meanwhile, and reports whether another t
+// changed the file while it ran, as whileUnlocked does.
+func runEditor(cmd *exec.Cmd) (bool, error) {
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	var err error
+	changed, lockErr := whileUnlocked(func() { err = cmd.Run() })
+	if err != nil {
+		return changed, fmt.Errorf("%s failed: %v", cmd.Args[0], err)
+	}
+	return changed, lockErr
+}
+
+// editFile handles --edit-file: it opens a copy of the tasks file in the
+// editor and writes it back, records the change for --undo and warns if
+// the file no longer reads as a list of tasks. If another t changed the
+// tasks file meanwhile, the edit isn't written back, but kept in the
+// copy.
+func editFile(path string) error {
+	before, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	file, err := ioutil.TempFile("", "t-tasks-*.txt")
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(before)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	changed, err := runEditor(editorCommand(file.Name()))
+	if err == nil && changed {
+		return fmt.Errorf("the tasks file changed while it was being edited, the edit is kept in %s", file.Name())
+	}
+	defer os.Remove(file.Name())
+	if err != nil {
+		return err
+	}
+	after, err := ioutil.ReadFile(file.Name())
+	if err != nil {
+		return err
+	}
+	if string(after) == string(before) {
+		return nil
+	}
+	if err := core.WriteAtomic(path, after); err != nil {
+		return err
+	}
+	recordHistory(path, string(before), string(after))
+	if _, err := readTaskList(path); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v, see t --check\n", err)
+	}
+	return nil
+}
+
+// editDescription handles t -e without a description: it opens the
+// task's description in the editor and returns what it was changed to.
+// An editor that fails, an empty description or one of several lines
+// leave the task as it was.
+func editDescription(description string) (string, error) {
+	file, err := ioutil.TempFile("", "t-task-*.txt")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(description + "\n")
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+	if _, err := runEditor(editorCommand(file.Name())); err != nil {
+		return "", fmt.Errorf("%v, the task wasn't changed", err)
+	}
+	text, err := ioutil.ReadFile(file.Name())
+	if err != nil {
+		return "", err
+	}
+	edited := strings.TrimSuffix(strings.TrimSuffix(string(text), "\n"), "\r")
+	if strings.TrimSpace(edited) == "" {
+		return "", errors.New("the description is empty, the task wasn't changed")
+	}
+	if strings.ContainsAny(edited, "\r\n") {
+		return "", errors.New("the description has to be a single line, the task wasn't changed")
+	}
+	return edited, nil
+}
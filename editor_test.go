@@ -0,0 +1,82 @@
+package main
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestEditorCommand(t *testing.T) {
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "code --wait")
+	if args := editorCommand("tasks").Args; !reflect.DeepEqual(args, []string{"code", "--wait", "tasks"}) {
+		t.Fatalf("Expected $EDITOR with its arguments, got %v", args)
+	}
+	t.Setenv("VISUAL", "nano")
+	if args := editorCommand("tasks").Args; !reflect.DeepEqual(args, []string{"nano", "tasks"}) {
+		t.Fatalf("Expected $VISUAL to win, got %v", args)
+	}
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "")
+	expected := "vi"
+	if runtime.GOOS == "windows" {
+		expected = "notepad"
+	}
+	if args := editorCommand("tasks").Args; args[0] != expected {
+		t.Fatalf("Expected %s without an editor set, got %v", expected, args)
+	}
+}
+
+func TestCliEditFile(t *testing.T) {
+	withCliSetup(t, func() {
+		exec.Command(tBinary, "foo").Run()
+		cmd := exec.Command(tBinary, "--edit-file")
+		cmd.Env = append(cmd.Environ(), "VISUAL=", "EDITOR=sed -i s/foo/bar/")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("Expected the edit to succeed, got %v: %s", err, out)
+		}
+		out, _ := exec.Command(tBinary).Output()
+		if string(out) != "0 - bar\n" {
+			t.Fatalf("Expected output to be '0 - bar\n', got '%s'", out)
+		}
+		exec.Command(tBinary, "--undo").Run()
+		out, _ = exec.Command(tBinary).Output()
+		if string(out) != "0 - foo\n" {
+			t.Fatalf("Expected --undo to undo the edit, got '%s'", out)
+		}
+	})
+}
+
+func TestCliEditInEditor(t *testing.T) {
+	cases := []struct {
+		editor   string
+		expected string
+		code     int
+	}{
+		{"sed -i s/foo/bar/", "bar baz", 0},
+		{"false", "foo baz", 1},
+		{"sed -i s/.*//", "foo baz", 1},
+		{"sed -i s/foo/bar\\nqux/", "foo baz", 1},
+	}
+	for _, c := range cases {
+		withCliSetup(t, func() {
+			ioutil.WriteFile("/tmp/tasks", []byte("foo baz\n"), 0644)
+			cmd := exec.Command(tBinary, "-e", "0")
+			cmd.Env = append(cmd.Environ(), "VISUAL=", "EDITOR="+c.editor)
+			out, err := cmd.CombinedOutput()
+			code := 0
+			if exitErr, ok := err.(*exec.ExitError); ok {
+				code = exitErr.ExitCode()
+			}
+			if code != c.code {
+				t.Fatalf("Expected editing with %s to exit with %d, got %v: %s", c.editor, c.code, err, out)
+			}
+			if text, _ := ioutil.ReadFile("/tmp/tasks"); !strings.HasPrefix(string(text), c.expected) {
+				t.Fatalf("Expected editing with %s to leave '%s', got '%s'", c.editor, c.expected, text)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// noProject groups the tasks without a proj: token.
+const noProject = "(no project)"
+
+// token returns the value of the first key:value word in the task's
+// description, like est:2h or proj:website.
+func token(task *Task, key string) (string, bool) {
+	for _, word := range strings.Fields(task.Description) {
+		if strings.HasPrefix(word, key+":") && len(word) > len(key)+1 {
+			return word[len(key)+1:], true
+		}
+	}
+	return "", false
+}
+
+// estimate returns the task's est: duration.
+func estimate(task *Task) (time.Duration, bool) {
+	value, ok := token(task, "est")
+	if !ok {
+		return 0, false
+	}
+	estimate, err := time.ParseDuration(value)
+	if err != nil || estimate < 0 {
+		return 0, false
+	}
+	return estimate, true
+}
+
+// formatEstimate renders a duration in hours and minutes, like 6h30m.
+func formatEstimate(d time.Duration) string {
+	d = d.Round(time.Minute)
+	hours, minutes := int(d/time.Hour), int(d%time.Hour/time.Minute)
+	switch {
+	case hours == 0:
+		return fmt.Sprintf("%dm", minutes)
+	case minutes == 0:
+		return fmt.Sprintf("%dh", hours)
+	}
+	return fmt.Sprintf("%dh%dm", hours, minutes)
+}
+
+// EstimateTotal sums up the estimates of a group of tasks.
+type EstimateTotal struct {
+	Name        string
+	Total       time.Duration
+	Tasks       int
+	Unestimated int
+}
+
+func (e EstimateTotal) String() string {
+	tasks := "tasks"
+	if e.Tasks == 1 {
+		tasks = "task"
+	}
+	s := fmt.Sprintf("%s %s (%d %s", e.Name, formatEstimate(e.Total), e.Tasks, tasks)
+	if e.Unestimated > 0 {
+		s += fmt.Sprintf(", %d unestimated", e.Unestimated)
+	}
+	return s + ")"
+}
+
+// EstimatesByProject sums up the estimates of the tasks of each project,
+// ordered by project name with tasks without a project last.
+func (t *TaskList) EstimatesByProject() []EstimateTotal {
+	totals := make(map[string]*EstimateTotal)
+	for _, task := range t.Tasks {
+		project, ok := token(task, "proj")
+		if !ok {
+			project = noProject
+		}
+		total, ok := totals[project]
+		if !ok {
+			total = &EstimateTotal{Name: project}
+			totals[project] = total
+		}
+		total.Tasks++
+		if estimate, ok := estimate(task); ok {
+			total.Total += estimate
+		} else {
+			total.Unestimated++
+		}
+	}
+	list := make([]EstimateTotal, 0, len(totals))
+	for _, total := range totals {
+		list = append(list, *total)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if (list[i].Name == noProject) != (list[j].Name == noProject) {
+			return list[j].Name == noProject
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
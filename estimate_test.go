@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatEstimate(t *testing.T) {
+	cases := map[time.Duration]string{
+		0:                             "0m",
+		45 * time.Minute:              "45m",
+		2 * time.Hour:                 "2h",
+		6*time.Hour + 30*time.Minute:  "6h30m",
+		26*time.Hour + 29*time.Second: "26h",
+	}
+	for d, expected := range cases {
+		if formatted := formatEstimate(d); formatted != expected {
+			t.Fatalf("formatEstimate(%v): expected %s, got %s", d, expected, formatted)
+		}
+	}
+}
+
+func TestEstimatesByProject(t *testing.T) {
+	tasklist := TaskList{}
+	tasklist.Add("fix login proj:website est:2h")
+	tasklist.Add("new footer est:30m proj:website")
+	tasklist.Add("update copy proj:website")
+	tasklist.Add("call bob est:15m")
+	tasklist.Add("tag release proj:api est:soon")
+
+	totals := tasklist.EstimatesByProject()
+	expected := []string{
+		"api 0m (1 task, 1 unestimated)",
+		"website 2h30m (3 tasks, 1 unestimated)",
+		"(no project) 15m (1 task)",
+	}
+	if len(totals) != len(expected) {
+		t.Fatalf("Expected %d totals, got %v", len(expected), totals)
+	}
+	for i := range expected {
+		if totals[i].String() != expected[i] {
+			t.Fatalf("Expected total '%s', got '%s'", expected[i], totals[i])
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// eventsFilePath is where a line is appended for every task added,
+// edited, finished or deleted, from the events_file setting. Empty
+// means nowhere. Unlike the audit log, t never reads it back or trims
+// it.
+var eventsFilePath string
+
+// taskEvent is a line of the events file. Fields may be added to it,
+// but never change meaning.
+type taskEvent struct {
+	Time        time.Time `json:"time"`
+	Op          string    `json:"op"`
+	Id          string    `json:"id"`
+	Description string    `json:"description"`
+	Tags        []string  `json:"tags"`
+}
+
+// newTaskEvent returns the event of an op on a task at now.
+func newTaskEvent(op string, task *Task, now time.Time) taskEvent {
+	tags := make([]string, 0)
+	for _, word := range strings.Fields(task.Description) {
+		if isTag(word) {
+			tags = append(tags, word)
+		}
+	}
+	return taskEvent{Time: now, Op: op, Id: task.Id, Description: task.Description, Tags: tags}
+}
+
+// streamEvents compares the tasks before and after a change and returns
+// an event for each task added, edited, finished or deleted by it, the
+// last two told apart by whether the task's id is in finished.
+func streamEvents(before, after *TaskList, finished map[string]bool, now time.Time) []taskEvent {
+	events := make([]taskEvent, 0)
+	old := make(map[string]*Task)
+	for _, task := range before.Tasks {
+		old[task.Id] = task
+	}
+	for _, task := range after.Tasks {
+		was, ok := old[task.Id]
+		switch {
+		case !ok:
+			events = append(events, newTaskEvent("add", task, now))
+		case was.Description != task.Description:
+			events = append(events, newTaskEvent("edit", task, now))
+		}
+		delete(old, task.Id)
+	}
+	for _, task := range before.Tasks {
+		if _, gone := old[task.Id]; !gone {
+			continue
+		}
+		if finished[task.Id] {
+			events = append(events, newTaskEvent("finish", task, now))
+		} else {
+			events = append(events, newTaskEvent("delete", task, now))
+		}
+	}
+	return events
+}
+
+// appendEvents appends the events to the file at path, one JSON object
+// a line, in a single write to a file opened for appending, so that the
+// lines of runs at the same time never end up mixed.
+func appendEvents(path string, events []taskEvent) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// recordEvents appends the events of a write of the list to the events
+// file. Like the audit log, failing to do so is only reported.
+func recordEvents(before, after string, finished map[string]bool) {
+	if eventsFilePath == "" {
+		return
+	}
+	oldList, newList := &TaskList{}, &TaskList{}
+	if oldList.UnmarshalText([]byte(before)) != nil || newList.UnmarshalText([]byte(after)) != nil {
+		return
+	}
+	events := streamEvents(oldList, newList, finished, time.Now().UTC().Truncate(time.Second))
+	if len(events) == 0 {
+		return
+	}
+	if err := appendEvents(eventsFilePath, events); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: can't write events file: %v\n", err)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStreamEvents(t *testing.T) {
+	before, after := &TaskList{}, &TaskList{}
+	before.UnmarshalText([]byte("keep\nrename me\nfinish me\ndelete me"))
+	after.UnmarshalText([]byte("keep\nrename me\nnew +home @town"))
+	after.Tasks[1].Description = "renamed"
+	finished := map[string]bool{before.Tasks[2].Id: true}
+	now := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	events := streamEvents(before, after, finished, now)
+	ops := make([]string, 0)
+	for _, event := range events {
+		ops = append(ops, event.Op+" "+event.Description)
+	}
+	expected := []string{"edit renamed", "add new +home @town", "finish finish me", "delete delete me"}
+	if !reflect.DeepEqual(ops, expected) {
+		t.Fatalf("Expected %v, got %v", expected, ops)
+	}
+	if !reflect.DeepEqual(events[1].Tags, []string{"+home", "@town"}) {
+		t.Fatalf("Expected the tags of the new task, got %v", events[1].Tags)
+	}
+	if text, _ := json.Marshal(events[0]); string(text) != `{"time":"2024-06-01T09:00:00Z","op":"edit","id":"`+events[0].Id+`","description":"renamed","tags":[]}` {
+		t.Fatalf("Expected tags to be [] rather than null, got %s", text)
+	}
+}
+
+func TestAppendEventsConcurrently(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			events := make([]taskEvent, 0)
+			for j := 0; j < 50; j++ {
+				task := &Task{Id: fmt.Sprintf("%d-%d", i, j), Description: fmt.Sprintf("task %d of writer %d", j, i)}
+				events = append(events, newTaskEvent("add", task, time.Now()))
+			}
+			if err := appendEvents(path, events); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	lines := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var event taskEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("Expected every line to parse, got '%s': %v", scanner.Text(), err)
+		}
+		lines++
+	}
+	if lines != 20*50 {
+		t.Fatalf("Expected %d lines, got %d", 20*50, lines)
+	}
+}
+
+func TestCliEventsFile(t *testing.T) {
+	withCliSetup(t, func() {
+		dir := t.TempDir()
+		events := filepath.Join(dir, "events.jsonl")
+		os.Mkdir(filepath.Join(dir, "t"), 0700)
+		ioutil.WriteFile(filepath.Join(dir, "t", "config"), []byte("events_file = "+events+"\n"), 0644)
+		env := append(os.Environ(), "XDG_CONFIG_HOME="+dir)
+		for _, args := range [][]string{{"foo"}, {"-e", "0", "bar"}, {"-f", "0"}} {
+			cmd := exec.Command(tBinary, args...)
+			cmd.Env = env
+			if err := cmd.Run(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		text, _ := ioutil.ReadFile(events)
+		var ops []string
+		scanner := bufio.NewScanner(bytes.NewReader(text))
+		for scanner.Scan() {
+			var event taskEvent
+			json.Unmarshal(scanner.Bytes(), &event)
+			ops = append(ops, event.Op+" "+event.Description)
+		}
+		if !reflect.DeepEqual(ops, []string{"add foo", "edit bar", "finish bar"}) {
+			t.Fatalf("Expected an event for each change, got '%s'", text)
+		}
+	})
+}
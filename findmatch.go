@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+var errNoTaskMatches = errors.New("no task matches")
+
+// FindByText returns the ids of the tasks whose description contains
+// text, ignoring case, and accents with foldSearch. Unlike Search, it
+// finds snoozed tasks too: whoever knows the text wants that task.
+func (t *TaskList) FindByText(text string) []int {
+	ids := make([]int, 0)
+	text = core.Fold(text, foldSearch)
+	for i, task := range t.Tasks {
+		if strings.Contains(core.Fold(task.Description, foldSearch), text) {
+			ids = append(ids, i)
+		}
+	}
+	return ids
+}
+
+// matchOne returns the id of the only task whose description contains
+// text, for --finish-match and --edit-match. If several match, the error
+// lists them with their ids, and nothing should change.
+func (t *TaskList) matchOne(text string, opts formatOptions) (int, error) {
+	ids := t.FindByText(text)
+	switch len(ids) {
+	case 0:
+		return -1, errNoTaskMatches
+	case 1:
+		return ids[0], nil
+	}
+	lines := []string{fmt.Sprintf("%d tasks match %q, pick one by its id:", len(ids), text)}
+	for _, taskId := range ids {
+		lines = append(lines, formatTask(taskId, t.Tasks[taskId], opts))
+	}
+	return -1, errors.New(strings.Join(lines, "\n"))
+}
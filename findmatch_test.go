@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFindByText(t *testing.T) {
+	tasklist := &TaskList{}
+	tasklist.Add("Call the dentist")
+	tasklist.Add("pay rent")
+	snoozed, _ := tasklist.Add("book dentist follow-up")
+	snoozed.SnoozedUntil = time.Now().AddDate(0, 0, 3)
+	cases := map[string][]int{"DENTIST": {0, 2}, "rent": {1}, "milk": {}}
+	for text, expected := range cases {
+		if ids := tasklist.FindByText(text); joinIds(ids) != joinIds(expected) {
+			t.Errorf("%s: expected %v, got %v", text, expected, ids)
+		}
+	}
+	if _, err := tasklist.matchOne("milk", formatOptions{}); err != errNoTaskMatches {
+		t.Fatalf("Expected no match, got %v", err)
+	}
+	if _, err := tasklist.matchOne("dentist", formatOptions{plain: true}); err == nil || !strings.Contains(err.Error(), "0 - Call the dentist") {
+		t.Fatalf("Expected the candidates listed, got %v", err)
+	}
+	if taskId, err := tasklist.matchOne("rent", formatOptions{}); err != nil || taskId != 1 {
+		t.Fatalf("Expected task 1, got %d (%v)", taskId, err)
+	}
+}
+
+func joinIds(ids []int) string {
+	words := make([]string, len(ids))
+	for i, id := range ids {
+		words[i] = string(rune('0' + id))
+	}
+	return strings.Join(words, ",")
+}
+
+func TestCliFinishMatch(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("call dentist\npay rent\ncall bob\ncall mom"), 0644)
+		out, err := exec.Command(tBinary, "--finish-match", "dentist").Output()
+		if err != nil || string(out) != "finished 0 - call dentist\n" {
+			t.Fatalf("Expected the task finished, got '%s' (%v)", out, err)
+		}
+		cmd := exec.Command(tBinary, "--finish-match", "call")
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err == nil || !strings.Contains(stderr.String(), "call bob") {
+			t.Fatalf("Expected the candidates and exit 1, got '%s' (%v)", stderr.String(), err)
+		}
+		cmd = exec.Command(tBinary, "--finish-match", "milk")
+		stderr.Reset()
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err == nil || stderr.String() != "no task matches\n" {
+			t.Fatalf("Expected no match, got '%s' (%v)", stderr.String(), err)
+		}
+		if text, _ := ioutil.ReadFile("/tmp/tasks"); !strings.HasPrefix(string(text), "pay rent\ncall bob\ncall mom") {
+			t.Fatalf("Expected only the dentist finished, got '%s'", text)
+		}
+	})
+}
+
+func TestCliEditMatch(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("call dentist\npay rent"), 0644)
+		out, err := exec.Command(tBinary, "--edit-match", "dentist", "call dentist at 3").Output()
+		if err != nil || string(out) != "edited 0 - call dentist at 3\n" {
+			t.Fatalf("Expected the task edited, got '%s' (%v)", out, err)
+		}
+		err = exec.Command(tBinary, "--edit-match", "rent").Run()
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 2 {
+			t.Fatalf("Expected a usage error without a description, got %v", err)
+		}
+	})
+}
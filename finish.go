@@ -0,0 +1,202 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// errCanceled is returned when the user backs out of a prompt.
+var errCanceled = errors.New("canceled")
+
+var errNoMatch = errors.New("no tasks match")
+
+// bareFlag lets a string flag be given without a value, like t -f or
+// t -f --match x, by turning it into -f= so flag parsing doesn't take
+// the next flag for its value.
+func bareFlag(args []string, name string) []string {
+	bare := make([]string, 0, len(args))
+	for i, arg := range args {
+		if i > 0 && (arg == "-"+name || arg == "--"+name) && (i == len(args)-1 || strings.HasPrefix(args[i+1], "-")) {
+			arg += "="
+		}
+		bare = append(bare, arg)
+	}
+	return bare
+}
+
+// flagPassed reports whether the flag was given on the command line.
+func flagPassed(name string) bool {
+	passed := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			passed = true
+		}
+	})
+	return passed
+}
+
+// stdinIsTerminal reports whether t is run interactively, which it never
+// is in strict mode.
+func stdinIsTerminal() bool {
+	if strictMode {
+		return false
+	}
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// finishIds returns the tasks t -f should finish. target is normally a
+// task id, or several separated by commas, and args may hold more. When
+// target is empty or they aren't all ids and t runs on a terminal, the
+// tasks matching them are listed to pick from instead.
+func finishIds(target string, args []string, opts formatOptions) ([]int, error) {
+	words := append(strings.Split(target, ","), args...)
+	ids := make([]int, 0, len(words))
+	for _, word := range words {
+		taskId, err := tasklist.resolveId(word)
+		if err != nil {
+			if !stdinIsTerminal() {
+				if target == "" {
+					return nil, errors.New("Usage: t -f <id>[,<id>...]")
+				}
+				return nil, err
+			}
+			return pickTasks(tasklist.Search(strings.Join(append([]string{target}, args...), " ")), opts)
+		}
+		ids = append(ids, taskId)
+	}
+	return ids, nil
+}
+
+// pickTasks lists the tasks with the given ids and asks for the ones to
+// pick, separated by spaces. An empty answer or Esc cancels. The ids
+// picked are those of the tasks shown in the list as it is after the
+// answer, which another t may have changed meanwhile.
+func pickTasks(ids []int, opts formatOptions) ([]int, error) {
+	if len(ids) == 0 {
+		return nil, errors.New("no matching tasks")
+	}
+	shown := make(map[int]bool)
+	stableIds := make(map[int]string)
+	for _, taskId := range ids {
+		fmt.Println(formatTask(taskId, tasklist.Tasks[taskId], opts))
+		shown[taskId] = true
+		stableIds[taskId] = tasklist.Tasks[taskId].Id
+	}
+	for {
+		answer := ask("finish which? (ids, empty to cancel)")
+		if answer == "" || strings.HasPrefix(answer, "\x1b") {
+			return nil, errCanceled
+		}
+		picked, err := parsePicked(answer, shown)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		for i, taskId := range picked {
+			if picked[i] = tasklist.IndexOf(stableIds[taskId]); picked[i] == -1 {
+				return nil, fmt.Errorf("task %d was finished or deleted meanwhile", displayId(taskId))
+			}
+		}
+		return picked, nil
+	}
+}
+
+// parsePicked parses the space-separated ids of an answer to pickTasks,
+// each of which has to be one of the shown tasks.
+func parsePicked(answer string, shown map[int]bool) ([]int, error) {
+	picked := make([]int, 0)
+	seen := make(map[int]bool)
+	for _, field := range strings.Fields(answer) {
+		taskId, err := parseId(field)
+		if err != nil || !shown[taskId] {
+			return nil, fmt.Errorf("%s isn't one of the listed tasks", field)
+		}
+		if !seen[taskId] {
+			picked = append(picked, taskId)
+			seen[taskId] = true
+		}
+	}
+	return picked, nil
+}
+
+// FinishAll finishes the tasks with the given ids, all of them ids in
+// the list as it is before any is finished. Every id is checked first,
+// so a bad one finishes none, as does one with open subtasks unless
+// the list cascades or keeps them. It returns the finished tasks in list
+// order.
+func (t *TaskList) FinishAll(ids []int) ([]*Task, error) {
+	seen := make(map[int]bool)
+	sorted := make([]int, 0, len(ids))
+	for _, taskId := range ids {
+		task, err := t.Get(taskId)
+		if err != nil {
+			return nil, fmt.Errorf("no task with id %d", displayId(taskId))
+		}
+		if n := len(task.Children); n > 0 && !t.cascade && !t.KeepChildren {
+			return nil, fmt.Errorf("task %d has %d open subtasks, finish them first, or all of them with --cascade, or keep them with --force", displayId(taskId), n)
+		}
+		if !seen[taskId] {
+			sorted = append(sorted, taskId)
+			seen[taskId] = true
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(sorted)))
+	finished := make([]*Task, len(sorted))
+	for i, taskId := range sorted {
+		finished[len(sorted)-1-i], _ = t.Finish(taskId)
+	}
+	return finished, nil
+}
+
+// finishTasks finishes the tasks with the given ids, writes the list
+// once and records the tasks in the done file. It confirms each task
+// with p, and recurring tasks, which stay, say when they are due next
+// on p's writer even when it is quiet.
+func finishTasks(p *printer, ids []int) error {
+	limit, err := doneLimit()
+	if err != nil {
+		return err
+	}
+	finished, err := tasklist.FinishAll(ids)
+	if err != nil {
+		return err
+	}
+	if err := tasklist.writeDone(finished, time.Now(), limit); err != nil {
+		return err
+	}
+	for _, task := range finished {
+		p.finished(task)
+		if task.RecurEvery > 0 {
+			fmt.Fprintln(p.out, formatNextDue(tasklist.Tasks[tasklist.IndexOf(task.Id)]))
+		}
+	}
+	return nil
+}
+
+// finishMatching handles t -f --match and --finish-matching: it
+// finishes the tasks with the given ids, those matching the query, after
+// one confirmation, writing them all or none. It returns errNoMatch if
+// nothing matched.
+func finishMatching(ids []int, yes bool, opts formatOptions) error {
+	if len(ids) == 0 {
+		return errNoMatch
+	}
+	for _, taskId := range ids {
+		fmt.Println(formatTask(taskId, tasklist.Tasks[taskId], opts))
+	}
+	if !yes && !confirm(fmt.Sprintf("Finish these %d tasks?", len(ids))) {
+		return errCanceled
+	}
+	// The tasks were listed already, so the count confirms them.
+	if err := finishTasks(&printer{out: os.Stdout, quiet: true}, ids); err != nil {
+		return err
+	}
+	confirmations.printf("finished %d tasks\n", len(ids))
+	return nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+func TestBareFlag(t *testing.T) {
+	cases := map[string][]string{
+		"trailing":  {"t", "-f"},
+		"long":      {"t", "--f"},
+		"with id":   {"t", "-f", "3"},
+		"elsewhere": {"t", "-g", "-f", "3"},
+		"followed":  {"t", "-f", "--match", "x"},
+	}
+	expected := map[string][]string{
+		"trailing":  {"t", "-f="},
+		"long":      {"t", "--f="},
+		"with id":   {"t", "-f", "3"},
+		"elsewhere": {"t", "-g", "-f", "3"},
+		"followed":  {"t", "-f=", "--match", "x"},
+	}
+	for name, args := range cases {
+		if got := bareFlag(args, "f"); !reflect.DeepEqual(got, expected[name]) {
+			t.Fatalf("%s: expected %q, got %q", name, expected[name], got)
+		}
+	}
+}
+
+func TestParsePicked(t *testing.T) {
+	shown := map[int]bool{1: true, 3: true, 4: true}
+	picked, err := parsePicked(" 3 1  3 ", shown)
+	if err != nil || !reflect.DeepEqual(picked, []int{3, 1}) {
+		t.Fatalf("Expected [3 1], got %v (%v)", picked, err)
+	}
+	if _, err := parsePicked("2", shown); err == nil {
+		t.Fatal("Expected a task that wasn't listed to be rejected")
+	}
+	if _, err := parsePicked("x", shown); err == nil {
+		t.Fatal("Expected a non-number to be rejected")
+	}
+}
+
+func TestFinishAll(t *testing.T) {
+	list := &TaskList{}
+	list.UnmarshalText([]byte("a\nb\nc\nd\ne"))
+	if _, err := list.FinishAll([]int{1, 9, 3}); err == nil || err.Error() != "no task with id 9" {
+		t.Fatalf("Expected the bad id to be named, got %v", err)
+	}
+	if len(list.Tasks) != 5 {
+		t.Fatalf("Expected a bad id to finish nothing, %d tasks left", len(list.Tasks))
+	}
+	finished, err := list.FinishAll([]int{3, 1, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(finished) != 2 || finished[0].Description != "b" || finished[1].Description != "d" {
+		t.Fatalf("Expected b and d to be finished, got %v", finished)
+	}
+	if len(list.Tasks) != 3 || list.Tasks[0].Description != "a" || list.Tasks[1].Description != "c" || list.Tasks[2].Description != "e" {
+		t.Fatalf("Expected a, c and e to be left, got %v", list.Tasks)
+	}
+}
+
+func TestCliFinishSeveral(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("a\nb\nc\nd\ne\nf"), 0644)
+		if err := exec.Command(tBinary, "-f", "1,3", "5").Run(); err != nil {
+			t.Fatal(err)
+		}
+		out, _ := exec.Command(tBinary).Output()
+		if string(out) != "0 - a\n1 - c\n2 - e\n" {
+			t.Fatalf("Expected a, c and e to be left, got '%s'", out)
+		}
+		err := exec.Command(tBinary, "-f", "0,7").Run()
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+			t.Fatalf("Expected a bad id to exit with 1, got %v", err)
+		}
+		done, _ := ioutil.ReadFile("/tmp/tasks.done")
+		if list := (&TaskList{}); list.UnmarshalText(done) != nil || len(list.Tasks) != 3 || list.Tasks[0].Description != "b" {
+			t.Fatalf("Expected b, d and f in the done file, got '%s'", done)
+		}
+	})
+}
+
+func TestCliDeleteTask(t *testing.T) {
+	withCliSetup(t, func() {
+		exec.Command(tBinary, "foo").Run()
+		exec.Command(tBinary, "bar").Run()
+		exec.Command(tBinary, "-f", "1").Run()
+		before, _ := ioutil.ReadFile("/tmp/tasks.done")
+		if err := exec.Command(tBinary, "-d", "0").Run(); err != nil {
+			t.Fatal(err)
+		}
+		out, _ := exec.Command(tBinary).Output()
+		if string(out) != "" {
+			t.Fatalf("Expected output to be '', got '%s'", out)
+		}
+		if after, _ := ioutil.ReadFile("/tmp/tasks.done"); string(after) != string(before) {
+			t.Fatalf("Expected the done file to stay '%s', got '%s'", before, after)
+		}
+	})
+	withCliSetup(t, func() {
+		exec.Command(tBinary, "foo").Run()
+		if err := exec.Command(tBinary, "--delete", "0").Run(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat("/tmp/tasks.done"); !os.IsNotExist(err) {
+			t.Fatalf("Expected no done file after deleting, got %v", err)
+		}
+		err := exec.Command(tBinary, "--delete", "0").Run()
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+			t.Fatalf("Expected deleting a missing task to exit with 1, got %v", err)
+		}
+	})
+}
+
+func TestCliDeleteAndFinish(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("a\nb"), 0644)
+		err := exec.Command(tBinary, "-d", "0", "-f", "1").Run()
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 2 {
+			t.Fatalf("Expected -d with -f to exit with 2, got %v", err)
+		}
+		if text, _ := ioutil.ReadFile("/tmp/tasks"); string(text) != "a\nb" {
+			t.Fatalf("Expected the tasks to be left alone, got '%s'", text)
+		}
+	})
+}
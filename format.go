@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// formatPresets are the listing formats --format takes by name.
+var formatPresets = map[string]string{
+	"simple": `{{.ID}} - {{.Description}}`,
+	"long":   `{{.ID}} - {{.Description}}{{if .Priority}} (P{{.Priority}}){{end}}{{if .Due}} (due {{.Due}}){{end}}{{if .Age}} (added {{.Age}} ago){{end}}`,
+	"csv":    `{{csv .ID}},{{csv .Description}},{{if .Priority}}{{.Priority}}{{end}},{{.Due}},{{.Created}}`,
+}
+
+// formatFields is a task as a --format template sees it. Due is
+// YYYY-MM-DD and Created RFC 3339 in UTC, or empty if the task has none;
+// Priority is 0 for a task that wasn't given one.
+type formatFields struct {
+	ID          string
+	Description string
+	Priority    int
+	Due         string
+	Created     string
+	Age         string
+	Tags        []string
+}
+
+// csvField quotes s as a CSV field if it has to be.
+func csvField(s string) string {
+	if !strings.ContainsAny(s, ",\"\r\n") {
+		return s
+	}
+	return `"` + strings.Replace(s, `"`, `""`, -1) + `"`
+}
+
+// parseFormat returns the template of a --format value, a preset name or
+// a template itself. It is tried on an empty task, so that a template
+// that can't be rendered, like one naming a field tasks don't have, is
+// refused before anything is listed.
+func parseFormat(format string) (*template.Template, error) {
+	if preset, ok := formatPresets[format]; ok {
+		format = preset
+	}
+	tmpl, err := template.New("format").Funcs(template.FuncMap{"csv": csvField}).Parse(format)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --format: %v", err)
+	}
+	if err := tmpl.Execute(ioutil.Discard, formatFields{}); err != nil {
+		return nil, fmt.Errorf("invalid --format: %v", err)
+	}
+	return tmpl, nil
+}
+
+// formatTemplate renders a task with the --format template of opts,
+// on one line.
+func formatTemplate(id string, task *Task, opts formatOptions) string {
+	fields := formatFields{ID: id, Description: task.Description, Priority: task.Priority, Tags: make([]string, 0)}
+	if !task.DueAt.IsZero() {
+		fields.Due = task.DueAt.Format(dateLayout)
+	}
+	if !task.CreatedAt.IsZero() {
+		fields.Created = task.CreatedAt.UTC().Format(time.RFC3339)
+		fields.Age = formatAge(opts.now.Sub(task.CreatedAt))
+	}
+	for _, word := range strings.Fields(task.Description) {
+		if isTag(word) {
+			fields.Tags = append(fields.Tags, word)
+		}
+	}
+	var line bytes.Buffer
+	if err := opts.template.Execute(&line, fields); err != nil {
+		// parseFormat tried the template already; a task it still can't
+		// render, like one with fewer tags than it indexes, is listed
+		// the plain way rather than left out.
+		return id + " - " + lineBreaks.Replace(task.Description)
+	}
+	return lineBreaks.Replace(line.String())
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestFormatPresets(t *testing.T) {
+	now := time.Date(2024, 6, 10, 9, 0, 0, 0, time.UTC)
+	list := &TaskList{}
+	rent, _ := list.Add("pay rent, by transfer")
+	rent.Priority = 2
+	rent.DueAt = time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)
+	rent.CreatedAt = now.Add(-72 * time.Hour)
+	bob, _ := list.Add(`call "bob" +work`)
+	bob.CreatedAt = time.Time{}
+	for _, c := range []struct {
+		format   string
+		expected string
+	}{
+		{"simple", "0 - pay rent, by transfer\n1 - call \"bob\" +work\n"},
+		{"long", "0 - pay rent, by transfer (P2) (due 2024-06-01) (added 3d ago)\n1 - call \"bob\" +work\n"},
+		{"csv", "0,\"pay rent, by transfer\",2,2024-06-01,2024-06-07T09:00:00Z\n1,\"call \"\"bob\"\" +work\",,,\n"},
+		{"{{.ID}}: {{range .Tags}}{{.}} {{end}}", "0: \n1: +work \n"},
+	} {
+		template, err := parseFormat(c.format)
+		if err != nil {
+			t.Fatalf("Expected %q to parse, got %v", c.format, err)
+		}
+		opts := formatOptions{template: template, now: now}
+		var out bytes.Buffer
+		for i, task := range list.Tasks {
+			out.WriteString(formatTask(i, task, opts) + "\n")
+		}
+		if out.String() != c.expected {
+			t.Errorf("Expected %q to list\n%s\ngot\n%s", c.format, c.expected, out.String())
+		}
+	}
+}
+
+func TestParseFormatInvalid(t *testing.T) {
+	for _, format := range []string{"{{.ID", "{{.Nope}}", "{{nope .ID}}"} {
+		if _, err := parseFormat(format); err == nil {
+			t.Errorf("Expected %q to be refused", format)
+		}
+	}
+}
+
+func TestCliFormat(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("pay rent\ncall bob"), 0644)
+		out, err := exec.Command(tBinary, "--format", "{{.ID}}={{.Description}}").Output()
+		if err != nil || string(out) != "0=pay rent\n1=call bob\n" {
+			t.Fatalf("Expected the tasks in the format, got '%s' (%v)", out, err)
+		}
+		cmd := exec.Command(tBinary)
+		cmd.Env = append(os.Environ(), "T_FORMAT=simple")
+		out, err = cmd.Output()
+		if err != nil || string(out) != "0 - pay rent\n1 - call bob\n" {
+			t.Fatalf("Expected T_FORMAT to be used, got '%s' (%v)", out, err)
+		}
+		cmd = exec.Command(tBinary, "--format", "{{.Nope}}")
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err = cmd.Output()
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 2 || len(out) > 0 {
+			t.Fatalf("Expected an invalid format to fail with 2 before any output, got '%s' (%v)", out, err)
+		}
+		if !bytes.Contains(stderr.Bytes(), []byte("invalid --format")) {
+			t.Fatalf("Expected the template error reported, got '%s'", stderr.String())
+		}
+	})
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+// The scores FuzzyFind gives: every matched rune scores fuzzyMatch, more
+// at the start of a word or right after the rune matched before, and a
+// gap between two matched runes costs fuzzyGapStart plus fuzzyGapExtend
+// for every rune after the first.
+const (
+	fuzzyMatch       = 16
+	fuzzyBoundary    = 8
+	fuzzyConsecutive = 4
+	fuzzyGapStart    = 3
+	fuzzyGapExtend   = 1
+)
+
+// --finish-match --fuzzy only finishes its best match if it scores at
+// least fuzzyMinScore for every rune of the query, and fuzzyMargin more
+// than the next best.
+const (
+	fuzzyMinScore = 12
+	fuzzyMargin   = fuzzyMatch
+)
+
+// Match is a task FuzzyFind found: its id, how well it matched and the
+// indexes of the runes of its description that matched the query.
+type Match struct {
+	taskId    int
+	score     int
+	positions []int
+}
+
+// fuzzyKey returns r as searches compare it, ignoring case and, with
+// foldSearch, accents, or false for a rune searches ignore.
+func fuzzyKey(r rune) (rune, bool) {
+	for _, key := range core.Fold(string(r), foldSearch) {
+		return key, true
+	}
+	return 0, false
+}
+
+// fuzzyMatchRunes finds query, as keys, in text as a subsequence: the
+// first place it ends, narrowed down to the shortest stretch ending
+// there, like fzf's first algorithm. It returns the matched positions
+// and their score, and false if text doesn't hold query.
+func fuzzyMatchRunes(query []rune, text []rune) ([]int, int, bool) {
+	keys := make([]rune, len(text))
+	for i, r := range text {
+		keys[i], _ = fuzzyKey(r)
+	}
+	end, q := -1, 0
+	for i, key := range keys {
+		if key == query[q] {
+			q++
+			if q == len(query) {
+				end = i
+				break
+			}
+		}
+	}
+	if end == -1 {
+		return nil, 0, false
+	}
+	positions := make([]int, len(query))
+	q = len(query) - 1
+	for i := end; q >= 0; i-- {
+		if keys[i] == query[q] {
+			positions[q] = i
+			q--
+		}
+	}
+	score := 0
+	for n, i := range positions {
+		score += fuzzyMatch
+		if i == 0 || !unicode.IsLetter(text[i-1]) && !unicode.IsDigit(text[i-1]) {
+			score += fuzzyBoundary
+		}
+		if n > 0 {
+			if gap := i - positions[n-1] - 1; gap == 0 {
+				score += fuzzyConsecutive
+			} else {
+				score -= fuzzyGapStart + (gap-1)*fuzzyGapExtend
+			}
+		}
+	}
+	return positions, score, true
+}
+
+// FuzzyFind returns the listed tasks whose description holds the runes
+// of query in order, though not necessarily next to each other, best
+// first and at most limit of them unless limit is 0. Ties go to the
+// shorter description, then to the task first in the list.
+func (t *TaskList) FuzzyFind(query string, limit int) []Match {
+	keys := make([]rune, 0)
+	for _, r := range query {
+		if key, ok := fuzzyKey(r); ok && !unicode.IsSpace(key) {
+			keys = append(keys, key)
+		}
+	}
+	matches := make([]Match, 0)
+	if len(keys) == 0 {
+		return matches
+	}
+	now := time.Now()
+	for i, task := range t.Tasks {
+		if !t.showHidden && core.Snoozed(task, now) {
+			continue
+		}
+		if positions, score, ok := fuzzyMatchRunes(keys, []rune(task.Description)); ok {
+			matches = append(matches, Match{taskId: i, score: score, positions: positions})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return len([]rune(t.Tasks[matches[i].taskId].Description)) < len([]rune(t.Tasks[matches[j].taskId].Description))
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// fuzzyOne returns the id of the task --finish-match --fuzzy finishes:
+// the best match for query, if it matches well and clearly better than
+// the next best. Otherwise the error lists the best matches with their
+// ids, and nothing should change. Snoozed tasks count, as they do
+// without --fuzzy.
+func (t *TaskList) fuzzyOne(query string, opts formatOptions) (int, error) {
+	showHidden := t.showHidden
+	t.showHidden = true
+	matches := t.FuzzyFind(query, 5)
+	t.showHidden = showHidden
+	if len(matches) == 0 {
+		return -1, errNoTaskMatches
+	}
+	best := matches[0]
+	if best.score >= fuzzyMinScore*len(best.positions) && (len(matches) == 1 || best.score-matches[1].score >= fuzzyMargin) {
+		return best.taskId, nil
+	}
+	lines := []string{fmt.Sprintf("no task matches %q clearly enough, pick one by its id:", query)}
+	for _, match := range matches {
+		lines = append(lines, formatTask(match.taskId, t.Tasks[match.taskId], opts))
+	}
+	return -1, errors.New(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fuzzyCorpus is the list the fuzzy tests search.
+func fuzzyCorpus() *TaskList {
+	list := &TaskList{}
+	for _, description := range []string{
+		"Call the dentist",
+		"Do taxes and list them",
+		"Dentist appointment notes",
+		"Update dns settings",
+		"pay rent",
+		"prepare the talk",
+		"Call the dentist",
+	} {
+		list.Add(description)
+	}
+	return list
+}
+
+func matchIds(matches []Match) string {
+	ids := make([]int, len(matches))
+	for i, match := range matches {
+		ids[i] = match.taskId
+	}
+	return joinIds(ids)
+}
+
+func TestFuzzyFind(t *testing.T) {
+	list := fuzzyCorpus()
+	cases := []struct {
+		query    string
+		expected []int
+	}{
+		// Equal scores go to the shorter description, then the first.
+		{"dntist", []int{0, 6, 2}},
+		{"DENTIST", []int{0, 6, 2}},
+		{"rent", []int{4}},
+		{"pt", []int{5, 4, 3, 2}},
+		{"tax", []int{1}},
+		{"d n s", []int{3, 0, 6, 2, 1}},
+		{"zzz", []int{}},
+		{"", []int{}},
+	}
+	for _, c := range cases {
+		if got := matchIds(list.FuzzyFind(c.query, 0)); got != joinIds(c.expected) {
+			t.Errorf("%q: expected %v, got %s", c.query, c.expected, got)
+		}
+	}
+	if got := matchIds(list.FuzzyFind("dntist", 2)); got != joinIds([]int{0, 6}) {
+		t.Errorf("Expected the limit kept, got %s", got)
+	}
+}
+
+func TestFuzzyScore(t *testing.T) {
+	positions, score, ok := fuzzyMatchRunes([]rune("dntist"), []rune("Call the dentist"))
+	if !ok || fmt.Sprint(positions) != "[9 11 12 13 14 15]" {
+		t.Fatalf("Expected the letters of dentist matched, got %v (%v)", positions, ok)
+	}
+	// d starts a word, n comes after a gap and the rest follow it.
+	if expected := 6*fuzzyMatch + fuzzyBoundary - fuzzyGapStart + 4*fuzzyConsecutive; score != expected {
+		t.Fatalf("Expected a score of %d, got %d", expected, score)
+	}
+	// The match is narrowed down to the shortest stretch ending where
+	// the query is first found.
+	if positions, _, _ := fuzzyMatchRunes([]rune("ab"), []rune("a a ab")); fmt.Sprint(positions) != "[4 5]" {
+		t.Fatalf("Expected the closest a, got %v", positions)
+	}
+	_, boundary, _ := fuzzyMatchRunes([]rune("t"), []rune("the"))
+	_, inside, _ := fuzzyMatchRunes([]rune("t"), []rune("att"))
+	if boundary <= inside {
+		t.Fatalf("Expected a match starting a word to score higher, got %d and %d", boundary, inside)
+	}
+}
+
+func TestFuzzyFindHidden(t *testing.T) {
+	list := fuzzyCorpus()
+	list.Tasks[0].SnoozedUntil = time.Now().AddDate(0, 0, 3)
+	if got := matchIds(list.FuzzyFind("dntist", 0)); got != joinIds([]int{6, 2}) {
+		t.Fatalf("Expected the snoozed task left out, got %s", got)
+	}
+	list.showHidden = true
+	if got := matchIds(list.FuzzyFind("dntist", 0)); got != joinIds([]int{0, 6, 2}) {
+		t.Fatalf("Expected the snoozed task with showHidden, got %s", got)
+	}
+}
+
+func TestFuzzyOne(t *testing.T) {
+	list := fuzzyCorpus()
+	if taskId, err := list.fuzzyOne("tax", formatOptions{}); err != nil || taskId != 1 {
+		t.Fatalf("Expected task 1, got %d (%v)", taskId, err)
+	}
+	// Two tasks match as well, so neither is picked.
+	if _, err := list.fuzzyOne("dntist", formatOptions{plain: true}); err == nil || !strings.Contains(err.Error(), "6 - Call the dentist") {
+		t.Fatalf("Expected the best matches listed, got %v", err)
+	}
+	// A match this scattered isn't trusted.
+	if _, err := list.fuzzyOne("dtn", formatOptions{}); err == nil {
+		t.Fatal("Expected a weak match refused")
+	}
+	if _, err := list.fuzzyOne("zzz", formatOptions{}); err != errNoTaskMatches {
+		t.Fatalf("Expected no match, got %v", err)
+	}
+	list.Tasks[0].SnoozedUntil = time.Now().AddDate(0, 0, 3)
+	list.Tasks = list.Tasks[:6]
+	if taskId, err := list.fuzzyOne("call dntist", formatOptions{}); err != nil || taskId != 0 {
+		t.Fatalf("Expected the snoozed task found, got %d (%v)", taskId, err)
+	}
+}
+
+func TestCliFuzzy(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("call dentist\npay rent\nprepare the talk\n"), 0644)
+		out, err := exec.Command(tBinary, "--fuzzy", "pt").Output()
+		if err != nil || string(out) != "2 - prepare the talk\n1 - pay rent\n" {
+			t.Fatalf("Expected the ranked matches, got '%s' (%v)", out, err)
+		}
+		if err := exec.Command(tBinary, "--fuzzy").Run(); err == nil {
+			t.Fatal("Expected --fuzzy without a query to fail")
+		}
+		out, err = exec.Command(tBinary, "--finish-match", "dntist", "--fuzzy").Output()
+		if err != nil || string(out) != "finished 0 - call dentist\n" {
+			t.Fatalf("Expected the best match finished, got '%s' (%v)", out, err)
+		}
+		cmd := exec.Command(tBinary, "--finish-match", "pt", "--fuzzy")
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err == nil || !strings.Contains(stderr.String(), "pay rent") {
+			t.Fatalf("Expected the candidates and exit 1, got '%s' (%v)", stderr.String(), err)
+		}
+		if text, _ := ioutil.ReadFile("/tmp/tasks"); strings.Contains(string(text), "dentist") || !strings.Contains(string(text), "pay rent") || !strings.Contains(string(text), "prepare the talk") {
+			t.Fatalf("Expected only the dentist finished, got '%s'", text)
+		}
+	})
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// gitMode commits every write of the list to the git work tree the
+// tasks file is in, with --git or T_GIT=1. Without git or a work tree,
+// it does nothing.
+var gitMode bool
+
+// workTree returns the root of the git repository the file at path is
+// in, or "" if there is none.
+func workTree(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	return gitRoot(filepath.Dir(abs))
+}
+
+// runGit runs git in the work tree at root and returns its output. A
+// failure is an error with what git said on stderr.
+func runGit(root string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", root}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+		return "", fmt.Errorf("git %s: %s", args[0], message)
+	}
+	return stdout.String(), nil
+}
+
+// gitPaths returns the files t commits for the tasks file at path,
+// relative to root: the tasks file and its done file, if they are in
+// the work tree and either there or in git, as a tasks file t removed
+// once it was empty is.
+func gitPaths(root, path string) []string {
+	paths := make([]string, 0, 2)
+	for _, file := range []string{path, donePath(path)} {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(root, abs)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		if _, err := os.Stat(abs); err != nil {
+			if tracked, err := runGit(root, "ls-files", "--", rel); err != nil || tracked == "" {
+				continue
+			}
+		}
+		paths = append(paths, rel)
+	}
+	return paths
+}
+
+// gitMessage describes a change of the list for its commit, like
+// "t: created: pay rent", or by the number of changes if there were
+// several.
+func gitMessage(before, after string, finished map[string]bool) string {
+	oldList, newList := &TaskList{}, &TaskList{}
+	if oldList.UnmarshalText([]byte(before)) != nil || newList.UnmarshalText([]byte(after)) != nil {
+		return "t: update"
+	}
+	events := taskEvents(oldList, newList, finished)
+	switch len(events) {
+	case 0:
+		return "t: update"
+	case 1:
+		event := events[0]
+		// Finished and removed tasks are only named by their id.
+		if taskId := oldList.IndexOf(event.Id); taskId != -1 && !strings.Contains(event.Event, ":") {
+			return fmt.Sprintf("t: %s: %s", event.Event, oldList.Tasks[taskId].Description)
+		}
+		return "t: " + event.Event
+	}
+	return fmt.Sprintf("t: %d changes", len(events))
+}
+
+// commitToGit commits the tasks file at path and its done file after a
+// write in gitMode, with a message saying what changed from before to
+// after. Git missing or the file outside a work tree leave it at the
+// write, and like a hook, git failing is only reported.
+func commitToGit(path, before, after string, finished map[string]bool) {
+	if !gitMode {
+		return
+	}
+	root := workTree(path)
+	if root == "" {
+		return
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		return
+	}
+	if err := gitCommit(root, gitPaths(root, path), gitMessage(before, after, finished)); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: not committed to git: %v\n", err)
+	}
+}
+
+// gitCommit commits the files at paths in the work tree at root, and
+// nothing else that is staged there, if they changed.
+func gitCommit(root string, paths []string, message string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	if _, err := runGit(root, append([]string{"add", "-A", "--"}, paths...)...); err != nil {
+		return err
+	}
+	status, err := runGit(root, append([]string{"status", "--porcelain", "--"}, paths...)...)
+	if err != nil || strings.TrimSpace(status) == "" {
+		return err
+	}
+	_, err = runGit(root, append([]string{"commit", "-q", "-m", message, "--"}, paths...)...)
+	return err
+}
+
+// errGitConflict stops --sync when the tasks file holds a merge conflict.
+var errGitConflict = errors.New("the tasks file has merge conflict markers, run t --fsck to see them and t --fsck --fix to split them into tasks")
+
+// syncTasks handles --sync: it commits the tasks file at path if it
+// changed, pulls with a rebase and pushes, stopping at the first git
+// command that fails. A conflict is left for the user to resolve, the
+// tasks file with its markers in it.
+func syncTasks(path string) error {
+	root := workTree(path)
+	if root == "" {
+		return fmt.Errorf("%s isn't in a git work tree", path)
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		return errors.New("git isn't installed")
+	}
+	if text, err := ioutil.ReadFile(path); err == nil && hasConflict(text) {
+		return errGitConflict
+	}
+	if err := gitCommit(root, gitPaths(root, path), "t: sync"); err != nil {
+		return err
+	}
+	if _, err := runGit(root, "pull", "--rebase", "-q"); err != nil {
+		if text, _ := ioutil.ReadFile(path); hasConflict(text) {
+			return fmt.Errorf("%v\n%v, then git rebase --continue and t --sync", err, errGitConflict)
+		}
+		return err
+	}
+	_, err := runGit(root, "push", "-q")
+	return err
+}
+
+// hasConflict reports whether the text of a tasks file has merge
+// conflict markers.
+func hasConflict(text []byte) bool {
+	for _, line := range strings.Split(string(text), "\n") {
+		if conflictMarker(line) != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+func TestGitMessage(t *testing.T) {
+	finished := map[string]bool{core.Hash("pay rent"): true}
+	for _, c := range []struct {
+		before, after, expected string
+	}{
+		{"pay rent", "pay rent\ncall bob", "t: created: call bob"},
+		{"pay rent\ncall bob", "call bob", "t: finished: pay rent"},
+		{"call bob", "", "t: removed: call bob"},
+		{"call bob", "call alice | id:" + core.Hash("call bob"), "t: edited: call bob → call alice"},
+		{"pay rent", "call bob\nwater the plants", "t: 3 changes"},
+		{"pay rent", "pay rent", "t: update"},
+	} {
+		if got := gitMessage(c.before, c.after, finished); got != c.expected {
+			t.Errorf("Expected %q, got %q", c.expected, got)
+		}
+	}
+}
+
+// gitEnv is the environment git and t run with in the git tests, so
+// that neither depends on the user's config.
+func gitEnv(path string, extra ...string) []string {
+	env := append(os.Environ(), "T_TASKS_FILE="+path, "GIT_CONFIG_GLOBAL="+os.DevNull, "GIT_CONFIG_NOSYSTEM=1",
+		"GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@example.com", "GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@example.com")
+	return append(env, extra...)
+}
+
+func TestCliGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+	dir := t.TempDir()
+	remote, a, b := filepath.Join(dir, "remote.git"), filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	tasksA, tasksB := filepath.Join(a, "tasks"), filepath.Join(b, "tasks")
+	git := func(args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Env = gitEnv("")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %s", args, out)
+		}
+		return string(out)
+	}
+	run := func(path string, extra []string, args ...string) (string, error) {
+		cmd := exec.Command(tBinary, args...)
+		cmd.Env = gitEnv(path, extra...)
+		out, err := cmd.CombinedOutput()
+		return string(out), err
+	}
+	gitOn := []string{"T_GIT=1"}
+	git("init", "-q", "--bare", remote)
+	git("clone", "-q", remote, a)
+	ioutil.WriteFile(filepath.Join(a, "README"), []byte("tasks\n"), 0644)
+	git("-C", a, "add", "README")
+	git("-C", a, "commit", "-q", "-m", "start")
+	git("-C", a, "push", "-q", "-u", "origin", "HEAD")
+	git("clone", "-q", remote, b)
+
+	run(tasksA, gitOn, "pay rent")
+	run(tasksA, nil, "--git", "call bob")
+	if out, err := run(tasksA, gitOn, "-f", "0"); err != nil {
+		t.Fatalf("Expected the task finished, got '%s' (%v)", out, err)
+	}
+	if log := git("-C", a, "log", "--format=%s"); log != "t: finished: pay rent\nt: created: call bob\nt: created: pay rent\nstart\n" {
+		t.Fatalf("Expected a commit for each change, got '%s'", log)
+	}
+	if files := git("-C", a, "ls-files"); files != "README\ntasks\ntasks.done\n" {
+		t.Fatalf("Expected the tasks and done files committed, got '%s'", files)
+	}
+	// Without --git, t leaves git alone, and --sync commits the change.
+	run(tasksA, nil, "water the plants")
+	if status := git("-C", a, "status", "--porcelain", "tasks"); status == "" {
+		t.Fatal("Expected the change left uncommitted")
+	}
+	if out, err := run(tasksA, nil, "--sync"); err != nil {
+		t.Fatalf("Expected the tasks pushed, got '%s' (%v)", out, err)
+	}
+	if out, err := run(tasksB, nil, "--sync"); err != nil {
+		t.Fatalf("Expected the tasks pulled, got '%s' (%v)", out, err)
+	}
+	if out, _ := run(tasksB, nil); out != "0 - call bob\n1 - water the plants\n" {
+		t.Fatalf("Expected the tasks synced, got '%s'", out)
+	}
+
+	// Both sides changing the same task conflict.
+	run(tasksA, gitOn, "-e", "0", "call alice")
+	run(tasksA, nil, "--sync")
+	run(tasksB, gitOn, "-e", "0", "call carol")
+	out, err := run(tasksB, nil, "--sync")
+	if err == nil || !strings.Contains(out, "t --fsck") {
+		t.Fatalf("Expected the conflict pointed at --fsck, got '%s' (%v)", out, err)
+	}
+	if out, err := run(tasksB, nil, "--sync"); err == nil || !strings.Contains(out, "merge conflict markers") {
+		t.Fatalf("Expected --sync refused until the conflict is resolved, got '%s' (%v)", out, err)
+	}
+
+	// Outside a work tree, --git changes nothing and --sync fails.
+	outside := filepath.Join(dir, "outside", "tasks")
+	os.Mkdir(filepath.Dir(outside), 0755)
+	if gitRoot(filepath.Dir(outside)) != "" {
+		t.Skip("the temporary directory is inside a git repository")
+	}
+	if out, err := run(outside, gitOn, "-q", "pay rent"); err != nil || out != "" {
+		t.Fatalf("Expected the task added quietly, got '%s' (%v)", out, err)
+	}
+	if out, err := run(outside, nil, "--sync"); err == nil || !strings.Contains(out, "isn't in a git work tree") {
+		t.Fatalf("Expected --sync refused, got '%s' (%v)", out, err)
+	}
+}
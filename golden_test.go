@@ -0,0 +1,203 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// updateGolden rewrites the golden files with what t prints now, which
+// it only does for a new formatVersion.
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// goldenFixture returns a list with a task of every kind the tasks file
+// and the listings have to render.
+func goldenFixture() *TaskList {
+	list := &TaskList{}
+	created := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	day := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+	}
+	add := func(description string, set func(task *Task)) *Task {
+		task, _ := list.Add(description)
+		task.CreatedAt = created
+		created = created.Add(time.Hour)
+		if set != nil {
+			set(task)
+		}
+		return task
+	}
+	bob := add("call bob +work @phone", func(task *Task) {
+		task.Priority = 3
+		task.DueAt = day(2099, 6, 1)
+	})
+	add("pay rent | by transfer", func(task *Task) {
+		task.Priority = 1
+		task.DueAt = day(2020, 1, 5)
+	})
+	add("water the plants", func(task *Task) {
+		task.Description = "water the plants\nand the garden"
+	})
+	add("ask Alice about the report", func(task *Task) {
+		task.Waiting = true
+		task.WaitingFor = "Alice's reply"
+	})
+	add("renew passport", func(task *Task) {
+		task.SnoozedUntil = day(2099, 1, 1)
+	})
+	add("stretch", func(task *Task) {
+		task.RecurEvery = 3 * everyUnit
+		task.DueAt = day(2020, 1, 1)
+	})
+	add("write report #work", func(task *Task) {
+		task.Pomodoros = 2
+		task.Attachments = []string{"~/notes/report.md"}
+		task.Links = []string{bob.Id}
+		task.Meta = map[string]string{"owner": "alice"}
+	})
+	task, _ := list.Add("added just now")
+	task.Description = "edited since"
+	return list
+}
+
+// rfc3339 matches the timestamps t writes.
+var rfc3339 = regexp.MustCompile(`\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d(?:Z|[+-]\d\d:\d\d)`)
+
+// normalizeGolden replaces the timestamps within a minute of now, which
+// change from one run to the next, with NOW.
+func normalizeGolden(text string, now time.Time) string {
+	return rfc3339.ReplaceAllStringFunc(text, func(s string) string {
+		at, err := time.Parse(time.RFC3339, s)
+		if err != nil || at.Sub(now) > time.Minute || now.Sub(at) > time.Minute {
+			return s
+		}
+		return "NOW"
+	})
+}
+
+// checkGolden compares got with the golden file name, which starts with
+// the formatVersion it was written for. With -update it rewrites the
+// file instead, but only if formatVersion went up or nothing changed:
+// changing what scripts read means bumping the version first.
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", "golden", name)
+	version, want := 0, ""
+	text, err := ioutil.ReadFile(path)
+	if err == nil {
+		parts := strings.SplitN(string(text), "\n", 2)
+		if len(parts) != 2 {
+			t.Fatalf("%s has no format header", path)
+		}
+		if _, err := fmt.Sscanf(parts[0], "format %d", &version); err != nil {
+			t.Fatalf("%s has an invalid format header %q", path, parts[0])
+		}
+		want = parts[1]
+	}
+	if *updateGolden {
+		if err == nil && want != got && version >= formatVersion {
+			t.Fatalf("%s would change; bump formatVersion before updating it", path)
+		}
+		header := fmt.Sprintf("format %d\n", formatVersion)
+		if err := ioutil.WriteFile(path, []byte(header+got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("%v, go test -update writes it", err)
+	}
+	if version != formatVersion {
+		t.Fatalf("%s is for format %d, but formatVersion is %d; go test -update rewrites it", path, version, formatVersion)
+	}
+	if want != got {
+		t.Fatalf("%s changed, which breaks the scripts reading it; if that is on purpose, bump formatVersion and run go test -update\nwant:\n%s\ngot:\n%s", path, want, got)
+	}
+}
+
+func TestNormalizeGolden(t *testing.T) {
+	now := time.Date(2024, 6, 1, 9, 0, 30, 0, time.UTC)
+	text := "created:2024-06-01T09:00:00Z done:2024-06-01T08:00:00Z at 2024-06-01T11:01:00+02:00"
+	expected := "created:NOW done:2024-06-01T08:00:00Z at NOW"
+	if normalized := normalizeGolden(text, now); normalized != expected {
+		t.Fatalf("Expected '%s', got '%s'", expected, normalized)
+	}
+}
+
+func TestGoldenStorage(t *testing.T) {
+	text, err := goldenFixture().MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "tasks.golden", normalizeGolden(string(text), time.Now()))
+}
+
+func TestGoldenStorageRoundTrip(t *testing.T) {
+	text, _ := goldenFixture().MarshalText()
+	list := &TaskList{}
+	if err := list.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+	again, _ := list.MarshalText()
+	if string(again) != string(text) {
+		t.Fatalf("Expected the tasks file to read back as written, got\n%s\ninstead of\n%s", again, text)
+	}
+}
+
+func TestCliGoldenListings(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		args []string
+	}{
+		{"listing.golden", []string{"--plain"}},
+		{"listing-all.golden", []string{"--plain", "--all"}},
+		{"json.golden", []string{"--json", "--all"}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			withCliSetup(t, func() {
+				text, _ := goldenFixture().MarshalText()
+				ioutil.WriteFile("/tmp/tasks", text, 0644)
+				out, err := exec.Command(tBinary, c.args...).Output()
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkGolden(t, c.name, normalizeGolden(string(out), time.Now()))
+			})
+		})
+	}
+}
+
+// TestCliGoldenPorcelain holds --porcelain to its golden file, which
+// unlike the others is never rewritten: go test -update only writes it
+// when it is missing.
+func TestCliGoldenPorcelain(t *testing.T) {
+	withCliSetup(t, func() {
+		text, _ := goldenFixture().MarshalText()
+		ioutil.WriteFile("/tmp/tasks", text, 0644)
+		out, err := exec.Command(tBinary, "--porcelain", "--all").Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := fmt.Sprintf("porcelain %d\n", porcelainVersion) + normalizeGolden(string(out), time.Now())
+		path := filepath.Join("testdata", "golden", "porcelain.golden")
+		want, err := ioutil.ReadFile(path)
+		if err != nil && *updateGolden {
+			if err := ioutil.WriteFile(path, []byte(got), 0644); err != nil {
+				t.Fatal(err)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("%v, go test -update writes it", err)
+		}
+		if string(want) != got {
+			t.Fatalf("--porcelain changed, which it never may\nwant:\n%s\ngot:\n%s", want, got)
+		}
+	})
+}
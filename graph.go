@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxGraphLabel is how many characters of a description a graph node
+// shows.
+const maxGraphLabel = 40
+
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// graphLabel returns a node label for a task: its id and its
+// description, shortened to maxGraphLabel characters.
+func graphLabel(taskId int, task *Task) string {
+	description := []rune(task.Description)
+	if len(description) > maxGraphLabel {
+		description = append(description[:maxGraphLabel-1], '…')
+	}
+	return dotEscaper.Replace(fmt.Sprintf("%d: %s", displayId(taskId), string(description)))
+}
+
+// Graph renders the tasks and the links between them as a Graphviz DOT
+// document. Links are related tasks rather than dependencies, so their
+// edges have no direction. Each link is drawn once, and links to tasks
+// that aren't on the list are left out.
+func (t *TaskList) Graph() string {
+	var dot strings.Builder
+	dot.WriteString("digraph tasks {\n\tnode [shape=box];\n")
+	for i, task := range t.Tasks {
+		fmt.Fprintf(&dot, "\tt%d [label=\"%s\"];\n", i, graphLabel(i, task))
+	}
+	for i, task := range t.Tasks {
+		for _, id := range task.Links {
+			if j := t.IndexOf(id); j > i {
+				fmt.Fprintf(&dot, "\tt%d -> t%d [dir=none];\n", i, j)
+			}
+		}
+	}
+	dot.WriteString("}\n")
+	return dot.String()
+}
@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestGraph(t *testing.T) {
+	tasklist := TaskList{}
+	tasklist.Add(`say "hi"`)
+	tasklist.Add("a very long task description that goes on and on and on")
+	tasklist.Add("c")
+	tasklist.Link(0, 1)
+	tasklist.Link(1, 2)
+	tasklist.Link(2, 0)
+	tasklist.Tasks[2].Links = append(tasklist.Tasks[2].Links, "gone")
+
+	expected := `digraph tasks {
+	node [shape=box];
+	t0 [label="0: say \"hi\""];
+	t1 [label="1: a very long task description that goes …"];
+	t2 [label="2: c"];
+	t0 -> t1 [dir=none];
+	t0 -> t2 [dir=none];
+	t1 -> t2 [dir=none];
+}
+`
+	if graph := tasklist.Graph(); graph != expected {
+		t.Fatalf("Expected graph to be\n%s\ngot\n%s", expected, graph)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+// inboxList is the list t --in captures tasks to.
+const inboxList = "inbox"
+
+// somedayList is the list for tasks that may never be done, which
+// --process moves them to. Like +someday tasks, they don't age, go stale
+// or get nagged about.
+const somedayList = "someday"
+
+// onSomedayList is set when the current list is somedayList.
+var onSomedayList = false
+
+// isSomeday reports whether the task may never be done: tagged +someday
+// or on somedayList.
+func isSomeday(task *Task) bool {
+	return onSomedayList || hasTag(task, "someday")
+}
+
+// processInbox handles --process: it steps through the inbox, which is
+// the current list, and asks what to do with each task. Every decision
+// is written right away, so quitting halfway loses nothing. Another t
+// may change the inbox while a question waits for its answer, so each
+// task is looked up again, by stable id, after every answer.
+func processInbox(opts formatOptions) {
+	now := opts.now
+	ids := make([]string, 0, len(tasklist.Tasks))
+	for _, task := range tasklist.Tasks {
+		if !core.Snoozed(task, now) {
+			ids = append(ids, task.Id)
+		}
+	}
+	for n := 0; n < len(ids); {
+		i := tasklist.IndexOf(ids[n])
+		if i == -1 {
+			n++
+			continue
+		}
+		fmt.Println(formatTask(i, tasklist.Tasks[i], opts))
+		action := ask("[m]ove to list, [t]ag, [d]efer, [x] delete, [s]kip, [q]uit?")
+		var answer string
+		switch action {
+		case "m":
+			answer = ask("list:")
+		case "t":
+			answer = ask("tags:")
+		case "d":
+			answer = ask("until (YYYY-MM-DD, tomorrow or +Nd):")
+		case "q", "":
+			return
+		}
+		if i = tasklist.IndexOf(ids[n]); i == -1 {
+			n++
+			continue
+		}
+		task := tasklist.Tasks[i]
+		switch action {
+		case "m":
+			if err := transferTask(answer, []string{fmt.Sprint(displayId(i))}, false); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				continue
+			}
+			n++
+		case "t":
+			for _, tag := range strings.Fields(answer) {
+				task.Description += " +" + strings.TrimPrefix(tag, "+")
+			}
+			if err := tasklist.write(true); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return
+			}
+		case "d":
+			until, err := parseDue(answer, now)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				continue
+			}
+			task.SnoozedUntil = until
+			if err := tasklist.write(true); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return
+			}
+			n++
+		case "x":
+			tasklist.Remove(i)
+			if err := tasklist.write(true); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return
+			}
+			n++
+		case "s":
+			n++
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// heatmapWeekdays label the rows of a heatmap, which start on Monday.
+var heatmapWeekdays = [7]string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}
+
+// heatmapShades are the cells of a heatmap from no completions to the
+// most, readable without colors.
+var heatmapShades = []string{"··", "░░", "▒▒", "▓▓", "██"}
+
+// heatmapColors are the 256 colors of the shades, dark green for few
+// completions to bright for many.
+var heatmapColors = []int{240, 22, 28, 34, 46}
+
+// heatmap counts finished tasks by the weekday, from Monday, and the
+// hour of the day they were finished at.
+type heatmap [7][24]int
+
+// heatmapRow is a weekday of a heatmap in --heatmap --json.
+type heatmapRow struct {
+	Weekday string `json:"weekday"`
+	Hours   []int  `json:"hours"`
+}
+
+// buildHeatmap counts the finished tasks by when they were finished in
+// loc, which is what makes a task finished at 8 in the morning count as
+// that both in winter and in summer time. It also returns how many
+// tasks have no finishing time.
+func buildHeatmap(done []*Task, loc *time.Location) (heatmap, int) {
+	var h heatmap
+	untimed := 0
+	for _, task := range done {
+		if task.DoneAt.IsZero() {
+			untimed++
+			continue
+		}
+		at := task.DoneAt.In(loc)
+		h[(int(at.Weekday())+6)%7][at.Hour()]++
+	}
+	return h, untimed
+}
+
+// heatmapShade returns the index into heatmapShades of a count,
+// spreading the counts from one up to most over the shades above none.
+func heatmapShade(count int, most int) int {
+	switch {
+	case count == 0:
+		return 0
+	case most == 1:
+		return len(heatmapShades) - 1
+	}
+	return 1 + (count-1)*(len(heatmapShades)-2)/(most-1)
+}
+
+// most returns the highest count of the heatmap.
+func (h heatmap) most() int {
+	most := 0
+	for _, hours := range h {
+		for _, count := range hours {
+			if count > most {
+				most = count
+			}
+		}
+	}
+	return most
+}
+
+// writeHeatmap renders the heatmap as a grid of weekdays by hours, in
+// colors if color is set.
+func writeHeatmap(w io.Writer, h heatmap, color bool) {
+	header := "    "
+	for hour := 0; hour < 24; hour += 3 {
+		header += fmt.Sprintf("%-6d", hour)
+	}
+	fmt.Fprintln(w, strings.TrimRight(header, " "))
+	most := h.most()
+	for day, hours := range h {
+		line := heatmapWeekdays[day] + " "
+		for _, count := range hours {
+			shade := heatmapShade(count, most)
+			if color {
+				line += colorCode(heatmapColors[shade]) + heatmapShades[shade] + colorReset
+			} else {
+				line += heatmapShades[shade]
+			}
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+// writeHeatmapJSON writes the counts of the heatmap as JSON, a row of
+// 24 hourly counts for each weekday.
+func writeHeatmapJSON(w io.Writer, h heatmap) error {
+	rows := make([]heatmapRow, 0, len(h))
+	for day, hours := range h {
+		rows = append(rows, heatmapRow{Weekday: heatmapWeekdays[day], Hours: append([]int(nil), hours[:]...)})
+	}
+	return json.NewEncoder(w).Encode(rows)
+}
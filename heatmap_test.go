@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestBuildHeatmapAcrossDST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make([]*Task, 0)
+	for _, at := range []string{
+		// Before and after clocks went forward on Sunday, 2024-03-31:
+		// 01:30 CET and 03:30 CEST.
+		"2024-03-31T00:30:00Z", "2024-03-31T01:30:00Z",
+		// 02:30 CEST and, an hour later, 02:30 CET again on Sunday,
+		// 2024-10-27, when they went back.
+		"2024-10-27T00:30:00Z", "2024-10-27T01:30:00Z",
+		// 09:15 on a Monday in winter and in summer time.
+		"2024-01-08T08:15:00Z", "2024-07-08T07:15:00Z",
+	} {
+		doneAt, _ := time.Parse(time.RFC3339, at)
+		done = append(done, &Task{Description: at, DoneAt: doneAt})
+	}
+	done = append(done, &Task{Description: "untimed"})
+	h, untimed := buildHeatmap(done, berlin)
+	if untimed != 1 {
+		t.Fatalf("Expected 1 untimed task, got %d", untimed)
+	}
+	expected := map[[2]int]int{{6, 1}: 1, {6, 3}: 1, {6, 2}: 2, {0, 9}: 2}
+	for day, hours := range h {
+		for hour, count := range hours {
+			if count != expected[[2]int{day, hour}] {
+				t.Fatalf("Expected %d tasks on %s at %d, got %d", expected[[2]int{day, hour}], heatmapWeekdays[day], hour, count)
+			}
+		}
+	}
+}
+
+func TestHeatmapShade(t *testing.T) {
+	cases := []struct{ count, most, shade int }{
+		{0, 5, 0}, {1, 1, 4}, {1, 5, 1}, {3, 5, 2}, {5, 5, 4},
+	}
+	for _, c := range cases {
+		if shade := heatmapShade(c.count, c.most); shade != c.shade {
+			t.Fatalf("heatmapShade(%d, %d): expected %d, got %d", c.count, c.most, c.shade, shade)
+		}
+	}
+}
+
+func TestWriteHeatmap(t *testing.T) {
+	var h heatmap
+	h[0][0] = 1
+	var buf bytes.Buffer
+	writeHeatmap(&buf, h, false)
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != 8 || !strings.HasPrefix(lines[0], "    0     3") {
+		t.Fatalf("Expected a header and 7 weekdays, got '%s'", buf.String())
+	}
+	if !strings.HasPrefix(lines[1], "Mon ██··") || strings.Contains(lines[1], "\x1b") {
+		t.Fatalf("Expected Monday to start with a full block and no colors, got '%s'", lines[1])
+	}
+	buf.Reset()
+	if err := writeHeatmapJSON(&buf, h); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), `[{"weekday":"Mon","hours":[1,0,`) {
+		t.Fatalf("Expected the counts as JSON, got '%s'", buf.String())
+	}
+}
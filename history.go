@@ -0,0 +1,299 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+// historySize is the number of changes t --undo can walk back.
+const historySize = 20
+
+// historyBytes bounds the contents the journal keeps, so that a large
+// tasks file doesn't make every write copy megabytes of history. The
+// latest change is kept whatever its size.
+const historyBytes = 1 << 20
+
+// historyEntry is one change to a tasks file, with the file's contents
+// before and after it. Done holds the lines the change added to the
+// done file, for the tasks it finished.
+type historyEntry struct {
+	Time   time.Time `json:"time"`
+	Op     string    `json:"op"`
+	Before string    `json:"before"`
+	After  string    `json:"after"`
+	Done   []string  `json:"done,omitempty"`
+}
+
+// history is the undo journal of a tasks file. The first Position
+// entries are applied, the ones after it have been undone and can be
+// redone.
+type history struct {
+	Entries  []historyEntry `json:"entries"`
+	Position int            `json:"position"`
+}
+
+var errHistoryStale = errors.New("the tasks file was changed outside of t, history cleared")
+
+// historyPath returns the path of the undo journal of a tasks file.
+func historyPath(path string) string {
+	return path + ".history"
+}
+
+// loadHistory reads the undo journal of a tasks file. A missing or
+// unreadable journal is an empty history.
+func loadHistory(path string) *history {
+	h := &history{}
+	text, err := ioutil.ReadFile(historyPath(path))
+	if err != nil || json.Unmarshal(text, h) != nil || h.Position > len(h.Entries) {
+		return &history{}
+	}
+	return h
+}
+
+func (h *history) save(path string) error {
+	text, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(historyPath(path), text, 0600)
+}
+
+// stage stages saving the history of the tasks file at path in tx.
+func (h *history) stage(tx *transaction, path string) error {
+	text, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	return tx.write(historyPath(path), text, 0600)
+}
+
+// record adds a change on top of the applied entries, dropping the ones
+// that were undone. If the file didn't look like history says it should
+// before the change, someone else changed it and the history so far
+// can't be trusted anymore.
+func (h *history) record(op string, before, after string, done []string, now time.Time) {
+	h.Entries = h.Entries[:h.Position]
+	if len(h.Entries) > 0 && h.Entries[len(h.Entries)-1].After != before {
+		h.Entries = nil
+	}
+	h.Entries = append(h.Entries, historyEntry{Time: now, Op: op, Before: before, After: after, Done: done})
+	if len(h.Entries) > historySize {
+		h.Entries = h.Entries[len(h.Entries)-historySize:]
+	}
+	for len(h.Entries) > 1 && h.size() > historyBytes {
+		h.Entries = h.Entries[1:]
+	}
+	h.Position = len(h.Entries)
+}
+
+// size returns how many bytes of contents the entries hold.
+func (h *history) size() int {
+	n := 0
+	for _, entry := range h.Entries {
+		n += len(entry.Before) + len(entry.After)
+		for _, line := range entry.Done {
+			n += len(line)
+		}
+	}
+	return n
+}
+
+// undo steps back one change and returns the entry undone. current is
+// what the tasks file contains now.
+func (h *history) undo(current string) (historyEntry, error) {
+	if h.Position == 0 {
+		return historyEntry{}, errors.New("nothing to undo")
+	}
+	entry := h.Entries[h.Position-1]
+	if entry.After != current {
+		*h = history{}
+		return historyEntry{}, errHistoryStale
+	}
+	h.Position--
+	return entry, nil
+}
+
+// redo steps forward one undone change and returns its entry.
+func (h *history) redo(current string) (historyEntry, error) {
+	if h.Position == len(h.Entries) {
+		return historyEntry{}, errors.New("nothing to redo")
+	}
+	entry := h.Entries[h.Position]
+	if entry.Before != current {
+		*h = history{}
+		return historyEntry{}, errHistoryStale
+	}
+	h.Position++
+	return entry, nil
+}
+
+// stageHistory stages adding a write of the tasks file at path to its
+// journal in tx, along with what tx adds to its done file. Failing to do
+// so doesn't fail the write, it is only reported.
+func stageHistory(tx *transaction, path string, before, after string) {
+	if before == after {
+		return
+	}
+	var done []string
+	if doneFile := donePath(path); tx.staged(doneFile) != -1 {
+		old, _ := ioutil.ReadFile(doneFile)
+		staged, _ := tx.read(doneFile)
+		done = addedLines(string(old), string(staged))
+	}
+	h := loadHistory(path)
+	h.record(strings.Join(os.Args[1:], " "), before, after, done, time.Now())
+	if err := h.stage(tx, path); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: can't save undo history: %v\n", err)
+	}
+}
+
+// recordHistory adds a change made to the tasks file at path other than
+// by writing it to its journal, like stageHistory.
+func recordHistory(path string, before, after string) {
+	tx := newTransaction(journalPath(path))
+	stageHistory(tx, path, before, after)
+	if err := tx.commit(); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: can't save undo history: %v\n", err)
+	}
+}
+
+// stepHistory handles --undo and --redo: it restores the tasks file to
+// the state before or after the change next in line.
+func stepHistory(redo bool) error {
+	current, err := ioutil.ReadFile(taskFilePath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	h := loadHistory(taskFilePath)
+	var entry historyEntry
+	content, verb := "", "undid"
+	if redo {
+		entry, err = h.redo(string(current))
+		content, verb = entry.After, "redid"
+	} else {
+		entry, err = h.undo(string(current))
+		content = entry.Before
+	}
+	if err == errHistoryStale {
+		h.save(taskFilePath)
+	}
+	if err != nil {
+		return err
+	}
+	tx := newTransaction(journalPath(taskFilePath))
+	if err := tx.write(taskFilePath, []byte(content), 0644); err != nil {
+		return err
+	}
+	if err := stageDoneLines(tx, donePath(taskFilePath), entry.Done, redo); err != nil {
+		tx.abort()
+		return err
+	}
+	if err := h.stage(tx, taskFilePath); err != nil {
+		tx.abort()
+		return err
+	}
+	if err := tx.commit(); err != nil {
+		return err
+	}
+	fmt.Printf("%s: t %s\n", verb, entry.Op)
+	for _, line := range restoredTasks(string(current), content) {
+		fmt.Println(line)
+	}
+	return nil
+}
+
+// addedLines returns the lines of after that aren't in before, as many
+// times as they were added.
+func addedLines(before, after string) []string {
+	count := make(map[string]int)
+	for _, line := range strings.Split(before, "\n") {
+		count[line]++
+	}
+	added := make([]string, 0)
+	for _, line := range strings.Split(after, "\n") {
+		if count[line] > 0 {
+			count[line]--
+		} else if line != "" {
+			added = append(added, line)
+		}
+	}
+	return added
+}
+
+// stageDoneLines stages putting back the lines a change added to the
+// done file at path in tx, when the change is redone, or taking them out
+// again, the last of each, when it is undone. Lines no longer there, like
+// those archived since, are left alone.
+func stageDoneLines(tx *transaction, path string, lines []string, redo bool) error {
+	if len(lines) == 0 {
+		return nil
+	}
+	text, err := tx.read(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	done := make([]string, 0)
+	if len(text) > 0 {
+		done = strings.Split(string(text), "\n")
+	}
+	if redo {
+		done = append(done, lines...)
+	} else {
+		for _, line := range lines {
+			for i := len(done) - 1; i >= 0; i-- {
+				if done[i] == line {
+					done = append(done[:i], done[i+1:]...)
+					break
+				}
+			}
+		}
+	}
+	return tx.write(path, []byte(strings.Join(done, "\n")), 0644)
+}
+
+// restoredTasks describes what stepping through the history did to the
+// tasks, going from the tasks file's contents from to to: the tasks it
+// brought back, like "restored task: foo", and the ones it took away.
+// An edit undone is one of each.
+func restoredTasks(from, to string) []string {
+	before, after := &TaskList{}, &TaskList{}
+	before.UnmarshalText([]byte(from))
+	after.UnmarshalText([]byte(to))
+	gone := make(map[string]int)
+	for _, task := range before.Tasks {
+		gone[task.Description]++
+	}
+	lines := make([]string, 0)
+	for _, task := range after.Tasks {
+		if gone[task.Description] > 0 {
+			gone[task.Description]--
+		} else {
+			lines = append(lines, "restored task: "+task.Description)
+		}
+	}
+	for _, task := range before.Tasks {
+		if gone[task.Description] > 0 {
+			gone[task.Description]--
+			lines = append(lines, "removed task: "+task.Description)
+		}
+	}
+	return lines
+}
+
+// printHistory handles --history: it lists the recorded changes, oldest
+// first, marking the ones that were undone.
+func printHistory() {
+	h := loadHistory(taskFilePath)
+	for i, entry := range h.Entries {
+		line := fmt.Sprintf("%s  t %s", entry.Time.Local().Format("2006-01-02 15:04:05"), entry.Op)
+		if i >= h.Position {
+			line += "  (undone)"
+		}
+		fmt.Println(line)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistoryUndoRedo(t *testing.T) {
+	now := time.Now()
+	h := &history{}
+	h.record("a", "", "a", nil, now)
+	h.record("b", "a", "a\nb", nil, now)
+
+	entry, err := h.undo("a\nb")
+	if err != nil || entry.Before != "a" {
+		t.Fatalf("Expected undo to go back to 'a', got '%s' (%v)", entry.Before, err)
+	}
+	entry, err = h.undo("a")
+	if err != nil || entry.Before != "" {
+		t.Fatalf("Expected undo to go back to '', got '%s' (%v)", entry.Before, err)
+	}
+	if _, err := h.undo(""); err == nil {
+		t.Fatal("Expected undo past the first entry to fail")
+	}
+	entry, err = h.redo("")
+	if err != nil || entry.After != "a" {
+		t.Fatalf("Expected redo to go forward to 'a', got '%s' (%v)", entry.After, err)
+	}
+
+	h.record("c", "a", "a\nc", nil, now)
+	if len(h.Entries) != 2 || h.Position != 2 {
+		t.Fatalf("Expected a new change to drop the undone ones, got %+v", h)
+	}
+	if _, err := h.redo("a\nc"); err == nil {
+		t.Fatal("Expected nothing to redo")
+	}
+}
+
+func TestHistoryStale(t *testing.T) {
+	now := time.Now()
+	h := &history{}
+	h.record("a", "", "a", nil, now)
+	if _, err := h.undo("changed elsewhere"); err != errHistoryStale {
+		t.Fatalf("Expected undo over an outside change to fail, got %v", err)
+	}
+	if len(h.Entries) != 0 {
+		t.Fatalf("Expected stale history to be cleared, got %+v", h)
+	}
+
+	h.record("a", "", "a", nil, now)
+	h.record("b", "changed elsewhere", "b", nil, now)
+	if len(h.Entries) != 1 {
+		t.Fatalf("Expected a change on top of an outside change to start over, got %+v", h)
+	}
+}
+
+func TestHistoryIsBounded(t *testing.T) {
+	h := &history{}
+	content := ""
+	for i := 0; i < historySize+5; i++ {
+		h.record("add", content, content+"x", nil, time.Now())
+		content += "x"
+	}
+	if len(h.Entries) != historySize || h.Position != historySize {
+		t.Fatalf("Expected history to keep %d entries, got %d", historySize, len(h.Entries))
+	}
+	big := strings.Repeat("x", historyBytes/3)
+	h = &history{}
+	h.record("a", "", big, nil, time.Now())
+	h.record("b", big, big+"\n"+big, nil, time.Now())
+	if len(h.Entries) != 1 || h.Entries[0].Op != "b" || h.Position != 1 {
+		t.Fatalf("Expected history to keep the entries under %d bytes, got %d", historyBytes, len(h.Entries))
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// hookEnv is set for the hook, so that a t it runs knows it is inside
+// one.
+const hookEnv = "T_IN_HOOK"
+
+// postWriteHook is a shell command run after every change to the list,
+// from the post_write_hook setting. Empty means none.
+var postWriteHook string
+
+// inHook is set when t is run by a hook. The t that ran the hook still
+// holds the lock and is writing the list, so this one only reads it.
+var inHook bool
+
+var errInHook = errors.New("can't change tasks from within a post_write_hook, t is read-only there")
+
+// runHook runs the post_write_hook with sh after a write of the list,
+// with T_TASKS_FILE set to the tasks file. Its output goes to stderr,
+// keeping t's own output clean for scripts, and like the status file, a
+// hook failing is only reported.
+func runHook() {
+	if postWriteHook == "" {
+		return
+	}
+	cmd := exec.Command("sh", "-c", postWriteHook)
+	cmd.Env = append(os.Environ(), hookEnv+"=1", "T_TASKS_FILE="+taskFilePath)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: post_write_hook failed: %v\n", err)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCliPostWriteHook(t *testing.T) {
+	withCliSetup(t, func() {
+		dir := t.TempDir()
+		listing := filepath.Join(dir, "listing")
+		refused := filepath.Join(dir, "refused")
+		hook := tBinary + " > " + listing + "; " + tBinary + " bar 2> " + refused
+		os.Mkdir(filepath.Join(dir, "t"), 0700)
+		ioutil.WriteFile(filepath.Join(dir, "t", "config"), []byte("post_write_hook = "+hook+"\n"), 0644)
+		cmd := exec.Command(tBinary, "foo")
+		cmd.Env = append(os.Environ(), "XDG_CONFIG_HOME="+dir)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("Expected the add to succeed, got %v: %s", err, out)
+		}
+		if out, _ := ioutil.ReadFile(listing); string(out) != "0 - foo\n" {
+			t.Fatalf("Expected the hook to list the new task, got '%s'", out)
+		}
+		if out, _ := ioutil.ReadFile(refused); !strings.Contains(string(out), "read-only") {
+			t.Fatalf("Expected the hook's add to be refused, got '%s'", out)
+		}
+		if out, _ := exec.Command(tBinary).Output(); string(out) != "0 - foo\n" {
+			t.Fatalf("Expected output to be '0 - foo\n', got '%s'", out)
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// minIdPrefix is the fewest characters of a stable id that t shows or
+// accepts in its place.
+const minIdPrefix = 2
+
+// idPrefixes returns the shortest prefix of each task's stable id that
+// no other task's id starts with, at least minIdPrefix characters long
+// and never all digits, so it can't be taken for a numeric id. Tasks
+// sharing an id get the whole id.
+func (t *TaskList) idPrefixes() map[*Task]string {
+	prefixes := make(map[*Task]string, len(t.Tasks))
+	for _, task := range t.Tasks {
+		length := minIdPrefix
+		for ; length < len(task.Id); length++ {
+			prefix := task.Id[:length]
+			if strings.Trim(prefix, "0123456789") != "" && !t.sharesPrefix(task, prefix) {
+				break
+			}
+		}
+		prefixes[task] = task.Id[:length]
+	}
+	return prefixes
+}
+
+// sharesPrefix reports whether a task other than the given one, with a
+// different id, has an id starting with prefix.
+func (t *TaskList) sharesPrefix(task *Task, prefix string) bool {
+	for _, other := range t.Tasks {
+		if other.Id != task.Id && strings.HasPrefix(other.Id, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isIdPrefix reports whether s could be the prefix of a stable id.
+func isIdPrefix(s string) bool {
+	return len(s) >= minIdPrefix && strings.Trim(s, "0123456789abcdef") == ""
+}
+
+// resolvePrefix returns the index of the task whose stable id starts
+// with prefix. A prefix more than one task's id starts with is an error
+// listing them.
+func (t *TaskList) resolvePrefix(prefix string) (int, error) {
+	matches := make([]int, 0)
+	for i, task := range t.Tasks {
+		if strings.HasPrefix(task.Id, prefix) {
+			matches = append(matches, i)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return -1, fmt.Errorf("no task with id %s", prefix)
+	case 1:
+		return matches[0], nil
+	}
+	candidates := make([]string, 0, len(matches))
+	for _, taskId := range matches {
+		candidates = append(candidates, fmt.Sprintf("  %d - %s", displayId(taskId), t.Tasks[taskId].Description))
+	}
+	return -1, fmt.Errorf("ambiguous id %s, could be:\n%s", prefix, strings.Join(candidates, "\n"))
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIdPrefixes(t *testing.T) {
+	list := &TaskList{}
+	list.UnmarshalText([]byte("foo | id:a3f0\nbar | id:a3b1\nbaz | id:7f00\nqux | id:1234ab"))
+	prefixes := list.idPrefixes()
+	for i, expected := range []string{"a3f", "a3b", "7f", "1234a"} {
+		if prefixes[list.Tasks[i]] != expected {
+			t.Errorf("Expected prefix of task %d to be %s, got %s", i, expected, prefixes[list.Tasks[i]])
+		}
+	}
+}
+
+func TestResolvePrefix(t *testing.T) {
+	list := &TaskList{}
+	list.UnmarshalText([]byte("foo | id:a3f0\nbar | id:a3b1\nbaz | id:7f00"))
+	if taskId, err := list.resolveId("A3B"); err != nil || taskId != 1 {
+		t.Fatalf("Expected a3b to resolve to 1, got %d, %v", taskId, err)
+	}
+	if taskId, err := list.resolveId("7f"); err != nil || taskId != 2 {
+		t.Fatalf("Expected 7f to resolve to 2, got %d, %v", taskId, err)
+	}
+	_, err := list.resolveId("a3")
+	if err == nil || !strings.Contains(err.Error(), "0 - foo") || !strings.Contains(err.Error(), "1 - bar") {
+		t.Fatalf("Expected a3 to be ambiguous between foo and bar, got %v", err)
+	}
+	if _, err := list.resolveId("ee"); err == nil {
+		t.Fatal("Expected an unknown prefix to fail")
+	}
+}
+
+func TestCliHashIds(t *testing.T) {
+	withCliSetup(t, func() {
+		dir, _ := ioutil.TempDir("", "t-config")
+		defer os.RemoveAll(dir)
+		os.MkdirAll(filepath.Join(dir, "t"), 0755)
+		ioutil.WriteFile(filepath.Join(dir, "t", "config"), []byte("ids = hash\n"), 0644)
+		ioutil.WriteFile("/tmp/tasks", []byte("foo | id:a3f0\nbar | id:7f00"), 0644)
+		env := append(os.Environ(), "XDG_CONFIG_HOME="+dir)
+		cmd := exec.Command(tBinary)
+		cmd.Env = env
+		out, _ := cmd.Output()
+		if string(out) != "a3 - foo\n7f - bar\n" {
+			t.Fatalf("Expected tasks listed by id prefix, got '%s'", out)
+		}
+		cmd = exec.Command(tBinary, "-f", "7f")
+		cmd.Env = env
+		if err := cmd.Run(); err != nil {
+			t.Fatal(err)
+		}
+		cmd = exec.Command(tBinary)
+		cmd.Env = env
+		out, _ = cmd.Output()
+		if string(out) != "a3 - foo\n" {
+			t.Fatalf("Expected only foo to be left, got '%s'", out)
+		}
+	})
+}
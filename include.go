@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+)
+
+// stageIncluded stages writing back the included files whose tasks
+// changed in tx.
+func (t *TaskList) stageIncluded(tx *transaction) error {
+	for path := range t.Included {
+		text, err := t.MarshalFile(path)
+		if err != nil {
+			return err
+		}
+		if current, err := ioutil.ReadFile(path); err == nil && bytes.Equal(current, text) {
+			continue
+		}
+		if err := tx.write(path, text, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkIncludedReadOnly fails if a read-only included file would change
+// with a write, before anything is written.
+func (t *TaskList) checkIncludedReadOnly() error {
+	if ignoreReadOnly {
+		return nil
+	}
+	for path, readOnly := range t.ReadOnlyIncluded {
+		if !readOnly {
+			continue
+		}
+		text, err := t.MarshalFile(path)
+		if err != nil {
+			return err
+		}
+		if current, err := ioutil.ReadFile(path); err != nil || !bytes.Equal(current, text) {
+			return readOnlyError(path)
+		}
+	}
+	return nil
+}
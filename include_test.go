@@ -0,0 +1,50 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+func TestIncludes(t *testing.T) {
+	dir := t.TempDir()
+	defer func(saved string) { taskFilePath = saved }(taskFilePath)
+	taskFilePath = filepath.Join(dir, "tasks")
+	ioutil.WriteFile(taskFilePath, []byte("#include team\n#include missing\nmine"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "team"), []byte("#include nested\nshared"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "nested"), []byte("#include tasks\ndeep"), 0644)
+
+	list, err := readTaskList(taskFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	descriptions := ""
+	for _, task := range list.Tasks {
+		descriptions += task.Description + ","
+	}
+	if descriptions != "deep,shared,mine," {
+		t.Fatalf("Expected included tasks before the file's own, got '%s'", descriptions)
+	}
+
+	list.Finish(1)
+	list.Edit(0, "deeper")
+	task, _ := list.Add("new")
+	task.CreatedAt = time.Time{}
+	if err := list.write(true); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"tasks":  "#include team\n#include missing\nmine\nnew",
+		"team":   "#include nested",
+		"nested": "#include tasks\ndeeper | id:" + core.Hash("deep"),
+	}
+	for name, contents := range expected {
+		text, _ := ioutil.ReadFile(filepath.Join(dir, name))
+		if string(text) != contents {
+			t.Fatalf("Expected %s to be '%s', got '%s'", name, contents, text)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// localTasksFile is the name of a project's own tasks file, which
+// --local finds in the current directory or one above it.
+const localTasksFile = ".tasks"
+
+var errNoLocal = errors.New("no " + localTasksFile + " file here or in a directory above, t --init creates one")
+
+// gitRoot returns the root of the git repository dir is in: the nearest
+// directory up from it with a .git, a directory or, in worktrees and
+// submodules, a file. Outside of a repository it returns "".
+func gitRoot(dir string) string {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// findLocal returns the .tasks file in dir or the nearest directory
+// above it that has one.
+func findLocal(dir string) (string, error) {
+	for {
+		path := filepath.Join(dir, localTasksFile)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", errNoLocal
+		}
+		dir = parent
+	}
+}
+
+// ignored reports whether a .gitignore ignores the .tasks file at the
+// root of the repository by a line of its own.
+func ignored(gitignore string) bool {
+	for _, line := range strings.Split(gitignore, "\n") {
+		switch strings.TrimSpace(line) {
+		case localTasksFile, "/" + localTasksFile:
+			return true
+		}
+	}
+	return false
+}
+
+// ignoreLocal adds the .tasks file to the .gitignore in root, unless it
+// is there already. It reports whether it added it.
+func ignoreLocal(root string) (bool, error) {
+	path := filepath.Join(root, ".gitignore")
+	text, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	if ignored(string(text)) {
+		return false, nil
+	}
+	line := localTasksFile + "\n"
+	if len(text) > 0 && !strings.HasSuffix(string(text), "\n") {
+		line = "\n" + line
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return false, err
+	}
+	_, err = file.WriteString(line)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err == nil, err
+}
+
+// initLocal handles --init: it creates a .tasks file at the root of the
+// git repository dir is in, or in dir itself outside of one, and asks
+// whether git should ignore it, which it shouldn't if the team shares
+// it. An existing .tasks file is left alone.
+func initLocal(dir string, ask func(question string) bool, out io.Writer) error {
+	root := gitRoot(dir)
+	inRepo := root != ""
+	if !inRepo {
+		root = dir
+	}
+	path := filepath.Join(root, localTasksFile)
+	if _, err := os.Stat(path); err == nil {
+		fmt.Fprintf(out, "%s already exists, use it with t --local\n", path)
+		return nil
+	}
+	if !inRepo && !ask(fmt.Sprintf("Not in a git repository, create %s?", path)) {
+		return errCanceled
+	}
+	if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "created %s\n", path)
+	if inRepo && ask("Add it to .gitignore? (no to share it through the repository)") {
+		added, err := ignoreLocal(root)
+		if err != nil {
+			return err
+		}
+		if added {
+			fmt.Fprintf(out, "added %s to %s\n", localTasksFile, filepath.Join(root, ".gitignore"))
+		}
+	}
+	fmt.Fprintf(out, "t --local uses it from %s and the directories below\n", root)
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGitRoot(t *testing.T) {
+	dir := t.TempDir()
+	repo := filepath.Join(dir, "repo")
+	nested := filepath.Join(repo, "src", "pkg")
+	os.MkdirAll(nested, 0755)
+	os.Mkdir(filepath.Join(repo, ".git"), 0755)
+	worktree := filepath.Join(dir, "worktree")
+	os.Mkdir(worktree, 0755)
+	ioutil.WriteFile(filepath.Join(worktree, ".git"), []byte("gitdir: ../repo/.git/worktrees/w\n"), 0644)
+	outside := filepath.Join(dir, "outside")
+	os.Mkdir(outside, 0755)
+
+	for start, expected := range map[string]string{repo: repo, nested: repo, worktree: worktree} {
+		if root := gitRoot(start); root != expected {
+			t.Fatalf("Expected the root of %s to be '%s', got '%s'", start, expected, root)
+		}
+	}
+	// The temporary directory itself may be inside a repository.
+	if root := gitRoot(outside); strings.HasPrefix(root, dir) {
+		t.Fatalf("Expected %s to be outside a repository, got '%s'", outside, root)
+	}
+}
+
+func TestFindLocal(t *testing.T) {
+	dir := t.TempDir()
+	nested := filepath.Join(dir, "a", "b")
+	os.MkdirAll(nested, 0755)
+	if path, err := findLocal(nested); err == nil && strings.HasPrefix(path, dir) {
+		t.Fatalf("Expected no .tasks file, got %s", path)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "a", localTasksFile), nil, 0644)
+	if path, err := findLocal(nested); err != nil || path != filepath.Join(dir, "a", localTasksFile) {
+		t.Fatalf("Expected the .tasks file above, got %s (%v)", path, err)
+	}
+}
+
+func TestInitLocal(t *testing.T) {
+	repo := t.TempDir()
+	os.Mkdir(filepath.Join(repo, ".git"), 0755)
+	ioutil.WriteFile(filepath.Join(repo, ".gitignore"), []byte("*.o"), 0644)
+	nested := filepath.Join(repo, "src")
+	os.Mkdir(nested, 0755)
+	yes := func(string) bool { return true }
+
+	var out bytes.Buffer
+	if err := initLocal(nested, yes, &out); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(repo, localTasksFile)); err != nil {
+		t.Fatalf("Expected a .tasks file at the root of the repository: %v", err)
+	}
+	if !strings.Contains(out.String(), "t --local uses it from "+repo) {
+		t.Fatalf("Expected the setup to be printed, got '%s'", out.String())
+	}
+	if gitignore, _ := ioutil.ReadFile(filepath.Join(repo, ".gitignore")); string(gitignore) != "*.o\n.tasks\n" {
+		t.Fatalf("Expected .tasks added to .gitignore, got '%s'", gitignore)
+	}
+
+	out.Reset()
+	if err := initLocal(repo, yes, &out); err != nil || !strings.Contains(out.String(), "already exists") {
+		t.Fatalf("Expected a second --init to do nothing, got '%s' (%v)", out.String(), err)
+	}
+	if added, _ := ignoreLocal(repo); added {
+		t.Fatal("Expected .tasks not to be added to .gitignore twice")
+	}
+
+	shared := t.TempDir()
+	os.Mkdir(filepath.Join(shared, ".git"), 0755)
+	if err := initLocal(shared, func(string) bool { return false }, &out); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(shared, ".gitignore")); !os.IsNotExist(err) {
+		t.Fatal("Expected no .gitignore when the .tasks file is shared")
+	}
+}
+
+func TestCliInitAndLocal(t *testing.T) {
+	dir := t.TempDir()
+	if gitRoot(dir) != "" {
+		t.Skip("the temporary directory is inside a git repository")
+	}
+	cmd := exec.Command(tBinary, "--init")
+	cmd.Dir, cmd.Stdin = dir, strings.NewReader("n\n")
+	if err := cmd.Run(); err == nil {
+		t.Fatal("Expected --init outside a repository to need confirming")
+	}
+	cmd = exec.Command(tBinary, "--init", "-y")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Expected --init -y to create the file, got %v: %s", err, out)
+	}
+	nested := filepath.Join(dir, "sub")
+	os.Mkdir(nested, 0755)
+	cmd = exec.Command(tBinary, "--local", "fix the build")
+	cmd.Dir = nested
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Expected --local to add to the .tasks file, got %v: %s", err, out)
+	}
+	if text, _ := ioutil.ReadFile(filepath.Join(dir, localTasksFile)); !strings.HasPrefix(string(text), "fix the build") {
+		t.Fatalf("Expected the task in the .tasks file, got '%s'", text)
+	}
+}
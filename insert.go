@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// InsertAt adds a task at position pos, moving the tasks from there on
+// down by one. pos may be the length of the list, which appends it.
+func (t *TaskList) InsertAt(pos int, description string) error {
+	if pos < 0 || pos > len(t.Tasks) {
+		return fmt.Errorf("can't insert a task at %d, the list has %d", displayId(pos), len(t.Tasks))
+	}
+	task, err := t.Add(description)
+	if err != nil {
+		return err
+	}
+	copy(t.Tasks[pos+1:], t.Tasks[pos:len(t.Tasks)-1])
+	t.Tasks[pos] = task
+	return nil
+}
+
+// Move moves the task at from to position to, shifting the tasks in
+// between by one. Both have to be positions in the list.
+func (t *TaskList) Move(from, to int) error {
+	if from < 0 || from >= len(t.Tasks) {
+		return fmt.Errorf("no task with id %d", displayId(from))
+	}
+	if to < 0 || to >= len(t.Tasks) {
+		return fmt.Errorf("can't move a task to %d, the list has %d", displayId(to), len(t.Tasks))
+	}
+	task := t.Tasks[from]
+	if from < to {
+		copy(t.Tasks[from:to], t.Tasks[from+1:to+1])
+	} else {
+		copy(t.Tasks[to+1:from+1], t.Tasks[to:from])
+	}
+	t.Tasks[to] = task
+	return nil
+}
+
+// moveTask handles --move: it moves a task to another position in the
+// list and writes the list in the new order.
+func moveTask(target string, position string) error {
+	if position == "" {
+		return errors.New("Usage: t --move <id> <position>")
+	}
+	from, err := tasklist.resolveId(target)
+	if err != nil {
+		return err
+	}
+	to, err := parseId(position)
+	if err != nil {
+		return fmt.Errorf("invalid position %q", position)
+	}
+	if err := tasklist.Move(from, to); err != nil {
+		return err
+	}
+	if from == to {
+		return nil
+	}
+	return tasklist.write(true)
+}
+
+// insertPosition returns where --before or --after put new tasks: at
+// the given task or right after it, and -1, the end of the list, if
+// neither was given.
+func insertPosition(before, after string) (int, error) {
+	if before != "" && after != "" {
+		return -1, errors.New("--before and --after can't be combined")
+	}
+	target := before + after
+	if target == "" {
+		return -1, nil
+	}
+	taskId, err := tasklist.resolveId(target)
+	if err != nil {
+		return -1, err
+	}
+	if _, err := tasklist.Get(taskId); err != nil {
+		return -1, err
+	}
+	if after != "" {
+		taskId++
+	}
+	return taskId, nil
+}
+
+// addTasks adds a task for each description, at pos and on as a block,
+// or at the end of the list if pos is -1, and returns them.
+func addTasks(descriptions []string, pos int) ([]*Task, error) {
+	added := make([]*Task, 0, len(descriptions))
+	for i, description := range descriptions {
+		if pos == -1 {
+			task, err := tasklist.Add(description)
+			if err != nil {
+				return nil, err
+			}
+			added = append(added, task)
+			continue
+		}
+		if err := tasklist.InsertAt(pos+i, description); err != nil {
+			return nil, err
+		}
+		added = append(added, tasklist.Tasks[pos+i])
+	}
+	return added, nil
+}
+
+const (
+	// defaultArgLimit and defaultArgBytes are how many arguments, and
+	// how many bytes in all, a single task may be added from before t
+	// takes it for a shell glob gone wrong, like t * in a big directory.
+	defaultArgLimit = 25
+	defaultArgBytes = 500
+)
+
+// argGuard holds the limits of the arguments a single task is added
+// from. Zero turns a limit off.
+type argGuard struct {
+	count int
+	bytes int
+}
+
+// argGuardSettings reads the arg_limit and arg_bytes settings.
+func argGuardSettings(config Config) (argGuard, error) {
+	guard := argGuard{defaultArgLimit, defaultArgBytes}
+	for _, setting := range []struct {
+		key   string
+		limit *int
+	}{{"arg_limit", &guard.count}, {"arg_bytes", &guard.bytes}} {
+		value, ok := config.Get(setting.key)
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return guard, fmt.Errorf("invalid %s = %s, expected a number, or 0 for no limit", setting.key, value)
+		}
+		*setting.limit = n
+	}
+	return guard, nil
+}
+
+// check describes how the arguments of a single task go over the
+// limits, or returns "" if they don't.
+func (g argGuard) check(args []string) string {
+	size := len(strings.Join(args, " "))
+	if (g.count > 0 && len(args) > g.count) || (g.bytes > 0 && size > g.bytes) {
+		return fmt.Sprintf("%d arguments (%d bytes)", len(args), size)
+	}
+	return ""
+}
+
+// guardArgs lets a single task be added from args unless they go over
+// the limits. Then, on a terminal, ask decides, and elsewhere the task
+// is refused.
+func guardArgs(args []string, g argGuard, terminal bool, ask func(string) bool) error {
+	problem := g.check(args)
+	if problem == "" {
+		return nil
+	}
+	if !terminal {
+		return fmt.Errorf("not added, %s for a single task: did you mean --multi, or quote your argument?", problem)
+	}
+	if !ask(fmt.Sprintf("Add a single task of %s?", problem)) {
+		return errors.New("not added")
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func joinDescriptions(tasks []*Task) string {
+	names := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		names = append(names, task.Description)
+	}
+	return strings.Join(names, " ")
+}
+
+func TestInsertAt(t *testing.T) {
+	tests := []struct {
+		pos  int
+		want string
+	}{
+		{0, "new a b c"},
+		{1, "a new b c"},
+		{3, "a b c new"},
+	}
+	for _, test := range tests {
+		tasklist := TaskList{}
+		tasklist.Add("a")
+		tasklist.Add("b")
+		tasklist.Add("c")
+		if err := tasklist.InsertAt(test.pos, "new"); err != nil {
+			t.Fatal(err)
+		}
+		if got := joinDescriptions(tasklist.Tasks); got != test.want {
+			t.Fatalf("Expected inserting at %d to give '%s', got '%s'", test.pos, test.want, got)
+		}
+	}
+}
+
+func TestInsertAtOutOfBounds(t *testing.T) {
+	for _, pos := range []int{-1, 2} {
+		tasklist := TaskList{}
+		tasklist.Add("a")
+		if err := tasklist.InsertAt(pos, "new"); err == nil {
+			t.Fatalf("Expected inserting at %d to fail", pos)
+		}
+		if len(tasklist.Tasks) != 1 {
+			t.Fatalf("Expected a failed insert to leave the list alone, got %d tasks", len(tasklist.Tasks))
+		}
+	}
+}
+
+func TestCliInsertBeforeAndAfter(t *testing.T) {
+	withCliSetup(t, func() {
+		exec.Command(tBinary, "foo").Run()
+		exec.Command(tBinary, "bar").Run()
+		if err := exec.Command(tBinary, "--before", "0", "first").Run(); err != nil {
+			t.Fatal(err)
+		}
+		if err := exec.Command(tBinary, "--after", "last", "--multi", "one", "two").Run(); err != nil {
+			t.Fatal(err)
+		}
+		out, _ := exec.Command(tBinary).Output()
+		expected := "0 - first\n1 - foo\n2 - bar\n3 - one\n4 - two\n"
+		if string(out) != expected {
+			t.Fatalf("Expected output to be '%s', got '%s'", expected, out)
+		}
+		err := exec.Command(tBinary, "--after", "9", "lost").Run()
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 2 {
+			t.Fatalf("Expected --after a missing task to exit with 2, got %v", err)
+		}
+	})
+}
+
+func TestMove(t *testing.T) {
+	tests := []struct {
+		from, to int
+		want     string
+	}{
+		{3, 0, "d a b c"},
+		{0, 2, "b c a d"},
+		{1, 3, "a c d b"},
+		{2, 2, "a b c d"},
+	}
+	for _, test := range tests {
+		tasklist := TaskList{}
+		for _, description := range []string{"a", "b", "c", "d"} {
+			tasklist.Add(description)
+		}
+		if err := tasklist.Move(test.from, test.to); err != nil {
+			t.Fatal(err)
+		}
+		if got := joinDescriptions(tasklist.Tasks); got != test.want {
+			t.Fatalf("Expected moving %d to %d to give '%s', got '%s'", test.from, test.to, test.want, got)
+		}
+	}
+}
+
+func TestMoveOutOfRange(t *testing.T) {
+	for _, test := range [][2]int{{-1, 0}, {2, 0}, {0, -1}, {0, 2}} {
+		tasklist := TaskList{}
+		tasklist.Add("a")
+		tasklist.Add("b")
+		if err := tasklist.Move(test[0], test[1]); err == nil {
+			t.Fatalf("Expected moving %d to %d to fail", test[0], test[1])
+		}
+		if got := joinDescriptions(tasklist.Tasks); got != "a b" {
+			t.Fatalf("Expected a failed move to leave the list alone, got '%s'", got)
+		}
+	}
+}
+
+func TestCliMove(t *testing.T) {
+	withCliSetup(t, func() {
+		for _, description := range []string{"foo", "bar", "baz"} {
+			exec.Command(tBinary, description).Run()
+		}
+		if err := exec.Command(tBinary, "--move", "2", "0").Run(); err != nil {
+			t.Fatal(err)
+		}
+		if err := exec.Command(tBinary, "-f", "1").Run(); err != nil {
+			t.Fatal(err)
+		}
+		out, _ := exec.Command(tBinary).Output()
+		if string(out) != "0 - baz\n1 - bar\n" {
+			t.Fatalf("Expected finishing to use the new order, got '%s'", out)
+		}
+		if err := exec.Command(tBinary, "--move", "0", "5").Run(); err == nil {
+			t.Fatal("Expected moving past the end to fail")
+		}
+		if err := exec.Command(tBinary, "--move", "0").Run(); err == nil {
+			t.Fatal("Expected --move without a position to fail")
+		}
+	})
+}
+
+func TestGuardArgs(t *testing.T) {
+	guard := argGuard{count: 3, bytes: 20}
+	asked := ""
+	yes := func(question string) bool { asked = question; return true }
+	no := func(question string) bool { asked = question; return false }
+	if err := guardArgs([]string{"pay", "rent"}, guard, false, no); err != nil || asked != "" {
+		t.Fatalf("Expected a short task to be added without asking, got %v", err)
+	}
+	many := []string{"a.go", "b.go", "c.go", "d.go"}
+	err := guardArgs(many, guard, false, yes)
+	if err == nil || !strings.Contains(err.Error(), "did you mean --multi") || asked != "" {
+		t.Fatalf("Expected too many arguments to be refused off a terminal with a hint, got %v", err)
+	}
+	if err := guardArgs(many, guard, true, yes); err != nil || asked != "Add a single task of 4 arguments (19 bytes)?" {
+		t.Fatalf("Expected the question to be asked and the task added, got %v after '%s'", err, asked)
+	}
+	if err := guardArgs([]string{strings.Repeat("x", 21)}, guard, true, no); err == nil {
+		t.Fatal("Expected a declined long argument not to be added")
+	}
+	if err := guardArgs(many, argGuard{}, false, no); err != nil {
+		t.Fatalf("Expected no limits when they are off, got %v", err)
+	}
+}
+
+func TestArgGuardSettings(t *testing.T) {
+	guard, err := argGuardSettings(Config{values: map[string]string{"arg_limit": "0", "arg_bytes": "200"}})
+	if err != nil || guard != (argGuard{0, 200}) {
+		t.Fatalf("Expected the count limit off and 200 bytes, got %v (%v)", guard, err)
+	}
+	if guard, _ := argGuardSettings(Config{values: map[string]string{}}); guard != (argGuard{defaultArgLimit, defaultArgBytes}) {
+		t.Fatalf("Expected the defaults, got %v", guard)
+	}
+	if _, err := argGuardSettings(Config{values: map[string]string{"arg_limit": "many"}}); err == nil {
+		t.Fatal("Expected arg_limit = many to be rejected")
+	}
+}
+
+func TestCliGuardArgs(t *testing.T) {
+	withCliSetup(t, func() {
+		args := make([]string, 0)
+		for i := 0; i < 30; i++ {
+			args = append(args, "file.go")
+		}
+		if err := exec.Command(tBinary, args...).Run(); err == nil {
+			t.Fatal("Expected a glob's worth of arguments to be refused when not on a terminal")
+		}
+		if out, _ := exec.Command(tBinary).Output(); string(out) != "" {
+			t.Fatalf("Expected nothing to be added, got '%s'", out)
+		}
+		if err := exec.Command(tBinary, append([]string{"-y"}, args...)...).Run(); err != nil {
+			t.Fatalf("Expected -y to add the task anyway, got %v", err)
+		}
+	})
+}
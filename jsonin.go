@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+// jsonOp is an operation of a --json-in batch.
+type jsonOp struct {
+	Op          string `json:"op"`
+	Id          *int   `json:"id"`
+	Description string `json:"description"`
+	Due         string `json:"due"`
+}
+
+// jsonResult is what happened to an operation of a --json-in batch.
+type jsonResult struct {
+	Op          string `json:"op"`
+	Ok          bool   `json:"ok"`
+	Id          *int   `json:"id,omitempty"`
+	StableId    string `json:"stable_id,omitempty"`
+	Description string `json:"description,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// applyJSONOps applies a batch of operations to the list. Ids refer to
+// the list as it was before the batch, whatever the operations before
+// did to it. It returns a result for each operation up to the first
+// that failed, and the tasks that were finished. If err is not nil, the
+// list must not be written.
+func applyJSONOps(t *TaskList, ops []jsonOp, now time.Time) ([]jsonResult, []*Task, error) {
+	snapshot := append([]*Task(nil), t.Tasks...)
+	results := make([]jsonResult, 0, len(ops))
+	finished := make([]*Task, 0)
+	for _, op := range ops {
+		result, task, err := applyJSONOp(t, snapshot, op, now)
+		if err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			return results, nil, err
+		}
+		result.Ok = true
+		results = append(results, result)
+		if op.Op == "finish" {
+			finished = append(finished, task)
+		}
+	}
+	return results, finished, nil
+}
+
+func applyJSONOp(t *TaskList, snapshot []*Task, op jsonOp, now time.Time) (jsonResult, *Task, error) {
+	result := jsonResult{Op: op.Op, Id: op.Id}
+	switch op.Op {
+	case "add", "finish", "edit":
+	default:
+		return result, nil, fmt.Errorf("unknown op %q", op.Op)
+	}
+	if op.Op == "add" {
+		if op.Description == "" {
+			return result, nil, fmt.Errorf("add needs a description")
+		}
+		task, err := t.Add(op.Description)
+		if err != nil {
+			return result, nil, err
+		}
+		if op.Due != "" {
+			dueAt, err := parseDue(op.Due, now)
+			if err != nil {
+				return result, nil, err
+			}
+			task.DueAt = dueAt
+		}
+		id := displayId(len(t.Tasks) - 1)
+		result.Id, result.StableId, result.Description = &id, task.Id, task.Description
+		return result, task, nil
+	}
+	if op.Id == nil || *op.Id-core.IndexBase < 0 || *op.Id-core.IndexBase >= len(snapshot) {
+		return result, nil, fmt.Errorf("%s needs the id of a task", op.Op)
+	}
+	task := snapshot[*op.Id-core.IndexBase]
+	taskId := -1
+	for i := range t.Tasks {
+		if t.Tasks[i] == task {
+			taskId = i
+		}
+	}
+	if taskId == -1 {
+		return result, nil, fmt.Errorf("task %d is already finished", *op.Id)
+	}
+	result.StableId = task.Id
+	if op.Op == "finish" {
+		if _, err := t.Finish(taskId); err != nil {
+			return result, nil, err
+		}
+	} else {
+		if op.Description == "" {
+			return result, nil, fmt.Errorf("edit needs a description")
+		}
+		if err := t.Edit(taskId, op.Description); err != nil {
+			return result, nil, err
+		}
+	}
+	result.Description = task.Description
+	return result, task, nil
+}
+
+// runJSONIn handles --json-in: it reads a JSON array of operations from
+// in, applies them all or none, and writes a JSON result per operation
+// to out, one per line.
+func runJSONIn(in io.Reader, out io.Writer, limit int) error {
+	var ops []jsonOp
+	if err := json.NewDecoder(in).Decode(&ops); err != nil {
+		return fmt.Errorf("invalid JSON operations: %v", err)
+	}
+	now := time.Now()
+	results, finished, applyErr := applyJSONOps(tasklist, ops, now)
+	encoder := json.NewEncoder(out)
+	for _, result := range results {
+		encoder.Encode(result)
+	}
+	if applyErr != nil {
+		return fmt.Errorf("batch aborted, nothing was changed: %v", applyErr)
+	}
+	return tasklist.writeDone(finished, now, limit)
+}
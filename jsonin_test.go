@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func TestApplyJSONOps(t *testing.T) {
+	tasklist := TaskList{}
+	tasklist.UnmarshalText([]byte("a\nb\nc"))
+	ops := []jsonOp{
+		{Op: "finish", Id: intPtr(0)},
+		{Op: "edit", Id: intPtr(2), Description: "c2"},
+		{Op: "add", Description: "d"},
+	}
+	results, finished, err := applyJSONOps(&tasklist, ops, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 || !results[2].Ok || *results[2].Id != 2 {
+		t.Fatalf("Expected three results with d added as 2, got %+v", results)
+	}
+	if len(finished) != 1 || finished[0].Description != "a" {
+		t.Fatalf("Expected a to be finished, got %v", finished)
+	}
+	text, _ := tasklist.MarshalText()
+	if len(tasklist.Tasks) != 3 || tasklist.Tasks[1].Description != "c2" {
+		t.Fatalf("Expected ids to refer to the list before the batch, got '%s'", text)
+	}
+
+	results, _, err = applyJSONOps(&tasklist, []jsonOp{{Op: "finish", Id: intPtr(7)}, {Op: "add", Description: "e"}}, time.Now())
+	if err == nil || len(results) != 1 || results[0].Ok || results[0].Error == "" {
+		t.Fatalf("Expected the batch to stop at the bad id, got %+v (%v)", results, err)
+	}
+}
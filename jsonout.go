@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// jsonTask is a task as --json lists it. Dates are always YYYY-MM-DD,
+// whatever the dateformat setting, and times RFC 3339 in UTC.
+type jsonTask struct {
+	Id       int    `json:"id"`
+	StableId string `json:"stable_id"`
+	Text     string `json:"text"`
+	Priority int    `json:"priority,omitempty"`
+	Due      string `json:"due,omitempty"`
+	Created  string `json:"created,omitempty"`
+	List     string `json:"list,omitempty"`
+}
+
+// jsonTasks returns the tasks with the given ids as --json lists them,
+// from the named list if there are several.
+func (t *TaskList) jsonTasks(ids []int, list string) []jsonTask {
+	tasks := make([]jsonTask, 0, len(ids))
+	for _, taskId := range ids {
+		task := t.Tasks[taskId]
+		record := jsonTask{Id: displayId(taskId), StableId: task.Id, Text: task.Description, Priority: task.Priority, List: list}
+		if !task.DueAt.IsZero() {
+			record.Due = task.DueAt.Format(dateLayout)
+		}
+		if !task.CreatedAt.IsZero() {
+			record.Created = task.CreatedAt.UTC().Format(time.RFC3339)
+		}
+		tasks = append(tasks, record)
+	}
+	return tasks
+}
+
+// MarshalJSON renders the whole list as --json prints it, an array of
+// tasks that is empty rather than null for an empty list.
+func (t *TaskList) MarshalJSON() ([]byte, error) {
+	ids := make([]int, len(t.Tasks))
+	for i := range ids {
+		ids[i] = i
+	}
+	return json.Marshal(t.jsonTasks(ids, ""))
+}
+
+// writeJSONTasks writes tasks to w as a JSON array on one line.
+func writeJSONTasks(w io.Writer, tasks []jsonTask) error {
+	text, err := json.Marshal(tasks)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", text)
+	return err
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	tasklist := TaskList{}
+	if text, err := json.Marshal(&tasklist); err != nil || string(text) != "[]" {
+		t.Fatalf("Expected an empty list to be [], got '%s' (%v)", text, err)
+	}
+	task, _ := tasklist.Add("pay rent - today")
+	task.Priority = 1
+	task.DueAt = time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)
+	task.CreatedAt = time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	text, err := json.Marshal(&tasklist)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `[{"id":0,"stable_id":"` + task.Id + `","text":"pay rent - today","priority":1,"due":"2024-06-01","created":"2024-05-01T10:00:00Z"}]`
+	if string(text) != expected {
+		t.Fatalf("Expected %s, got %s", expected, text)
+	}
+}
+
+func TestCliJSON(t *testing.T) {
+	withCliSetup(t, func() {
+		out, _ := exec.Command(tBinary, "--json").Output()
+		if string(out) != "[]\n" {
+			t.Fatalf("Expected output to be '[]\n', got '%s'", out)
+		}
+		exec.Command(tBinary, "foo").Run()
+		exec.Command(tBinary, "bar - baz").Run()
+		out, _ = exec.Command(tBinary, "--json", "-g", "bar").Output()
+		var tasks []jsonTask
+		if err := json.Unmarshal(out, &tasks); err != nil {
+			t.Fatal(err)
+		}
+		if len(tasks) != 1 || tasks[0].Id != 1 || tasks[0].Text != "bar - baz" {
+			t.Fatalf("Expected only task 1, got '%s'", out)
+		}
+	})
+}
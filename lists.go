@@ -0,0 +1,259 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// taskDirFlag is the directory given with --task-dir.
+var taskDirFlag string
+
+// taskDirConfig is the directory taskdir in the config sets.
+var taskDirConfig string
+
+// getTaskDir returns the directory named lists live in: the one given
+// with --task-dir, T_TASKS_DIR (or T_TASK_DIR), or ~/.t by default. It
+// is created when a list in it is first written.
+func getTaskDir() (string, error) {
+	if taskDirFlag != "" {
+		return expandPath(taskDirFlag, "--task-dir")
+	}
+	if taskDirConfig != "" {
+		return expandPath(taskDirConfig, "taskdir")
+	}
+	for _, setting := range []string{"T_TASKS_DIR", "T_TASK_DIR"} {
+		if taskDir := os.Getenv(setting); taskDir != "" {
+			return expandPath(taskDir, setting)
+		}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("no place for named lists: %v; set T_TASKS_DIR or use --task-dir", err)
+	}
+	return filepath.Join(home, ".t"), nil
+}
+
+// listPath returns the tasks file of the named list.
+func listPath(name string) (string, error) {
+	// Dots are reserved for the files kept next to a list, like its
+	// undo history.
+	if name == "" || strings.ContainsAny(name, `/\.`) {
+		return "", fmt.Errorf("invalid list name %q", name)
+	}
+	taskDir, err := getTaskDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(taskDir, name), nil
+}
+
+// listFlag collects the list names of repeated -l flags.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(name string) error {
+	*l = append(*l, name)
+	return nil
+}
+
+// splitListId splits a list-qualified task id like work/3 into the list
+// name and the id within that list. Plain ids have no list name.
+func splitListId(s string) (string, string) {
+	if i := strings.LastIndex(s, "/"); i != -1 {
+		return s[:i], s[i+1:]
+	}
+	return "", s
+}
+
+// listNames returns the names of all lists in the task dir.
+func listNames() ([]string, error) {
+	taskDir, err := getTaskDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := ioutil.ReadDir(taskDir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0)
+	for _, entry := range entries {
+		if !entry.IsDir() && !strings.Contains(entry.Name(), ".") {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// searchAllLists handles --all-lists: it prints the tasks of every list
+// matching pattern, prefixed with their list name, or as one JSON array
+// with --json. Lists that can't be read are reported and skipped.
+func searchAllLists(pattern string, isRegexp bool, opts formatOptions) {
+	names, err := listNames()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		return
+	}
+	records := make([]jsonTask, 0)
+	for _, name := range names {
+		path, _ := listPath(name)
+		list, err := readTaskList(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: skipping list %s: %v\n", name, err)
+			continue
+		}
+		ids, _ := list.searchIds(pattern, isRegexp)
+		if opts.json {
+			records = append(records, list.jsonTasks(ids, name)...)
+			continue
+		}
+		for _, taskId := range ids {
+			fmt.Printf("%s/%s\n", name, formatTask(taskId, list.Tasks[taskId], opts))
+		}
+	}
+	if opts.json {
+		writeJSONTasks(os.Stdout, records)
+	}
+}
+
+// showLists prints the tasks of several lists, each under a header with
+// its name. Plain output drops the headers and qualifies every id with
+// its list name instead, and --json prints one array of all the tasks,
+// each with its list.
+func showLists(names []string, pattern string, isRegexp bool, opts formatOptions) error {
+	records := make([]jsonTask, 0)
+	for i, name := range names {
+		path, err := listPath(name)
+		if err != nil {
+			return err
+		}
+		list, err := readTaskList(path)
+		if err != nil {
+			return err
+		}
+		ids, err := list.searchIds(pattern, isRegexp)
+		if err != nil {
+			return err
+		}
+		if opts.json {
+			records = append(records, list.jsonTasks(ids, name)...)
+			continue
+		}
+		if !opts.plain {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("%s:\n", name)
+		}
+		for _, taskId := range ids {
+			if opts.plain {
+				fmt.Printf("%s/", name)
+			}
+			fmt.Println(formatTask(taskId, list.Tasks[taskId], opts))
+		}
+	}
+	if opts.json {
+		return writeJSONTasks(os.Stdout, records)
+	}
+	return nil
+}
+
+// transferTask handles --move-to and --copy-to: it appends the task
+// given in args to the named list and, unless copying, removes it from
+// the current one, both in one transaction journaled under both lists,
+// so that a crash is finished whichever of them t reads next. Links
+// don't cross lists: the task keeps those to tasks of the destination,
+// and a moved task's links on the current list go.
+func transferTask(destName string, args []string, copying bool) error {
+	if len(args) != 1 {
+		return fmt.Errorf("Usage: t --move-to|--copy-to <list> <id>")
+	}
+	destPath, err := listPath(destName)
+	if err != nil {
+		return err
+	}
+	if destPath == taskFilePath {
+		return fmt.Errorf("task is already on list %s", destName)
+	}
+	if err := checkReadOnly(destPath, destName); err != nil {
+		return err
+	}
+	// Both lists are written, so both are locked. t takes the lock on
+	// the destination along with its own when it knows it up front.
+	if _, ok := otherLocks[destPath]; !ok {
+		lock, err := acquireLock(destPath)
+		if err != nil {
+			return err
+		}
+		defer lock.Close()
+	}
+	taskId, err := tasklist.resolveId(args[0])
+	if err != nil {
+		return err
+	}
+	var task *Task
+	if copying {
+		task, err = tasklist.Get(taskId)
+		if task != nil {
+			task = task.Clone()
+			// A copy is a new task as far as its age goes.
+			task.CreatedAt = time.Now().Truncate(time.Second)
+		}
+	} else {
+		task, err = tasklist.Remove(taskId)
+		if err == nil {
+			tasklist.Unlink(task.Id)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	// The task belongs to the destination's own file now.
+	task.Source = ""
+	if _, err := os.Stat(destPath); os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "creating list %s\n", destName)
+	}
+	dest, err := readTaskList(destPath)
+	if err != nil {
+		return err
+	}
+	if copying {
+		// A copy is a task of its own, which hasn't been worked on yet.
+		task.Id = tasklist.NewId(task.Description, &dest.TaskList)
+		task.Pomodoros = 0
+	}
+	links := make([]string, 0, len(task.Links))
+	for _, id := range task.Links {
+		if dest.IndexOf(id) != -1 {
+			links = append(links, id)
+		}
+	}
+	if len(links) == 0 {
+		links = nil
+	}
+	task.Links = links
+	dest.Tasks = append(dest.Tasks, task)
+	// A moved task leaves the list in the same transaction it lands in
+	// the destination in, so that it is never on both or on neither.
+	tx := newTransaction(journalPath(taskFilePath))
+	tx.journalUnder(destPath)
+	if err := dest.stageTo(tx, destPath, false); err != nil {
+		tx.abort()
+		return err
+	}
+	if copying {
+		if err := tx.commit(); err != nil {
+			return err
+		}
+	} else if err := tasklist.commit(tx, true); err != nil {
+		return err
+	}
+	fmt.Printf("%s/%d - %s\n", destName, displayId(len(dest.Tasks)-1), task.Description)
+	return nil
+}
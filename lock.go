@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	// lockTimeout is how long t waits for another t to let go of a
+	// tasks file.
+	lockTimeout = 2 * time.Second
+	// lockRetry is how often it tries again meanwhile.
+	lockRetry = 10 * time.Millisecond
+)
+
+var errLocked = errors.New("tasks file is locked by another process")
+
+// lockFile holds the lock taken by lockTasksFile, which a file closed by
+// the garbage collector would lose.
+var lockFile *os.File
+
+// lockPath returns the lock file guarding the tasks file at path. The
+// lock is taken on a file of its own because writing replaces the tasks
+// file, and a lock on the old one wouldn't keep anyone out of the new.
+func lockPath(path string) string {
+	return path + ".lock"
+}
+
+// lockTasksFile takes the lock on the tasks file at path, waiting up to
+// lockTimeout for another t holding it. The lock is let go of when t
+// exits, so a run that reads, changes and writes the list does it all
+// without another run changing the list in between, or while it waits
+// with whileUnlocked.
+func lockTasksFile(path string) error {
+	f, err := acquireLock(path)
+	if err != nil {
+		return err
+	}
+	lockFile = f
+	return nil
+}
+
+// unlockTasksFile lets go of the lock lockTasksFile took.
+func unlockTasksFile() {
+	if lockFile != nil {
+		lockFile.Close()
+		lockFile = nil
+	}
+}
+
+// whileUnlocked runs wait, which waits on the user or a timer, without
+// holding the lock on the tasks file, so that a question, an editor or
+// a pomodoro doesn't keep every other t out, and takes the lock again
+// afterwards. It reports whether another t changed the tasks file
+// meanwhile, in which case the list, if it was read, is read anew:
+// whatever the caller found in it before, it has to look up again, by
+// stable id.
+func whileUnlocked(wait func()) (bool, error) {
+	if lockFile == nil {
+		wait()
+		return false, nil
+	}
+	stamp := stampOf(taskFilePath)
+	unlockTasksFile()
+	wait()
+	if err := lockTasksFile(taskFilePath); err != nil {
+		return false, err
+	}
+	if stampOf(taskFilePath) == stamp {
+		return false, nil
+	}
+	if err := recoverWrites(taskFilePath, true); err != nil {
+		return true, err
+	}
+	if tasklist != nil {
+		current, err := readTaskList(taskFilePath)
+		if err != nil {
+			return true, err
+		}
+		// The list read anew keeps how commands should change it.
+		current.Raw, current.Truncate, current.KeepTombstones = tasklist.Raw, tasklist.Truncate, tasklist.KeepTombstones
+		current.KeepChildren, current.Finished = tasklist.KeepChildren, tasklist.Finished
+		tasklist.TaskList = current.TaskList
+	}
+	return true, nil
+}
+
+// otherLocks holds the locks lockTasksFiles took on other tasks files
+// than t's own, by path.
+var otherLocks = make(map[string]*os.File)
+
+// lockTasksFiles takes the lock on the tasks file at path like
+// lockTasksFile, and those on the tasks files at others that the run
+// changes too. They are taken in the order of their paths, so that two
+// runs changing the same files never each hold one the other waits for.
+func lockTasksFiles(path string, others ...string) error {
+	paths := append([]string{path}, others...)
+	sort.Strings(paths)
+	for _, p := range paths {
+		if p == path {
+			if err := lockTasksFile(path); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, ok := otherLocks[p]; ok {
+			continue
+		}
+		f, err := acquireLock(p)
+		if err != nil {
+			return err
+		}
+		otherLocks[p] = f
+	}
+	return nil
+}
+
+// acquireLock takes the lock on the tasks file at path like
+// lockTasksFile, returning the file holding it; closing it lets go of
+// the lock.
+func acquireLock(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("can't lock %s: %v", path, err)
+	}
+	f, err := os.OpenFile(lockPath(path), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("can't lock %s: %v", path, err)
+	}
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		locked, err := tryLock(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("can't lock %s: %v", path, err)
+		}
+		if locked {
+			return f, nil
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, errLocked
+		}
+		time.Sleep(lockRetry)
+	}
+}
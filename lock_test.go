@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLockTasksFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks")
+	if err := lockTasksFile(path); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		lockFile.Close()
+		lockFile = nil
+	}()
+	cmd := exec.Command(tBinary, "--file", path, "foo")
+	out, err := cmd.CombinedOutput()
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+		t.Fatalf("Expected an add to a locked list to fail, got %v", err)
+	}
+	if !strings.Contains(string(out), errLocked.Error()) {
+		t.Fatalf("Expected '%v', got '%s'", errLocked, out)
+	}
+}
+
+func TestCliConcurrentAdds(t *testing.T) {
+	withCliSetup(t, func() {
+		var wg sync.WaitGroup
+		for _, description := range []string{"foo", "bar", "baz", "qux"} {
+			wg.Add(1)
+			go func(description string) {
+				defer wg.Done()
+				if err := exec.Command(tBinary, description).Run(); err != nil {
+					t.Error(err)
+				}
+			}(description)
+		}
+		wg.Wait()
+		out, _ := exec.Command(tBinary).Output()
+		for _, description := range []string{"foo", "bar", "baz", "qux"} {
+			if !strings.Contains(string(out), description) {
+				t.Fatalf("Expected %s to be on the list, got '%s'", description, out)
+			}
+		}
+	})
+}
+
+func TestCliWaitingKeepsNoLock(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("write report"), 0644)
+		pomodoro := exec.Command(tBinary, "--pomodoro", "0", "--pomodoro-length", "3s")
+		if err := pomodoro.Start(); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(200 * time.Millisecond)
+		if out, err := exec.Command(tBinary, "foo").CombinedOutput(); err != nil {
+			t.Fatalf("Expected an add during a pomodoro to work, got '%s' (%v)", out, err)
+		}
+		if err := pomodoro.Wait(); err != nil {
+			t.Fatal(err)
+		}
+		text, _ := ioutil.ReadFile("/tmp/tasks")
+		if !strings.Contains(string(text), "write report | pomodoros:1") || !strings.Contains(string(text), "\nfoo") {
+			t.Fatalf("Expected both the pomodoro and the add, got '%s'", text)
+		}
+
+		session := exec.Command(tBinary, "-i")
+		in, _ := session.StdinPipe()
+		var out bytes.Buffer
+		session.Stdout = &out
+		if err := session.Start(); err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintln(in, "a call bob")
+		time.Sleep(200 * time.Millisecond)
+		if out, err := exec.Command(tBinary, "pay rent").CombinedOutput(); err != nil {
+			t.Fatalf("Expected an add during -i to work, got '%s' (%v)", out, err)
+		}
+		fmt.Fprintln(in, "f 0")
+		in.Close()
+		if err := session.Wait(); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasSuffix(out.String(), "0 - foo\n1 - call bob\n2 - pay rent\n") {
+			t.Fatalf("Expected -i to see the task added meanwhile, got '%s'", out.String())
+		}
+	})
+}
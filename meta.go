@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// isCustomKey reports whether key is free for metadata of the user's
+// own, rather than one t reads into a field of its own, like due.
+func isCustomKey(key string) bool {
+	probe := Task{}
+	probe.SetMeta([][2]string{{key, ""}})
+	_, custom := probe.Meta[key]
+	return custom
+}
+
+// searchText is what searches look through: the description and the
+// task's own key:value metadata, like owner:alice.
+func searchText(task *Task) string {
+	if len(task.Meta) == 0 {
+		return task.Description
+	}
+	keys := make([]string, 0, len(task.Meta))
+	for key := range task.Meta {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	text := task.Description
+	for _, key := range keys {
+		text += " " + key + ":" + task.Meta[key]
+	}
+	return text
+}
+
+// setCustomMeta handles --meta: it sets each key:value pair in pairs on
+// the task, or removes the key if the value is empty. Keys of t's own
+// fields have their own options and are refused.
+func setCustomMeta(target string, pairs []string) error {
+	if len(pairs) == 0 {
+		return fmt.Errorf("Usage: t --meta <id> key:value...")
+	}
+	taskId, err := tasklist.resolveId(target)
+	if err != nil {
+		return err
+	}
+	task, err := tasklist.Get(taskId)
+	if err != nil {
+		return err
+	}
+	for _, pair := range pairs {
+		sep := strings.Index(pair, ":")
+		if sep < 1 || strings.ContainsAny(pair[:sep], " |") {
+			return fmt.Errorf("invalid metadata %q, expected key:value", pair)
+		}
+		if key := pair[:sep]; !isCustomKey(key) {
+			return fmt.Errorf("%s is set with its own option, not with --meta", key)
+		}
+	}
+	for _, pair := range pairs {
+		sep := strings.Index(pair, ":")
+		key, value := pair[:sep], pair[sep+1:]
+		if value == "" {
+			delete(task.Meta, key)
+			continue
+		}
+		if task.Meta == nil {
+			task.Meta = make(map[string]string)
+		}
+		task.Meta[key] = value
+	}
+	return tasklist.write(true)
+}
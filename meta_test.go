@@ -0,0 +1,71 @@
+package main
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIsCustomKey(t *testing.T) {
+	for _, key := range []string{"owner", "ticket", "x-sync"} {
+		if !isCustomKey(key) {
+			t.Errorf("Expected %s to be a custom key", key)
+		}
+	}
+	for _, key := range []string{"id", "due", "priority", "link", "created", "deleted"} {
+		if isCustomKey(key) {
+			t.Errorf("Expected %s to be one of t's own keys", key)
+		}
+	}
+}
+
+func TestCustomMetaSurvivesEdits(t *testing.T) {
+	list := &TaskList{}
+	list.UnmarshalText([]byte("fix login | due:2024-06-01 owner:alice ticket:PROJ-42 zz-tool:a%20b"))
+	if err := list.Edit(0, "fix login page"); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"owner": "alice", "ticket": "PROJ-42", "zz-tool": "a b"}
+	if !reflect.DeepEqual(list.Tasks[0].Meta, expected) {
+		t.Fatalf("Expected metadata %v to survive the edit, got %v", expected, list.Tasks[0].Meta)
+	}
+	text, _ := list.MarshalText()
+	if !strings.HasSuffix(string(text), " owner:alice ticket:PROJ-42 zz-tool:a%20b") {
+		t.Fatalf("Expected unknown keys to be written back untouched, got '%s'", text)
+	}
+}
+
+func TestSearchMeta(t *testing.T) {
+	list := &TaskList{}
+	list.UnmarshalText([]byte("fix login | ticket:PROJ-42\nfix logout | ticket:PROJ-7\nwrite PROJ-42 notes"))
+	if ids := list.Search("ticket:proj-42"); !reflect.DeepEqual(ids, []int{0}) {
+		t.Fatalf("Expected only the task with the ticket to match, got %v", ids)
+	}
+	if ids, _ := list.searchIds("ticket:PROJ-[0-9]$", true); !reflect.DeepEqual(ids, []int{1}) {
+		t.Fatalf("Expected the regexp to match the metadata, got %v", ids)
+	}
+}
+
+func TestCliMeta(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("fix login\nwater plants"), 0644)
+		if err := exec.Command(tBinary, "--meta", "0", "owner:alice", "ticket:PROJ-42").Run(); err != nil {
+			t.Fatal(err)
+		}
+		out, _ := exec.Command(tBinary, "-g", "ticket:PROJ-42").Output()
+		if string(out) != "0 - fix login\n" {
+			t.Fatalf("Expected the task to be found by its ticket, got '%s'", out)
+		}
+		exec.Command(tBinary, "-e", "0", "fix the login").Run()
+		exec.Command(tBinary, "--meta", "0", "owner:").Run()
+		out, _ = exec.Command(tBinary, "--show", "0").Output()
+		if !strings.Contains(string(out), "description: fix the login\n") || !strings.Contains(string(out), "ticket: PROJ-42\n") || strings.Contains(string(out), "owner") {
+			t.Fatalf("Expected the ticket to survive the edit and the owner to be gone, got '%s'", out)
+		}
+		if err := exec.Command(tBinary, "--meta", "0", "due:2024-06-01").Run(); err == nil {
+			t.Fatal("Expected --meta to refuse t's own keys")
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// nagAge is how old the oldest task must be for t to nag about it.
+const nagAge = 30
+
+// nagInterval reads the nag setting, the least time between two nags.
+// Zero, the default, turns nagging off.
+func nagInterval(config Config) (time.Duration, error) {
+	value, ok := config.Get("nag")
+	if !ok {
+		return 0, nil
+	}
+	d, err := parseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid nag = %s, expected e.g. 4h or 1d", value)
+	}
+	return d, nil
+}
+
+// nagPath returns the cache file recording when t last nagged.
+func nagPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "t", "nag"), nil
+}
+
+// nagLine returns what t nags about at now: the overdue tasks if there
+// are any, otherwise the oldest task if it is at least nagAge days old,
+// or "" for nothing. Waiting and someday tasks are left out, and
+// recurring ones don't count as old.
+func nagLine(t *TaskList, now time.Time) string {
+	overdue, oldest := 0, -1
+	for _, taskId := range t.Search("") {
+		task := t.Tasks[taskId]
+		if task.Waiting || isSomeday(task) {
+			continue
+		}
+		if !task.DueAt.IsZero() && daysBetween(now, task.DueAt) < 0 {
+			overdue++
+		}
+		if !task.CreatedAt.IsZero() && task.RecurEvery == 0 && (oldest == -1 || task.CreatedAt.Before(t.Tasks[oldest].CreatedAt)) {
+			oldest = taskId
+		}
+	}
+	switch {
+	case overdue == 1:
+		return "1 task overdue"
+	case overdue > 1:
+		return fmt.Sprintf("%d tasks overdue", overdue)
+	case oldest != -1:
+		if days := daysBetween(t.Tasks[oldest].CreatedAt, now); days >= nagAge {
+			return fmt.Sprintf("oldest task is %d days old", days)
+		}
+	}
+	return ""
+}
+
+// nagDue reports whether interval has passed at now since the nag
+// recorded in the cache file at path. Without a readable record, it has.
+func nagDue(path string, interval time.Duration, now time.Time) bool {
+	text, err := ioutil.ReadFile(path)
+	if err != nil {
+		return true
+	}
+	last, err := time.Parse(time.RFC3339, strings.TrimSpace(string(text)))
+	return err != nil || !now.Before(last.Add(interval))
+}
+
+// recordNag records in the cache file at path that t nagged at now.
+func recordNag(path string, now time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, []byte(now.UTC().Format(time.RFC3339)+"\n"), 0600)
+}
+
+// nag writes a line about the list to w if interval has passed on c
+// since the last one and there is something to nag about. Failing to
+// record it only means the next run may nag again, so it isn't
+// reported.
+func nag(w io.Writer, t *TaskList, c clock, path string, interval time.Duration) {
+	now := c.Now()
+	if !nagDue(path, interval, now) {
+		return
+	}
+	line := nagLine(t, now)
+	if line == "" {
+		return
+	}
+	fmt.Fprintln(w, line)
+	recordNag(path, now)
+}
+
+// nagShown reports whether both stdout and stderr are terminals, so
+// that a nag never ends up in a pipe or a file.
+func nagShown() bool {
+	for _, f := range []*os.File{os.Stdout, os.Stderr} {
+		info, err := f.Stat()
+		if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+func TestNagLine(t *testing.T) {
+	now := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	fresh := &Task{Description: "fresh", CreatedAt: now.AddDate(0, 0, -3)}
+	old := &Task{Description: "old", CreatedAt: now.AddDate(0, 0, -90)}
+	overdue := &Task{Description: "overdue", DueAt: now.AddDate(0, 0, -2)}
+	for _, c := range []struct {
+		tasks    []*Task
+		expected string
+	}{
+		{[]*Task{}, ""},
+		{[]*Task{fresh}, ""},
+		{[]*Task{fresh, old}, "oldest task is 90 days old"},
+		{[]*Task{old, overdue}, "1 task overdue"},
+		{[]*Task{overdue, overdue}, "2 tasks overdue"},
+		{[]*Task{{Description: "someday +someday", CreatedAt: now.AddDate(-1, 0, 0)}}, ""},
+		{[]*Task{{Description: "waiting", CreatedAt: now.AddDate(-1, 0, 0), Waiting: true}}, ""},
+	} {
+		if line := nagLine(&TaskList{TaskList: core.TaskList{Tasks: c.tasks}}, now); line != c.expected {
+			t.Errorf("Expected '%s' for %s, got '%s'", c.expected, joinDescriptions(c.tasks), line)
+		}
+	}
+}
+
+func TestNagRateLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "t", "nag")
+	c := &fakeClock{now: time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)}
+	tasklist := &TaskList{TaskList: core.TaskList{Tasks: []*Task{{Description: "late", DueAt: c.now.AddDate(0, 0, -1)}}}}
+	var out bytes.Buffer
+	for _, step := range []struct {
+		after    time.Duration
+		expected string
+	}{
+		{0, "1 task overdue\n"},
+		{time.Hour, ""},
+		{3*time.Hour + 59*time.Minute, ""},
+		{4 * time.Hour, "1 task overdue\n"},
+	} {
+		out.Reset()
+		c.now = time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC).Add(step.after)
+		nag(&out, tasklist, c, path, 4*time.Hour)
+		if out.String() != step.expected {
+			t.Fatalf("Expected '%s' after %s, got '%s'", step.expected, step.after, out.String())
+		}
+	}
+}
+
+func TestNagNothingToSay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nag")
+	c := &fakeClock{now: time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)}
+	var out bytes.Buffer
+	nag(&out, &TaskList{}, c, path, time.Hour)
+	if out.Len() != 0 || !nagDue(path, time.Hour, c.now) {
+		t.Fatal("Expected no nag, and none to be recorded")
+	}
+}
+
+func TestNagDueCorruptCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nag")
+	now := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	if err := recordNag(path, now); err != nil {
+		t.Fatal(err)
+	}
+	if nagDue(path, time.Hour, now.Add(time.Minute)) {
+		t.Fatal("Expected no nag a minute after the last one")
+	}
+	ioutil.WriteFile(path, []byte("garbage"), 0600)
+	if !nagDue(path, time.Hour, now.Add(time.Minute)) {
+		t.Fatal("Expected an unreadable record to allow a nag")
+	}
+}
+
+func TestNagInterval(t *testing.T) {
+	if d, err := nagInterval(Config{values: map[string]string{}}); d != 0 || err != nil {
+		t.Fatalf("Expected nagging off by default, got %s (%v)", d, err)
+	}
+	if d, err := nagInterval(Config{values: map[string]string{"nag": "4h"}}); d != 4*time.Hour || err != nil {
+		t.Fatalf("Expected 4h, got %s (%v)", d, err)
+	}
+	if _, err := nagInterval(Config{values: map[string]string{"nag": "often"}}); err == nil {
+		t.Fatal("Expected an error for nag = often")
+	}
+}
+
+func TestCliNoNagWhenPiped(t *testing.T) {
+	withCliSetup(t, func() {
+		dir := t.TempDir()
+		os.Mkdir(filepath.Join(dir, "t"), 0700)
+		ioutil.WriteFile(filepath.Join(dir, "t", "config"), []byte("nag = 1m\n"), 0644)
+		ioutil.WriteFile("/tmp/tasks", []byte("late | due:2020-01-01"), 0644)
+		cmd := exec.Command(tBinary)
+		cmd.Env = append(os.Environ(), "XDG_CONFIG_HOME="+dir, "XDG_CACHE_HOME="+dir)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil || stderr.Len() != 0 {
+			t.Fatalf("Expected no nag when piped, got '%s' (%v)", stderr.String(), err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "t", "nag")); !os.IsNotExist(err) {
+			t.Fatal("Expected no nag to be recorded")
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// noteSeparator separates the notes of a task when they are edited
+// together, on a line of its own.
+const noteSeparator = "---"
+
+// formatNote renders a note the way --show lists it, its lines after
+// the first indented under it.
+func formatNote(note string) string {
+	return "note: " + strings.Replace(strings.TrimRight(note, "\n"), "\n", "\n  ", -1) + "\n"
+}
+
+// joinNotes returns the notes as they are edited, separated by lines of
+// noteSeparator.
+func joinNotes(notes []string) string {
+	return strings.Join(notes, "\n"+noteSeparator+"\n")
+}
+
+// splitNotes undoes joinNotes, dropping notes left empty.
+func splitNotes(text string) []string {
+	text = strings.Replace(text, "\r\n", "\n", -1)
+	notes := make([]string, 0)
+	for _, note := range strings.Split("\n"+text+"\n", "\n"+noteSeparator+"\n") {
+		if note = strings.Trim(note, "\n"); strings.TrimSpace(note) != "" {
+			notes = append(notes, note)
+		}
+	}
+	return notes
+}
+
+// noteTask handles --note: it adds the text in args as a note to the
+// task, or without one opens the task's notes in the editor.
+func noteTask(target string, args []string) error {
+	taskId, err := tasklist.resolveId(target)
+	if err != nil {
+		return err
+	}
+	task, err := tasklist.Get(taskId)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		notes, err := editNotes(task.Notes)
+		if err != nil {
+			return err
+		}
+		// Another t may have changed the list while the editor ran.
+		if taskId = tasklist.IndexOf(task.Id); taskId == -1 {
+			return errors.New("the task was finished or deleted while its notes were being edited")
+		}
+		tasklist.Tasks[taskId].Notes = notes
+		return tasklist.write(true)
+	}
+	note := strings.Join(args, " ")
+	if strings.TrimSpace(note) == "" {
+		return fmt.Errorf("Usage: t --note <id> [text]")
+	}
+	task.Notes = append(task.Notes, note)
+	return tasklist.write(true)
+}
+
+// editNotes opens notes in the editor, separated by lines of
+// noteSeparator, and returns them as they were saved. Emptying a note
+// removes it; an editor that fails leaves them as they were.
+func editNotes(notes []string) ([]string, error) {
+	file, err := ioutil.TempFile("", "t-notes-*.txt")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	text := joinNotes(notes)
+	if text != "" {
+		text += "\n"
+	}
+	_, err = file.WriteString(text)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := runEditor(editorCommand(file.Name())); err != nil {
+		return nil, fmt.Errorf("%v, the notes weren't changed", err)
+	}
+	edited, err := ioutil.ReadFile(file.Name())
+	if err != nil {
+		return nil, err
+	}
+	return splitNotes(string(edited)), nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitNotes(t *testing.T) {
+	text := joinNotes([]string{"one", "two\nlines"}) + "\n---\n\n  \n---\r\nthree\r\n"
+	notes := splitNotes(text)
+	expected := []string{"one", "two\nlines", "three"}
+	if !reflect.DeepEqual(notes, expected) {
+		t.Fatalf("Expected %q, got %q", expected, notes)
+	}
+	if notes := splitNotes("\n\n"); len(notes) != 0 {
+		t.Fatalf("Expected no notes, got %q", notes)
+	}
+}
+
+func TestCliNote(t *testing.T) {
+	withCliSetup(t, func() {
+		exec.Command(tBinary, "pay rent").Run()
+		if out, err := exec.Command(tBinary, "--note", "0", "see", "https://example.com/a|b").CombinedOutput(); err != nil {
+			t.Fatalf("Expected the note to be added, got %v: %s", err, out)
+		}
+		out, _ := exec.Command(tBinary, "--show", "0").Output()
+		if !strings.Contains(string(out), "note: see https://example.com/a|b\n") {
+			t.Fatalf("Expected --show to list the note, got '%s'", out)
+		}
+		exec.Command(tBinary, "-e", "0", "pay the rent").Run()
+		cmd := exec.Command(tBinary, "--note", "0")
+		cmd.Env = append(cmd.Environ(), "VISUAL=", "EDITOR=sed -i $a---\\nsecond\\nline")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("Expected the notes to be edited, got %v: %s", err, out)
+		}
+		out, _ = exec.Command(tBinary, "--show", "0").Output()
+		if !strings.Contains(string(out), "pay the rent") || !strings.Contains(string(out), "note: see https://example.com/a|b\nnote: second\n  line\n") {
+			t.Fatalf("Expected both notes kept across the edit, got '%s'", out)
+		}
+		exec.Command(tBinary, "-f", "0").Run()
+		if text, _ := ioutil.ReadFile("/tmp/tasks.done"); !strings.Contains(string(text), "note:second%0Aline") {
+			t.Fatalf("Expected the notes in the done file, got '%s'", text)
+		}
+		if err := exec.Command(tBinary, "--note", "9", "x").Run(); err == nil {
+			t.Fatal("Expected a note on a missing task to fail")
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// muted reports whether notifications about the task are suppressed at
+// now.
+func muted(task *Task, now time.Time) bool {
+	return now.Before(task.MutedUntil)
+}
+
+// dueNotifications returns the ids of the listed tasks --notify is about:
+// those due today or overdue, unless they are muted.
+func (t *TaskList) dueNotifications(now time.Time) []int {
+	ids := make([]int, 0)
+	for _, taskId := range t.Search("") {
+		task := t.Tasks[taskId]
+		if !task.DueAt.IsZero() && daysBetween(now, task.DueAt) <= 0 && !muted(task, now) {
+			ids = append(ids, taskId)
+		}
+	}
+	return ids
+}
+
+// notifyTasks handles --notify: it sends a desktop notification for
+// each task due today or overdue, or prints the tasks when there is no
+// notify-send, which makes cron mail them.
+func notifyTasks(now time.Time) error {
+	notifier, err := exec.LookPath("notify-send")
+	for _, taskId := range tasklist.dueNotifications(now) {
+		task := tasklist.Tasks[taskId]
+		if err != nil {
+			fmt.Println(formatTask(taskId, task, formatOptions{plain: true, now: now}))
+			continue
+		}
+		title := "t: due today"
+		if daysBetween(now, task.DueAt) < 0 {
+			title = "t: overdue"
+		}
+		if err := exec.Command(notifier, title, task.Description).Run(); err != nil {
+			return fmt.Errorf("notify-send failed: %v", err)
+		}
+	}
+	return nil
+}
+
+// muteTask handles --mute: it keeps --notify quiet about a task for a
+// while, like 2d, without changing its due date.
+func muteTask(target string, duration string, now time.Time) error {
+	if duration == "" {
+		return fmt.Errorf("Usage: t --mute <id> <duration>")
+	}
+	d, err := parseDuration(duration)
+	if err != nil {
+		return err
+	}
+	taskId, err := tasklist.resolveId(target)
+	if err != nil {
+		return err
+	}
+	task, err := tasklist.Get(taskId)
+	if err != nil {
+		return err
+	}
+	task.MutedUntil = now.Add(d).Truncate(time.Second)
+	return tasklist.write(true)
+}
+
+// unmuteTask handles --unmute: it lets --notify report a muted task
+// again.
+func unmuteTask(target string) error {
+	taskId, err := tasklist.resolveId(target)
+	if err != nil {
+		return err
+	}
+	task, err := tasklist.Get(taskId)
+	if err != nil {
+		return err
+	}
+	task.MutedUntil = time.Time{}
+	return tasklist.write(true)
+}
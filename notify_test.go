@@ -0,0 +1,62 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+func TestDueNotifications(t *testing.T) {
+	now := time.Now()
+	today := startOfDay(now)
+	tasklist := TaskList{TaskList: core.TaskList{Tasks: []*Task{
+		{Description: "overdue", DueAt: today.AddDate(0, 0, -1)},
+		{Description: "today", DueAt: today},
+		{Description: "tomorrow", DueAt: today.AddDate(0, 0, 1)},
+		{Description: "muted", DueAt: today, MutedUntil: now.Add(time.Hour)},
+		{Description: "mute ended", DueAt: today, MutedUntil: now.Add(-time.Hour)},
+		{Description: "no due date"},
+	}}}
+	if ids := tasklist.dueNotifications(now); !reflect.DeepEqual(ids, []int{0, 1, 4}) {
+		t.Fatalf("Expected [0 1 4] to be notified about, got %v", ids)
+	}
+}
+
+func TestCliMute(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("pay rent | due:2024-06-01"), 0644)
+		// Without notify-send on the PATH, --notify prints the tasks.
+		env := append(os.Environ(), "PATH=")
+		notify := func() string {
+			cmd := exec.Command(tBinary, "--notify")
+			cmd.Env = env
+			out, _ := cmd.Output()
+			return string(out)
+		}
+		if out := notify(); out != "0 - pay rent (due 2024-06-01)\n" {
+			t.Fatalf("Expected the overdue task to be reported, got '%s'", out)
+		}
+		if err := exec.Command(tBinary, "--mute", "0", "2d").Run(); err != nil {
+			t.Fatal(err)
+		}
+		if out := notify(); out != "" {
+			t.Fatalf("Expected the muted task not to be reported, got '%s'", out)
+		}
+		out, _ := exec.Command(tBinary, "--show", "0").Output()
+		if !strings.Contains(string(out), "due: 2024-06-01\nmuted until: ") {
+			t.Fatalf("Expected --show to tell the task is muted, got '%s'", out)
+		}
+		if err := exec.Command(tBinary, "--unmute", "0").Run(); err != nil {
+			t.Fatal(err)
+		}
+		if out := notify(); out == "" {
+			t.Fatal("Expected the unmuted task to be reported again")
+		}
+	})
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	// planDays is how many weekdays --plan fills.
+	planDays = 5
+	// defaultPlanBudget is how much estimated work --plan puts on a day
+	// unless plan_budget says otherwise.
+	defaultPlanBudget = 4 * time.Hour
+	// defaultPlanEstimate is what a task without an est: counts for.
+	defaultPlanEstimate = time.Hour
+)
+
+// weekPlan is the tasks planned for each day, by id, and those that
+// didn't fit.
+type weekPlan struct {
+	days     []time.Time
+	planned  [][]int
+	load     []time.Duration
+	overflow []int
+}
+
+// planEstimate returns what a task counts for in a plan.
+func planEstimate(task *Task) time.Duration {
+	if estimate, ok := estimate(task); ok {
+		return estimate
+	}
+	return defaultPlanEstimate
+}
+
+// planWeekdays returns the next n weekdays, starting today if it is one.
+func planWeekdays(now time.Time, n int) []time.Time {
+	days := make([]time.Time, 0, n)
+	for day := startOfDay(now); len(days) < n; day = day.AddDate(0, 0, 1) {
+		if day.Weekday() != time.Saturday && day.Weekday() != time.Sunday {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+// planOrder returns the ids of the listed tasks in the order they
+// should be worked on, as for --next, at most n of them unless n is 0.
+func (t *TaskList) planOrder(n int, now time.Time) []int {
+	ids := t.Search("")
+	sort.SliceStable(ids, func(i, j int) bool {
+		return t.before(ids[i], ids[j], now)
+	})
+	if n > 0 && len(ids) > n {
+		ids = ids[:n]
+	}
+	return ids
+}
+
+// packPlan puts the tasks with the given ids, in order, each on the
+// first day that still has room for its estimate within budget. A task
+// due during the plan goes no later than its due date, and one due
+// before it goes on the first day or not at all. Tasks that fit nowhere
+// are overflow.
+func (t *TaskList) packPlan(ids []int, days []time.Time, budget time.Duration) weekPlan {
+	plan := weekPlan{
+		days:     days,
+		planned:  make([][]int, len(days)),
+		load:     make([]time.Duration, len(days)),
+		overflow: make([]int, 0),
+	}
+	for _, taskId := range ids {
+		task := t.Tasks[taskId]
+		estimate := planEstimate(task)
+		placed := false
+		for day := range days {
+			if !task.DueAt.IsZero() && day > 0 && days[day].After(task.DueAt) {
+				break
+			}
+			if plan.load[day]+estimate <= budget {
+				plan.planned[day] = append(plan.planned[day], taskId)
+				plan.load[day] += estimate
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			plan.overflow = append(plan.overflow, taskId)
+		}
+	}
+	return plan
+}
+
+// printPlan prints the plan day by day, then the overflow.
+func (t *TaskList) printPlan(plan weekPlan, budget time.Duration, opts formatOptions) {
+	for day, ids := range plan.planned {
+		fmt.Printf("%s (%s of %s)\n", plan.days[day].Format("Mon 2006-01-02"),
+			formatEstimate(plan.load[day]), formatEstimate(budget))
+		for _, taskId := range ids {
+			fmt.Println("  " + formatTask(taskId, t.Tasks[taskId], opts))
+		}
+	}
+	if len(plan.overflow) > 0 {
+		fmt.Println("overflow")
+		for _, taskId := range plan.overflow {
+			fmt.Println("  " + formatTask(taskId, t.Tasks[taskId], opts))
+		}
+	}
+}
+
+// applyPlan makes each planned task due on its day.
+func (t *TaskList) applyPlan(plan weekPlan) {
+	for day, ids := range plan.planned {
+		for _, taskId := range ids {
+			t.Tasks[taskId].DueAt = plan.days[day]
+		}
+	}
+}
+
+// planBudget reads the plan_budget setting.
+func planBudget(config Config) (time.Duration, error) {
+	value, ok := config.Get("plan_budget")
+	if !ok {
+		return defaultPlanBudget, nil
+	}
+	budget, err := time.ParseDuration(value)
+	if err != nil || budget <= 0 {
+		return 0, fmt.Errorf("invalid plan_budget = %s, expected e.g. 4h or 6h30m", value)
+	}
+	return budget, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlanWeekdays(t *testing.T) {
+	saturday := time.Date(2024, 6, 8, 15, 0, 0, 0, time.UTC)
+	days := planWeekdays(saturday, 5)
+	if len(days) != 5 || days[0].Format(dateLayout) != "2024-06-10" || days[4].Format(dateLayout) != "2024-06-14" {
+		t.Fatalf("Expected Monday to Friday of the next week, got %v", days)
+	}
+	wednesday := time.Date(2024, 6, 12, 9, 0, 0, 0, time.UTC)
+	days = planWeekdays(wednesday, 5)
+	if days[0].Format(dateLayout) != "2024-06-12" || days[3].Format(dateLayout) != "2024-06-17" {
+		t.Fatalf("Expected the plan to start today and skip the weekend, got %v", days)
+	}
+}
+
+func TestPackPlan(t *testing.T) {
+	days := planWeekdays(time.Date(2024, 6, 10, 9, 0, 0, 0, time.UTC), 5)
+	list := &TaskList{}
+	list.UnmarshalText([]byte(strings.Join([]string{
+		"write spec est:3h",
+		"review est:2h",
+		"call bank",
+		"too big est:5h",
+		"pay rent est:2h | due:2024-06-11",
+		"overdue est:2h | due:2024-06-01",
+		"ship est:4h | due:2024-06-10",
+	}, "\n")))
+	plan := list.packPlan([]int{0, 1, 2, 3, 4, 5, 6}, days, 4*time.Hour)
+	expected := [][]int{{0, 2}, {1, 4}, nil, nil, nil}
+	if !reflect.DeepEqual(plan.planned, expected) {
+		t.Fatalf("Expected plan %v, got %v", expected, plan.planned)
+	}
+	if !reflect.DeepEqual(plan.overflow, []int{3, 5, 6}) {
+		t.Fatalf("Expected tasks 3, 5 and 6 to overflow, got %v", plan.overflow)
+	}
+	if plan.load[0] != 4*time.Hour || plan.load[1] != 4*time.Hour {
+		t.Fatalf("Expected loads of 4h, got %v", plan.load)
+	}
+	if plan := list.packPlan([]int{5, 4}, days, 4*time.Hour); !reflect.DeepEqual(plan.planned[0], []int{5, 4}) {
+		t.Fatalf("Expected overdue and due tasks on the first day while it has room, got %v", plan.planned)
+	}
+	again := list.packPlan([]int{0, 1, 2, 3, 4, 5, 6}, days, 4*time.Hour)
+	if !reflect.DeepEqual(plan, again) {
+		t.Fatal("Expected the same tasks to be packed the same way")
+	}
+}
+
+func TestPlanOrder(t *testing.T) {
+	list := &TaskList{}
+	list.UnmarshalText([]byte("later\nsoon | due:2024-06-12\nurgent | priority:1\nsnoozed | priority:1 snooze:2099-01-01"))
+	now := time.Date(2024, 6, 10, 9, 0, 0, 0, time.UTC)
+	if ids := list.planOrder(0, now); !reflect.DeepEqual(ids, []int{2, 1, 0}) {
+		t.Fatalf("Expected tasks by priority, then due date, got %v", ids)
+	}
+	if ids := list.planOrder(2, now); !reflect.DeepEqual(ids, []int{2, 1}) {
+		t.Fatalf("Expected the top 2 tasks, got %v", ids)
+	}
+}
+
+func TestCliPlanApply(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("write spec est:3h\ntoo big est:5h"), 0644)
+		out, err := exec.Command(tBinary, "--plan", "--apply").Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(out), "(3h of 4h)\n  0 - write spec est:3h") || !strings.Contains(string(out), "overflow\n  1 - too big est:5h") {
+			t.Fatalf("Expected a plan with one task and one overflow, got '%s'", out)
+		}
+		text, _ := ioutil.ReadFile("/tmp/tasks")
+		lines := strings.Split(string(text), "\n")
+		if !strings.Contains(lines[0], "due:") || strings.Contains(lines[1], "due:") {
+			t.Fatalf("Expected only the planned task to get a due date, got '%s'", text)
+		}
+	})
+}
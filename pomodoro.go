@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"time"
+)
+
+// clock is the source of time for the pomodoro countdown, so that tests
+// can run it against a fake one.
+type clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// countdown waits until length has passed on c, calling render with the
+// remaining time about once a second. It returns false if cancel fires
+// before the time is up.
+func countdown(c clock, length time.Duration, render func(remaining time.Duration), cancel <-chan os.Signal) bool {
+	end := c.Now().Add(length)
+	for {
+		remaining := end.Sub(c.Now())
+		if remaining <= 0 {
+			render(0)
+			return true
+		}
+		render(remaining)
+		tick := time.Second
+		if remaining < tick {
+			tick = remaining
+		}
+		select {
+		case <-c.After(tick):
+		case <-cancel:
+			return false
+		}
+	}
+}
+
+// formatRemaining renders a remaining duration as mm:ss.
+func formatRemaining(remaining time.Duration) string {
+	seconds := int((remaining + time.Second - 1) / time.Second)
+	return fmt.Sprintf("%02d:%02d", seconds/60, seconds%60)
+}
+
+// runPomodoro handles --pomodoro: it counts down on the terminal and,
+// unless interrupted with Ctrl-C, records the pomodoro on the task.
+func runPomodoro(target string, length time.Duration) error {
+	taskId, err := tasklist.resolveId(target)
+	if err != nil {
+		return err
+	}
+	task, err := tasklist.Get(taskId)
+	if err != nil {
+		return err
+	}
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	// Other runs of t can change the list while the timer runs; t takes
+	// the lock again, and reads the list anew if it changed, only to
+	// record the pomodoro.
+	var done bool
+	if _, err := whileUnlocked(func() {
+		done = countdown(realClock{}, length, func(remaining time.Duration) {
+			fmt.Printf("\r%s %s ", formatRemaining(remaining), task.Description)
+		}, interrupt)
+		fmt.Println()
+	}); err != nil {
+		return err
+	}
+	if !done {
+		return fmt.Errorf("pomodoro cancelled")
+	}
+	fmt.Print("\a")
+	if notifier, err := exec.LookPath("notify-send"); err == nil {
+		exec.Command(notifier, "Pomodoro done", task.Description).Run()
+	}
+
+	recorded := tasklist.relocate(taskId, task.Description)
+	if recorded == nil {
+		return fmt.Errorf("task %d changed while the pomodoro was running", displayId(taskId))
+	}
+	recorded.Pomodoros++
+	return tasklist.write(true)
+}
+
+// relocate finds a task again after the list was reloaded: the task at
+// taskId if it still has the same description, else the first task with
+// that description.
+func (t *TaskList) relocate(taskId int, description string) *Task {
+	if task, err := t.Get(taskId); err == nil && task.Description == description {
+		return task
+	}
+	for _, task := range t.Tasks {
+		if task.Description == description {
+			return task
+		}
+	}
+	return nil
+}
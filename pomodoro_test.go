@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// fakeClock advances its time whenever it is waited on.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.now = c.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+func TestCountdown(t *testing.T) {
+	c := &fakeClock{now: time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)}
+	rendered := make([]string, 0)
+	done := countdown(c, 2500*time.Millisecond, func(remaining time.Duration) {
+		rendered = append(rendered, formatRemaining(remaining))
+	}, nil)
+	if !done {
+		t.Fatal("Expected countdown to run to completion")
+	}
+	expected := []string{"00:03", "00:02", "00:01", "00:00"}
+	if len(rendered) != len(expected) {
+		t.Fatalf("Expected renders %v, got %v", expected, rendered)
+	}
+	for i := range expected {
+		if rendered[i] != expected[i] {
+			t.Fatalf("Expected renders %v, got %v", expected, rendered)
+		}
+	}
+}
+
+func TestCountdownCancel(t *testing.T) {
+	c := &fakeClock{now: time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)}
+	cancel := make(chan os.Signal, 1)
+	cancel <- os.Interrupt
+	renders := 0
+	// The fake clock is always ready too, so it may take a few ticks
+	// until the cancel gets picked.
+	done := countdown(c, time.Hour, func(time.Duration) { renders++ }, cancel)
+	if done {
+		t.Fatal("Expected cancelled countdown to report false")
+	}
+	if renders == 0 {
+		t.Fatal("Expected countdown to render before being cancelled")
+	}
+}
+
+func TestFormatRemaining(t *testing.T) {
+	cases := map[time.Duration]string{
+		25 * time.Minute:       "25:00",
+		90*time.Second + 1:     "01:31",
+		500 * time.Millisecond: "00:01",
+		0:                      "00:00",
+	}
+	for remaining, expected := range cases {
+		if formatted := formatRemaining(remaining); formatted != expected {
+			t.Fatalf("formatRemaining(%v): expected %s, got %s", remaining, expected, formatted)
+		}
+	}
+}
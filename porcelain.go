@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// porcelainVersion is the version of the --porcelain format. Unlike
+// formatVersion it never goes up: a script reading --porcelain relies on
+// the fields below staying as they are, so new ones would need a new
+// option rather than a change to this one.
+const porcelainVersion = 1
+
+// porcelainEscaper escapes the tabs, line breaks and backslashes of a
+// --porcelain field, so that a line is always one task and a tab always
+// ends a field.
+var porcelainEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// porcelainLine renders a task for --porcelain, version 1: its id,
+// priority (1 to 9), creation time (RFC 3339, UTC), due date
+// (YYYY-MM-DD), tags (separated by spaces) and description, separated
+// by tabs. Fields a task doesn't have are empty.
+func porcelainLine(id string, task *Task) string {
+	fields := []string{id, "", "", "", "", task.Description}
+	if task.Priority != 0 {
+		fields[1] = strconv.Itoa(task.Priority)
+	}
+	if !task.CreatedAt.IsZero() {
+		fields[2] = task.CreatedAt.UTC().Format(time.RFC3339)
+	}
+	if !task.DueAt.IsZero() {
+		fields[3] = task.DueAt.Format(dateLayout)
+	}
+	tags := make([]string, 0)
+	for _, word := range strings.Fields(task.Description) {
+		if isTag(word) {
+			tags = append(tags, word)
+		}
+	}
+	fields[4] = strings.Join(tags, " ")
+	for i, field := range fields {
+		fields[i] = porcelainEscaper.Replace(field)
+	}
+	return strings.Join(fields, "\t")
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestPorcelainLine(t *testing.T) {
+	task := &Task{
+		Description: "fix\tthe deck\\ +home\n@garden",
+		Priority:    2,
+		CreatedAt:   time.Date(2024, 6, 1, 11, 0, 0, 0, time.FixedZone("", 2*3600)),
+		DueAt:       time.Date(2024, 6, 14, 0, 0, 0, 0, time.Local),
+	}
+	expected := "3\t2\t2024-06-01T09:00:00Z\t2024-06-14\t+home @garden\tfix\\tthe deck\\\\ +home\\n@garden"
+	if line := porcelainLine("3", task); line != expected {
+		t.Fatalf("Expected %q, got %q", expected, line)
+	}
+	if line := porcelainLine("0", &Task{Description: "pay rent"}); line != "0\t\t\t\t\tpay rent" {
+		t.Fatalf("Expected empty fields left blank, got %q", line)
+	}
+}
+
+func TestCliPorcelainFilters(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("pay rent\ncall bob +work\nwrite report +work"), 0644)
+		for _, c := range []struct {
+			args     []string
+			expected string
+		}{
+			{[]string{"--porcelain", "-g", "bob"}, "1\t\t\t\t+work\tcall bob +work\n"},
+			{[]string{"--porcelain", "--tag", "work", "--color", "always"}, "1\t\t\t\t+work\tcall bob +work\n2\t\t\t\t+work\twrite report +work\n"},
+		} {
+			out, err := exec.Command(tBinary, c.args...).Output()
+			if err != nil || string(out) != c.expected {
+				t.Fatalf("Expected %v to print %q, got %q (%v)", c.args, c.expected, out, err)
+			}
+		}
+		dir := t.TempDir()
+		ioutil.WriteFile(dir+"/work", []byte("review"), 0644)
+		cmd := exec.Command(tBinary, "-l", "work", "--porcelain")
+		cmd.Env = append(cmd.Environ(), "T_TASKS_DIR="+dir)
+		if out, err := cmd.Output(); err != nil || string(out) != "0\t\t\t\t\treview\n" {
+			t.Fatalf("Expected the work list, got %q (%v)", out, err)
+		}
+	})
+}
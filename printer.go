@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// shortIdLength is how much of a stable id confirmations show, the way
+// git shortens a commit hash. It is long enough to give to -e or -f in
+// place of the task's id.
+const shortIdLength = 7
+
+// printer prints what a command that changes the list did, like
+// "added: pay rent (id 7, 3fa2b1c)", so that it's plain it worked
+// without listing the tasks again. With -q it prints nothing, for
+// scripts.
+type printer struct {
+	out   io.Writer
+	quiet bool
+}
+
+// confirmations is the printer the command line confirms with, set up
+// in main once the flags are parsed.
+var confirmations = &printer{out: ioutil.Discard}
+
+// printf prints a confirmation unless the printer is quiet.
+func (p *printer) printf(format string, args ...interface{}) {
+	if !p.quiet {
+		fmt.Fprintf(p.out, format, args...)
+	}
+}
+
+// added confirms a task added with the id it's listed with.
+func (p *printer) added(id string, task *Task) {
+	if short := shortId(task); short != "" {
+		id += ", " + short
+	}
+	p.printf("added: %s (id %s)\n", lineBreaks.Replace(task.Description), id)
+}
+
+// finished confirms a finished task.
+func (p *printer) finished(task *Task) {
+	p.printf("finished: %s%s\n", lineBreaks.Replace(task.Description), inParens(shortId(task)))
+}
+
+// deleted confirms a task deleted without being finished.
+func (p *printer) deleted(task *Task) {
+	p.printf("deleted: %s%s\n", lineBreaks.Replace(task.Description), inParens(shortId(task)))
+}
+
+// edited confirms that the description of the task with the given id
+// changed from old to the task's.
+func (p *printer) edited(taskId int, old string, task *Task) {
+	p.printf("edited %d: %s → %s%s\n", displayId(taskId), lineBreaks.Replace(old), lineBreaks.Replace(task.Description), inParens(shortId(task)))
+}
+
+// shortId returns the start of a task's stable id that confirmations
+// show, or "" for a task without one.
+func shortId(task *Task) string {
+	if len(task.Id) > shortIdLength {
+		return task.Id[:shortIdLength]
+	}
+	return task.Id
+}
+
+// inParens returns s in parentheses after a space, or "" if s is empty.
+func inParens(s string) string {
+	if s == "" {
+		return ""
+	}
+	return " (" + s + ")"
+}
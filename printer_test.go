@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"os/exec"
+	"testing"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+func TestPrinter(t *testing.T) {
+	list := &TaskList{}
+	task, _ := list.Add("pay rent")
+	short := core.Hash("pay rent")[:shortIdLength]
+	var out bytes.Buffer
+	p := &printer{out: &out}
+	p.added("7", task)
+	p.finished(task)
+	p.edited(2, "pay\nrent", task)
+	p.deleted(task)
+	p.finished(&Task{Description: "legacy"})
+	expected := "added: pay rent (id 7, " + short + ")\nfinished: pay rent (" + short + ")\n" +
+		"edited 2: pay⏎rent → pay rent (" + short + ")\ndeleted: pay rent (" + short + ")\nfinished: legacy\n"
+	if out.String() != expected {
+		t.Fatalf("Expected '%s', got '%s'", expected, out.String())
+	}
+	out.Reset()
+	p.quiet = true
+	p.added("7", task)
+	p.finished(task)
+	p.edited(2, "pay rent", task)
+	p.deleted(task)
+	p.printf("deleted %d tasks\n", 1)
+	if out.Len() != 0 {
+		t.Fatalf("Expected a quiet printer to print nothing, got '%s'", out.String())
+	}
+}
+
+func TestCliConfirmations(t *testing.T) {
+	withCliSetup(t, func() {
+		short := core.Hash("foo")[:shortIdLength]
+		for _, c := range []struct {
+			args     []string
+			expected string
+		}{
+			{[]string{"foo"}, "added: foo (id 0, " + short + ")\n"},
+			{[]string{"bar"}, "added: bar (id 1, " + core.Hash("bar")[:shortIdLength] + ")\n"},
+			{[]string{"-e", "0", "baz"}, "edited 0: foo → baz (" + short + ")\n"},
+			{[]string{"--sub", "0", "qux"}, "added: qux (id 0.0, " + core.Hash("qux")[:shortIdLength] + ")\n"},
+			{[]string{"-f", "0.0"}, "finished: qux (" + core.Hash("qux")[:shortIdLength] + ")\n"},
+			{[]string{"-f", "0"}, "finished: baz (" + short + ")\n"},
+			{[]string{"a"}, "added: a (id 1, " + core.Hash("a")[:shortIdLength] + ")\n"},
+			{[]string{"--append", "1", "b"}, "edited 1: a → a b (" + core.Hash("a")[:shortIdLength] + ")\n"},
+			{[]string{"--prepend", "1", "c"}, "edited 1: a b → c a b (" + core.Hash("a")[:shortIdLength] + ")\n"},
+			{[]string{"-d", "1"}, "deleted: c a b (" + core.Hash("a")[:shortIdLength] + ")\n"},
+		} {
+			out, err := exec.Command(tBinary, c.args...).Output()
+			if err != nil || string(out) != c.expected {
+				t.Fatalf("t %v: expected '%s', got '%s' (%v)", c.args, c.expected, out, err)
+			}
+		}
+		// With -q, t says nothing, as it did before it confirmed.
+		for _, args := range [][]string{{"-q", "foo"}, {"-q", "-e", "0", "baz"}, {"-q", "--sub", "0", "qux"}, {"-q", "-f", "0.0"}, {"-q", "-f", "0,1"}, {"-q", "a"}, {"-q", "--append", "0", "b"}, {"-q", "-d", "0"}, {"-q", "--stdin"}, {"-q", "--clear", "-y"}} {
+			out, err := exec.Command(tBinary, args...).Output()
+			if err != nil || string(out) != "" {
+				t.Fatalf("t %v: expected no output, got '%s' (%v)", args, out, err)
+			}
+		}
+		if out, _ := exec.Command(tBinary).Output(); string(out) != "" {
+			t.Fatalf("Expected every task finished, got '%s'", out)
+		}
+	})
+}
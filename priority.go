@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+const (
+	// highestPriority and lowestPriority bound task priorities; lower
+	// numbers are more important.
+	highestPriority = core.HighestPriority
+	lowestPriority  = core.LowestPriority
+	// defaultPriority is the priority of tasks that weren't given one.
+	defaultPriority = 5
+	// maxAgingBoost caps how many levels aging can raise a priority.
+	maxAgingBoost = 3
+)
+
+// priorityAging raises the effective priority of a task by one level
+// for every period it has been on the list. A zero period disables it.
+type priorityAging struct {
+	period time.Duration
+}
+
+// aging is the priority aging in effect, set from T_PRIORITY_AGING.
+var aging priorityAging
+
+// parseAging parses the T_PRIORITY_AGING setting, a period like 14d,
+// 2w or 36h. An empty setting leaves aging off.
+func parseAging(s string) (priorityAging, error) {
+	if s == "" {
+		return priorityAging{}, nil
+	}
+	period, err := parseDuration(s)
+	if err != nil || period <= 0 {
+		return priorityAging{}, fmt.Errorf("invalid T_PRIORITY_AGING %q, expected e.g. 14d or 2w", s)
+	}
+	return priorityAging{period: period}, nil
+}
+
+// boost returns how many levels a task of the given age is raised by.
+func (a priorityAging) boost(age time.Duration) int {
+	if a.period <= 0 || age < 0 {
+		return 0
+	}
+	boost := int(age / a.period)
+	if boost > maxAgingBoost {
+		boost = maxAgingBoost
+	}
+	return boost
+}
+
+// parsePriority parses a priority given on the command line.
+func parsePriority(s string) (int, error) {
+	priority, err := strconv.Atoi(s)
+	if err != nil || priority < highestPriority || priority > lowestPriority {
+		return 0, fmt.Errorf("invalid priority %q, expected %d (highest) to %d", s, highestPriority, lowestPriority)
+	}
+	return priority, nil
+}
+
+// setPriority handles -P: it gives the task with the given id a new
+// priority.
+func setPriority(target string, priority int) error {
+	if target == "" {
+		return fmt.Errorf("Usage: t -P <priority> <id>")
+	}
+	taskId, err := tasklist.resolveId(target)
+	if err != nil {
+		return err
+	}
+	task, err := tasklist.Get(taskId)
+	if err != nil {
+		return err
+	}
+	task.Priority = priority
+	return tasklist.write(true)
+}
+
+// storedPriority returns the priority the task was given, or
+// defaultPriority.
+func storedPriority(task *Task) int {
+	if task.Priority == 0 {
+		return defaultPriority
+	}
+	return task.Priority
+}
+
+// effectivePriority returns the task's priority raised by aging. Tasks
+// without a creation time, waiting tasks and someday tasks don't age.
+func effectivePriority(task *Task, now time.Time) int {
+	priority := storedPriority(task)
+	if task.CreatedAt.IsZero() || task.Waiting || isSomeday(task) {
+		return priority
+	}
+	priority -= aging.boost(now.Sub(task.CreatedAt))
+	if priority < highestPriority {
+		priority = highestPriority
+	}
+	return priority
+}
+
+// hasTag reports whether the description contains the tag, as +tag or
+// #tag, ignoring case.
+func hasTag(task *Task, tag string) bool {
+	return hasWord(task, "+"+tag) || hasWord(task, "#"+tag)
+}
+
+// hasWord reports whether the description contains the word, ignoring
+// case.
+func hasWord(task *Task, word string) bool {
+	for _, field := range strings.Fields(task.Description) {
+		if strings.EqualFold(field, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatPriority renders the task's priority for listings: P2 for a
+// given priority, P2→P1 when aging raised it, and nothing for a task
+// at the default priority.
+func formatPriority(task *Task, now time.Time) string {
+	stored, effective := storedPriority(task), effectivePriority(task, now)
+	switch {
+	case stored != effective:
+		return fmt.Sprintf("P%d→P%d", stored, effective)
+	case task.Priority != 0:
+		return fmt.Sprintf("P%d", stored)
+	}
+	return ""
+}
+
+// nextTask returns the id of the task to work on next: the listed task
+// with the highest effective priority, then the earliest due date, then
+// the lowest id. It returns -1 if there are no tasks.
+func (t *TaskList) nextTask(now time.Time) int {
+	next := -1
+	for _, taskId := range t.Search("") {
+		if next == -1 || t.before(taskId, next, now) {
+			next = taskId
+		}
+	}
+	return next
+}
+
+// before reports whether task a should be worked on before task b.
+func (t *TaskList) before(a, b int, now time.Time) bool {
+	taskA, taskB := t.Tasks[a], t.Tasks[b]
+	priorityA, priorityB := effectivePriority(taskA, now), effectivePriority(taskB, now)
+	if priorityA != priorityB {
+		return priorityA < priorityB
+	}
+	if sortKeys["due"](taskA, taskB) || sortKeys["due"](taskB, taskA) {
+		return sortKeys["due"](taskA, taskB)
+	}
+	return a < b
+}
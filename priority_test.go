@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestAgingBoost(t *testing.T) {
+	a := priorityAging{period: 14 * 24 * time.Hour}
+	cases := map[int]int{0: 0, 13: 0, 14: 1, 27: 1, 28: 2, 42: 3, 400: maxAgingBoost}
+	for days, expected := range cases {
+		if boost := a.boost(time.Duration(days) * 24 * time.Hour); boost != expected {
+			t.Fatalf("boost after %d days: expected %d, got %d", days, expected, boost)
+		}
+	}
+	if boost := (priorityAging{}).boost(1000 * time.Hour); boost != 0 {
+		t.Fatalf("Expected disabled aging not to boost, got %d", boost)
+	}
+	if _, err := parseAging("soon"); err == nil {
+		t.Fatal("Expected an invalid aging period to be rejected")
+	}
+}
+
+func TestEffectivePriority(t *testing.T) {
+	defer func(saved priorityAging) { aging = saved }(aging)
+	aging, _ = parseAging("2w")
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.Local)
+	created := now.AddDate(0, 0, -30)
+	cases := []struct {
+		task     Task
+		expected string
+	}{
+		{Task{Description: "fresh"}, ""},
+		{Task{Description: "explicit", Priority: 2}, "P2"},
+		{Task{Description: "old", CreatedAt: created}, "P5→P3"},
+		{Task{Description: "old and urgent", Priority: 2, CreatedAt: created}, "P2→P1"},
+		{Task{Description: "old +Someday", CreatedAt: created}, ""},
+	}
+	for _, c := range cases {
+		if formatted := formatPriority(&c.task, now); formatted != c.expected {
+			t.Fatalf("%s: expected '%s', got '%s'", c.task.Description, c.expected, formatted)
+		}
+	}
+}
+
+func TestNextTask(t *testing.T) {
+	now := time.Now()
+	tasklist := TaskList{}
+	if next := tasklist.nextTask(now); next != -1 {
+		t.Fatalf("Expected no next task, got %d", next)
+	}
+	tasklist.Add("whenever")
+	soon, _ := tasklist.Add("due soon")
+	soon.DueAt = startOfDay(now).AddDate(0, 0, 3)
+	sooner, _ := tasklist.Add("due sooner")
+	sooner.DueAt = startOfDay(now).AddDate(0, 0, 1)
+	if next := tasklist.nextTask(now); next != 2 {
+		t.Fatalf("Expected the earliest due task to be next, got %d", next)
+	}
+	important, _ := tasklist.Add("important")
+	important.Priority = 1
+	if next := tasklist.nextTask(now); next != 3 {
+		t.Fatalf("Expected the most important task to be next, got %d", next)
+	}
+}
+
+func TestParsePriority(t *testing.T) {
+	for in, valid := range map[string]bool{"1": true, "9": true, "0": false, "10": false, "high": false} {
+		if _, err := parsePriority(in); (err == nil) != valid {
+			t.Fatalf("parsePriority(%q): expected valid to be %v, got %v", in, valid, err)
+		}
+	}
+}
+
+func TestCliPriority(t *testing.T) {
+	withCliSetup(t, func() {
+		exec.Command(tBinary, "foo").Run()
+		exec.Command(tBinary, "baz").Run()
+		if err := exec.Command(tBinary, "-p", "1", "bar").Run(); err != nil {
+			t.Fatal(err)
+		}
+		if err := exec.Command(tBinary, "-P", "3", "0").Run(); err != nil {
+			t.Fatal(err)
+		}
+		out, _ := exec.Command(tBinary).Output()
+		expected := "2 - bar (P1)\n0 - foo (P3)\n1 - baz\n"
+		if string(out) != expected {
+			t.Fatalf("Expected output to be '%s', got '%s'", expected, out)
+		}
+		if err := exec.Command(tBinary, "-f", "2").Run(); err != nil {
+			t.Fatal(err)
+		}
+		out, _ = exec.Command(tBinary).Output()
+		if string(out) != "0 - foo (P3)\n1 - baz\n" {
+			t.Fatalf("Expected -f to finish the task listed as 2, got '%s'", out)
+		}
+		err := exec.Command(tBinary, "-P", "10", "0").Run()
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 2 {
+			t.Fatalf("Expected priority 10 to be a usage error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// stdin is shared by all prompts so that buffered input isn't lost
+// between two questions.
+var stdin = bufio.NewReader(os.Stdin)
+
+// errChangedMeanwhile refuses to go on with a command confirmed after
+// another t changed the list it was asked about.
+var errChangedMeanwhile = errors.New("the tasks file changed while t was asking, nothing was changed; run t again")
+
+// confirm asks a yes/no question and reports whether it was answered
+// with yes. Anything else, including end of input, counts as no. The
+// question is about the list as it was read, so if another t changes it
+// while t waits for the answer, t exits with errChangedMeanwhile.
+func confirm(question string) bool {
+	if strictMode {
+		refusePrompt(question)
+	}
+	fmt.Printf("%s [y/N] ", question)
+	answer, changed := readAnswer()
+	if changed {
+		fmt.Fprintln(os.Stderr, errChangedMeanwhile)
+		os.Exit(1)
+	}
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes"
+}
+
+// ask prints a question and returns the trimmed line answering it. The
+// list may have been read anew meanwhile, so tasks found before have to
+// be looked up again.
+func ask(question string) string {
+	if strictMode {
+		refusePrompt(question)
+	}
+	fmt.Printf("%s ", question)
+	answer, _ := readAnswer()
+	return answer
+}
+
+// readAnswer reads the trimmed line answering a question, without
+// holding the lock on the tasks file meanwhile, and reports whether the
+// list was read anew because another t changed it.
+func readAnswer() (string, bool) {
+	var answer string
+	changed, err := whileUnlocked(func() {
+		answer, _ = stdin.ReadString('\n')
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	return strings.TrimSpace(answer), changed
+}
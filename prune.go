@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+// touchedIds returns the stable ids of the tasks the audit log has
+// recorded anything for after their creation.
+func touchedIds(events []auditEvent) map[string]bool {
+	touched := make(map[string]bool)
+	for _, event := range events {
+		if !strings.HasPrefix(event.Event, "created:") {
+			touched[event.Id] = true
+		}
+	}
+	return touched
+}
+
+// untouched reports whether a task has sat on the list as it was added:
+// never edited, deferred, muted, put on waiting, prioritized or worked
+// on, without attachments or links, and with nothing in the audit log
+// since its creation.
+func untouched(task *Task, touched map[string]bool) bool {
+	return task.Id == core.Hash(task.Description) && task.SnoozedUntil.IsZero() &&
+		task.MutedUntil.IsZero() && !task.Waiting && task.Priority == 0 && task.Pomodoros == 0 &&
+		len(task.Attachments) == 0 && len(task.Links) == 0 && !touched[task.Id]
+}
+
+// pruneIds returns the ids of the tasks that were added more than age
+// ago and never touched since. Tasks without a creation time are left
+// alone.
+func (t *TaskList) pruneIds(age time.Duration, now time.Time, touched map[string]bool) []int {
+	cutoff := now.Add(-age)
+	ids := make([]int, 0)
+	for taskId, task := range t.Tasks {
+		if !task.CreatedAt.IsZero() && task.CreatedAt.Before(cutoff) && untouched(task, touched) {
+			ids = append(ids, taskId)
+		}
+	}
+	return ids
+}
+
+// pruneTasks handles --prune: it steps through the tasks pruneIds finds
+// and asks whether to keep, finish, defer or delete each, or with yes
+// applies action, finish or delete, to all of them. Nothing is written
+// until the end, and then all at once. The decisions are kept by stable
+// id, as another t may change the list while t waits for an answer.
+func pruneTasks(age time.Duration, yes bool, action string, opts formatOptions) error {
+	if yes && action != "finish" && action != "delete" {
+		return errors.New("Usage: t --prune <age> -y finish|delete")
+	}
+	if !yes && !stdinIsTerminal() {
+		return errors.New("--prune asks about each task, use -y finish|delete to prune without asking")
+	}
+	ids := tasklist.pruneIds(age, opts.now, touchedIds(loadAudit(taskFilePath)))
+	if len(ids) == 0 {
+		fmt.Println("nothing to prune")
+		return nil
+	}
+	stableIds := make([]string, len(ids))
+	for i, taskId := range ids {
+		stableIds[i] = tasklist.Tasks[taskId].Id
+	}
+	decisions := make(map[string]string)
+	deferrals := make(map[string]time.Time)
+	for _, id := range stableIds {
+		if yes {
+			decisions[id] = action
+			continue
+		}
+		taskId := tasklist.IndexOf(id)
+		if taskId == -1 {
+			continue
+		}
+		fmt.Println(formatTask(taskId, tasklist.Tasks[taskId], opts))
+		answer := ""
+		for answer == "" {
+			switch ask("[k]eep, [f]inish, [d]efer, [x] delete, [q]uit?") {
+			case "k":
+				answer = "keep"
+			case "f":
+				answer = "finish"
+			case "x":
+				answer = "delete"
+			case "d":
+				until, err := parseDue(ask("until (YYYY-MM-DD, tomorrow or +Nd):"), opts.now)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					continue
+				}
+				deferrals[id] = until
+				answer = "defer"
+			case "q", "":
+				answer = "quit"
+			}
+		}
+		if answer == "quit" {
+			break
+		}
+		decisions[id] = answer
+	}
+	return applyPrune(decisions, deferrals)
+}
+
+// applyPrune finishes, deletes and defers the tasks decided on, by
+// stable id, writes the list once and prints a summary. Tasks another t
+// finished or deleted meanwhile are left out.
+func applyPrune(decisions map[string]string, deferrals map[string]time.Time) error {
+	limit, err := doneLimit()
+	if err != nil {
+		return err
+	}
+	ids := make([]int, 0, len(decisions))
+	counts := make(map[string]int)
+	for id, decision := range decisions {
+		taskId := tasklist.IndexOf(id)
+		if taskId == -1 {
+			continue
+		}
+		if until, ok := deferrals[id]; ok {
+			tasklist.Tasks[taskId].SnoozedUntil = until
+		}
+		ids = append(ids, taskId)
+		counts[decision]++
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(ids)))
+	finished := make([]*Task, 0)
+	for _, taskId := range ids {
+		switch decisions[tasklist.Tasks[taskId].Id] {
+		case "finish":
+			task, _ := tasklist.Finish(taskId)
+			finished = append(finished, task)
+		case "delete":
+			tasklist.Delete(taskId)
+		}
+	}
+	if counts["finish"]+counts["delete"]+counts["defer"] > 0 {
+		// The tasks were finished from the last up; the done file gets
+		// them in list order.
+		for i, j := 0, len(finished)-1; i < j; i, j = i+1, j-1 {
+			finished[i], finished[j] = finished[j], finished[i]
+		}
+		if err := tasklist.writeDone(finished, time.Now(), limit); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("finished %d, deleted %d, deferred %d, kept %d\n",
+		counts["finish"], counts["delete"], counts["defer"], counts["keep"])
+	return nil
+}
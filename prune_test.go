@@ -0,0 +1,53 @@
+package main
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPruneIds(t *testing.T) {
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.Local)
+	old := now.AddDate(0, 0, -100)
+	tasklist := TaskList{}
+	tasklist.UnmarshalText([]byte("forgotten\nedited | id:abc\nprioritized | priority:2\nrecent\nlegacy\nlogged"))
+	for _, task := range tasklist.Tasks {
+		task.CreatedAt = old
+	}
+	tasklist.Tasks[3].CreatedAt = now.AddDate(0, 0, -10)
+	tasklist.Tasks[4].CreatedAt = time.Time{}
+	touched := touchedIds([]auditEvent{
+		{Id: tasklist.Tasks[0].Id, Event: "created: forgotten"},
+		{Id: tasklist.Tasks[5].Id, Event: "due: none → 2024-05-01"},
+	})
+	ids := tasklist.pruneIds(90*24*time.Hour, now, touched)
+	if !reflect.DeepEqual(ids, []int{0}) {
+		t.Fatalf("Expected only task 0 to be pruned, got %v", ids)
+	}
+}
+
+func TestCliPruneYes(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("forgotten | created:2020-01-01T00:00:00Z\nfresh"), 0644)
+		out, err := exec.Command(tBinary, "--prune", "90d", "--yes", "finish").Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != "finished 1, deleted 0, deferred 0, kept 0\n" {
+			t.Fatalf("Expected a summary, got '%s'", out)
+		}
+		text, _ := ioutil.ReadFile("/tmp/tasks")
+		if string(text) != "fresh" {
+			t.Fatalf("Expected only the fresh task to be left, got '%s'", text)
+		}
+		if err := exec.Command(tBinary, "--undo").Run(); err != nil {
+			t.Fatal(err)
+		}
+		text, _ = ioutil.ReadFile("/tmp/tasks")
+		if string(text) != "forgotten | created:2020-01-01T00:00:00Z\nfresh" {
+			t.Fatalf("Expected --undo to bring the task back, got '%s'", text)
+		}
+	})
+}
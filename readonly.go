@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+// readOnlyLists are the named lists set read-only in the config, with
+// readonly.<list> = true.
+var readOnlyLists = make(map[string]bool)
+
+// ignoreReadOnly lets t change read-only lists anyway, as set by
+// --force.
+var ignoreReadOnly = false
+
+// readOnlySettings reads the readonly.<list> settings of the config.
+func readOnlySettings(config Config) (map[string]bool, error) {
+	lists := make(map[string]bool)
+	for name, value := range config.withPrefix("readonly.") {
+		switch value {
+		case "true":
+			lists[name] = true
+		case "false":
+		default:
+			return nil, fmt.Errorf("invalid readonly.%s = %s, expected true or false", name, value)
+		}
+	}
+	return lists, nil
+}
+
+// hasReadOnlyMarker reports whether the tasks file at path has a
+// #readonly line among the # lines it starts with.
+func hasReadOnlyMarker(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == core.ReadOnlyDirective {
+			return true
+		}
+		if !strings.HasPrefix(line, "#") {
+			return false
+		}
+	}
+	return false
+}
+
+// readOnlyError is the error for trying to change a read-only list.
+func readOnlyError(name string) error {
+	return fmt.Errorf("%s is read-only, use --force to change it anyway", name)
+}
+
+// checkReadOnly fails if the tasks file at path, the list named name or
+// "" for the default one, is read-only and --force wasn't given. It
+// only reads, so it can run before anything is written.
+func checkReadOnly(path string, name string) error {
+	if ignoreReadOnly {
+		return nil
+	}
+	if (name != "" && readOnlyLists[name]) || hasReadOnlyMarker(path) {
+		if name == "" {
+			name = path
+		}
+		return readOnlyError(name)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestHasReadOnlyMarker(t *testing.T) {
+	dir := t.TempDir()
+	cases := map[string]bool{
+		"#readonly\nfoo":                  true,
+		"#include shared\n#readonly\nfoo": true,
+		"foo\n#readonly":                  false,
+		"foo":                             false,
+	}
+	for text, expected := range cases {
+		path := filepath.Join(dir, "tasks")
+		ioutil.WriteFile(path, []byte(text), 0644)
+		if hasReadOnlyMarker(path) != expected {
+			t.Fatalf("hasReadOnlyMarker(%q): expected %v", text, expected)
+		}
+	}
+}
+
+func TestReadOnlyRoundTrip(t *testing.T) {
+	tasklist := TaskList{}
+	tasklist.UnmarshalText([]byte("#readonly\nfoo"))
+	if !tasklist.ReadOnly || len(tasklist.Tasks) != 1 {
+		t.Fatalf("Expected a read-only list of one task, got %v", tasklist.Tasks)
+	}
+	if text, _ := tasklist.MarshalText(); string(text) != "#readonly\nfoo" {
+		t.Fatalf("Expected the marker to be kept, got '%s'", text)
+	}
+}
+
+func TestCliReadOnly(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("#readonly\nfoo"), 0644)
+		if err := exec.Command(tBinary, "-f", "0").Run(); err == nil {
+			t.Fatal("Expected finishing a task of a read-only list to fail")
+		}
+		if err := exec.Command(tBinary, "bar").Run(); err == nil {
+			t.Fatal("Expected adding to a read-only list to fail")
+		}
+		out, err := exec.Command(tBinary).Output()
+		if err != nil || string(out) != "0 - foo\n" {
+			t.Fatalf("Expected listing to work, got '%s' (%v)", out, err)
+		}
+		if err := exec.Command(tBinary, "--force", "-f", "0").Run(); err != nil {
+			t.Fatal(err)
+		}
+		text, _ := ioutil.ReadFile("/tmp/tasks")
+		if string(text) != "#readonly" {
+			t.Fatalf("Expected --force to finish the task and keep the marker, got '%s'", text)
+		}
+	})
+}
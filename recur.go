@@ -0,0 +1,17 @@
+package main
+
+import (
+	"fmt"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+// everyUnit is the unit recurring tasks come back in; due dates have no
+// time of day.
+const everyUnit = core.EveryUnit
+
+// formatNextDue renders where finishing a recurring task left it, like
+// "water plants: next due 2024-06-04".
+func formatNextDue(task *Task) string {
+	return fmt.Sprintf("%s: next due %s", lineBreaks.Replace(task.Description), displayDate(task.DueAt))
+}
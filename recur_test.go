@@ -0,0 +1,62 @@
+package main
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFinishRecurring(t *testing.T) {
+	tasklist := &TaskList{}
+	tasklist.Add("pay rent")
+	task, _ := tasklist.Add("water plants")
+	task.RecurEvery = 3 * everyUnit
+	task.DueAt = startOfDay(time.Now())
+	finished, err := tasklist.Finish(1)
+	if err != nil || finished.Description != "water plants" || finished == task {
+		t.Fatalf("Expected a copy of the task for the done file, got %v (%v)", finished, err)
+	}
+	if len(tasklist.Tasks) != 2 || daysBetween(time.Now(), task.DueAt) != 3 {
+		t.Fatalf("Expected the task to stay, due in 3 days, got %d tasks due %s", len(tasklist.Tasks), task.DueAt)
+	}
+	if ids := tasklist.Search(""); len(ids) != 1 || ids[0] != 0 {
+		t.Fatalf("Expected the task hidden until due, got %v", ids)
+	}
+	tasklist.showHidden = true
+	if ids := tasklist.Search(""); len(ids) != 2 {
+		t.Fatalf("Expected showHidden to list it, got %v", ids)
+	}
+}
+
+func TestCliEvery(t *testing.T) {
+	withCliSetup(t, func() {
+		if err := exec.Command(tBinary, "--every", "3d", "water", "plants").Run(); err != nil {
+			t.Fatal(err)
+		}
+		text, _ := ioutil.ReadFile("/tmp/tasks")
+		today := time.Now().Format(dateLayout)
+		if !strings.Contains(string(text), "water plants | due:"+today+" every:3d") {
+			t.Fatalf("Expected the interval stored with a due date of today, got '%s'", text)
+		}
+		out, err := exec.Command(tBinary, "-q", "-f", "0").Output()
+		next := time.Now().AddDate(0, 0, 3).Format(dateLayout)
+		if err != nil || string(out) != "water plants: next due "+next+"\n" {
+			t.Fatalf("Expected the next due date, got '%s' (%v)", out, err)
+		}
+		if out, _ := exec.Command(tBinary).Output(); string(out) != "" {
+			t.Fatalf("Expected the task hidden until due, got '%s'", out)
+		}
+		if out, _ := exec.Command(tBinary, "--all", "--plain").Output(); !strings.Contains(string(out), "water plants") {
+			t.Fatalf("Expected --all to show the task, got '%s'", out)
+		}
+		done, _ := ioutil.ReadFile("/tmp/tasks.done")
+		if !strings.Contains(string(done), "water plants") {
+			t.Fatalf("Expected the task in the done file, got '%s'", done)
+		}
+		if err := exec.Command(tBinary, "--every", "12h", "feed cat").Run(); err == nil {
+			t.Fatal("Expected an interval under a day to be refused")
+		}
+	})
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+// reminderKey is the metadata key keeping the Reminders identifier of
+// an imported task, which re-imports skip.
+const reminderKey = "reminder"
+
+// reminder is an item of a Reminders export.
+type reminder struct {
+	identifier  string
+	title       string
+	notes       string
+	due         time.Time
+	completed   bool
+	completedAt time.Time
+}
+
+// reminderFields are the names exports use for each field, as Shortcuts
+// and the usual export scripts write them.
+var reminderFields = map[string][]string{
+	"identifier":  {"identifier", "calendarItemIdentifier", "id"},
+	"title":       {"title", "name"},
+	"notes":       {"notes", "body"},
+	"due":         {"dueDate", "due"},
+	"completed":   {"isCompleted", "completed"},
+	"completedAt": {"completionDate", "completedDate"},
+}
+
+// field returns the value of the first of the field's names the record
+// has.
+func field(record map[string]interface{}, name string) interface{} {
+	for _, key := range reminderFields[name] {
+		if value, ok := record[key]; ok {
+			return value
+		}
+	}
+	return nil
+}
+
+// parseReminderDate parses a date of an export: RFC 3339 as in JSON and
+// plist exports, or a plain YYYY-MM-DD.
+func parseReminderDate(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case nil:
+		return time.Time{}, nil
+	case time.Time:
+		return v, nil
+	case string:
+		if v == "" {
+			return time.Time{}, nil
+		}
+		if date, err := time.Parse(time.RFC3339, v); err == nil {
+			return date, nil
+		}
+		if date, err := time.ParseInLocation(dateLayout, v, time.Local); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %v", value)
+}
+
+// toReminder reads a record of an export into a reminder.
+func toReminder(record map[string]interface{}) (reminder, error) {
+	r := reminder{}
+	r.identifier, _ = field(record, "identifier").(string)
+	r.title, _ = field(record, "title").(string)
+	r.notes, _ = field(record, "notes").(string)
+	r.title = strings.TrimSpace(r.title)
+	if r.title == "" {
+		return r, errors.New("no title")
+	}
+	switch completed := field(record, "completed").(type) {
+	case bool:
+		r.completed = completed
+	case string:
+		r.completed, _ = strconv.ParseBool(completed)
+	}
+	var err error
+	if r.due, err = parseReminderDate(field(record, "due")); err != nil {
+		return r, err
+	}
+	if r.completedAt, err = parseReminderDate(field(record, "completedAt")); err != nil {
+		return r, err
+	}
+	return r, nil
+}
+
+// parseReminders reads the records of an export, a JSON array of
+// objects, maybe under a "reminders" key, or an XML property list of an
+// array of dicts.
+func parseReminders(data []byte) ([]map[string]interface{}, error) {
+	var value interface{}
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("bplist")):
+		return nil, errors.New("binary property lists aren't supported, convert it with plutil -convert xml1")
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		v, err := decodePlist(xml.NewDecoder(bytes.NewReader(trimmed)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid property list: %v", err)
+		}
+		value = v
+	default:
+		if err := json.Unmarshal(trimmed, &value); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %v", err)
+		}
+	}
+	if wrapper, ok := value.(map[string]interface{}); ok {
+		value = wrapper["reminders"]
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, errors.New("expected a list of reminders")
+	}
+	records := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		record, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("expected each reminder to be a dictionary")
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// errPlistEnd is what decodePlist returns at the end of a dict or an
+// array instead of a value.
+var errPlistEnd = errors.New("unexpected end of element")
+
+// decodePlist decodes the next value of an XML property list.
+func decodePlist(d *xml.Decoder) (interface{}, error) {
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.EndElement:
+			return nil, errPlistEnd
+		case xml.StartElement:
+			return decodePlistElement(d, t)
+		}
+	}
+}
+
+// decodePlistElement decodes the value of a property list element that
+// starts with start: a dict, an array, a string, a date, a number or a
+// boolean, or the plist element around them.
+func decodePlistElement(d *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	switch start.Name.Local {
+	case "plist":
+		return decodePlist(d)
+	case "dict":
+		dict := make(map[string]interface{})
+		for {
+			key, err := decodePlist(d)
+			if err == errPlistEnd {
+				return dict, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, errors.New("expected a key in dict")
+			}
+			if dict[name], err = decodePlist(d); err != nil {
+				return nil, err
+			}
+		}
+	case "array":
+		array := make([]interface{}, 0)
+		for {
+			value, err := decodePlist(d)
+			if err == errPlistEnd {
+				return array, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+	case "true", "false":
+		return start.Name.Local == "true", d.Skip()
+	}
+	var text string
+	if err := d.DecodeElement(&text, &start); err != nil {
+		return nil, err
+	}
+	switch start.Name.Local {
+	case "date":
+		return time.Parse(time.RFC3339, text)
+	case "integer", "real":
+		return strconv.ParseFloat(text, 64)
+	}
+	return text, nil
+}
+
+// importedReminders returns the identifiers of the reminders imported
+// into the list or its done history before.
+func importedReminders() (map[string]bool, error) {
+	done, err := readDoneHistory(donePath(taskFilePath))
+	if err != nil {
+		return nil, err
+	}
+	imported := make(map[string]bool)
+	for _, task := range append(append([]*Task(nil), tasklist.Tasks...), done.Tasks...) {
+		if id := task.Meta[reminderKey]; id != "" {
+			imported[id] = true
+		}
+	}
+	return imported, nil
+}
+
+// importReminders handles --import-reminders: it adds the reminders of
+// an export to the list with their notes and due dates, and the
+// completed ones to the done file. Reminders imported before, going by
+// their identifier, are skipped, and so are those without a title, with
+// a warning.
+func importReminders(name string, out io.Writer) error {
+	data, err := ioutil.ReadFile(core.ExpandHome(name))
+	if err != nil {
+		return err
+	}
+	records, err := parseReminders(data)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	limit, err := doneLimit()
+	if err != nil {
+		return err
+	}
+	imported, err := importedReminders()
+	if err != nil {
+		return err
+	}
+	added, skipped := 0, 0
+	completed := make([]*Task, 0)
+	for i, record := range records {
+		r, err := toReminder(record)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: skipping reminder %d: %v\n", i+1, err)
+			continue
+		}
+		if r.identifier != "" && imported[r.identifier] {
+			skipped++
+			continue
+		}
+		task, err := tasklist.Add(r.title)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: skipping reminder %d: %v\n", i+1, err)
+			continue
+		}
+		task.Meta = make(map[string]string)
+		if r.identifier != "" {
+			task.Meta[reminderKey] = r.identifier
+			imported[r.identifier] = true
+		}
+		if notes := strings.TrimSpace(r.notes); notes != "" {
+			task.Meta["notes"] = notes
+		}
+		if !r.due.IsZero() {
+			task.DueAt = startOfDay(r.due.In(time.Local))
+		}
+		added++
+		if r.completed {
+			// Completed reminders go to the done file only.
+			tasklist.Tasks = tasklist.Tasks[:len(tasklist.Tasks)-1]
+			task.DoneAt = r.completedAt
+			if task.DoneAt.IsZero() {
+				task.DoneAt = time.Now()
+			}
+			completed = append(completed, task)
+		}
+	}
+	tx := newTransaction(journalPath(taskFilePath))
+	for _, task := range completed {
+		if err := stageDone(tx, donePath(taskFilePath), []*Task{task}, task.DoneAt, limit); err != nil {
+			tx.abort()
+			return err
+		}
+	}
+	if added > len(completed) {
+		if err := tasklist.commit(tx, true); err != nil {
+			return err
+		}
+	} else if err := tx.commit(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "imported %d reminders, %d of them completed", added, len(completed))
+	if skipped > 0 {
+		fmt.Fprintf(out, ", skipped %d imported before", skipped)
+	}
+	fmt.Fprintln(out)
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRemindersShapes(t *testing.T) {
+	for _, name := range []string{"reminders.json", "reminders.plist"} {
+		data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		records, err := parseReminders(data)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(records) != 4 {
+			t.Fatalf("%s: expected 4 reminders, got %d", name, len(records))
+		}
+		r, err := toReminder(records[0])
+		due := time.Date(2024, 6, 14, 7, 0, 0, 0, time.UTC)
+		if err != nil || r.identifier != "5B1B4F2E-8C7A-4F0E-9B3D-2A6C1E7D9F01" || r.title != "Renew passport" ||
+			r.notes != "Photos are in the top drawer" || !r.due.Equal(due) || r.completed {
+			t.Fatalf("%s: expected the passport reminder, got %+v (%v)", name, r, err)
+		}
+		r, _ = toReminder(records[1])
+		if !r.completed || !r.completedAt.Equal(time.Date(2024, 5, 28, 16, 45, 0, 0, time.UTC)) {
+			t.Fatalf("%s: expected the dentist reminder completed, got %+v", name, r)
+		}
+		if _, err := toReminder(records[2]); err == nil {
+			t.Fatalf("%s: expected the reminder without a title to be refused", name)
+		}
+	}
+	for _, data := range []string{`{"reminders": [{"title": "x"}]}`, `[{"name": "x"}]`} {
+		if records, err := parseReminders([]byte(data)); err != nil || len(records) != 1 {
+			t.Fatalf("Expected %s to hold one reminder, got %v (%v)", data, records, err)
+		}
+	}
+	for _, data := range []string{"bplist00", "{}", "<plist><dict><key>a</key></dict></plist>", "[1]"} {
+		if _, err := parseReminders([]byte(data)); err == nil {
+			t.Fatalf("Expected %q to be refused", data)
+		}
+	}
+}
+
+func TestCliImportReminders(t *testing.T) {
+	for _, name := range []string{"reminders.json", "reminders.plist"} {
+		withCliSetup(t, func() {
+			path, _ := filepath.Abs(filepath.Join("testdata", name))
+			cmd := exec.Command(tBinary, "--import-reminders", path)
+			out, err := cmd.CombinedOutput()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(out), "warning: skipping reminder 3: no title") ||
+				!strings.HasSuffix(string(out), "imported 3 reminders, 1 of them completed\n") {
+				t.Fatalf("%s: expected a warning and a summary, got '%s'", name, out)
+			}
+			out, _ = exec.Command(tBinary, "--plain").Output()
+			if string(out) != "0 - Renew passport (due 2024-06-14)\n1 - Water the plants +home\n" {
+				t.Fatalf("%s: expected the open reminders, got '%s'", name, out)
+			}
+			done, _ := ioutil.ReadFile("/tmp/tasks.done")
+			if !strings.HasPrefix(string(done), "Book dentist | ") || !strings.Contains(string(done), "done:2024-05-28T16:45:00Z") {
+				t.Fatalf("%s: expected the completed reminder in the done file, got '%s'", name, done)
+			}
+			out, _ = exec.Command(tBinary, "--import-reminders", path).Output()
+			if string(out) != "imported 0 reminders, 0 of them completed, skipped 3 imported before\n" {
+				t.Fatalf("%s: expected a re-import to skip everything, got '%s'", name, out)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// replHelp lists the commands -i takes.
+const replHelp = "commands: a <text>, f <id>, e <id> <text>, l, q"
+
+// printTasks lists the tasks the way -i shows them after every change.
+func (t *TaskList) printTasks(out io.Writer) {
+	opts := formatOptions{now: time.Now()}
+	for _, taskId := range t.Search("") {
+		fmt.Fprintln(out, formatTask(taskId, t.Tasks[taskId], opts))
+	}
+}
+
+// replCommand runs one line of -i. It returns the finished task, if
+// any, whether the list changed, and whether to quit.
+func (t *TaskList) replCommand(line string) (*Task, bool, bool, error) {
+	command, rest := line, ""
+	if i := strings.IndexAny(line, " \t"); i != -1 {
+		command, rest = line[:i], strings.TrimSpace(line[i+1:])
+	}
+	switch command {
+	case "a":
+		if rest == "" {
+			return nil, false, false, fmt.Errorf("Usage: a <text>")
+		}
+		_, err := t.Add(rest)
+		return nil, err == nil, false, err
+	case "f":
+		taskId, err := t.resolveId(rest)
+		if err != nil {
+			return nil, false, false, err
+		}
+		finished, err := t.Finish(taskId)
+		return finished, err == nil, false, err
+	case "e":
+		fields := strings.SplitN(rest, " ", 2)
+		if len(fields) != 2 || strings.TrimSpace(fields[1]) == "" {
+			return nil, false, false, fmt.Errorf("Usage: e <id> <text>")
+		}
+		taskId, err := t.resolveId(fields[0])
+		if err != nil {
+			return nil, false, false, err
+		}
+		err = t.Edit(taskId, strings.TrimSpace(fields[1]))
+		return nil, err == nil, false, err
+	case "l":
+		return nil, false, false, nil
+	case "q":
+		return nil, false, true, nil
+	}
+	return nil, false, false, fmt.Errorf("unknown command %q, %s", command, replHelp)
+}
+
+// repl handles -i: it runs the commands read from in one line at a time,
+// printing prompt before each, until q or the end of the input. The
+// list is printed after every change and on l, and save is called after
+// every change with the finished task, if any, so that a crash loses
+// nothing. A command that fails is reported and the next one read.
+func (t *TaskList) repl(in io.Reader, out io.Writer, prompt string, save func(finished *Task) error) error {
+	lines := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(out, prompt)
+		if !lines.Scan() {
+			if prompt != "" {
+				fmt.Fprintln(out)
+			}
+			return lines.Err()
+		}
+		line := strings.TrimSpace(lines.Text())
+		if line == "" {
+			continue
+		}
+		finished, changed, quit, err := t.replCommand(line)
+		if err != nil {
+			fmt.Fprintln(out, err)
+			continue
+		}
+		if quit {
+			return nil
+		}
+		if changed {
+			if err := save(finished); err != nil {
+				return err
+			}
+		}
+		t.printTasks(out)
+	}
+}
+
+// unlockedReader reads from r without holding the lock on the tasks
+// file, like a prompt, so that -i waiting for its next command doesn't
+// keep every other t out. The commands it then reads run on the list as
+// another t may have left it meanwhile.
+type unlockedReader struct {
+	r io.Reader
+}
+
+func (u unlockedReader) Read(p []byte) (n int, err error) {
+	if _, lockErr := whileUnlocked(func() { n, err = u.r.Read(p) }); lockErr != nil {
+		return 0, lockErr
+	}
+	return n, err
+}
+
+// interactiveSession handles -i: it runs repl on stdin, writing the list
+// after every change, with a prompt if stdin is a terminal.
+func interactiveSession() error {
+	limit, err := doneLimit()
+	if err != nil {
+		return err
+	}
+	prompt := ""
+	if stdinIsTerminal() {
+		prompt = "t> "
+	}
+	return tasklist.repl(unlockedReader{stdin}, os.Stdout, prompt, func(finished *Task) error {
+		if finished == nil {
+			return tasklist.write(true)
+		}
+		return tasklist.writeDone([]*Task{finished}, time.Now(), limit)
+	})
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestRepl(t *testing.T) {
+	list := &TaskList{}
+	list.Add("pay rent")
+	in := strings.NewReader("a call bob\n\n  \nf 0\ne 0 call alice\nf 9\nzap\nl\nq\na never read\n")
+	var out bytes.Buffer
+	saves := make([]string, 0)
+	err := list.repl(in, &out, "", func(finished *Task) error {
+		if finished != nil {
+			saves = append(saves, "done "+finished.Description)
+		} else {
+			saves = append(saves, "write")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "0 - pay rent\n1 - call bob\n" +
+		"0 - call bob\n" +
+		"0 - call alice\n" +
+		"no task for id 9\n" +
+		"unknown command \"zap\", " + replHelp + "\n" +
+		"0 - call alice\n"
+	if out.String() != expected {
+		t.Fatalf("Expected\n%s\ngot\n%s", expected, out.String())
+	}
+	if strings.Join(saves, ", ") != "write, done pay rent, write" {
+		t.Fatalf("Expected a save after every change, got %v", saves)
+	}
+}
+
+func TestReplEndOfInput(t *testing.T) {
+	list := &TaskList{}
+	var out bytes.Buffer
+	if err := list.repl(strings.NewReader("a pay rent"), &out, "t> ", func(*Task) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "t> 0 - pay rent\nt> \n" {
+		t.Fatalf("Expected the session to end quietly, got %q", out.String())
+	}
+}
+
+func TestCliInteractive(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("pay rent"), 0644)
+		cmd := exec.Command(tBinary, "-i")
+		cmd.Stdin = strings.NewReader("a call bob\nf 0\ne 0 call alice\n")
+		out, err := cmd.Output()
+		expected := "0 - pay rent\n1 - call bob\n0 - call bob\n0 - call alice\n"
+		if err != nil || string(out) != expected {
+			t.Fatalf("Expected '%s', got '%s' (%v)", expected, out, err)
+		}
+		list, _ := readTaskList("/tmp/tasks")
+		if len(list.Tasks) != 1 || list.Tasks[0].Description != "call alice" {
+			t.Fatalf("Expected the edited task written, got %v", list.Tasks)
+		}
+		done, _ := readTaskList("/tmp/tasks.done")
+		if len(done.Tasks) != 1 || done.Tasks[0].Description != "pay rent" {
+			t.Fatalf("Expected the finished task in the done file, got %v", done.Tasks)
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	_ "embed"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+)
+
+//go:embed report.tmpl
+var reportTemplate string
+
+var reportPage = template.Must(template.New("report").Parse(reportTemplate))
+
+// reportRow is an open task in the --html report.
+type reportRow struct {
+	Id          int
+	Description string
+	Priority    string
+	Due         string
+	Tags        string
+	Age         string
+	Overdue     bool
+}
+
+// reportDone is a task of the --html report finished this week.
+type reportDone struct {
+	Date        string
+	Description string
+}
+
+// report is what the --html report shows.
+type report struct {
+	Date string
+	Open []reportRow
+	Done []reportDone
+}
+
+// startOfWeek returns the start of the Monday of the week of t.
+func startOfWeek(t time.Time) time.Time {
+	return startOfDay(t).AddDate(0, 0, -(int(t.Weekday())+6)%7)
+}
+
+// buildReport gathers the listed tasks of the list and the tasks of the
+// done list finished since the start of the week.
+func buildReport(t *TaskList, done *TaskList, now time.Time) report {
+	r := report{Date: now.Format(dateLayout)}
+	for _, taskId := range t.Search("") {
+		task := t.Tasks[taskId]
+		row := reportRow{Id: displayId(taskId), Description: task.Description, Priority: formatPriority(task, now)}
+		if !task.DueAt.IsZero() {
+			row.Due = task.DueAt.Format(dateLayout)
+			row.Overdue = daysBetween(now, task.DueAt) < 0
+		}
+		if !task.CreatedAt.IsZero() {
+			row.Age = formatAge(now.Sub(task.CreatedAt))
+		}
+		tags := make([]string, 0)
+		for _, word := range strings.Fields(task.Description) {
+			if isTag(word) {
+				tags = append(tags, word)
+			}
+		}
+		row.Tags = strings.Join(tags, " ")
+		r.Open = append(r.Open, row)
+	}
+	finished, _ := done.doneSince(startOfWeek(now))
+	for _, task := range finished {
+		r.Done = append(r.Done, reportDone{Date: task.DoneAt.Local().Format(dateLayout), Description: task.Description})
+	}
+	return r
+}
+
+// writeReport renders a report as a standalone HTML page.
+func writeReport(w io.Writer, r report) error {
+	return reportPage.Execute(w, r)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+func TestStartOfWeek(t *testing.T) {
+	for day := 24; day <= 30; day++ {
+		start := startOfWeek(time.Date(2024, 6, day, 15, 0, 0, 0, time.Local))
+		if !start.Equal(time.Date(2024, 6, 24, 0, 0, 0, 0, time.Local)) {
+			t.Fatalf("Expected the week of June %d to start on Monday June 24, got %v", day, start)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	now := time.Date(2024, 6, 27, 12, 0, 0, 0, time.Local)
+	tasklist := &TaskList{TaskList: core.TaskList{Tasks: []*Task{
+		{Description: "<script>alert(1)</script> & more +web", CreatedAt: now.AddDate(0, 0, -3)},
+		{Description: "pay rent", DueAt: time.Date(2024, 6, 25, 0, 0, 0, 0, time.Local), Priority: 2},
+	}}}
+	done := &TaskList{TaskList: core.TaskList{Tasks: []*Task{
+		{Description: "last week", DoneAt: now.AddDate(0, 0, -7)},
+		{Description: "this week", DoneAt: now.AddDate(0, 0, -1)},
+	}}}
+	var page bytes.Buffer
+	if err := writeReport(&page, buildReport(tasklist, done, now)); err != nil {
+		t.Fatal(err)
+	}
+	html := page.String()
+	for _, expected := range []string{
+		"&lt;script&gt;alert(1)&lt;/script&gt; &amp; more &#43;web",
+		`<td>&#43;web</td><td class="age">3d</td>`,
+		`<tr class="overdue"><td class="id">1</td><td>pay rent</td><td>P2</td><td class="due">2024-06-25</td>`,
+		"this week",
+	} {
+		if !strings.Contains(html, expected) {
+			t.Fatalf("Expected the report to contain %q, got\n%s", expected, html)
+		}
+	}
+	if strings.Contains(html, "<script>") || strings.Contains(html, "last week") {
+		t.Fatalf("Expected no raw script and nothing finished last week, got\n%s", html)
+	}
+}
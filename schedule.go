@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxCatchUpDays bounds how many missed days are looked at when t
+// hasn't run for a long time.
+const maxCatchUpDays = 366
+
+// scheduleEntry is a line of the schedule file: a recurring task and
+// the days it is due on.
+type scheduleEntry struct {
+	description string
+	matches     func(day time.Time) bool
+}
+
+// weekdays maps the full and short lowercase names of the weekdays to
+// them.
+var weekdays = make(map[string]time.Weekday)
+
+func init() {
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		name := strings.ToLower(weekday.String())
+		weekdays[name] = weekday
+		weekdays[name[:3]] = weekday
+	}
+}
+
+// schedulePath returns the schedule file, schedule in t's config
+// directory.
+func schedulePath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "schedule"), nil
+}
+
+// parseSchedule parses a schedule file. Each line is a day followed by
+// the task: daily, a weekday like mon or monday, or a day of the month
+// like 1, which in shorter months falls on their last day. Empty lines
+// and lines starting with # are ignored.
+func parseSchedule(text string) ([]scheduleEntry, error) {
+	entries := make([]scheduleEntry, 0)
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("schedule line %d: expected a day and a task", n)
+		}
+		matches, err := parseScheduleDay(strings.ToLower(fields[0]))
+		if err != nil {
+			return nil, fmt.Errorf("schedule line %d: %v", n, err)
+		}
+		entries = append(entries, scheduleEntry{
+			description: strings.Join(fields[1:], " "),
+			matches:     matches,
+		})
+	}
+	return entries, scanner.Err()
+}
+
+func parseScheduleDay(s string) (func(day time.Time) bool, error) {
+	if s == "daily" {
+		return func(time.Time) bool { return true }, nil
+	}
+	if weekday, ok := weekdays[s]; ok {
+		return func(day time.Time) bool { return day.Weekday() == weekday }, nil
+	}
+	dayOfMonth, err := strconv.Atoi(s)
+	if err != nil || dayOfMonth < 1 || dayOfMonth > 31 {
+		return nil, fmt.Errorf("invalid day %q, expected daily, a weekday or a day of the month", s)
+	}
+	return func(day time.Time) bool {
+		lastDay := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, day.Location()).Day()
+		if dayOfMonth > lastDay {
+			return day.Day() == lastDay
+		}
+		return day.Day() == dayOfMonth
+	}, nil
+}
+
+// dueEntries returns the entries due on any day after the day of last up
+// to and including the day of now, each once. Without a last check only
+// today counts.
+func dueEntries(entries []scheduleEntry, last time.Time, now time.Time) []scheduleEntry {
+	today := startOfDay(now)
+	from := today
+	if !last.IsZero() {
+		from = startOfDay(last).AddDate(0, 0, 1)
+		if daysBetween(from, today) > maxCatchUpDays {
+			from = today.AddDate(0, 0, -maxCatchUpDays)
+		}
+	}
+	due := make([]scheduleEntry, 0)
+	for _, entry := range entries {
+		for day := from; !day.After(today); day = day.AddDate(0, 0, 1) {
+			if entry.matches(day) {
+				due = append(due, entry)
+				break
+			}
+		}
+	}
+	return due
+}
+
+// schedulePending reports whether injectScheduled has a round to do,
+// that is whether there is a schedule and it wasn't looked at today, so
+// that t can take the lock on the tasks file before reading it.
+func schedulePending(now time.Time) bool {
+	path, err := schedulePath()
+	if err != nil {
+		return false
+	}
+	if _, err := os.Stat(path); err != nil {
+		return false
+	}
+	state, err := ioutil.ReadFile(path + ".last")
+	if err != nil {
+		return true
+	}
+	// An invalid date is for injectScheduled to report.
+	last, err := time.ParseInLocation(dateLayout, strings.TrimSpace(string(state)), now.Location())
+	return err != nil || daysBetween(last, now) > 0
+}
+
+// injectScheduled adds the scheduled tasks that came due since the last
+// injection to the list, skipping those still open from an earlier
+// round, and records the injection so that no task is added twice. It
+// returns how many tasks were added.
+func injectScheduled(now time.Time) (int, error) {
+	path, err := schedulePath()
+	if err != nil {
+		return 0, err
+	}
+	text, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	entries, err := parseSchedule(string(text))
+	if err != nil {
+		return 0, err
+	}
+	statePath := path + ".last"
+	var last time.Time
+	if state, err := ioutil.ReadFile(statePath); err == nil {
+		last, err = time.ParseInLocation(dateLayout, strings.TrimSpace(string(state)), now.Location())
+		if err != nil {
+			return 0, fmt.Errorf("invalid date in %s", statePath)
+		}
+	}
+	if !last.IsZero() && daysBetween(last, now) <= 0 {
+		return 0, nil
+	}
+	added := 0
+	for _, entry := range dueEntries(entries, last, now) {
+		if isOpen(entry.description) {
+			continue
+		}
+		if _, err := tasklist.Add(entry.description); err != nil {
+			return 0, err
+		}
+		added++
+	}
+	if added > 0 {
+		if err := tasklist.write(true); err != nil {
+			return 0, err
+		}
+	}
+	return added, ioutil.WriteFile(statePath, []byte(now.Format(dateLayout)+"\n"), 0644)
+}
+
+// isOpen reports whether a task with exactly this description is on the
+// list.
+func isOpen(description string) bool {
+	for _, task := range tasklist.Tasks {
+		if task.Description == description {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	entries, err := parseSchedule("# chores\n\nmon water plants\nFriday  take out trash\n31 pay rent\ndaily stretch\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 || entries[1].description != "take out trash" {
+		t.Fatalf("Expected 4 entries, got %v", entries)
+	}
+	monday := time.Date(2024, 6, 3, 0, 0, 0, 0, time.Local)
+	if !entries[0].matches(monday) || entries[0].matches(monday.AddDate(0, 0, 1)) {
+		t.Fatal("Expected mon to match Mondays only")
+	}
+	if !entries[2].matches(time.Date(2024, 2, 29, 0, 0, 0, 0, time.Local)) {
+		t.Fatal("Expected day 31 to fall on the last day of shorter months")
+	}
+	for _, text := range []string{"water plants\n", "32 pay rent\n", "someday read\n"} {
+		if _, err := parseSchedule(text); err == nil {
+			t.Fatalf("Expected '%s' to be rejected", text)
+		}
+	}
+}
+
+func TestDueEntries(t *testing.T) {
+	entries, _ := parseSchedule("mon water plants\n1 pay rent\ndaily stretch\n")
+	wednesday := time.Date(2024, 6, 5, 8, 0, 0, 0, time.Local)
+
+	due := dueEntries(entries, time.Time{}, wednesday)
+	if len(due) != 1 || due[0].description != "stretch" {
+		t.Fatalf("Expected only today's tasks on the first run, got %v", due)
+	}
+	due = dueEntries(entries, wednesday.AddDate(0, 0, -5), wednesday)
+	if len(due) != 3 {
+		t.Fatalf("Expected missed days to be caught up, each task once, got %v", due)
+	}
+	due = dueEntries(entries, wednesday.AddDate(0, 0, -1), wednesday)
+	if len(due) != 1 {
+		t.Fatalf("Expected only today's tasks after yesterday's check, got %v", due)
+	}
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+// serveTimeout is how long the CLI waits for t --serve before doing the
+// command itself.
+const serveTimeout = time.Second
+
+// socketPath returns the unix socket t --serve listens on: t.sock in
+// $XDG_RUNTIME_DIR, or in the temporary directory without one.
+func socketPath() string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "t.sock")
+}
+
+// fileStamp is what the tasks file looked like when t --serve last read
+// or wrote it, to tell when another t changed it.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func stampOf(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{info.ModTime(), info.Size()}
+}
+
+// server answers the requests of t --serve from the list it keeps in
+// tasklist, one request at a time.
+type server struct {
+	mu sync.Mutex
+	// path is the tasks file served, as an absolute path.
+	path string
+	// opts are the options of a bare t, with which LIST lists the tasks.
+	opts formatOptions
+	// inject adds the scheduled tasks before every change, as a t using
+	// the default list does. LIST only reads the tasks file.
+	inject bool
+	// hashIds shows stable id prefixes rather than numbers, with ids =
+	// hash.
+	hashIds bool
+	stamp   fileStamp
+	// stale is set when a command failed halfway, leaving tasklist
+	// other than the file.
+	stale bool
+}
+
+// newServer returns a server for the tasks file at path, which tasklist
+// was just read from.
+func newServer(path string, opts formatOptions, inject, hashIds bool) (*server, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	// The CLI only asks for listings it wouldn't color or cut short,
+	// whatever the server's own output goes to.
+	opts.color, opts.width = false, 0
+	return &server{path: abs, opts: opts, inject: inject, hashIds: hashIds, stamp: stampOf(path)}, nil
+}
+
+// serveTasks handles --serve: it answers requests on the socket until
+// interrupted. Another t already serving on the socket is an error, and
+// a socket left behind by one that is gone is replaced.
+func serveTasks(socket string, opts formatOptions, inject, hashIds bool) error {
+	s, err := newServer(taskFilePath, opts, inject, hashIds)
+	if err != nil {
+		return err
+	}
+	if conn, err := net.DialTimeout("unix", socket, serveTimeout); err == nil {
+		conn.Close()
+		return fmt.Errorf("t is already serving on %s", socket)
+	}
+	os.Remove(socket)
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return err
+	}
+	// The temporary directory is shared, and the socket changes the
+	// tasks of whoever can connect to it.
+	if err := os.Chmod(socket, 0600); err != nil {
+		listener.Close()
+		return err
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		listener.Close()
+	}()
+	fmt.Fprintf(os.Stderr, "serving %s on %s\n", s.path, socket)
+	return s.serve(listener)
+}
+
+// serve answers the connections to listener until it is closed.
+func (s *server) serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+// handle answers one connection, which holds one request: an optional
+// FILE line naming the tasks file the client means and a command. The
+// answer is OK followed by the output of the command, ERR with the
+// exit status and the error, or NO if another tasks file is served.
+func (s *server) handle(conn net.Conn) {
+	defer conn.Close()
+	lines := bufio.NewScanner(conn)
+	if !lines.Scan() {
+		return
+	}
+	line := lines.Text()
+	if path := strings.TrimPrefix(line, "FILE "); path != line {
+		if path != s.path {
+			fmt.Fprintf(conn, "NO %s\n", s.path)
+			return
+		}
+		if !lines.Scan() {
+			return
+		}
+		line = lines.Text()
+	}
+	var out strings.Builder
+	status, err := s.run(line, &out)
+	if err != nil {
+		fmt.Fprintf(conn, "ERR %d %v\n", status, err)
+		return
+	}
+	fmt.Fprintf(conn, "OK\n%s", out.String())
+}
+
+// refresh reads the tasks file again if another t changed it since the
+// server last read or wrote it.
+func (s *server) refresh() error {
+	stamp := stampOf(s.path)
+	if stamp == s.stamp && !s.stale {
+		return nil
+	}
+	list, err := readTaskList(s.path)
+	if err != nil {
+		return err
+	}
+	list.Raw, list.Truncate, list.KeepTombstones = tasklist.Raw, tasklist.Truncate, tasklist.KeepTombstones
+	tasklist, s.stamp, s.stale = list, stamp, false
+	return nil
+}
+
+// run runs one command of the protocol, writing what t would print for
+// it to out: LIST, ADD <text>, FINISH <id> or EDIT <id> <text>. Changes
+// are written right away, holding the lock on the tasks file like t
+// does. On an error, it returns the status t would exit with.
+func (s *server) run(line string, out io.Writer) (status int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	command, rest := line, ""
+	if i := strings.Index(line, " "); i != -1 {
+		command, rest = line[:i], line[i+1:]
+	}
+	mutating := command != "LIST"
+	if mutating {
+		lock, err := acquireLock(s.path)
+		if err != nil {
+			return 1, err
+		}
+		defer lock.Close()
+	}
+	if err := s.refresh(); err != nil {
+		return 1, err
+	}
+	// Whatever gets written, by the command or the scheduled tasks, is
+	// what the server has read.
+	defer func() {
+		s.stamp, s.stale = stampOf(s.path), err != nil
+	}()
+	if mutating && s.inject && !inHook && (!tasklist.ReadOnly || ignoreReadOnly) {
+		if _, err := injectScheduled(time.Now()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+	opts := s.opts
+	opts.now = time.Now()
+	if s.hashIds {
+		opts.idPrefixes = tasklist.idPrefixes()
+	}
+	// Changes are confirmed as t confirms them.
+	p := &printer{out: out}
+	switch command {
+	case "LIST":
+		ids, _ := tasklist.searchIds("", false)
+		ids, err := tasklist.orderIds(ids, "priority")
+		if err != nil {
+			return 2, err
+		}
+		for _, taskId := range ids {
+			fmt.Fprintln(out, formatTask(taskId, tasklist.Tasks[taskId], opts))
+			for _, line := range formatChildren(taskId, tasklist.Tasks[taskId], opts) {
+				fmt.Fprintln(out, line)
+			}
+		}
+		return 0, nil
+	case "ADD":
+		if strings.TrimSpace(rest) == "" {
+			return 2, errors.New("Usage: ADD <text>")
+		}
+		task, err := tasklist.Add(rest)
+		if err != nil {
+			return 1, err
+		}
+		if err := tasklist.write(true); err != nil {
+			return 1, err
+		}
+		p.added(strconv.Itoa(displayId(tasklist.IndexOf(task.Id))), task)
+		return 0, nil
+	case "FINISH":
+		taskId, err := tasklist.resolveId(rest)
+		if err != nil {
+			return 2, err
+		}
+		if err := finishTasks(p, []int{taskId}); err != nil {
+			return 1, err
+		}
+		return 0, nil
+	case "EDIT":
+		fields := strings.SplitN(rest, " ", 2)
+		if len(fields) != 2 {
+			return 2, errors.New("Usage: EDIT <id> <text>")
+		}
+		taskId, err := tasklist.resolveId(fields[0])
+		if err != nil {
+			return 2, err
+		}
+		task, err := tasklist.Get(taskId)
+		if err != nil {
+			return 1, err
+		}
+		old := task.Description
+		if err := tasklist.Edit(taskId, fields[1]); err != nil {
+			if err == core.ErrEmptyDescription {
+				err = errors.New("the description is empty, the task wasn't changed; -f finishes it and -d deletes it")
+			}
+			return 1, err
+		}
+		if err := tasklist.write(true); err != nil {
+			return 1, err
+		}
+		p.edited(taskId, old, tasklist.Tasks[taskId])
+		return 0, nil
+	}
+	return 2, fmt.Errorf("unknown command %q, expected LIST, ADD, FINISH or EDIT", command)
+}
+
+// serverCommand returns the request to t --serve that does what the
+// command line asks, for the commands it answers exactly as t would: a
+// bare t, t -f <id> and t -e <id> <text>. It returns "" for anything
+// else, and whenever the output goes to a terminal, where t colors it
+// and nags, so that t does the command itself.
+func serverCommand(editId, finishId string, opts formatOptions) string {
+	if opts.color || strictMode {
+		return ""
+	}
+	if info, err := os.Stdout.Stat(); err != nil || info.Mode()&os.ModeCharDevice != 0 {
+		return ""
+	}
+	switch {
+	case flag.NFlag() == 0 && flag.NArg() == 0:
+		return "LIST"
+	case flag.NFlag() != 1:
+		return ""
+	case flagPassed("f") && finishId != "" && !strings.Contains(finishId, ",") && !isChildId(finishId) && flag.NArg() == 0 && !stdinIsTerminal():
+		return "FINISH " + finishId
+	case flagPassed("e") && flag.NArg() > 0 && !strings.Contains(editId, " "):
+		text := strings.Join(flag.Args(), " ")
+		if strings.TrimSpace(text) == "" || strings.ContainsAny(text, "\r\n") {
+			return ""
+		}
+		return "EDIT " + editId + " " + text
+	}
+	return ""
+}
+
+// askServer sends command to the t --serve listening on socket, if
+// there is one serving the tasks file at path, and copies its output to
+// stdout and its error to stderr. It returns the status to exit with,
+// and false if t has to do the command itself. Once the command is
+// sent, it may have been done, so t never does it again: a server that
+// doesn't answer is an error.
+func askServer(socket, path, command string, stdout, stderr io.Writer) (int, bool) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return 0, false
+	}
+	conn, err := net.DialTimeout("unix", socket, serveTimeout)
+	if err != nil {
+		return 0, false
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(lockTimeout + serveTimeout))
+	if _, err := fmt.Fprintf(conn, "FILE %s\n%s\n", abs, command); err != nil {
+		return 0, false
+	}
+	answer := bufio.NewReader(conn)
+	status, err := answer.ReadString('\n')
+	if err != nil {
+		fmt.Fprintf(stderr, "t --serve didn't answer: %v\n", err)
+		return 1, true
+	}
+	switch {
+	case status == "OK\n":
+		if _, err := io.Copy(stdout, answer); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1, true
+		}
+		return 0, true
+	case strings.HasPrefix(status, "ERR "):
+		fields := strings.SplitN(strings.TrimSuffix(status, "\n"), " ", 3)
+		if len(fields) == 3 {
+			if code, err := strconv.Atoi(fields[1]); err == nil {
+				fmt.Fprintln(stderr, fields[2])
+				return code, true
+			}
+		}
+	case strings.HasPrefix(status, "NO "):
+		return 0, false
+	}
+	fmt.Fprintf(stderr, "t --serve answered %q\n", strings.TrimSpace(status))
+	return 1, true
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// startServer serves an empty tasks file in a temporary directory and
+// returns it and the socket. With inject, the server adds the scheduled
+// tasks as t --serve does for the default list.
+func startServer(t *testing.T, inject bool) (string, string) {
+	dir := t.TempDir()
+	path, socket := filepath.Join(dir, "tasks"), filepath.Join(dir, "t.sock")
+	oldPath, oldDone, oldList := taskFilePath, doneFilePath, tasklist
+	taskFilePath, doneFilePath, tasklist = path, "", &TaskList{}
+	s, err := newServer(path, formatOptions{}, inject, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error)
+	go func() { served <- s.serve(listener) }()
+	t.Cleanup(func() {
+		listener.Close()
+		if err := <-served; err != nil {
+			t.Error(err)
+		}
+		taskFilePath, doneFilePath, tasklist = oldPath, oldDone, oldList
+	})
+	return path, socket
+}
+
+func askTest(t *testing.T, socket, path, command string) (string, string, int) {
+	var out, errOut strings.Builder
+	status, ok := askServer(socket, path, command, &out, &errOut)
+	if !ok {
+		t.Fatalf("Expected the server to answer %q", command)
+	}
+	return out.String(), errOut.String(), status
+}
+
+func TestServeConcurrently(t *testing.T) {
+	path, socket := startServer(t, false)
+	var wg sync.WaitGroup
+	for _, name := range []string{"a", "b"} {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				if _, errOut, status := askTest(t, socket, path, fmt.Sprintf("ADD %s%d", name, i)); status != 0 {
+					t.Errorf("Expected %s%d added, got %d: %s", name, i, status, errOut)
+				}
+			}
+		}(name)
+	}
+	wg.Wait()
+	list, err := readTaskList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Tasks) != 40 {
+		t.Fatalf("Expected 40 tasks in the file, got %d", len(list.Tasks))
+	}
+	for _, name := range []string{"a", "b"} {
+		for i := 0; i < 20; i++ {
+			if !list.hasDescription(fmt.Sprintf("%s%d", name, i)) {
+				t.Errorf("Expected %s%d in the file", name, i)
+			}
+		}
+	}
+	out, _, _ := askTest(t, socket, path, "LIST")
+	if lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n"); len(lines) != 40 || !strings.HasPrefix(lines[0], "0 - ") {
+		t.Fatalf("Expected the 40 tasks listed, got '%s'", out)
+	}
+}
+
+func TestServeCommands(t *testing.T) {
+	path, socket := startServer(t, false)
+	askTest(t, socket, path, "ADD pay rent")
+	askTest(t, socket, path, "ADD call bob")
+	if _, errOut, status := askTest(t, socket, path, "EDIT 1 call alice"); status != 0 {
+		t.Fatalf("Expected the task edited, got %d: %s", status, errOut)
+	}
+	if _, errOut, status := askTest(t, socket, path, "FINISH 0"); status != 0 {
+		t.Fatalf("Expected the task finished, got %d: %s", status, errOut)
+	}
+	if out, _, _ := askTest(t, socket, path, "LIST"); out != "0 - call alice\n" {
+		t.Fatalf("Expected the changes listed, got '%s'", out)
+	}
+	if text, _ := ioutil.ReadFile(path + ".done"); !strings.HasPrefix(string(text), "pay rent | ") {
+		t.Fatalf("Expected the finished task in the done file, got '%s'", text)
+	}
+	// Another t changing the file is seen by the server.
+	if err := ioutil.WriteFile(path, []byte("water the plants\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out, _, _ := askTest(t, socket, path, "LIST"); out != "0 - water the plants\n" {
+		t.Fatalf("Expected the file read again, got '%s'", out)
+	}
+	for command, expected := range map[string]int{"FINISH 7": 1, "EDIT 0  ": 1, "ADD ": 2, "PURGE": 2} {
+		if _, errOut, status := askTest(t, socket, path, command); status != expected || errOut == "" {
+			t.Errorf("Expected %q to fail with %d, got %d: %s", command, expected, status, errOut)
+		}
+	}
+	if text, _ := ioutil.ReadFile(path); string(text) != "water the plants\n" {
+		t.Fatalf("Expected the failed commands to change nothing, got '%s'", text)
+	}
+	if _, ok := askServer(socket, path+".other", "LIST", ioutil.Discard, ioutil.Discard); ok {
+		t.Fatal("Expected the server to leave another tasks file to t")
+	}
+	if _, ok := askServer(filepath.Join(t.TempDir(), "t.sock"), path, "LIST", ioutil.Discard, ioutil.Discard); ok {
+		t.Fatal("Expected no server without a socket")
+	}
+}
+
+func TestServeInjectsOnChanges(t *testing.T) {
+	dir := t.TempDir()
+	defer func(saved string) { os.Setenv("XDG_CONFIG_HOME", saved) }(os.Getenv("XDG_CONFIG_HOME"))
+	os.Setenv("XDG_CONFIG_HOME", dir)
+	os.MkdirAll(filepath.Join(dir, "t"), 0700)
+	ioutil.WriteFile(filepath.Join(dir, "t", "schedule"), []byte("daily stretch\n"), 0644)
+	path, socket := startServer(t, true)
+	if out, _, _ := askTest(t, socket, path, "LIST"); out != "" {
+		t.Fatalf("Expected LIST to leave the scheduled tasks out, got '%s'", out)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("Expected LIST not to write the tasks file, got %v", err)
+	}
+	askTest(t, socket, path, "ADD pay rent")
+	if out, _, _ := askTest(t, socket, path, "LIST"); out != "0 - stretch\n1 - pay rent\n" {
+		t.Fatalf("Expected the scheduled task added with the change, got '%s'", out)
+	}
+}
+
+func TestCliServe(t *testing.T) {
+	withCliSetup(t, func() {
+		exec.Command(tBinary, "pay rent").Run()
+		exec.Command(tBinary, "call bob").Run()
+		direct, _ := exec.Command(tBinary).Output()
+		runtime := t.TempDir()
+		env := append(os.Environ(), "XDG_RUNTIME_DIR="+runtime)
+		serve := func(extra ...string) *exec.Cmd {
+			cmd := exec.Command(tBinary, "--serve")
+			cmd.Env = append(env, extra...)
+			if err := cmd.Start(); err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 100; i++ {
+				if _, err := os.Stat(filepath.Join(runtime, "t.sock")); err == nil {
+					return cmd
+				}
+				time.Sleep(20 * time.Millisecond)
+			}
+			cmd.Process.Kill()
+			t.Fatal("Expected t --serve to listen on the socket")
+			return nil
+		}
+		run := func(args ...string) string {
+			cmd := exec.Command(tBinary, args...)
+			cmd.Env = env
+			out, err := cmd.CombinedOutput()
+			if err != nil {
+				t.Fatalf("Expected t %v to work, got '%s' (%v)", args, out, err)
+			}
+			return string(out)
+		}
+		server := serve()
+		if out := run(); out != string(direct) {
+			t.Fatalf("Expected the same listing from the server, got '%s', not '%s'", out, direct)
+		}
+		run("-e", "1", "call alice")
+		run("-f", "0")
+		server.Process.Signal(os.Interrupt)
+		server.Wait()
+		if _, err := os.Stat(filepath.Join(runtime, "t.sock")); !os.IsNotExist(err) {
+			t.Fatal("Expected the socket removed once interrupted")
+		}
+		if out := run(); out != "0 - call alice\n" {
+			t.Fatalf("Expected the changes in the tasks file, got '%s'", out)
+		}
+		// A server numbering tasks from 1 tells its answers apart.
+		server = serve("T_INDEX_BASE=1")
+		defer server.Process.Kill()
+		if out := run(); out != "1 - call alice\n" {
+			t.Fatalf("Expected the server to answer, got '%s'", out)
+		}
+		if out := run("--count"); out != "1\n" {
+			t.Fatalf("Expected other commands done directly, got '%s'", out)
+		}
+	})
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// parseSince parses the moment given to --since: a date like 2024-05-01,
+// meaning its start in local time, or how long ago, like 7d or 36h.
+func parseSince(s string, now time.Time) (time.Time, error) {
+	since, err := parseDate(s, now.Location())
+	if err != errNotADate {
+		return since, err
+	}
+	d, err := parseDuration(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --since %q, expected a date (%s) or a duration like 7d", s, dateForms())
+	}
+	return now.Add(-d), nil
+}
+
+// sinceIds returns the ids of the tasks created after since, and how
+// many tasks were left out because they have no creation time.
+func (t *TaskList) sinceIds(ids []int, since time.Time) ([]int, int) {
+	recent := make([]int, 0)
+	untimed := 0
+	for _, taskId := range ids {
+		task := t.Tasks[taskId]
+		if task.CreatedAt.IsZero() {
+			untimed++
+		} else if task.CreatedAt.After(since) {
+			recent = append(recent, taskId)
+		}
+	}
+	return recent, untimed
+}
+
+// doneSince returns the tasks of a done list finished after since, and
+// how many tasks were left out because they have no finishing time.
+func (t *TaskList) doneSince(since time.Time) ([]*Task, int) {
+	done := make([]*Task, 0)
+	untimed := 0
+	for _, task := range t.Tasks {
+		if task.DoneAt.IsZero() {
+			untimed++
+		} else if task.DoneAt.After(since) {
+			done = append(done, task)
+		}
+	}
+	return done, untimed
+}
+
+// formatDone renders a finished task with the day it was finished, if
+// that is known.
+func formatDone(task *Task) string {
+	if task.DoneAt.IsZero() {
+		return task.Description
+	}
+	return fmt.Sprintf("%s - %s", displayDate(task.DoneAt.Local()), task.Description)
+}
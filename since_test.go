@@ -0,0 +1,57 @@
+package main
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"reflect"
+	"testing"
+	"time"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.Local)
+	cases := map[string]time.Time{
+		"2024-05-01": time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local),
+		"7d":         now.AddDate(0, 0, -7),
+		"36h":        now.Add(-36 * time.Hour),
+	}
+	for in, expected := range cases {
+		since, err := parseSince(in, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !since.Equal(expected) {
+			t.Fatalf("parseSince(%q): expected %v, got %v", in, expected, since)
+		}
+	}
+	if _, err := parseSince("last week", now); err == nil {
+		t.Fatal("Expected an invalid --since to fail")
+	}
+}
+
+func TestSinceIds(t *testing.T) {
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.Local)
+	tasklist := TaskList{TaskList: core.TaskList{Tasks: []*Task{
+		{Description: "old", CreatedAt: now.AddDate(0, 0, -40)},
+		{Description: "new", CreatedAt: now.AddDate(0, 0, -2)},
+		{Description: "legacy"},
+	}}}
+	ids, untimed := tasklist.sinceIds([]int{0, 1, 2}, now.AddDate(0, 0, -7))
+	if !reflect.DeepEqual(ids, []int{1}) || untimed != 1 {
+		t.Fatalf("Expected [1] with 1 untimed task, got %v with %d", ids, untimed)
+	}
+}
+
+func TestCliSinceDone(t *testing.T) {
+	withCliSetup(t, func() {
+		recent := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+		ioutil.WriteFile("/tmp/tasks.done", []byte("ancient | done:2020-01-01T00:00:00Z\nrecent | done:"+recent+"\nunknown\n"), 0644)
+		out, _ := exec.Command(tBinary, "--since", "7d", "--done").Output()
+		expected := time.Now().Add(-time.Hour).Format(dateLayout) + " - recent\n(1 finished tasks without a finishing time not shown)\n"
+		if string(out) != expected {
+			t.Fatalf("Expected output to be %q, got %q", expected, out)
+		}
+	})
+}
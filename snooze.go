@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// parseWake parses when a snoozed task wakes as given to --snooze: a
+// number of days or weeks from today like 3d or 2w, or anything
+// parseDue takes.
+func parseWake(s string, now time.Time) (time.Time, error) {
+	if !strings.HasPrefix(s, "+") && !strings.HasPrefix(s, "-") {
+		if days, err := parseDays(s); err == nil {
+			return startOfDay(now).AddDate(0, 0, days), nil
+		}
+	}
+	return parseDue(s, now)
+}
+
+// formatWake renders when a snoozed task wakes: the day of the week
+// within the coming week, the date after that.
+func formatWake(until, now time.Time) string {
+	switch days := daysBetween(now, until); {
+	case days == 1:
+		return "tomorrow"
+	case days > 1 && days < 7:
+		return until.Format("Mon")
+	}
+	return displayDate(until)
+}
+
+// Snooze hides a task from listings until the day until, replacing any
+// earlier snooze. A day that isn't after now wakes the task instead.
+func (t *TaskList) Snooze(taskId int, until, now time.Time) error {
+	task, err := t.Get(taskId)
+	if err != nil {
+		return err
+	}
+	if !until.After(now) {
+		until = time.Time{}
+	}
+	task.SnoozedUntil = until
+	return nil
+}
+
+// snoozeTask handles --snooze: it snoozes the task until the day given
+// in args and says until when.
+func snoozeTask(target string, args []string, now time.Time) error {
+	if len(args) != 1 {
+		return fmt.Errorf("Usage: t --snooze <id> <3d, 2w, tomorrow or a date>")
+	}
+	until, err := parseWake(args[0], now)
+	if err != nil {
+		return err
+	}
+	taskId, err := tasklist.resolveId(target)
+	if err != nil {
+		return err
+	}
+	if err := tasklist.Snooze(taskId, until, now); err != nil {
+		return err
+	}
+	if err := tasklist.write(true); err != nil {
+		return err
+	}
+	if until.After(now) {
+		fmt.Printf("snoozed until %s\n", displayDate(until))
+	} else {
+		fmt.Println("woken up")
+	}
+	return nil
+}
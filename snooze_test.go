@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseWake(t *testing.T) {
+	now := time.Date(2024, 6, 5, 15, 0, 0, 0, time.Local)
+	cases := map[string]time.Time{
+		"3d":         time.Date(2024, 6, 8, 0, 0, 0, 0, time.Local),
+		"2w":         time.Date(2024, 6, 19, 0, 0, 0, 0, time.Local),
+		"+1d":        time.Date(2024, 6, 6, 0, 0, 0, 0, time.Local),
+		"tomorrow":   time.Date(2024, 6, 6, 0, 0, 0, 0, time.Local),
+		"2024-07-01": time.Date(2024, 7, 1, 0, 0, 0, 0, time.Local),
+	}
+	for s, expected := range cases {
+		if until, err := parseWake(s, now); err != nil || !until.Equal(expected) {
+			t.Errorf("Expected %s to wake on %v, got %v (%v)", s, expected, until, err)
+		}
+	}
+	if _, err := parseWake("soon", now); err == nil {
+		t.Error("Expected an invalid wake time to fail")
+	}
+}
+
+func TestFormatWake(t *testing.T) {
+	now := time.Date(2024, 6, 5, 15, 0, 0, 0, time.Local)
+	cases := map[int]string{1: "tomorrow", 5: "Mon", 7: "2024-06-12"}
+	for days, expected := range cases {
+		if got := formatWake(startOfDay(now).AddDate(0, 0, days), now); got != expected {
+			t.Errorf("Expected %d days to be %s, got %s", days, expected, got)
+		}
+	}
+}
+
+func TestSnooze(t *testing.T) {
+	now := time.Now()
+	list := &TaskList{}
+	list.Add("renew passport")
+	list.Add("pay rent")
+	list.Snooze(0, now.AddDate(0, 0, 3), now)
+	list.Snooze(0, now.AddDate(0, 0, 1), now)
+	opts := formatOptions{now: now}
+	if got := list.Search(""); len(got) != 1 || got[0] != 1 {
+		t.Fatalf("Expected only the awake task listed, got %v", got)
+	}
+	if got := formatTask(0, list.Tasks[0], opts); got != "0 - renew passport (snoozed until tomorrow)" {
+		t.Fatalf("Expected the latest snooze to win, got '%s'", got)
+	}
+	opts.now = now.AddDate(0, 0, 2)
+	if got := formatTask(0, list.Tasks[0], opts); got != "0 - renew passport" {
+		t.Fatalf("Expected the task awake again, got '%s'", got)
+	}
+	list.Snooze(1, now.AddDate(0, 0, 3), now)
+	list.Snooze(1, now.AddDate(0, 0, -1), now)
+	if !list.Tasks[1].SnoozedUntil.IsZero() {
+		t.Fatal("Expected a past day to wake the task")
+	}
+}
+
+func TestCliSnooze(t *testing.T) {
+	withCliSetup(t, func() {
+		exec.Command(tBinary, "renew passport").Run()
+		exec.Command(tBinary, "pay rent").Run()
+		out, err := exec.Command(tBinary, "--snooze", "0", "3d").Output()
+		if err != nil || !strings.HasPrefix(string(out), "snoozed until ") {
+			t.Fatalf("Expected the task snoozed, got '%s' (%v)", out, err)
+		}
+		if out, _ := exec.Command(tBinary).Output(); string(out) != "1 - pay rent\n" {
+			t.Fatalf("Expected the snoozed task hidden, got '%s'", out)
+		}
+		out, _ = exec.Command(tBinary, "--all").Output()
+		wake := formatWake(startOfDay(time.Now()).AddDate(0, 0, 3), time.Now())
+		if !strings.Contains(string(out), "0 - renew passport (snoozed until "+wake+")\n") {
+			t.Fatalf("Expected --all to show the snoozed task, got '%s'", out)
+		}
+		exec.Command(tBinary, "-e", "0", "renew the passport").Run()
+		if out, _ := exec.Command(tBinary, "--all", "--plain").Output(); !strings.Contains(string(out), "0 - renew the passport (snoozed until ") {
+			t.Fatalf("Expected -e to reach the snoozed task, got '%s'", out)
+		}
+		if err := exec.Command(tBinary, "--snooze", "0", "soon").Run(); err == nil {
+			t.Fatal("Expected an invalid wake time to fail")
+		}
+		exec.Command(tBinary, "--snooze", "0", "today").Run()
+		if out, _ := exec.Command(tBinary).Output(); string(out) != "0 - renew the passport\n1 - pay rent\n" {
+			t.Fatalf("Expected snoozing until today to wake the task, got '%s'", out)
+		}
+	})
+}
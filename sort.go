@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+// taskLess reports whether task a sorts before task b for one sort key.
+type taskLess func(a, b *Task) bool
+
+var sortKeys = map[string]taskLess{
+	"age": func(a, b *Task) bool {
+		// Oldest first; tasks without a creation time go last.
+		if a.CreatedAt.IsZero() || b.CreatedAt.IsZero() {
+			return !a.CreatedAt.IsZero() && b.CreatedAt.IsZero()
+		}
+		return a.CreatedAt.Before(b.CreatedAt)
+	},
+	"alpha": func(a, b *Task) bool {
+		return strings.ToLower(a.Description) < strings.ToLower(b.Description)
+	},
+	"due": func(a, b *Task) bool {
+		if a.DueAt.IsZero() || b.DueAt.IsZero() {
+			return !a.DueAt.IsZero() && b.DueAt.IsZero()
+		}
+		return a.DueAt.Before(b.DueAt)
+	},
+	"priority": func(a, b *Task) bool {
+		now := time.Now()
+		return effectivePriority(a, now) < effectivePriority(b, now)
+	},
+}
+
+// untimedLast holds, for the sort keys that put tasks without a time
+// last whichever the direction, how to tell those tasks.
+var untimedLast = map[string]func(task *Task) bool{
+	"age": func(task *Task) bool { return task.CreatedAt.IsZero() },
+}
+
+// SortedIds returns the ids of all tasks ordered by the given sort key.
+// A leading "-" reverses the order. Ties keep their list order.
+func (t *TaskList) SortedIds(by string) ([]int, error) {
+	ids := make([]int, len(t.Tasks))
+	for i := range ids {
+		ids[i] = i
+	}
+	return t.orderIds(ids, by)
+}
+
+// orderIds sorts the given task ids like SortedIds does.
+func (t *TaskList) orderIds(ids []int, by string) ([]int, error) {
+	key := strings.TrimPrefix(by, "-")
+	less, ok := sortKeys[key]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort key %q", by)
+	}
+	if strings.HasPrefix(by, "-") {
+		ascending, untimed := less, untimedLast[key]
+		less = func(a, b *Task) bool {
+			if untimed != nil && (untimed(a) || untimed(b)) {
+				return ascending(a, b)
+			}
+			return ascending(b, a)
+		}
+	}
+	sorted := append([]int(nil), ids...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return less(t.Tasks[sorted[i]], t.Tasks[sorted[j]])
+	})
+	return sorted, nil
+}
+
+// shuffleIds puts ids in a random order determined by seed.
+func shuffleIds(ids []int, seed int64) {
+	r := rand.New(rand.NewSource(seed))
+	r.Shuffle(len(ids), func(i, j int) {
+		ids[i], ids[j] = ids[j], ids[i]
+	})
+}
+
+// Sort reorders the tasks themselves by the given sort key, so the new
+// order is what gets written back to the tasks file.
+func (t *TaskList) Sort(by string) error {
+	ids, err := t.SortedIds(by)
+	if err != nil {
+		return err
+	}
+	sorted := make([]*Task, len(ids))
+	for i, taskId := range ids {
+		sorted[i] = t.Tasks[taskId]
+	}
+	t.Tasks = sorted
+	return nil
+}
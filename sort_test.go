@@ -0,0 +1,69 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSortedIds(t *testing.T) {
+	tasklist := TaskList{}
+	b, _ := tasklist.Add("b")
+	b.CreatedAt = time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC)
+	c, _ := tasklist.Add("C")
+	c.DueAt = time.Date(2024, 6, 2, 0, 0, 0, 0, time.Local)
+	c.CreatedAt = time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	a, _ := tasklist.Add("a")
+	a.DueAt = time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)
+	a.CreatedAt = time.Time{}
+	B, _ := tasklist.Add("B")
+	B.CreatedAt = time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)
+
+	cases := map[string][]int{
+		"alpha":  {2, 0, 3, 1},
+		"-alpha": {1, 0, 3, 2},
+		"due":    {2, 1, 0, 3},
+		"-due":   {0, 3, 1, 2},
+		"age":    {1, 3, 0, 2},
+		"-age":   {0, 3, 1, 2},
+	}
+	for by, expected := range cases {
+		ids, err := tasklist.SortedIds(by)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(ids, expected) {
+			t.Fatalf("Sorting by %s: expected %v, got %v", by, expected, ids)
+		}
+	}
+	if _, err := tasklist.SortedIds("size"); err == nil {
+		t.Fatal("Expected unknown sort key to fail")
+	}
+}
+
+func TestSort(t *testing.T) {
+	tasklist := TaskList{}
+	tasklist.Add("b")
+	tasklist.Add("a")
+	tasklist.Sort("alpha")
+	if tasklist.Tasks[0].Description != "a" || tasklist.Tasks[1].Description != "b" {
+		t.Fatalf("Expected tasks to be reordered, got %v", tasklist.listing())
+	}
+}
+
+func TestShuffleIds(t *testing.T) {
+	ids := []int{0, 1, 2, 3, 4, 5, 6, 7}
+	shuffleIds(ids, 42)
+	again := []int{0, 1, 2, 3, 4, 5, 6, 7}
+	shuffleIds(again, 42)
+	if !reflect.DeepEqual(ids, again) {
+		t.Fatalf("Expected the same seed to give the same order, got %v and %v", ids, again)
+	}
+	seen := make(map[int]bool)
+	for _, id := range ids {
+		seen[id] = true
+	}
+	if len(seen) != 8 {
+		t.Fatalf("Expected shuffle to keep every id, got %v", ids)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// defaultStaleAfter is how old a task gets before listings mark it
+// (stale), unless --stale-after or stale_after say otherwise.
+const defaultStaleAfter = 14 * 24 * time.Hour
+
+// isStale reports whether the task was added more than age before now.
+// Tasks without a creation time aren't stale, and neither are waiting
+// or someday tasks or tasks whose deferral ended less than age ago,
+// which explains why they are still around.
+func isStale(task *Task, now time.Time, age time.Duration) bool {
+	cutoff := now.Add(-age)
+	if task.CreatedAt.IsZero() || task.Waiting || isSomeday(task) || !task.CreatedAt.Before(cutoff) {
+		return false
+	}
+	return task.SnoozedUntil.IsZero() || !task.SnoozedUntil.After(cutoff)
+}
+
+// staleIds returns the ids of the tasks that are stale at now, added
+// more than age ago.
+func (t *TaskList) staleIds(ids []int, age time.Duration, now time.Time) []int {
+	stale := make([]int, 0)
+	for _, taskId := range ids {
+		if isStale(t.Tasks[taskId], now, age) {
+			stale = append(stale, taskId)
+		}
+	}
+	return stale
+}
+
+// staleAfter returns how old a task gets before it is stale: the
+// --stale-after value if there is one, then the stale_after setting,
+// then defaultStaleAfter. Both take 30, 30d, 2w or a Go duration.
+func staleAfter(value string, config Config) (time.Duration, error) {
+	if value != "" {
+		return parseDuration(value)
+	}
+	value, ok := config.Get("stale_after")
+	if !ok {
+		return defaultStaleAfter, nil
+	}
+	age, err := parseDuration(value)
+	if err != nil {
+		return defaultStaleAfter, fmt.Errorf("invalid stale_after = %s, expected e.g. 30d or 2w", value)
+	}
+	return age, nil
+}
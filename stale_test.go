@@ -0,0 +1,105 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+func TestStaleIds(t *testing.T) {
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.Local)
+	tasklist := TaskList{TaskList: core.TaskList{Tasks: []*Task{
+		{Description: "old", CreatedAt: now.AddDate(0, 0, -40)},
+		{Description: "new", CreatedAt: now.AddDate(0, 0, -10)},
+		{Description: "legacy"},
+		{Description: "deferred", CreatedAt: now.AddDate(0, 0, -40), SnoozedUntil: now.AddDate(0, 0, -5)},
+		{Description: "deferred long ago", CreatedAt: now.AddDate(0, 0, -90), SnoozedUntil: now.AddDate(0, 0, -60)},
+		{Description: "learn the cello +someday", CreatedAt: now.AddDate(0, 0, -90)},
+	}}}
+	stale := tasklist.staleIds([]int{0, 1, 2, 3, 4, 5}, 30*24*time.Hour, now)
+	if !reflect.DeepEqual(stale, []int{0, 4}) {
+		t.Fatalf("Expected [0 4] to be stale, got %v", stale)
+	}
+}
+
+func TestStaleAfter(t *testing.T) {
+	none := Config{values: map[string]string{}}
+	set := Config{values: map[string]string{"stale_after": "2w"}}
+	for _, c := range []struct {
+		value    string
+		config   Config
+		expected time.Duration
+	}{
+		{"", none, defaultStaleAfter},
+		{"", set, 14 * 24 * time.Hour},
+		{"30d", set, 30 * 24 * time.Hour},
+		{"36h", none, 36 * time.Hour},
+		{"3", none, 3 * 24 * time.Hour},
+	} {
+		if age, err := staleAfter(c.value, c.config); err != nil || age != c.expected {
+			t.Errorf("%q: expected %s, got %s (%v)", c.value, c.expected, age, err)
+		}
+	}
+	if _, err := staleAfter("soon", none); err == nil {
+		t.Fatal("Expected --stale-after soon refused")
+	}
+	if age, err := staleAfter("", Config{values: map[string]string{"stale_after": "soon"}}); err == nil || age != defaultStaleAfter {
+		t.Fatalf("Expected stale_after = soon refused for the default, got %s (%v)", age, err)
+	}
+}
+
+func TestFormatStale(t *testing.T) {
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.Local)
+	task := &Task{Description: "old", CreatedAt: now.AddDate(0, 0, -20)}
+	opts := formatOptions{now: now, staleAfter: defaultStaleAfter}
+	if line := formatTask(0, task, opts); line != "0 - old (stale)" {
+		t.Fatalf("Expected the task marked, got '%s'", line)
+	}
+	// The clock decides, not the time the test runs.
+	opts.now = now.AddDate(0, 0, -10)
+	if line := formatTask(0, task, opts); line != "0 - old" {
+		t.Fatalf("Expected the task not stale yet, got '%s'", line)
+	}
+	opts.now, opts.plain = now, true
+	if line := formatTask(0, task, opts); line != "0 - old" {
+		t.Fatalf("Expected plain output unmarked, got '%s'", line)
+	}
+}
+
+func TestCliStale(t *testing.T) {
+	withCliSetup(t, func() {
+		created := func(days int) string {
+			return time.Now().AddDate(0, 0, -days).UTC().Format(time.RFC3339)
+		}
+		ioutil.WriteFile("/tmp/tasks", []byte("fresh | created:"+created(3)+"\nold | created:"+created(20)+"\nlegacy\nancient | created:"+created(70)+"\n"), 0644)
+		if out, _ := exec.Command(tBinary).Output(); string(out) != "0 - fresh\n1 - old (stale)\n2 - legacy\n3 - ancient (stale)\n" {
+			t.Fatalf("Expected the old tasks marked, got '%s'", out)
+		}
+		if out, _ := exec.Command(tBinary, "--stale").Output(); string(out) != "3 - ancient (10w)\n1 - old (2w)\n" {
+			t.Fatalf("Expected the stale tasks oldest first, got '%s'", out)
+		}
+		if out, _ := exec.Command(tBinary, "--stale", "60").Output(); string(out) != "3 - ancient (10w)\n" {
+			t.Fatalf("Expected the tasks over 60 days, got '%s'", out)
+		}
+		if out, _ := exec.Command(tBinary, "--stale-after", "30d").Output(); string(out) != "0 - fresh\n1 - old\n2 - legacy\n3 - ancient (stale)\n" {
+			t.Fatalf("Expected --stale-after kept, got '%s'", out)
+		}
+		if err := exec.Command(tBinary, "--stale-after", "soon").Run(); err == nil {
+			t.Fatal("Expected --stale-after soon refused")
+		}
+		dir := t.TempDir()
+		os.Mkdir(filepath.Join(dir, "t"), 0700)
+		ioutil.WriteFile(filepath.Join(dir, "t", "config"), []byte("stale_after = 2\n"), 0644)
+		cmd := exec.Command(tBinary, "--stale")
+		cmd.Env = append(os.Environ(), "XDG_CONFIG_HOME="+dir)
+		if out, _ := cmd.Output(); string(out) != "3 - ancient (10w)\n1 - old (2w)\n0 - fresh (3d)\n" {
+			t.Fatalf("Expected stale_after in the config kept, got '%s'", out)
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+// statusVersion is the version of the status file's format. It goes up
+// whenever a field changes meaning or goes away.
+const statusVersion = 1
+
+// statusFilePath is where a summary of the list is written after every
+// change, from the status_file setting. Empty means nowhere.
+var statusFilePath string
+
+// status is the summary of the list in the status file, for dashboards.
+type status struct {
+	Version       int         `json:"version"`
+	Updated       time.Time   `json:"updated"`
+	Open          int         `json:"open"`
+	Overdue       int         `json:"overdue"`
+	NextDue       *statusTask `json:"next_due"`
+	LastCompleted *time.Time  `json:"last_completed"`
+}
+
+// statusTask is a task in the status file.
+type statusTask struct {
+	Id          int    `json:"id"`
+	Description string `json:"description"`
+	Due         string `json:"due"`
+}
+
+// buildStatus summarizes the list at now. lastCompleted is when a task
+// was last finished, nil if never.
+func buildStatus(t *TaskList, now time.Time, lastCompleted *time.Time) status {
+	s := status{
+		Version:       statusVersion,
+		Updated:       now.UTC().Truncate(time.Second),
+		Open:          len(t.Tasks),
+		Overdue:       len(t.Overdue(now)),
+		LastCompleted: lastCompleted,
+	}
+	next := -1
+	for _, taskId := range t.Search("") {
+		task := t.Tasks[taskId]
+		if !task.DueAt.IsZero() && (next == -1 || task.DueAt.Before(t.Tasks[next].DueAt)) {
+			next = taskId
+		}
+	}
+	if next != -1 {
+		task := t.Tasks[next]
+		s.NextDue = &statusTask{Id: displayId(next), Description: task.Description, Due: task.DueAt.Format(dateLayout)}
+	}
+	return s
+}
+
+// lastCompletion returns when a task was last finished: now if the list
+// was just written with finished tasks, otherwise what the previous
+// status file says, or the last task in the done file.
+func lastCompletion(t *TaskList, now time.Time) *time.Time {
+	if len(t.Finished) > 0 {
+		completed := now.UTC().Truncate(time.Second)
+		return &completed
+	}
+	var previous status
+	if text, err := ioutil.ReadFile(statusFilePath); err == nil && json.Unmarshal(text, &previous) == nil {
+		return previous.LastCompleted
+	}
+	done, err := readDoneList(donePath(taskFilePath))
+	if err != nil || len(done.Tasks) == 0 || done.Tasks[len(done.Tasks)-1].DoneAt.IsZero() {
+		return nil
+	}
+	completed := done.Tasks[len(done.Tasks)-1].DoneAt.UTC()
+	return &completed
+}
+
+// recordStatus writes the status file after a write of the list, in one
+// step so that a dashboard never reads half of it. Like the undo
+// history, failing to do so is only reported.
+func recordStatus(t *TaskList) {
+	if statusFilePath == "" {
+		return
+	}
+	now := time.Now()
+	text, _ := json.Marshal(buildStatus(t, now, lastCompletion(t, now)))
+	err := os.MkdirAll(filepath.Dir(statusFilePath), 0700)
+	if err == nil {
+		err = core.WriteAtomic(statusFilePath, append(text, '\n'))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: can't write status file: %v\n", err)
+	}
+}
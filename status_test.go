@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+func TestBuildStatus(t *testing.T) {
+	now := time.Date(2024, 6, 10, 8, 0, 0, 0, time.UTC)
+	completed := time.Date(2024, 6, 9, 17, 30, 0, 0, time.UTC)
+	tasklist := &TaskList{TaskList: core.TaskList{Tasks: []*Task{
+		{Description: "no due date"},
+		{Description: "pay rent", DueAt: time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)},
+		{Description: "file taxes", DueAt: time.Date(2024, 6, 15, 0, 0, 0, 0, time.Local)},
+	}}}
+	text, _ := json.Marshal(buildStatus(tasklist, now, &completed))
+	expected := `{"version":1,"updated":"2024-06-10T08:00:00Z","open":3,"overdue":1,` +
+		`"next_due":{"id":1,"description":"pay rent","due":"2024-06-01"},"last_completed":"2024-06-09T17:30:00Z"}`
+	if string(text) != expected {
+		t.Fatalf("Expected %s, got %s", expected, text)
+	}
+	text, _ = json.Marshal(buildStatus(&TaskList{}, now, nil))
+	expected = `{"version":1,"updated":"2024-06-10T08:00:00Z","open":0,"overdue":0,"next_due":null,"last_completed":null}`
+	if string(text) != expected {
+		t.Fatalf("Expected %s, got %s", expected, text)
+	}
+}
+
+func TestCliStatusFile(t *testing.T) {
+	withCliSetup(t, func() {
+		configHome := t.TempDir()
+		statusFile := filepath.Join(configHome, "cache", "status.json")
+		os.MkdirAll(filepath.Join(configHome, "t"), 0700)
+		ioutil.WriteFile(filepath.Join(configHome, "t", "config"), []byte("status_file = "+statusFile), 0644)
+		env := append(os.Environ(), "XDG_CONFIG_HOME="+configHome)
+		run := func(args ...string) {
+			cmd := exec.Command(tBinary, args...)
+			cmd.Env = env
+			if err := cmd.Run(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		run("foo")
+		run("bar")
+		run("-f", "0")
+		var s status
+		text, _ := ioutil.ReadFile(statusFile)
+		if err := json.Unmarshal(text, &s); err != nil {
+			t.Fatal(err)
+		}
+		if s.Version != statusVersion || s.Open != 1 || s.LastCompleted == nil {
+			t.Fatalf("Expected one open task and a completion, got %s", text)
+		}
+		run("baz")
+		text, _ = ioutil.ReadFile(statusFile)
+		json.Unmarshal(text, &s)
+		if s.Open != 2 || s.LastCompleted == nil {
+			t.Fatalf("Expected the last completion to be kept, got %s", text)
+		}
+	})
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// addLines adds a task for each line read from r that isn't blank, and
+// returns how many it added. Lines may be of any length, the last one
+// need not end in a newline, and a line that can't be added stops it
+// at that line.
+func (t *TaskList) addLines(r io.Reader) (int, error) {
+	reader := bufio.NewReader(r)
+	added := 0
+	for n := 1; ; n++ {
+		line, err := reader.ReadString('\n')
+		if description := strings.TrimSpace(line); description != "" {
+			if _, err := t.Add(description); err != nil {
+				return added, fmt.Errorf("line %d: %v", n, err)
+			}
+			added++
+		}
+		if err == io.EOF {
+			return added, nil
+		}
+		if err != nil {
+			return added, err
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+func TestAddLines(t *testing.T) {
+	tasklist := TaskList{}
+	long := strings.Repeat("x", 9000)
+	added, err := tasklist.addLines(strings.NewReader("first\n\n   \t\n  second  \r\n" + long + "\nlast"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "first second " + long + " last"; added != 4 || joinDescriptions(tasklist.Tasks) != expected {
+		t.Fatalf("Expected 4 tasks, got %d: %v", added, joinDescriptions(tasklist.Tasks))
+	}
+	_, err = tasklist.addLines(strings.NewReader("fine\n" + strings.Repeat("x", core.DescriptionLimit+1)))
+	if err == nil || !strings.HasPrefix(err.Error(), "line 2:") {
+		t.Fatalf("Expected a too long line to fail with its number, got %v", err)
+	}
+}
+
+func TestCliStdin(t *testing.T) {
+	withCliSetup(t, func() {
+		cmd := exec.Command(tBinary, "--stdin")
+		cmd.Stdin = strings.NewReader("call mom\n\nbuy milk\n")
+		out, err := cmd.Output()
+		if err != nil || string(out) != "added 2 tasks\n" {
+			t.Fatalf("Expected output to be 'added 2 tasks\n', got '%s' (%v)", out, err)
+		}
+		out, _ = exec.Command(tBinary).Output()
+		if string(out) != "0 - call mom\n1 - buy milk\n" {
+			t.Fatalf("Expected output to be '0 - call mom\n1 - buy milk\n', got '%s'", out)
+		}
+	})
+}
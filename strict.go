@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// strictMode is set with --strict or T_STRICT=1, for scripts: ids are
+// only taken exactly, t never asks anything, and a command that changes
+// the list prints nothing on stdout but the data it was asked for.
+var strictMode bool
+
+// strictSetting reports whether strict mode is on, from the flag or the
+// environment.
+func strictSetting(flagSet bool) bool {
+	return flagSet || os.Getenv("T_STRICT") == "1"
+}
+
+// stableIdLength is the length of a stable id written out in full,
+// the only form of one strict mode takes.
+const stableIdLength = 40
+
+// resolveStrictId turns a task id into the task's index the way strict
+// mode takes it: a number, or a stable id in full.
+func (t *TaskList) resolveStrictId(s string) (int, error) {
+	if taskId, err := parseId(s); err == nil {
+		return taskId, nil
+	}
+	if len(s) == stableIdLength && isIdPrefix(s) {
+		return t.resolvePrefix(s)
+	}
+	return -1, fmt.Errorf("invalid task id %q, --strict only takes numbers and full stable ids", s)
+}
+
+// refusePrompt ends a strict run that would have to ask question. By
+// then nothing is written yet.
+func refusePrompt(question string) {
+	fmt.Fprintf(os.Stderr, "not asking %q with --strict, -y answers yes\n", question)
+	os.Exit(1)
+}
+
+// strictDataFlags are the flags whose output is the data asked for even
+// though they change the list.
+var strictDataFlags = map[string]bool{"json-in": true}
+
+// quietStdout sends what a strict run that changes the list would print
+// to stdout to stderr instead, unless it was asked for.
+func quietStdout() {
+	requested := false
+	for name := range strictDataFlags {
+		if flagPassed(name) {
+			requested = true
+		}
+	}
+	if !requested {
+		os.Stdout = os.Stderr
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+func TestResolveStrictId(t *testing.T) {
+	tasklist := &TaskList{}
+	tasklist.Add("pay rent")
+	tasklist.Add("call bob")
+	id := core.Hash("call bob")
+	if taskId, err := tasklist.resolveStrictId("1"); err != nil || taskId != 1 {
+		t.Fatalf("Expected a number to be taken, got %d (%v)", taskId, err)
+	}
+	if taskId, err := tasklist.resolveStrictId(id); err != nil || taskId != 1 {
+		t.Fatalf("Expected a full stable id to be taken, got %d (%v)", taskId, err)
+	}
+	for _, s := range []string{"last", "first", "oldest", id[:6], "bob"} {
+		if _, err := tasklist.resolveStrictId(s); err == nil {
+			t.Errorf("Expected %q to be refused", s)
+		}
+	}
+}
+
+// strictRun runs t with args and env, returning stdout, stderr and the
+// exit status.
+func strictRun(env []string, args ...string) (string, string, int) {
+	cmd := exec.Command(tBinary, args...)
+	cmd.Env = append(os.Environ(), env...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	status := 0
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		status = exitErr.ExitCode()
+	}
+	return stdout.String(), stderr.String(), status
+}
+
+func TestCliStrict(t *testing.T) {
+	cases := []struct {
+		name   string
+		args   []string
+		stdout string
+		status int
+		tasks  string
+	}{
+		{"list", []string{"--strict"}, "0 - pay rent\n1 - call bob\n", 0, "pay rent\ncall bob"},
+		{"add", []string{"--strict", "buy milk"}, "", 0, "pay rent\ncall bob\nbuy milk"},
+		{"no dup", []string{"--strict", "--no-dup", "pay rent"}, "", 0, "pay rent\ncall bob"},
+		{"finish", []string{"--strict", "-f", "1"}, "", 0, "pay rent"},
+		{"finish keyword", []string{"--strict", "-f", "last"}, "", 2, "pay rent\ncall bob"},
+		{"finish prefix", []string{"--strict", "-f", core.Hash("call bob")[:6]}, "", 2, "pay rent\ncall bob"},
+		{"finish text", []string{"--strict", "-f", "bob"}, "", 2, "pay rent\ncall bob"},
+		{"finish full id", []string{"--strict", "-f", core.Hash("call bob")}, "", 0, "pay rent"},
+		{"edit keyword", []string{"--strict", "-e", "last", "call alice"}, "", 2, "pay rent\ncall bob"},
+		{"finish matching", []string{"--strict", "-f", "--match", "bob"}, "", 1, "pay rent\ncall bob"},
+		{"finish matching -y", []string{"--strict", "-y", "-f", "--match", "bob"}, "", 0, "pay rent"},
+		{"clear", []string{"--strict", "--clear"}, "", 1, "pay rent\ncall bob"},
+		{"environment", []string{"-f", "last"}, "", 2, "pay rent\ncall bob"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			withCliSetup(t, func() {
+				ioutil.WriteFile("/tmp/tasks", []byte("pay rent\ncall bob"), 0644)
+				var env []string
+				if c.name == "environment" {
+					env = []string{"T_STRICT=1"}
+				}
+				stdout, stderr, status := strictRun(env, c.args...)
+				if stdout != c.stdout || status != c.status {
+					t.Fatalf("Expected '%s' and %d, got '%s' and %d (%s)", c.stdout, c.status, stdout, status, stderr)
+				}
+				list, _ := readTaskList("/tmp/tasks")
+				descriptions := make([]string, 0)
+				for _, task := range list.Tasks {
+					descriptions = append(descriptions, task.Description)
+				}
+				if strings.Join(descriptions, "\n") != c.tasks {
+					t.Fatalf("Expected the tasks '%s', got %q", c.tasks, descriptions)
+				}
+			})
+		})
+	}
+}
+
+func TestCliStrictKeepsRequestedData(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("pay rent"), 0644)
+		cmd := exec.Command(tBinary, "--strict", "--json-in")
+		cmd.Stdin = strings.NewReader(`[{"op": "finish", "id": 0}]`)
+		out, err := cmd.Output()
+		if err != nil || !strings.Contains(string(out), "pay rent") {
+			t.Fatalf("Expected the results on stdout, got '%s' (%v)", out, err)
+		}
+	})
+}
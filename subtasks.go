@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// subtaskIndent is what subtasks are indented with under their task in
+// listings.
+const subtaskIndent = "  "
+
+// AddChild adds a subtask to the task with the given id, made the way
+// Add makes a task.
+func (t *TaskList) AddChild(taskId int, description string) (*Task, error) {
+	parent, err := t.Get(taskId)
+	if err != nil {
+		return nil, err
+	}
+	child, err := t.NewTask(description)
+	if err != nil {
+		return nil, err
+	}
+	parent.Children = append(parent.Children, child)
+	return child, nil
+}
+
+// FinishChild takes the nth subtask off the task with the given id and
+// returns it, for the done file.
+func (t *TaskList) FinishChild(taskId, n int) (*Task, error) {
+	parent, err := t.Get(taskId)
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 || len(parent.Children) <= n {
+		return nil, fmt.Errorf("no subtask for id %d.%d", displayId(taskId), displayId(n))
+	}
+	child := parent.Children[n]
+	parent.Children = append(append([]*Task(nil), parent.Children[:n]...), parent.Children[n+1:]...)
+	if len(parent.Children) == 0 {
+		parent.Children = nil
+	}
+	if t.Finished == nil {
+		t.Finished = make(map[string]bool)
+	}
+	t.Finished[child.Id] = true
+	return child, nil
+}
+
+// withChildren returns the tasks, each followed by its subtasks.
+func withChildren(tasks []*Task) []*Task {
+	all := make([]*Task, 0, len(tasks))
+	for _, task := range tasks {
+		all = append(all, task)
+		all = append(all, task.Children...)
+	}
+	return all
+}
+
+// formatChildren renders the subtasks of the task with the given id the
+// way they are listed under it, indented and numbered after it, like
+// 3.0 and 3.1.
+func formatChildren(taskId int, task *Task, opts formatOptions) []string {
+	if len(task.Children) == 0 {
+		return nil
+	}
+	id := taskLabel(taskId, task, opts)
+	if opts.width > len(subtaskIndent) {
+		opts.width -= len(subtaskIndent)
+	}
+	lines := make([]string, 0, len(task.Children))
+	for n, child := range task.Children {
+		lines = append(lines, subtaskIndent+formatTaskId(fmt.Sprintf("%s.%d", id, displayId(n)), child, opts))
+	}
+	return lines
+}
+
+// isChildId reports whether s names a subtask, a task id and the
+// subtask's number separated by a dot, like 3.1.
+func isChildId(s string) bool {
+	i := strings.LastIndex(s, ".")
+	if i < 1 || i == len(s)-1 {
+		return false
+	}
+	_, err := strconv.Atoi(s[i+1:])
+	return err == nil
+}
+
+// resolveChildId turns a subtask id given on the command line into the
+// index of its task and its own index under it. The task's part is
+// resolved like any task id.
+func (t *TaskList) resolveChildId(s string) (int, int, error) {
+	if !isChildId(s) {
+		return -1, -1, fmt.Errorf("invalid subtask id %q", s)
+	}
+	i := strings.LastIndex(s, ".")
+	taskId, err := t.resolveId(s[:i])
+	if err != nil {
+		return -1, -1, err
+	}
+	if _, err := t.Get(taskId); err != nil {
+		return -1, -1, err
+	}
+	n, _ := parseId(s[i+1:])
+	return taskId, n, nil
+}
+
+// addSubtask handles --sub: it adds the words of args as a subtask of
+// the task target names.
+func addSubtask(target string, args []string) error {
+	taskId, err := tasklist.resolveId(target)
+	if err != nil {
+		return err
+	}
+	description := strings.Join(args, " ")
+	if strings.TrimSpace(description) == "" {
+		return fmt.Errorf("Usage: t --sub <id> <text>")
+	}
+	child, err := tasklist.AddChild(taskId, description)
+	if err != nil {
+		return err
+	}
+	if err := tasklist.write(true); err != nil {
+		return err
+	}
+	parent := tasklist.Tasks[taskId]
+	confirmations.added(fmt.Sprintf("%d.%d", displayId(taskId), displayId(len(parent.Children)-1)), child)
+	return nil
+}
+
+// finishSubtask handles t -f with a subtask id: it finishes the subtask
+// and records it in the done file.
+func finishSubtask(target string) error {
+	limit, err := doneLimit()
+	if err != nil {
+		return err
+	}
+	taskId, n, err := tasklist.resolveChildId(target)
+	if err != nil {
+		return err
+	}
+	child, err := tasklist.FinishChild(taskId, n)
+	if err != nil {
+		return err
+	}
+	if err := tasklist.writeDone([]*Task{child}, time.Now(), limit); err != nil {
+		return err
+	}
+	confirmations.finished(child)
+	return nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"testing"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+// subtaskList is a list with two subtasks under its first task.
+func subtaskList() *TaskList {
+	list := &TaskList{}
+	list.Add("ship the release")
+	list.Add("pay rent")
+	list.AddChild(0, "write tests")
+	list.AddChild(0, "tag the commit")
+	return list
+}
+
+func TestListSubtasks(t *testing.T) {
+	list := subtaskList()
+	if got := strings.Join(list.listing(), "\n"); got != "0 - ship the release\n  0.0 - write tests\n  0.1 - tag the commit\n1 - pay rent" {
+		t.Fatalf("Expected the subtasks indented under their task, got '%s'", got)
+	}
+	if !isChildId("0.1") || isChildId("0") || isChildId(".1") || isChildId("0.") || isChildId("0.x") {
+		t.Fatal("Expected only dotted ids to name subtasks")
+	}
+	if _, _, err := list.resolveChildId("2.0"); err == nil {
+		t.Fatal("Expected a subtask of a missing task refused")
+	}
+	if taskId, n, err := list.resolveChildId("0.1"); err != nil || taskId != 0 || n != 1 {
+		t.Fatalf("Expected 0.1 to be the second subtask of task 0, got %d.%d (%v)", taskId, n, err)
+	}
+}
+
+func TestFinishSubtaskFirst(t *testing.T) {
+	list := subtaskList()
+	if _, err := list.FinishAll([]int{0}); err == nil || !strings.Contains(err.Error(), "2 open subtasks") {
+		t.Fatalf("Expected a task with open subtasks refused, got %v", err)
+	}
+	if len(list.Tasks) != 2 {
+		t.Fatal("Expected nothing finished")
+	}
+	if _, err := list.FinishChild(0, 2); err == nil {
+		t.Fatal("Expected a missing subtask refused")
+	}
+	for _, n := range []int{1, 0} {
+		if _, err := list.FinishChild(0, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	finished, err := list.FinishAll([]int{0})
+	if err != nil || joinDescriptions(finished) != "ship the release" || joinDescriptions(list.Tasks) != "pay rent" {
+		t.Fatalf("Expected the task finished once its subtasks were, got %v (%v)", finished, err)
+	}
+}
+
+func TestFinishParentFirst(t *testing.T) {
+	list := subtaskList()
+	list.cascade = true
+	finished, err := list.FinishAll([]int{0})
+	if err != nil || joinDescriptions(withChildren(finished)) != "ship the release write tests tag the commit" || joinDescriptions(list.Tasks) != "pay rent" {
+		t.Fatalf("Expected the subtasks finished with their task, got %v (%v)", finished, err)
+	}
+
+	list = subtaskList()
+	list.KeepChildren = true
+	finished, err = list.FinishAll([]int{0})
+	if err != nil || joinDescriptions(withChildren(finished)) != "ship the release" {
+		t.Fatalf("Expected the task finished alone, got %v (%v)", finished, err)
+	}
+	if joinDescriptions(list.Tasks) != "write tests tag the commit pay rent" {
+		t.Fatalf("Expected the subtasks on the list in its place, got %s", joinDescriptions(list.Tasks))
+	}
+}
+
+func TestCliSubtasks(t *testing.T) {
+	withCliSetup(t, func() {
+		exec.Command(tBinary, "ship the release").Run()
+		exec.Command(tBinary, "pay rent").Run()
+		if err := exec.Command(tBinary, "--sub", "0", "write tests").Run(); err != nil {
+			t.Fatal(err)
+		}
+		exec.Command(tBinary, "--sub", "0", "tag the commit").Run()
+		if err := exec.Command(tBinary, "--sub", "0").Run(); err == nil {
+			t.Fatal("Expected --sub without a description to fail")
+		}
+		out, _ := exec.Command(tBinary).Output()
+		if string(out) != "0 - ship the release\n  0.0 - write tests\n  0.1 - tag the commit\n1 - pay rent\n" {
+			t.Fatalf("Expected the subtasks listed, got '%s'", out)
+		}
+		if out, err := exec.Command(tBinary, "-f", "0.1").CombinedOutput(); err != nil {
+			t.Fatalf("Expected the subtask finished, got '%s' (%v)", out, err)
+		}
+		if out, err := exec.Command(tBinary, "-f", "0").CombinedOutput(); err == nil || !strings.Contains(string(out), "--cascade") {
+			t.Fatalf("Expected the task refused while it has a subtask, got '%s'", out)
+		}
+		if out, err := exec.Command(tBinary, "-f", "0", "--cascade").CombinedOutput(); err != nil {
+			t.Fatalf("Expected the task finished with its subtask, got '%s' (%v)", out, err)
+		}
+		if out, _ := exec.Command(tBinary).Output(); string(out) != "0 - pay rent\n" {
+			t.Fatalf("Expected only the other task left, got '%s'", out)
+		}
+		text, _ := ioutil.ReadFile("/tmp/tasks.done")
+		done := strings.Split(string(text), "\n")
+		if len(done) != 3 || !strings.HasPrefix(done[0], "tag the commit | ") || !strings.HasPrefix(done[1], "ship the release | ") ||
+			!strings.HasPrefix(done[2], "write tests | ") || strings.Contains(string(text), core.ParentKey) {
+			t.Fatalf("Expected the three tasks in the done file, got '%s'", text)
+		}
+	})
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Count returns the number of open tasks as t lists them, which leaves
+// out the snoozed ones.
+func (t *TaskList) Count() int {
+	return len(t.Search(""))
+}
+
+// doneToday returns the number of tasks in the done file at path that
+// were finished today and match pattern. A missing done file has none.
+func doneToday(path string, pattern string, isRegexp bool, now time.Time) (int, error) {
+	today := startOfDay(now)
+	done, err := readDoneSince(path, today, now)
+	if err != nil {
+		return 0, err
+	}
+	ids, err := done.searchIds(pattern, isRegexp)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, taskId := range ids {
+		if !done.Tasks[taskId].DoneAt.Before(today) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// formatSummary renders what --summary prints, like "7 open, 3 done
+// today".
+func formatSummary(open int, done int) string {
+	return fmt.Sprintf("%d open, %d done today", open, done)
+}
+
+// defaultPromptFormat is what --prompt prints without prompt_format in
+// the config, like "3t 60%".
+const defaultPromptFormat = "{open}t {percent}"
+
+// doneTodayCache is the count of tasks finished today kept next to the
+// done file, like tasks.done.today, so that --prompt needn't read the
+// whole done file each time. It holds for as long as the day and the
+// done file's size and modification time are the same.
+type doneTodayCache struct {
+	Day     string `json:"day"`
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mod_time"`
+	Done    int    `json:"done"`
+}
+
+// cachedDoneToday returns the number of tasks in the done file at path
+// finished today, from its cache if the done file didn't change since
+// the cache was written, and otherwise by reading it and updating the
+// cache. Failing to write the cache only makes the next call slower.
+func cachedDoneToday(path string, now time.Time) (int, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	current := doneTodayCache{Day: now.Format(dateLayout), Size: info.Size(), ModTime: info.ModTime().UnixNano()}
+	var cached doneTodayCache
+	if text, err := ioutil.ReadFile(path + ".today"); err == nil && json.Unmarshal(text, &cached) == nil {
+		if cached.Day == current.Day && cached.Size == current.Size && cached.ModTime == current.ModTime {
+			return cached.Done, nil
+		}
+	}
+	if current.Done, err = doneToday(path, "", false, now); err != nil {
+		return 0, err
+	}
+	if text, err := json.Marshal(current); err == nil {
+		ioutil.WriteFile(path+".today", text, 0600)
+	}
+	return current.Done, nil
+}
+
+// formatPrompt renders what --prompt prints from format, where {open}
+// is the number of open tasks, {done} that of the tasks finished today
+// and {percent} the share of the tasks of today that are done, like
+// 60%, or nothing if none was finished today. Spaces around it are
+// trimmed, so that a prompt doesn't end in a space.
+func formatPrompt(format string, open int, done int) string {
+	percent := ""
+	if done > 0 {
+		percent = strconv.Itoa(done*100/(done+open)) + "%"
+	}
+	replacer := strings.NewReplacer("{open}", strconv.Itoa(open), "{done}", strconv.Itoa(done), "{percent}", percent)
+	return strings.TrimSpace(replacer.Replace(format))
+}
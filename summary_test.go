@@ -0,0 +1,101 @@
+package main
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCount(t *testing.T) {
+	tasklist := TaskList{}
+	tasklist.Add("foo")
+	snoozed, _ := tasklist.Add("bar")
+	snoozed.SnoozedUntil = time.Now().AddDate(0, 0, 1)
+	if n := tasklist.Count(); n != 1 {
+		t.Fatalf("Expected 1 open task, the other snoozed, got %d", n)
+	}
+}
+
+func TestCliCountAndSummary(t *testing.T) {
+	withCliSetup(t, func() {
+		expect := func(expected string, args ...string) {
+			if out, _ := exec.Command(tBinary, args...).Output(); string(out) != expected {
+				t.Fatalf("Expected t %v to print '%s', got '%s'", args, expected, out)
+			}
+		}
+		for _, description := range []string{"work: report", "work: review", "home: dishes", "home: laundry"} {
+			exec.Command(tBinary, description).Run()
+		}
+		expect("4\n", "--count")
+		expect("4 open, 0 done today\n", "--summary")
+		expect("2\n", "-g", "work", "--count")
+		exec.Command(tBinary, "-f", "0").Run()
+		exec.Command(tBinary, "-f", "2").Run()
+		expect("2 open, 2 done today\n", "--summary")
+		expect("1 open, 1 done today\n", "-g", "work", "--summary")
+		expect("0\n", "-l", "elsewhere", "--count")
+	})
+}
+
+func TestFormatPrompt(t *testing.T) {
+	cases := []struct {
+		format   string
+		open     int
+		done     int
+		expected string
+	}{
+		{defaultPromptFormat, 2, 3, "2t 60%"},
+		{defaultPromptFormat, 4, 0, "4t"},
+		{defaultPromptFormat, 0, 1, "0t 100%"},
+		{"{percent} done, {done}/{open}", 1, 1, "50% done, 1/1"},
+	}
+	for _, c := range cases {
+		if line := formatPrompt(c.format, c.open, c.done); line != c.expected {
+			t.Errorf("%q with %d open and %d done: expected %q, got %q", c.format, c.open, c.done, c.expected, line)
+		}
+	}
+}
+
+func TestCachedDoneToday(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.done")
+	now := time.Now()
+	if done, err := cachedDoneToday(path, now); done != 0 || err != nil {
+		t.Fatalf("Expected none done without a done file, got %d (%v)", done, err)
+	}
+	today := now.UTC().Truncate(time.Second).Format(time.RFC3339)
+	ioutil.WriteFile(path, []byte("a | done:2020-01-01T09:00:00Z\nb | done:"+today), 0644)
+	if done, err := cachedDoneToday(path, now); done != 1 || err != nil {
+		t.Fatalf("Expected 1 done today, got %d (%v)", done, err)
+	}
+	// A cache that matches the done file is believed without reading it.
+	text, _ := ioutil.ReadFile(path + ".today")
+	ioutil.WriteFile(path+".today", []byte(strings.Replace(string(text), `"done":1`, `"done":7`, 1)), 0600)
+	if done, _ := cachedDoneToday(path, now); done != 7 {
+		t.Fatalf("Expected the cached count, got %d", done)
+	}
+	ioutil.WriteFile(path, []byte("b | done:"+today+"\nc | done:"+today), 0644)
+	if done, _ := cachedDoneToday(path, now); done != 2 {
+		t.Fatalf("Expected a changed done file to be read again, got %d", done)
+	}
+	if done, _ := cachedDoneToday(path, now.AddDate(0, 0, 1)); done != 0 {
+		t.Fatalf("Expected the cache not to hold the next day, got %d", done)
+	}
+}
+
+func TestCliPrompt(t *testing.T) {
+	withCliSetup(t, func() {
+		for _, description := range []string{"report", "review", "dishes", "laundry", "+work call"} {
+			exec.Command(tBinary, description).Run()
+		}
+		exec.Command(tBinary, "-f", "0,1,2").Run()
+		if out, _ := exec.Command(tBinary, "--prompt").Output(); string(out) != "2t 60%" {
+			t.Fatalf("Expected '2t 60%%' without a newline, got '%s'", out)
+		}
+		if out, _ := exec.Command(tBinary, "--prompt", "-g", "+work").Output(); string(out) != "1t" {
+			t.Fatalf("Expected '1t' for +work, got '%s'", out)
+		}
+	})
+}
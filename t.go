@@ -6,88 +6,295 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
-	"os/user"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"text/template"
+	"time"
+
+	core "github.com/t-900/t/tasklist"
 )
 
-type Task struct {
-	description string
-}
+// formatVersion is the version of the tasks file format and of the
+// listings scripts read, plain and --json. It goes up with any change a
+// script could notice; the golden files in testdata/golden hold each as
+// it is at this version, and the tests refuse to update them otherwise.
+const formatVersion = 2
 
+// Task is a task of the list, kept in package tasklist along with the
+// tasks file format.
+type Task = core.Task
+
+// TaskList is the list the command line works on: the tasks file as
+// package tasklist reads it, and how commands should change it.
 type TaskList struct {
-	tasks []*Task
+	core.TaskList
+	// showHidden lists snoozed tasks and recurring ones not due yet
+	// along with the others, as --all does.
+	showHidden bool
+	// cascade lets FinishAll finish tasks with open subtasks, and the
+	// subtasks with them, as --cascade does. KeepChildren lets it finish
+	// them too, their subtasks becoming tasks of their own, as --force
+	// does.
+	cascade bool
 }
 
-func (t *TaskList) Add(taskDescription string) {
-	if t.tasks == nil {
-		t.tasks = make([]*Task, 0)
+// formatDetails renders everything known about a task, one labeled
+// line per field.
+func (t *TaskList) formatDetails(taskId int, task *Task) string {
+	details := fmt.Sprintf("id: %d\nstable id: %s\ndescription: %s\n", displayId(taskId), task.Id, lineBreaks.Replace(task.Description))
+	tags := make([]string, 0)
+	for _, word := range strings.Fields(task.Description) {
+		if isTag(word) {
+			tags = append(tags, word)
+		}
+	}
+	if len(tags) > 0 {
+		details += fmt.Sprintf("tags: %s\n", strings.Join(tags, " "))
+	}
+	if !task.CreatedAt.IsZero() {
+		details += fmt.Sprintf("created: %s\n", task.CreatedAt.Local().Format(time.RFC3339))
+	}
+	if !task.DueAt.IsZero() {
+		details += fmt.Sprintf("due: %s\n", displayDate(task.DueAt))
+	}
+	if task.RecurEvery > 0 {
+		details += fmt.Sprintf("every: %s\n", core.FormatEvery(task.RecurEvery))
+	}
+	if !task.SnoozedUntil.IsZero() {
+		details += fmt.Sprintf("snoozed until: %s\n", displayDate(task.SnoozedUntil))
+	}
+	if muted(task, time.Now()) {
+		details += fmt.Sprintf("muted until: %s\n", task.MutedUntil.Local().Format("2006-01-02 15:04"))
+	}
+	if task.Waiting {
+		details += fmt.Sprintf("waiting for: %s\n", task.WaitingFor)
+	}
+	if task.Priority != 0 {
+		details += fmt.Sprintf("priority: %d\n", task.Priority)
+	}
+	if task.Pomodoros > 0 {
+		details += fmt.Sprintf("pomodoros: %d\n", task.Pomodoros)
+	}
+	for _, path := range task.Attachments {
+		details += fmt.Sprintf("attachment: %s\n", path)
+	}
+	for _, id := range task.Links {
+		if i := t.IndexOf(id); i != -1 {
+			details += fmt.Sprintf("see also: %s\n", formatTask(i, t.Tasks[i], formatOptions{plain: true}))
+		}
 	}
-	task := Task{description: taskDescription}
-	t.tasks = append(t.tasks, &task)
+	for _, note := range task.Notes {
+		details += formatNote(note)
+	}
+	keys := make([]string, 0, len(task.Meta))
+	for key := range task.Meta {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		details += fmt.Sprintf("%s: %s\n", key, task.Meta[key])
+	}
+	return details
 }
 
-func (t *TaskList) List() []string {
-	tasks := make([]*Task, 0)
-	for _, t := range t.tasks {
-		tasks = append(tasks, t)
-	}
-	list := make([]string, 0)
-	for i, task := range t.tasks {
-		list = append(list, fmt.Sprintf("%d - %s", i, task.description))
+// foldSearch makes Search ignore accents too, as set by --fold.
+var foldSearch = false
+
+// Search returns the ids of all listed tasks whose description contains
+// the pattern, ignoring case, and accents with foldSearch.
+func (t *TaskList) Search(pattern string) []int {
+	ids := make([]int, 0)
+	pattern = core.Fold(pattern, foldSearch)
+	now := time.Now()
+	for i, task := range t.Tasks {
+		if (t.showHidden || !core.Snoozed(task, now)) && strings.Contains(core.Fold(searchText(task), foldSearch), pattern) {
+			ids = append(ids, i)
+		}
 	}
-	return list
+	return ids
 }
 
-func (t *TaskList) Finish(taskId int) error {
-	if t.tasks == nil {
-		return errors.New("No tasks found")
+// searchIds returns the ids of the listed tasks matching pattern, a
+// substring as for Search or, if isRegexp, a regular expression.
+func (t *TaskList) searchIds(pattern string, isRegexp bool) ([]int, error) {
+	if !isRegexp {
+		return t.Search(pattern), nil
 	}
-	if len(t.tasks) <= taskId {
-		return errors.New("No task for id found")
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %v", pattern, err)
 	}
-	newTasks := make([]*Task, 0)
-	for i, task := range t.tasks {
-		if i != taskId {
-			newTasks = append(newTasks, task)
+	ids := make([]int, 0)
+	now := time.Now()
+	for i, task := range t.Tasks {
+		if (t.showHidden || !core.Snoozed(task, now)) && re.MatchString(searchText(task)) {
+			ids = append(ids, i)
 		}
 	}
-	t.tasks = newTasks
-	return nil
+	return ids, nil
 }
 
-func (t *TaskList) Edit(taskId int, newDescription string) error {
-	if t.tasks == nil {
-		return errors.New("No tasks found")
+// Filter renders the listed tasks matching pattern like listing does,
+// each with its id in the whole list.
+func (t *TaskList) Filter(pattern string, isRegexp bool) ([]string, error) {
+	ids, err := t.searchIds(pattern, isRegexp)
+	if err != nil {
+		return nil, err
 	}
-	if len(t.tasks) <= taskId {
-		return errors.New("No task for id found")
+	list := make([]string, 0, len(ids))
+	opts := formatOptions{now: time.Now()}
+	for _, taskId := range ids {
+		list = append(list, formatTask(taskId, t.Tasks[taskId], opts))
 	}
-	t.tasks[taskId].description = newDescription
-	return nil
+	return list, nil
 }
 
-func (t *TaskList) MarshalText() ([]byte, error) {
+// listing renders the tasks List returns the way t lists them, each
+// followed by its subtasks.
+func (t *TaskList) listing() []string {
 	list := make([]string, 0)
-	for _, task := range t.tasks {
-		list = append(list, task.description)
+	opts := formatOptions{now: time.Now()}
+	for _, taskId := range t.List(opts.now) {
+		list = append(list, formatTask(taskId, t.Tasks[taskId], opts))
+		list = append(list, formatChildren(taskId, t.Tasks[taskId], opts)...)
 	}
-	return []byte(strings.Join(list, "\n")), nil
+	return list
+}
+
+// formatOptions control how tasks are rendered in listings.
+type formatOptions struct {
+	// plain output is meant for scripts: dates are always absolute.
+	plain bool
+	// age shows how long ago each task was created.
+	age bool
+	// color shows tasks in their tag's color from tagColors, overdue
+	// tasks in red, urgent ones in red and bold, tags in cyan and ids
+	// dimmed.
+	color     bool
+	tagColors map[string]string
+	// quote shell-quotes descriptions.
+	quote bool
+	// json lists tasks as a JSON array instead, for scripts.
+	json bool
+	// idPrefixes, if set, holds the stable id prefixes shown instead of
+	// numeric ids, for the tasks that have one.
+	idPrefixes map[*Task]string
+	// template, if set, renders each task instead, from --format.
+	template *template.Template
+	// width cuts lines longer than that many runes short, 0 for none.
+	width int
+	// staleAfter marks the tasks added more than that long ago (stale),
+	// but for plain output, 0 for none.
+	staleAfter time.Duration
+	now        time.Time
+}
+
+// shellQuote quotes s as a single word for POSIX shells. Within single
+// quotes nothing is special but the closing quote, so each quote in s
+// ends the quoting, is escaped and starts it again.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// lineBreaks shows the line breaks of a description as ⏎, so that
+// listings keep to one line per task.
+var lineBreaks = strings.NewReplacer("\r\n", "⏎", "\n", "⏎", "\r", "⏎")
+
+// formatTask renders a task the way it is shown in listings.
+func formatTask(taskId int, task *Task, opts formatOptions) string {
+	return formatTaskId(taskLabel(taskId, task, opts), task, opts)
 }
 
-func (t *TaskList) UnmarshalText(text []byte) error {
-	in := string(text)
-	list := strings.Split(in, "\n")
+// taskLabel returns the id a task is shown with: its stable id prefix
+// if opts has one for it, its numeric id otherwise.
+func taskLabel(taskId int, task *Task, opts formatOptions) string {
+	if prefix, ok := opts.idPrefixes[task]; ok {
+		return prefix
+	}
+	return strconv.Itoa(displayId(taskId))
+}
 
-	t.tasks = make([]*Task, 0)
-	for _, taskDescription := range list {
-		if taskDescription != "" {
-			task := Task{description: taskDescription}
-			t.tasks = append(t.tasks, &task)
+// formatTaskId renders a task like formatTask, shown with the given id.
+func formatTaskId(id string, task *Task, opts formatOptions) string {
+	description := lineBreaks.Replace(task.Description)
+	if opts.quote {
+		description = shellQuote(description)
+	}
+	if opts.template != nil {
+		return formatTemplate(id, task, opts)
+	}
+	base := ""
+	if opts.color {
+		base = lineColor(task, opts)
+		id = paint(colorDim, id, base)
+		description = paintTags(description, opts, base)
+	}
+	line := fmt.Sprintf("%s - %s", id, description)
+	if priority := formatPriority(task, opts.now); priority != "" {
+		line += fmt.Sprintf(" (%s)", priority)
+	}
+	if !task.DueAt.IsZero() {
+		if opts.plain {
+			line += fmt.Sprintf(" (due %s)", task.DueAt.Format(dateLayout))
+		} else {
+			line += fmt.Sprintf(" (due %s)", formatDue(task.DueAt, opts.now))
+		}
+	}
+	if opts.now.Before(task.SnoozedUntil) {
+		if opts.plain {
+			line += fmt.Sprintf(" (snoozed until %s)", task.SnoozedUntil.Format(dateLayout))
+		} else {
+			line += fmt.Sprintf(" (snoozed until %s)", formatWake(task.SnoozedUntil, opts.now))
+		}
+	}
+	if task.Waiting {
+		line += " " + formatWaiting(task, opts.plain)
+	} else if opts.age && !task.CreatedAt.IsZero() {
+		if opts.plain {
+			line += fmt.Sprintf(" (created %s)", task.CreatedAt.UTC().Format(time.RFC3339))
+		} else {
+			line += fmt.Sprintf(" (%s)", formatAge(opts.now.Sub(task.CreatedAt)))
 		}
 	}
+	if !opts.plain && opts.staleAfter > 0 && isStale(task, opts.now, opts.staleAfter) {
+		line += " (stale)"
+	}
+	if base != "" {
+		line = base + line + colorReset
+	}
+	return truncate(line, opts.width)
+}
+
+// Bump moves the due date of the given task the given number of days
+// forward, counting from today if the task has no due date yet.
+func (t *TaskList) Bump(taskId int, days int, now time.Time) error {
+	task, err := t.Get(taskId)
+	if err != nil {
+		return err
+	}
+	from := task.DueAt
+	if from.IsZero() {
+		from = startOfDay(now)
+	}
+	task.DueAt = from.AddDate(0, 0, days)
 	return nil
 }
 
+// Overdue returns the ids of all tasks due before today.
+func (t *TaskList) Overdue(now time.Time) []int {
+	ids := make([]int, 0)
+	for i, task := range t.Tasks {
+		if !task.DueAt.IsZero() && daysBetween(now, task.DueAt) < 0 {
+			ids = append(ids, i)
+		}
+	}
+	return ids
+}
+
 var tasklist *TaskList
 var taskFilePath string
 
@@ -99,10 +306,338 @@ List all tasks:
   t
 Add task:
   t "Buy milk"
-Edit a given task:
+Edit a given task, or without a description in $VISUAL or $EDITOR:
   t -e 0 "Buy two milk bottles"
-Finish a task:
+  t -e 0
+Add text to the end or the front of a task's description:
+  t --append 2 "before Friday"
+  t --prepend 2 "URGENT:"
+Finish a task or several, or pick the tasks to finish from a list (on a
+terminal):
   t -f 0
+  t -f 2,5 7
+  t -f
+  t -f +errands
+Say nothing about what was added, finished, edited or deleted, for scripts:
+  t -q "Buy milk"
+  t -q -f 0
+Delete a task that shouldn't have been there, without it going to the done
+file (also --delete):
+  t -d 0
+Delete every task, after confirming (-y skips it, and is needed when not
+on a terminal):
+  t --clear
+Drop the finished tasks older than a duration from the done file and its
+segments:
+  t --purge-done 8760h
+Move the tasks finished over 30 days (or --older-than) ago from the done file
+to monthly archives in ~/.t/archive (archive_dir in the config):
+  t --archive-done
+  t --archive-done --older-than 90d
+List finished tasks, optionally matching a search; --all-history also
+lists those rolled over into older segments of the done file (set it
+with T_DONE_FILE or --done-file):
+  t -D
+  t -D --all-history +errands
+Finish every task matching a search, after confirming (-y skips it):
+  t -f --match WONTFIX
+Finish or edit the one task containing a text, without looking up its id
+(if several do, they are listed and nothing changes):
+  t --finish-match dentist
+  t --edit-match dentist "Call the dentist at 3"
+Or find them roughly, despite typos and abbreviations:
+  t --fuzzy dntist
+  t --finish-match dntist --fuzzy
+Add a task with a due date (YYYY-MM-DD, today, tomorrow, +Nd or as set
+by dateformat in the config, like dateformat = 02.01.2006):
+  t --due tomorrow "Call the dentist"
+Add a recurring task: finishing it makes it due again that many days later,
+and listings hide it until then (--all shows it dimmed):
+  t --every 3d water plants
+Add a task before or after another one, or several tasks at once:
+  t --before 4 "Book the venue"
+  t --after 4 --multi "Send invites" "Order cake"
+A task of more than 25 arguments or 500 bytes, like from t * by mistake, is
+asked about on a terminal and refused elsewhere (-y adds it; set arg_limit
+and arg_bytes in the config, 0 for no limit).
+Move task 5 to the top of the list, the tasks in between shifting down:
+  t --move 5 0
+Push a task's due date forward, or those of all overdue tasks:
+  t --bump 0 +2d
+  t --bump overdue +1w
+Set the due date of every task matching a query, or of all tasks:
+  t --set-due +sprint12 2024-06-14
+  t --set-due --all +1w
+Remove duplicate tasks, keeping the oldest (--dry-run to preview, --exact
+to only match descriptions that are the same, case and all):
+  t --dedupe
+Don't add a task that is already on the list (no_dup = true in the config
+always does):
+  t --no-dup "Buy milk"
+List tasks sorted by alpha, due or priority, keeping their ids (-due reverses):
+  t --sort due
+List tasks with how long ago they were added, like 3d or 6w (also -v):
+  t --age
+List only the tasks added more than 30 days (or 2w, 36h...) ago, or without
+a value, the tasks marked (stale), those older than --stale-after (or
+stale_after in the config, 14d by default), oldest first:
+  t --stale 30
+  t --stale
+  t --stale-after 30d
+List only the tasks due within 7 days or overdue, soonest first:
+  t --due-soon
+Mark a task as waiting for someone, so it doesn't age; list the waiting tasks;
+take one off waiting (editing it does too):
+  t --waiting 3 "Bob's reply"
+  t --waiting
+  t --unwait 3
+List only the tasks added since a date or in the last 7 days (or 2w, 36h...);
+with --done, the tasks finished since then:
+  t --since 2024-05-01
+  t --since 7d --done
+Print what was finished in the last 7 days (or between two days) by project
+or tag, as Markdown for a status report:
+  t --changelog 7d
+  t --changelog --since 2024-06-01 --until 2024-06-07
+List at most n tasks, optionally in random order (--seed makes it repeatable):
+  t --shuffle -n 3
+Print only the number of open tasks, or a summary with those done today, for
+a shell prompt (with -g or -l for a part of them):
+  t --count
+  t -g work --summary
+Print only the ids of the open tasks, one per line, or a completion script for
+bash or zsh that completes flags and, after -f, -e or -d, task ids:
+  t --ids
+  source <(t --completion bash)
+Print a compact summary without a newline for a prompt, like 3t 60% for the
+share of today's tasks that are done (prompt_format in the config changes it):
+  t --prompt
+Reorder the tasks file itself (-y skips the confirmation):
+  t --sort due --save-order
+Add a task with a priority from 1 (highest) to 9, or change that of task 4;
+listings show the most important tasks first:
+  t -p 1 "Pay rent"
+  t -P 3 4
+Show the task to work on next, by priority (priority:1 to 9), then due date;
+with T_PRIORITY_AGING=14d, tasks rise a level every 14 days, up to 3 levels:
+  t --next
+Give the tasks added to a list tags and a priority: set tags and priority
+under [list.work] in the config, or under [defaults] for every list.
+Keep a JSON summary for dashboards up to date: set status_file in the config.
+Append a JSON line for every task added, edited, finished or deleted: set
+events_file in the config.
+Be reminded of overdue or old tasks on a terminal at most every 4 hours: set
+nag = 4h in the config (--no-nag skips it once).
+Commit every change to the git repository the tasks file is in (or set
+T_GIT=1), or commit it, pull with a rebase and push:
+  t --git "Buy milk"
+  t --sync
+Run a command after every change: set post_write_hook in the config; t run
+from it can only read tasks.
+Refuse to add a task over the wip_limit set in ~/.config/t/config:
+  t --strict-wip "One more thing"
+Number tasks from 1 instead of 0, in listings and in the ids given to t:
+  T_INDEX_BASE=1 t
+Check from scripts, silently, whether any task matches or none is left
+(exit 0 if so, 1 if not, 2 on errors):
+  t --has +urgent && alert
+  t --empty || remind-me
+In scripts, take ids only as numbers or full stable ids, fail instead of
+asking anything, and print only requested data on stdout (also T_STRICT=1;
+exit 0 on success, 1 on failure, 2 on a usage error):
+  t --strict -y -f --match WONTFIX
+Every task also has a stable id; any unique prefix of it works as its id,
+and ids = hash in the config lists tasks by the shortest one:
+  t -f a3
+Show long tasks whole rather than cut at the width of the terminal:
+  t --wide
+List tasks for scripts, with descriptions quoted for the shell, or as JSON:
+  t --plain --quote
+  t --json -g rent
+Or as tab-separated id, priority, created, due, tags and description, a
+format that never changes:
+  t --porcelain --tag work
+List each task with a Go text/template, with .ID, .Description, .Priority,
+.Due, .Created, .Age and .Tags, or a preset: simple, long or csv (format in
+the config, or under [list.work] for one list, or T_FORMAT sets one for every
+run):
+  t --format '{{.ID}}: {{.Description}}'
+  t --format csv
+Color listings on a terminal (urgent tasks in bold red, tags in cyan, ids
+dimmed), or always, like for less -R, or never:
+  t --color=always | less -R
+Move to another machine: pack the tasks file, the done file, the config,
+the schedule and the named lists, then restore them there (--force
+overwrites files that exist):
+  t --bundle t.tar.gz
+  t --unbundle t.tar.gz
+Use another tasks file than $XDG_DATA_HOME/t/tasks, or ~/tasks if it is
+there (~ and $VARS are expanded, also in T_TASKS_FILE):
+  t --file ~/sync/tasks
+Create a .tasks file for a project at the root of its git repository, then
+use it from anywhere in the project:
+  t --init
+  t --local "Fix the flaky test"
+Use a named list instead of the default tasks file:
+  t -l work "Buy a standing desk"
+  t --task-dir ~/sync/lists -l work
+List only tasks containing a pattern, in this list or in all named lists:
+  t -g deploy
+  t --all-lists -g deploy
+List only tasks matching a regular expression:
+  t -E -g '^(call|email) '
+Search ignoring accents, so cafe matches "Café com João":
+  t --fold -g cafe
+Finish or edit a task of another list, or by keyword (first, last, oldest):
+  t -f work/3
+  t -f last
+Change a list marked read-only (by a #readonly line or readonly.<list> = true):
+  t --force -f 0
+Print how long each phase took on stderr (or set T_TIMINGS=1):
+  t --timings
+Keep an added or edited task exactly as typed, tabs and all:
+  t --raw "Column	one"
+Shorten a task longer than description_limit (default 10240 bytes) to fit,
+instead of refusing it:
+  t --truncate "$(cat build.log)"
+Check the tasks file for problems (also --fsck), and repair what can be
+repaired, keeping the file as it was in a .corrupt file, or find the lines of
+the done file that can't be read and move them to a .corrupt file:
+  t --check
+  t --fsck --fix
+  t --check --done --fix
+Check the tasks file, the config and the environment, with hints for what fails:
+  t --doctor
+Undo the last changes one by one, redo them, or list them:
+  t --undo
+  t --redo
+  t --history
+Edit the tasks file in $VISUAL or $EDITOR (notepad or vi if neither is set):
+  t --edit-file
+Or edit the list as lines of id and description: change lines to edit tasks,
+remove them to finish them, add lines without an id and reorder them:
+  t --bulk
+Keep the list loaded for scripts and status bars that run t often:
+  t --serve
+Show several lists at once:
+  t -l work -l home
+Capture a task in the inbox list, then sort the inbox out interactively:
+  t --in "Random idea"
+  t --process
+Show everything about a task, or everything that happened to it:
+  t --show 0
+  t --log 0
+Attach key:value metadata of your own to a task (key: removes it); it is
+shown by --show, found by -g and kept when the task is edited:
+  t --meta 0 owner:alice ticket:PROJ-42
+Work on a task for one pomodoro (--pomodoro-length changes the 25m):
+  t --pomodoro 0
+Attach a file to a task and open it again later:
+  t --attach 0 ~/docs/spec.pdf
+  t --open-attachment 0
+Link two related tasks, shown as "see also" by --show:
+  t --link 0 1
+Hide a task until it can be worked on, for 3 days or until a date; --all
+lists it anyway, with the day it wakes:
+  t --snooze 0 3d
+  t --snooze 0 2024-06-03
+Add a note to a task, or edit its notes in $VISUAL or $EDITOR:
+  t --note 0 "the spec is at https://example.com/spec"
+  t --note 0
+Add a subtask under a task, listed as 3.0, 3.1...; finish it, or the task
+and its open subtasks (--force finishes the task alone, keeping them):
+  t --sub 3 "write tests"
+  t -f 3.1
+  t -f 3 --cascade
+Apply a batch of operations (add, finish, edit) all at once or not at all:
+  echo '[{"op":"add","description":"x"},{"op":"finish","id":3}]' | t --json-in
+List the +tags and @contexts in use, most used first (--done counts finished
+tasks too, --plain leaves out the counts), or the tasks with a tag (+home or
+#home, which count as the same):
+  t --tags
+  t --tag home
+Go through the tasks added more than 90 days ago and never touched since,
+keeping, finishing, deferring or deleting each, or finish them all:
+  t --prune 90d
+  t --prune 90d --yes finish
+Add a task for each line of a file or the output of a command:
+  cat brainstorm.txt | t --stdin
+Go through the tasks one at a time, the oldest first, with single keys:
+f finish, d delete, e edit, p priority, s skip, q quit:
+  t --triage
+Type commands at t, the list printed and written after each: a <text> adds,
+f <id> finishes, e <id> <text> edits, l lists, q quits:
+  t -i
+Notify about the tasks due today or overdue (from cron, say), and keep quiet
+about a task for two days, or again from now on:
+  t --notify
+  t --mute 3 2d
+  t --unmute 3
+Copy the description of a task to the clipboard (--show also prints it):
+  t --yank 3
+  t --yank 3 --show
+Show the tasks finished today and those added today, for the standup:
+  t --today
+Show when tasks get finished, by weekday and hour, or the counts as JSON:
+  t --heatmap
+  t --heatmap --json
+Write a standalone HTML report of the open tasks and those finished this week:
+  t --html > report.html
+Draw the tasks and their links with Graphviz:
+  t --graph | dot -Tpng > tasks.png
+Sum up the est: estimates (like est:1h30m) of each proj: project:
+  t --estimate-by project
+Plan the next 5 weekdays, up to plan_budget (default 4h) of est: estimates
+a day, with the top n tasks; --apply makes each task due on its day:
+  t --plan -n 10 --apply
+See whether the est: estimates of the tasks due by a date fit in
+daily_capacity (default plan_budget) a weekday until then:
+  t --capacity 2024-06-14
+Keep hidden tombstones of finished tasks for syncing (tombstones = true in
+the config), and drop those older than tombstone_window (default 30d):
+  t --vacuum
+Archive all tasks to a dated file, list the archives and bring one back:
+  t --archive
+  t --archives
+  t --import tasks.archive-2024-06-01
+Resume an import that stopped at a bad line, once the line is fixed:
+  t --import tasks.archive-2024-06-01 --resume-from 4182
+Import an Apple Reminders export (JSON or an XML property list), again later
+without duplicates:
+  t --import-reminders ~/Desktop/reminders.json
+Add a "read: <title> <url> +reading" task for each bookmark of a browser's
+HTML export, or of one folder of it, skipping URLs already in a task:
+  t --import-bookmarks bookmarks.html --folder "Read Later"
+Print the tasks in todo.txt syntax, priorities 1 to 9 as (A) to (I) and
+#tags as +projects, or add those of a todo.txt file (completed ones are left
+out, and lines that can't be read are skipped with a warning):
+  t --export-todotxt > todo.txt
+  t --import-todotxt todo.txt
+Move from Taskwarrior and back: pending tasks go to the list and completed
+ones to the done file, and the export has both:
+  t --import-tw export.json
+  t --export-tw > export.json
+Show how another tasks file differs (- only here, + only there, ~ changed),
+exiting with 1 if it does; --done compares the done files:
+  t --diff ~/sync/tasks
+Show what changed since the list was last shown this way, like when a shared
+list was synced (+ new, ~ changed, - gone):
+  t --changed
+Save the tasks under a name before a risky change, list the saved
+checkpoints and bring one back (saving the current tasks first):
+  t --checkpoint pre-cleanup
+  t --checkpoints
+  t --restore pre-cleanup
+The last 3 versions of the tasks file are kept as backups as well
+(T_BACKUPS sets how many, 0 none); bring one back after seeing how it
+differs (a checkpoint named by a number is checkpoint:<name>):
+  t --restore 2
+Add recurring tasks from ~/.config/t/schedule (lines like "mon Water plants",
+"1 Pay rent" or "daily Stretch"); t does this by itself on the default list:
+  t --inject
+Move or copy a task to another list:
+  t --move-to home 0
+  t --copy-to home 0
 `
 
 func usage() {
@@ -112,66 +647,1893 @@ func usage() {
 func main() {
 	flag.Usage = usage
 	var (
-		editTask   = flag.Int("e", -1, "edit the tasklist")
-		finishTask = flag.Int("f", -1, "finish task #")
+		editTask       = flag.String("e", "", "edit task #")
+		appendTo       = flag.String("append", "", "add the text to the end of task #")
+		prependTo      = flag.String("prepend", "", "add the text to the front of task #")
+		finishTask     = flag.String("f", "", "finish task #")
+		deleteTask     = flag.String("d", "", "delete task # without finishing it")
+		clearAll       = flag.Bool("clear", false, "delete every task, after confirming")
+		purgeDoneAge   = flag.String("purge-done", "", "drop the finished tasks older than this from the done file, like 720h or 90d")
+		archiveOld     = flag.Bool("archive-done", false, "move the tasks finished over 30 days ago from the done file to monthly archives")
+		olderThan      = flag.String("older-than", "", "with --archive-done, move the tasks finished longer ago than this, like 90d")
+		dueDate        = flag.String("due", "", "due date of the added task")
+		bumpTask       = flag.String("bump", "", "push due date of task # (or overdue) forward")
+		setDue         = flag.String("set-due", "", "set the due date of every task matching the query")
+		all            = flag.Bool("all", false, "with --set-due, change every task; in listings, show hidden tasks too")
+		dedupe         = flag.Bool("dedupe", false, "remove duplicate tasks")
+		dryRun         = flag.Bool("dry-run", false, "only show what would be changed")
+		fuzzy          = flag.Bool("fuzzy", false, "list the tasks roughly matching the words given, best first; with --finish-match, finish the clearly best match; with --dedupe, also treat near-identical tasks as duplicates")
+		exact          = flag.Bool("exact", false, "with --dedupe, only treat tasks with the same description, case and all, as duplicates")
+		noDup          = flag.Bool("no-dup", false, "don't add a task whose description is already on the list")
+		sortBy         = flag.String("sort", "", "list tasks sorted by age, alpha, due or priority (prefix - to reverse)")
+		saveOrder      = flag.Bool("save-order", false, "write the --sort order back to the tasks file")
+		yes            = flag.Bool("y", false, "don't ask for confirmation")
+		quiet          = flag.Bool("q", false, "don't confirm what was added, finished, edited or deleted, as for scripts")
+		lists          listFlag
+		moveTo         = flag.String("move-to", "", "move task # to the given list")
+		copyTo         = flag.String("copy-to", "", "copy task # to the given list")
+		grep           = flag.String("g", "", "only list tasks matching the pattern")
+		regexpSearch   = flag.Bool("E", false, "with -g, match a regular expression")
+		fold           = flag.Bool("fold", false, "ignore accents when searching, so cafe matches café")
+		allLists       = flag.Bool("all-lists", false, "list tasks of all named lists")
+		plain          = flag.Bool("plain", false, "plain output for scripts")
+		porcelain      = flag.Bool("porcelain", false, "list tasks as tab-separated fields that never change: id, priority, created, due, tags, description")
+		colorMode      = flag.String("color", "auto", "color listings: always, never or auto, on a terminal")
+		quote          = flag.Bool("quote", false, "shell-quote descriptions in listings")
+		wide           = flag.Bool("wide", false, "show long tasks whole instead of cutting them at the width of the terminal")
+		toInbox        = flag.Bool("in", false, "add the task to the inbox list")
+		process        = flag.Bool("process", false, "go through the inbox list")
+		showTask       = flag.String("show", "", "show all details of task #")
+		pomodoro       = flag.String("pomodoro", "", "run a pomodoro timer for task #")
+		pomodoroLength = flag.Duration("pomodoro-length", 25*time.Minute, "length of a pomodoro")
+		attach         = flag.String("attach", "", "attach a file to task #")
+		note           = flag.String("note", "", "add a note to task #, or without one edit its notes in $VISUAL or $EDITOR")
+		sub            = flag.String("sub", "", "add a subtask to task #, listed under it as <id>.0, <id>.1 and so on")
+		cascade        = flag.Bool("cascade", false, "with -f, also finish the open subtasks of the tasks")
+		snooze         = flag.String("snooze", "", "hide task # from listings until a date or for a number of days")
+		openAttachment = flag.String("open-attachment", "", "open the attachment of task #")
+		link           = flag.String("link", "", "link task # to another task")
+		estimateBy     = flag.String("estimate-by", "", "sum up est: estimates by project")
+		vacuum         = flag.Bool("vacuum", false, "drop the tombstones older than tombstone_window")
+		plan           = flag.Bool("plan", false, "plan the next 5 weekdays by est: estimates")
+		capacity       = flag.String("capacity", "", "sum up the est: estimates of the tasks due by a date against daily_capacity")
+		apply          = flag.Bool("apply", false, "with --plan, make each task due on its day")
+		has            = flag.String("has", "", "exit with 0 if a task matches, 1 if not, silently")
+		empty          = flag.Bool("empty", false, "exit with 0 if there are no tasks, 1 if there are, silently")
+		shuffle        = flag.Bool("shuffle", false, "list tasks in random order")
+		seed           = flag.Int64("seed", 0, "random seed for --shuffle")
+		limit          = flag.Int("n", 0, "list at most n tasks")
+		countOnly      = flag.Bool("count", false, "print only the number of open tasks")
+		idsOnly        = flag.Bool("ids", false, "print only the ids of the open tasks, one per line")
+		promptLine     = flag.Bool("prompt", false, "print a short summary for a shell prompt, like 3t 60%, without a newline")
+		summary        = flag.Bool("summary", false, "print the number of open tasks and of those done today")
+		showTimings    = flag.Bool("timings", false, "print how long loading, filtering, rendering and writing took on stderr")
+		raw            = flag.Bool("raw", false, "don't normalize whitespace in added or edited tasks")
+		truncate       = flag.Bool("truncate", false, "shorten added or edited descriptions over description_limit instead of refusing them")
+		check          = flag.Bool("check", false, "check the tasks file for problems: conflict markers, invalid UTF-8, control characters, duplicate ids, long lines")
+		fix            = flag.Bool("fix", false, "with --check or --fsck, repair what can be repaired safely, keeping the file as it was in a .corrupt file")
+		undo           = flag.Bool("undo", false, "undo the last change")
+		redo           = flag.Bool("redo", false, "redo the last undone change")
+		showHistory    = flag.Bool("history", false, "list the changes --undo can walk back")
+		showAge        = flag.Bool("age", false, "show how long ago each task was added")
+		archive        = flag.Bool("archive", false, "move all tasks to a dated archive file")
+		showArchives   = flag.Bool("archives", false, "list the archive files")
+		importFrom     = flag.String("import", "", "bring back the tasks of an archive file")
+		resumeFrom     = flag.Int("resume-from", 1, "with --import, start at this line of the archive")
+		checkpoint     = flag.String("checkpoint", "", "save the tasks file as it is now under a name")
+		checkpointList = flag.Bool("checkpoints", false, "list the saved checkpoints")
+		restore        = flag.String("restore", "", "bring back the tasks file of a checkpoint, or backup # after showing what changes")
+		diffWith       = flag.String("diff", "", "show how another tasks file differs from the list")
+		changedOnly    = flag.Bool("changed", false, "show only how the list changed since it was last shown with --changed")
+		importRemind   = flag.String("import-reminders", "", "import an Apple Reminders export, JSON or a property list")
+		importMarks    = flag.String("import-bookmarks", "", "add a task to read for each bookmark of a browser's HTML export")
+		folder         = flag.String("folder", "", "with --import-bookmarks, only import the bookmarks in this folder")
+		exportTodoTxt  = flag.Bool("export-todotxt", false, "print the tasks in todo.txt syntax")
+		importTodoTxt  = flag.String("import-todotxt", "", "add the tasks of a todo.txt file")
+		exportTw       = flag.Bool("export-tw", false, "print the tasks and the finished ones in Taskwarrior's JSON export format")
+		importTw       = flag.String("import-tw", "", "add the pending tasks of a Taskwarrior JSON export, and the completed ones to the done file")
+		fromStdin      = flag.Bool("stdin", false, "add a task for each line read from stdin")
+		triage         = flag.Bool("triage", false, "go through the tasks one at a time, the oldest first")
+		prune          = flag.String("prune", "", "go through the tasks added longer ago than this and never touched, like 90d")
+		notify         = flag.Bool("notify", false, "send a notification for each task due today or overdue")
+		mute           = flag.String("mute", "", "keep --notify quiet about task # for a while, like t --mute 3 2d")
+		waiting        = flag.String("waiting", "", "mark task # as waiting for someone, or list the waiting tasks")
+		unwait         = flag.String("unwait", "", "take task # off waiting")
+		unmute         = flag.String("unmute", "", "let --notify report task # again")
+		before         = flag.String("before", "", "add the task before task # instead of at the end")
+		after          = flag.String("after", "", "add the task after task # instead of at the end")
+		move           = flag.String("move", "", "move task # to another position, like t --move 5 0")
+		multi          = flag.Bool("multi", false, "add each argument as a task of its own")
+		addPriority    = flag.String("p", "", "add the task with a priority from 1 (highest) to 9")
+		every          = flag.String("every", "", "add a recurring task, due again that long after it's finished, e.g. 3d")
+		strict         = flag.Bool("strict", false, "for scripts: exact ids only, no questions, no chatter on stdout (also T_STRICT=1)")
+		newPriority    = flag.String("P", "", "change the priority of a task to 1 (highest) to 9")
+		setMeta        = flag.String("meta", "", "set key:value metadata of task #, like t --meta 3 owner:alice")
+		yank           = flag.String("yank", "", "copy the description of task # to the clipboard")
+		showHeatmap    = flag.Bool("heatmap", false, "show when tasks get finished, by weekday and hour")
+		showToday      = flag.Bool("today", false, "show the tasks finished and those added since midnight")
+		bulk           = flag.Bool("bulk", false, "edit the whole list in $VISUAL or $EDITOR: change, remove (finish) or add lines, or reorder them")
+		serve          = flag.Bool("serve", false, "keep the list loaded and answer t on a unix socket in $XDG_RUNTIME_DIR until interrupted")
+		useGit         = flag.Bool("git", false, "commit every change of the tasks file to the git work tree it is in (also T_GIT=1)")
+		syncGit        = flag.Bool("sync", false, "commit the tasks file, then git pull --rebase and push")
+		jsonOut        = flag.Bool("json", false, "list tasks as JSON, or the --heatmap counts or the --capacity check")
+		html           = flag.Bool("html", false, "print an HTML report of the open tasks and those finished this week")
+		inject         = flag.Bool("inject", false, "add the scheduled tasks that are due")
+		next           = flag.Bool("next", false, "show the task to work on next")
+		editTasksFile  = flag.Bool("edit-file", false, "open the tasks file in $VISUAL or $EDITOR")
+		graph          = flag.Bool("graph", false, "print the linked tasks as a Graphviz graph")
+		showLog        = flag.String("log", "", "show everything that happened to task #")
+		jsonIn         = flag.Bool("json-in", false, "apply a JSON array of operations read from stdin")
+		match          = flag.String("match", "", "with -f, finish every task matching the query")
+		file           = flag.String("file", "", "use this tasks file instead of T_TASKS_FILE")
+		local          = flag.Bool("local", false, "use the .tasks file of the project, here or in a directory above")
+		initProject    = flag.Bool("init", false, "create a .tasks file for --local at the root of the git repository")
+		taskDir        = flag.String("task-dir", "", "keep named lists in this directory instead of taskdir in the config or T_TASKS_DIR")
+		doneFile       = flag.String("done-file", "", "keep finished tasks in this file instead of done_file in the config or T_DONE_FILE")
+		listDone       = flag.Bool("D", false, "list finished tasks")
+		allHistory     = flag.Bool("all-history", false, "with -D, also list the tasks rolled over into older segments")
+		strictWip      = flag.Bool("strict-wip", false, "refuse to add tasks over the WIP limit")
+		showTags       = flag.Bool("tags", false, "list the tags and contexts in use, most used first")
+		withTag        = flag.String("tag", "", "list only the tasks with the tag")
+		withDone       = flag.Bool("done", false, "with --tags, also count finished tasks; with --since, list finished tasks; with --diff, compare the done files; with --check, check the done file")
+		changes        = flag.String("changelog", "", "print the tasks finished in a window, like 7d, by project as Markdown")
+		until          = flag.String("until", "", "with --changelog, end the window on this day")
+		since          = flag.String("since", "", "only list tasks added since a date or for a duration, like 2024-05-01 or 7d")
+		stale          = flag.String("stale", "", "only list tasks added more than this long ago, like 30 or 2w, or than --stale-after, oldest first")
+		staleAfterAge  = flag.String("stale-after", "", "mark tasks added more than this long ago (stale) in listings, like 30d (default 14d)")
+		dueSoon        = flag.Bool("due-soon", false, "only list tasks due within 7 days or overdue, soonest first")
+		finishMatch    = flag.String("finish-matching", "", "finish every task matching the query")
+		finishOne      = flag.String("finish-match", "", "finish the one task containing the text")
+		editMatch      = flag.String("edit-match", "", "replace the description of the one task containing the text")
+		force          = flag.Bool("force", false, "change a read-only list anyway; with -f, finish tasks with open subtasks, which stay as tasks; with --unbundle, overwrite existing files")
+		bundlePath     = flag.String("bundle", "", "pack the tasks, done, config and schedule files and the named lists into this .tar.gz")
+		unbundlePath   = flag.String("unbundle", "", "restore the files of a --bundle to where they go on this machine")
+		doctor         = flag.Bool("doctor", false, "check the tasks file, the config and the environment for problems")
+		noNag          = flag.Bool("no-nag", false, "don't nag about overdue or old tasks this time")
+		interactive    = flag.Bool("i", false, "read commands from stdin: a <text>, f <id>, e <id> <text>, l, q")
+		completion     = flag.String("completion", "", "print the completion script for bash or zsh")
+		format         = flag.String("format", "", "list each task with a text/template, or the simple, long or csv preset (also format in the config or T_FORMAT)")
 	)
+	flag.Var(&lists, "l", "use the named task list (repeat to show several)")
+	flag.Var(&lists, "list", "use the named task list (repeat to show several)")
 
+	flag.BoolVar(yes, "yes", false, "don't ask for confirmation")
+	flag.StringVar(deleteTask, "delete", "", "delete task # without finishing it, like -d")
+	flag.BoolVar(showAge, "v", false, "show how long ago each task was added, like --age")
+	flag.BoolVar(check, "fsck", false, "check the tasks file for problems, like --check")
+	flag.BoolVar(showAge, "verbose", false, "show how long ago each task was added, like --age")
+	os.Args = bareFlag(bareFlag(bareFlag(bareFlag(bareFlag(os.Args, "f"), "show"), "set-due"), "waiting"), "changelog")
+	os.Args = bareFlag(os.Args, "stale")
 	flag.Parse()
+	strictMode = strictSetting(*strict)
+	gitMode = *useGit || os.Getenv("T_GIT") == "1"
+	if strictMode && isMutating() {
+		quietStdout()
+	}
+	confirmations = &printer{out: os.Stdout, quiet: *quiet}
+	var timings *metrics
+	if *showTimings || os.Getenv("T_TIMINGS") == "1" {
+		timings = newMetrics(realClock{})
+		defer timings.report(os.Stderr)
+	}
+	foldSearch = *fold
+	if *deleteTask != "" && (flagPassed("f") || *finishMatch != "") {
+		fmt.Fprintln(os.Stderr, "a task is either deleted or finished, -d can't be combined with -f")
+		os.Exit(2)
+	}
+	if *completion != "" {
+		script, err := completionScript(*completion)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		fmt.Print(script)
+		return
+	}
+	opts := formatOptions{plain: *plain, age: *showAge, quote: *quote, json: *jsonOut, now: time.Now()}
+	var err error
+	if aging, err = parseAging(os.Getenv("T_PRIORITY_AGING")); err != nil && !*doctor {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	var staleAge time.Duration
+	if *stale != "" {
+		if staleAge, err = parseDuration(*stale); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+	var sinceTime time.Time
+	if *since != "" {
+		if sinceTime, err = parseSince(*since, opts.now); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+	untilTime := opts.now
+	if flagPassed("changelog") {
+		if *changes != "" && *since != "" {
+			fmt.Fprintln(os.Stderr, "--changelog takes a window or --since, not both")
+			os.Exit(2)
+		}
+		if *since == "" {
+			window := *changes
+			if window == "" {
+				window = defaultChangelog
+			}
+			if sinceTime, err = parseSince(window, opts.now); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(2)
+			}
+		}
+		if *until != "" {
+			if untilTime, err = parseUntil(*until, opts.now.Location()); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(2)
+			}
+		}
+	} else if *until != "" {
+		fmt.Fprintln(os.Stderr, "--until only goes with --changelog")
+		os.Exit(2)
+	}
+	config, configPath := Config{}, ""
+	if dir, err := configDir(); err == nil {
+		configPath = filepath.Join(dir, "config")
+		if config, err = loadConfig(configPath); err != nil && !*doctor {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+	base := os.Getenv("T_INDEX_BASE")
+	if value, ok := config.Get("index_base"); ok {
+		base = value
+	}
+	if base != "" {
+		if core.IndexBase, err = parseIndexBase(base); err != nil && !*doctor {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+	if dateFormat, err = parseDateFormat(config); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	if core.DescriptionLimit, err = parseDescriptionLimit(config); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		core.DescriptionLimit = core.MaxDescriptionLength
+	}
+	keepTombstones, tombstoneWindow, err := tombstoneSettings(config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	if readOnlyLists, err = readOnlySettings(config); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	nagEvery, err := nagInterval(config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	if opts.staleAfter, err = staleAfter(*staleAfterAge, config); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if *staleAfterAge != "" {
+			os.Exit(2)
+		}
+	}
+	if flagPassed("stale") && *stale == "" {
+		staleAge = opts.staleAfter
+	}
+	ignoreReadOnly = *force
+	hashIds := false
+	if value, ok := config.Get("ids"); ok {
+		switch value {
+		case "number":
+		case "hash":
+			hashIds = true
+		default:
+			fmt.Fprintf(os.Stderr, "invalid ids = %s, expected number or hash\n", value)
+		}
+	}
+	postWriteHook, _ = config.Get("post_write_hook")
+	if value, ok := config.Get("events_file"); ok {
+		if eventsFilePath, err = expandPath(value, "events_file"); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+	if value, ok := config.Get("status_file"); ok {
+		if statusFilePath, err = expandPath(value, "status_file"); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
 
-	tasklist = &(TaskList{})
-	taskFilePath = getTaskFilePath()
+	if *regexpSearch {
+		if _, err := regexp.Compile(*grep); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid pattern %q: %v\n", *grep, err)
+			os.Exit(2)
+		}
+	}
+	listName := ""
+	if len(lists) == 1 {
+		listName = lists[0]
+	}
+	if *toInbox || *process {
+		listName = inboxList
+	}
 
-	file, err := os.Open(taskFilePath)
+	for _, taskId := range []*string{editTask, appendTo, prependTo, finishTask, deleteTask, showTask, pomodoro, attach, note, snooze, openAttachment, showLog, yank, mute, unmute, setMeta} {
+		if name, id := splitListId(*taskId); name != "" {
+			listName, *taskId = name, id
+		}
+	}
+	onSomedayList = listName == somedayList
+	// The options given to t win over those of the list in the config,
+	// which win over the config's own and then the environment's.
+	if !flagPassed("format") {
+		*format = os.Getenv("T_FORMAT")
+		if value, ok := config.listSetting(listName, "format"); ok {
+			if _, err := parseFormat(value); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: format: %v\n", configPath, err)
+			} else {
+				*format = value
+			}
+		}
+	}
+	if *format != "" {
+		if opts.template, err = parseFormat(*format); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+	if value, ok := config.listSetting(listName, "color"); ok && !flagPassed("color") {
+		if _, err := colorSetting(value, opts.plain); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: invalid color = %s, expected always, never or auto\n", configPath, value)
+		} else {
+			*colorMode = value
+		}
+	}
+	if opts.color, err = colorSetting(*colorMode, opts.plain); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if opts.color {
+		if opts.tagColors, err = tagColors(config); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+	if !*wide {
+		opts.width = listingWidth()
+	}
+	if len(lists) > 1 {
+		if isMutating() {
+			fmt.Fprintln(os.Stderr, "several lists can only be shown, not changed")
+			os.Exit(2)
+		}
+		if err := showLists(lists, *grep, *regexpSearch, opts); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		return
+	}
+	if *initProject || *local {
+		dir, err := os.Getwd()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if *initProject {
+			err := initLocal(dir, func(question string) bool { return *yes || confirm(question) }, os.Stdout)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
+		if *file != "" || listName != "" {
+			fmt.Fprintln(os.Stderr, "--local can't be combined with --file or a list")
+			os.Exit(2)
+		}
+		if *file, err = findLocal(dir); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	taskDirFlag = *taskDir
+	taskDirConfig, _ = config.Get("taskdir")
+	taskFilePath, err = getTaskFilePath(*file, listName)
 	if err != nil {
-		if !os.IsNotExist(err) {
-			fmt.Print(err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if listName == "" {
+		if doneFilePath, err = getDoneFilePath(*doneFile, config); err != nil && !*doctor {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+
+	inHook = os.Getenv(hookEnv) == "1"
+	// A t --serve serving the tasks file answers the commands it can,
+	// which spares reading the file.
+	if command := serverCommand(*editTask, *finishTask, opts); command != "" && !inHook && timings == nil {
+		if status, ok := askServer(socketPath(), taskFilePath, command, os.Stdout, os.Stderr); ok {
+			if status != 0 {
+				os.Exit(status)
+			}
+			return
+		}
+	}
+	// Scheduled tasks go to the default list, whenever it is used and
+	// can be changed. Adding them changes the list, so it takes the lock
+	// before the list is read, like any change.
+	scheduling := *inject || (listName == "" && !inHook && !*serve && schedulePending(time.Now()))
+	if isMutating() {
+		if inHook {
+			fmt.Fprintln(os.Stderr, errInHook)
+			os.Exit(1)
+		}
+		if err := checkReadOnly(taskFilePath, listName); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		// --move-to and --copy-to write the destination list as well.
+		others := make([]string, 0, 1)
+		for _, dest := range []string{*moveTo, *copyTo} {
+			if destPath, err := listPath(dest); dest != "" && err == nil && destPath != taskFilePath {
+				others = append(others, destPath)
+			}
+		}
+		if err := lockTasksFiles(taskFilePath, others...); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if scheduling {
+		// A command that only reads leaves them to the next run when
+		// the list can't be changed now.
+		if checkReadOnly(taskFilePath, listName) != nil || lockTasksFile(taskFilePath) != nil {
+			scheduling = false
+		}
+	}
+	if err := recoverWrites(taskFilePath, lockFile != nil); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *bundlePath != "" || *unbundlePath != "" {
+		roots, err := currentBundleRoots()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if *bundlePath != "" {
+			n, err := bundle(core.ExpandHome(*bundlePath), roots)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			fmt.Printf("bundled %d files\n", n)
+			return
+		}
+		in, err := os.Open(core.ExpandHome(*unbundlePath))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer in.Close()
+		paths, err := unbundle(in, roots, *force)
+		for _, path := range paths {
+			fmt.Printf("restored %s\n", path)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if *syncGit {
+		if err := syncTasks(taskFilePath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if *check {
+		if *withDone {
+			os.Exit(checkDone(donePath(taskFilePath), *fix, *yes))
+		}
+		os.Exit(checkFile(*fix, *yes))
+	}
+	if *doctor {
+		os.Exit(runDoctor(taskFilePath, configPath, time.Now()))
+	}
+	if *undo || *redo {
+		if err := stepHistory(*redo); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if *showHistory {
+		printHistory()
+		return
+	}
+	if *editTasksFile {
+		if err := editFile(taskFilePath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
+		return
 	}
-	defer file.Close()
-	if file != nil {
-		taskBytes, err := ioutil.ReadAll(file)
+
+	timings.phase("setup", "")
+	tasklist, err = readTaskList(taskFilePath)
+	timings.phase("load", formatCount(len(tasklist.Tasks))+" tasks")
+	// --has and --empty only answer with their exit status, so they
+	// need another one for errors and must not change anything.
+	if flagPassed("has") || *empty {
 		if err != nil {
-			fmt.Print(err.Error())
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		if (*empty && len(tasklist.Search("")) > 0) || (!*empty && len(tasklist.Search(*has)) == 0) {
+			os.Exit(1)
+		}
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	tasklist.Raw = *raw
+	tasklist.Truncate = *truncate
+	tasklist.KeepTombstones = keepTombstones
+	tasklist.cascade = *cascade
+	tasklist.KeepChildren = *force
+	if *serve {
+		if err := serveTasks(socketPath(), opts, listName == "", hashIds); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
-		err = tasklist.UnmarshalText(taskBytes)
+		return
+	}
+	// A nag goes after the output of the command, and only to people
+	// reading it on a terminal.
+	if nagEvery > 0 && !*noNag && nagShown() {
+		if path, err := nagPath(); err == nil {
+			defer func() { nag(os.Stderr, tasklist, realClock{}, path, nagEvery) }()
+		}
+	}
+	if hashIds {
+		opts.idPrefixes = tasklist.idPrefixes()
+	}
+	if *inject || (scheduling && (!tasklist.ReadOnly || ignoreReadOnly)) {
+		added, err := injectScheduled(time.Now())
 		if err != nil {
-			fmt.Print(err.Error())
+			fmt.Fprintln(os.Stderr, err)
+			if *inject {
+				os.Exit(1)
+			}
+		}
+		if *inject {
+			confirmations.printf("added %d scheduled tasks\n", added)
+			return
 		}
 	}
 
 	text := strings.Join(flag.Args(), " ")
-	if *editTask != -1 {
-		tasklist.Edit(*editTask, text)
-		tasklist.write(true)
-	} else if *finishTask != -1 {
-		tasklist.Finish(*finishTask)
-		tasklist.write(true)
+	if *editTask != "" {
+		taskId, err := tasklist.resolveId(*editTask)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		task, err := tasklist.Get(taskId)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		old := task.Description
+		if text == "" {
+			text, err = editDescription(task.Description)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			// Another t may have changed the list while the editor ran.
+			if taskId = tasklist.IndexOf(task.Id); taskId == -1 {
+				fmt.Fprintln(os.Stderr, "the task was finished or deleted while it was being edited")
+				os.Exit(1)
+			}
+		}
+		if err := tasklist.Edit(taskId, text); err != nil {
+			if err == core.ErrEmptyDescription {
+				err = errors.New("the description is empty, the task wasn't changed; -f finishes it and -d deletes it")
+			}
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		timings.phase("mutate", "")
+		if err := tasklist.write(true); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		timings.phase("write", "")
+		confirmations.edited(taskId, old, tasklist.Tasks[taskId])
+	} else if *appendTo != "" || *prependTo != "" {
+		if *appendTo != "" && *prependTo != "" || strings.TrimSpace(text) == "" {
+			fmt.Fprintln(os.Stderr, "Usage: t --append <id> <text> or t --prepend <id> <text>")
+			os.Exit(2)
+		}
+		amend, target := tasklist.Append, *appendTo
+		if *prependTo != "" {
+			amend, target = tasklist.Prepend, *prependTo
+		}
+		taskId, err := tasklist.resolveId(target)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		task, err := tasklist.Get(taskId)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		old := task.Description
+		if err := amend(taskId, text); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		timings.phase("mutate", "")
+		if err := tasklist.write(true); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		timings.phase("write", "")
+		confirmations.edited(taskId, old, task)
+	} else if *clearAll {
+		if !*yes && !stdinIsTerminal() {
+			fmt.Fprintln(os.Stderr, "--clear needs -y when not run on a terminal")
+			os.Exit(1)
+		}
+		if !*yes && !confirm(fmt.Sprintf("Delete all %d tasks?", len(tasklist.Tasks))) {
+			os.Exit(1)
+		}
+		n := tasklist.Clear()
+		if err := tasklist.write(true); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		confirmations.printf("deleted %d tasks\n", n)
+	} else if *purgeDoneAge != "" {
+		age, err := parseDuration(*purgeDoneAge)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		purged, err := purgeDone(donePath(taskFilePath), age)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		confirmations.printf("dropped %d finished tasks\n", purged)
+	} else if *archiveOld {
+		age := defaultArchiveAge
+		if *olderThan != "" {
+			if age, err = parseDuration(*olderThan); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(2)
+			}
+		}
+		dir, err := getArchiveDir(config)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		archived, files, err := archiveDone(donePath(taskFilePath), time.Now().Add(-age), dir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		confirmations.printf("archived %d tasks into %d files\n", archived, files)
+	} else if *deleteTask != "" {
+		taskId, err := tasklist.resolveId(*deleteTask)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		task, err := tasklist.Get(taskId)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if err := tasklist.Delete(taskId); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if err := tasklist.write(true); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		confirmations.deleted(task)
+	} else if *finishOne != "" {
+		find := tasklist.matchOne
+		if *fuzzy {
+			find = tasklist.fuzzyOne
+		}
+		taskId, err := find(*finishOne, opts)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		line := formatTask(taskId, tasklist.Tasks[taskId], opts)
+		// The line shows which task matched, so it confirms it.
+		if err := finishTasks(&printer{out: os.Stdout, quiet: true}, []int{taskId}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		confirmations.printf("finished %s\n", line)
+	} else if *editMatch != "" {
+		if strings.TrimSpace(text) == "" {
+			fmt.Fprintln(os.Stderr, "Usage: t --edit-match <text> <new description>")
+			os.Exit(2)
+		}
+		taskId, err := tasklist.matchOne(*editMatch, opts)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if err := tasklist.Edit(taskId, text); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if err := tasklist.write(true); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		confirmations.printf("edited %s\n", formatTask(taskId, tasklist.Tasks[taskId], opts))
+	} else if *finishMatch != "" || (flagPassed("f") && *match != "") {
+		query := *finishMatch
+		if query == "" {
+			query = *match
+		}
+		ids := tasklist.Search(query)
+		if flagPassed("stale") {
+			ids = tasklist.staleIds(ids, staleAge, opts.now)
+		}
+		err := finishMatching(ids, *yes, opts)
+		if err == errCanceled {
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if isChildId(*finishTask) && flag.NArg() == 0 {
+		if err := finishSubtask(*finishTask); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *finishTask != "" || flagPassed("f") {
+		ids, err := finishIds(*finishTask, flag.Args(), opts)
+		if err == errCanceled {
+			return
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		if err := finishTasks(confirmations, ids); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *setDue != "" || flagPassed("set-due") {
+		if err := setDueMatching(*setDue, *all, flag.Args(), time.Now()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if err := tasklist.write(true); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *bumpTask != "" {
+		if err := bump(*bumpTask, flag.Args(), time.Now()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if err := tasklist.write(true); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *moveTo != "" || *copyTo != "" {
+		dest := *moveTo
+		if *copyTo != "" {
+			dest = *copyTo
+		}
+		if err := transferTask(dest, flag.Args(), *copyTo != ""); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *importMarks != "" {
+		if err := importBookmarks(*importMarks, *folder, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *importRemind != "" {
+		if err := importReminders(*importRemind, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *exportTodoTxt {
+		os.Stdout.Write(tasklist.ExportTodoTxt())
+	} else if *importTodoTxt != "" {
+		data, err := ioutil.ReadFile(*importTodoTxt)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		count := len(tasklist.Tasks)
+		if err := tasklist.ImportTodoTxt(data); err != nil {
+			if _, ok := err.(todoTxtLines); !ok {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			fmt.Fprintln(os.Stderr, "warning:", err)
+		}
+		if err := tasklist.write(true); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		confirmations.printf("imported %d tasks\n", len(tasklist.Tasks)-count)
+	} else if *exportTw {
+		if err := exportTaskwarrior(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *importTw != "" {
+		if err := importTaskwarrior(*importTw, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *fromStdin {
+		added, err := tasklist.addLines(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, "nothing was added")
+			os.Exit(1)
+		}
+		if added > 0 {
+			if err := tasklist.write(true); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		}
+		confirmations.printf("added %d tasks\n", added)
+	} else if *triage {
+		if err := triageTasks(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *interactive {
+		if err := interactiveSession(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *prune != "" {
+		age, err := parseDuration(*prune)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		if err := pruneTasks(age, *yes, flag.Arg(0), opts); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *notify {
+		if err := notifyTasks(time.Now()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *setMeta != "" {
+		if err := setCustomMeta(*setMeta, flag.Args()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *newPriority != "" {
+		priority, err := parsePriority(*newPriority)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		if err := setPriority(flag.Arg(0), priority); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *move != "" {
+		if err := moveTask(*move, flag.Arg(0)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *waiting != "" || *unwait != "" {
+		if *waiting != "" {
+			err = waitTask(*waiting, strings.Join(flag.Args(), " "))
+		} else {
+			err = unwaitTask(*unwait)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *mute != "" || *unmute != "" {
+		if *mute != "" {
+			err = muteTask(*mute, flag.Arg(0), time.Now())
+		} else {
+			err = unmuteTask(*unmute)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *yank != "" {
+		c, err := systemClipboard()
+		if err == nil {
+			err = yankTask(*yank, c, flagPassed("show"))
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *showTask != "" || flagPassed("show") {
+		taskId, err := tasklist.resolveId(*showTask)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		task, err := tasklist.Get(taskId)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Print(tasklist.formatDetails(taskId, task))
+	} else if *pomodoro != "" {
+		if err := runPomodoro(*pomodoro, *pomodoroLength); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *attach != "" {
+		if err := attachFile(*attach, flag.Args()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *note != "" {
+		if err := noteTask(*note, flag.Args()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *sub != "" {
+		if err := addSubtask(*sub, flag.Args()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *snooze != "" {
+		if err := snoozeTask(*snooze, flag.Args(), time.Now()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *openAttachment != "" {
+		if err := openAttached(*openAttachment, flag.Args()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *link != "" {
+		if err := linkTasks(*link, flag.Args()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *estimateBy != "" {
+		if *estimateBy != "project" {
+			fmt.Fprintf(os.Stderr, "can't sum up estimates by %q, only by project\n", *estimateBy)
+			os.Exit(2)
+		}
+		for _, total := range tasklist.EstimatesByProject() {
+			fmt.Println(total)
+		}
+	} else if *plan {
+		budget, err := planBudget(config)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		planned := tasklist.packPlan(tasklist.planOrder(*limit, opts.now), planWeekdays(opts.now, planDays), budget)
+		tasklist.printPlan(planned, budget, opts)
+		if *apply {
+			tasklist.applyPlan(planned)
+			if err := tasklist.write(true); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		}
+	} else if *capacity != "" {
+		daily, err := dailyCapacity(config)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		by, err := parseDue(*capacity, opts.now)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		check := tasklist.checkCapacity(by, opts.now, daily)
+		if *jsonOut {
+			err = tasklist.writeCapacityJSON(os.Stdout, check)
+		} else {
+			tasklist.printCapacity(os.Stdout, check, opts)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *vacuum {
+		dropped := tasklist.vacuum(tombstoneWindow, time.Now())
+		if dropped > 0 {
+			if err := tasklist.write(true); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		}
+		confirmations.printf("dropped %d tombstones\n", dropped)
+	} else if *archive {
+		if err := archiveTasks(time.Now()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *showArchives {
+		names, err := archiveNames()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		for _, name := range names {
+			fmt.Println(name)
+		}
+	} else if *importFrom != "" {
+		if err := importArchive(*importFrom, *resumeFrom); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *bulk {
+		if err := bulkEdit(time.Now()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *showToday {
+		midnight := startOfDay(opts.now)
+		done, err := readDoneSince(donePath(taskFilePath), midnight, opts.now)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		writeToday(os.Stdout, tasklist, done.Tasks, midnight, opts)
+	} else if *showHeatmap {
+		done, err := readDoneHistory(donePath(taskFilePath))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		counts, untimed := buildHeatmap(done.Tasks, time.Local)
+		if *jsonOut {
+			err = writeHeatmapJSON(os.Stdout, counts)
+		} else {
+			writeHeatmap(os.Stdout, counts, opts.color)
+			if untimed > 0 && !opts.plain {
+				fmt.Printf("(%d finished tasks without a finishing time not counted)\n", untimed)
+			}
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *html {
+		now := time.Now()
+		done, err := readDoneSince(donePath(taskFilePath), startOfWeek(now), now)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if err := writeReport(os.Stdout, buildReport(tasklist, done, now)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *diffWith != "" {
+		os.Exit(diffFiles(*diffWith, *withDone))
+	} else if *changedOnly {
+		if err := changedTasks(os.Stdout, tasklist, taskFilePath, opts); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *checkpoint != "" || *checkpointList || *restore != "" {
+		limit, err := checkpointLimit(config)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		switch {
+		case *checkpoint != "":
+			err = saveCheckpoint(taskFilePath, *checkpoint, limit)
+		case *restore != "":
+			if n, ok := backupNumber(*restore); ok {
+				if !*yes && !stdinIsTerminal() {
+					err = errors.New("--restore needs -y when not run on a terminal")
+					break
+				}
+				err = restoreBackup(taskFilePath, n, func(question string) bool { return *yes || confirm(question) })
+				break
+			}
+			name := strings.TrimPrefix(*restore, checkpointPrefix)
+			var previous string
+			if previous, err = restoreCheckpoint(taskFilePath, name, limit, time.Now()); err == nil {
+				fmt.Printf("restored %s, the previous tasks are saved as %s\n", name, previous)
+			}
+		default:
+			var saved []os.FileInfo
+			saved, err = checkpoints(taskFilePath)
+			for _, file := range saved {
+				fmt.Printf("%s  %s\n", file.ModTime().Format("2006-01-02 15:04:05"), file.Name())
+			}
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *process {
+		processInbox(opts)
+	} else if *dedupe {
+		if *exact && (*fuzzy || *dryRun) {
+			fmt.Fprintln(os.Stderr, "--exact can't be combined with --fuzzy or --dry-run")
+			os.Exit(2)
+		}
+		if *exact {
+			confirmations.printf("removed %d duplicates\n", tasklist.Dedupe())
+		} else if err := removeDuplicates(*fuzzy, *dryRun); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if !*dryRun {
+			if err := tasklist.write(true); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		}
+	} else if *saveOrder {
+		if *sortBy == "" {
+			fmt.Fprintln(os.Stderr, "--save-order needs a --sort key")
+			os.Exit(2)
+		}
+		if *grep != "" || flagPassed("stale") || *since != "" || *withTag != "" || *dueSoon {
+			fmt.Fprintln(os.Stderr, "--save-order can't be combined with filters")
+			os.Exit(2)
+		}
+		if _, err := tasklist.SortedIds(*sortBy); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		if !*yes && !confirm(fmt.Sprintf("Rewrite the tasks file in %s order?", *sortBy)) {
+			os.Exit(1)
+		}
+		tasklist.Sort(*sortBy)
+		if err := tasklist.write(true); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *jsonIn {
+		limit, err := doneLimit()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		if err := runJSONIn(os.Stdin, os.Stdout, limit); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *showLog != "" {
+		if err := printLog(*showLog); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if *showTags {
+		lists := []*TaskList{tasklist}
+		if *withDone {
+			done, err := readDoneList(donePath(taskFilePath))
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			lists = append(lists, done)
+		}
+		for _, count := range tagCounts(lists...) {
+			if opts.plain {
+				fmt.Println(count.tag)
+			} else {
+				fmt.Println(count)
+			}
+		}
+	} else if *listDone {
+		var done *TaskList
+		if *allHistory {
+			done, err = readDoneHistory(donePath(taskFilePath))
+		} else {
+			done, err = readDoneList(donePath(taskFilePath))
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		for _, taskId := range done.Search(text) {
+			fmt.Println(formatDone(done.Tasks[taskId]))
+		}
+	} else if flagPassed("changelog") {
+		done, err := readDoneSince(donePath(taskFilePath), sinceTime, opts.now)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		writeChangelog(os.Stdout, changelog(done.Tasks, sinceTime, untilTime), sinceTime, untilTime)
+	} else if *since != "" && *withDone {
+		done, err := readDoneSince(donePath(taskFilePath), sinceTime, opts.now)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		tasks, untimed := done.doneSince(sinceTime)
+		for _, task := range tasks {
+			fmt.Println(formatDone(task))
+		}
+		if untimed > 0 && !opts.plain {
+			fmt.Printf("(%d finished tasks without a finishing time not shown)\n", untimed)
+		}
+	} else if *graph {
+		fmt.Print(tasklist.Graph())
+	} else if *next {
+		if taskId := tasklist.nextTask(opts.now); taskId != -1 {
+			fmt.Println(formatTask(taskId, tasklist.Tasks[taskId], opts))
+		}
+	} else if *allLists {
+		searchAllLists(*grep, *regexpSearch, opts)
+	} else if *fuzzy {
+		if strings.TrimSpace(text) == "" {
+			fmt.Fprintln(os.Stderr, "Usage: t --fuzzy <query>")
+			os.Exit(2)
+		}
+		tasklist.showHidden = *all
+		matches := tasklist.FuzzyFind(text, *limit)
+		if len(matches) == 0 {
+			fmt.Fprintln(os.Stderr, errNoTaskMatches)
+			os.Exit(1)
+		}
+		for _, match := range matches {
+			fmt.Println(formatTask(match.taskId, tasklist.Tasks[match.taskId], opts))
+		}
 	} else {
 		if len(flag.Args()) > 0 {
-			tasklist.Add(text)
-			tasklist.write(true)
+			pos, err := insertPosition(*before, *after)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(2)
+			}
+			var dueAt time.Time
+			if *dueDate != "" {
+				if dueAt, err = parseDue(*dueDate, time.Now()); err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					os.Exit(2)
+				}
+			}
+			var recurEvery time.Duration
+			if *every != "" {
+				if recurEvery, err = core.ParseEvery(*every); err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					os.Exit(2)
+				}
+			}
+			priority := 0
+			if *addPriority != "" {
+				if priority, err = parsePriority(*addPriority); err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					os.Exit(2)
+				}
+			}
+			descriptions := []string{text}
+			if *multi {
+				descriptions = flag.Args()
+			} else if !*yes {
+				guard, err := argGuardSettings(config)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, err)
+				}
+				if err := guardArgs(flag.Args(), guard, stdinIsTerminal(), confirm); err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					os.Exit(1)
+				}
+			}
+			defaults, err := listDefaults(config, listName)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			tagged := make(map[string]bool)
+			for i, description := range descriptions {
+				descriptions[i] = defaults.withTags(description)
+				tagged[descriptions[i]] = descriptions[i] != description
+			}
+			skipDups, err := noDupSetting(config)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			if *noDup || skipDups {
+				kept := make([]string, 0, len(descriptions))
+				for _, description := range descriptions {
+					if tasklist.hasDescription(description) {
+						fmt.Printf("already on list: %s\n", description)
+					} else {
+						kept = append(kept, description)
+					}
+				}
+				if len(kept) == 0 {
+					return
+				}
+				descriptions = kept
+			}
+			added, err := addTasks(descriptions, pos)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			// Tasks that got defaults are shown, so that none of them
+			// comes as a surprise.
+			defaulted := make([]bool, len(added))
+			for i := range added {
+				defaulted[i] = tagged[descriptions[i]]
+			}
+			limits, err := wipLimits(config)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			for i, task := range added {
+				task.Priority = priority
+				if *addPriority == "" && defaults.priority != 0 {
+					task.Priority = defaults.priority
+					defaulted[i] = true
+				}
+				task.DueAt = dueAt
+				if recurEvery > 0 {
+					task.RecurEvery = recurEvery
+					if task.DueAt.IsZero() {
+						task.DueAt = startOfDay(time.Now())
+					}
+				}
+				if exceeded := tasklist.wipExceeded(task, limits); len(exceeded) > 0 {
+					for _, problem := range exceeded {
+						fmt.Fprintf(os.Stderr, "warning: %s\n", problem)
+					}
+					if *strictWip {
+						fmt.Fprintln(os.Stderr, "not added, --strict-wip is on")
+						os.Exit(1)
+					}
+				}
+			}
+			timings.phase("mutate", "")
+			if err := tasklist.write(true); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			timings.phase("write", "")
+			for i, task := range added {
+				taskId := tasklist.IndexOf(task.Id)
+				if defaulted[i] {
+					confirmations.printf("added %s\n", formatTask(taskId, task, opts))
+				} else {
+					confirmations.added(strconv.Itoa(displayId(taskId)), task)
+				}
+			}
 		} else {
-			for _, task := range tasklist.List() {
-				fmt.Println(task)
+			tasklist.showHidden = *all
+			ids, _ := tasklist.searchIds(*grep, *regexpSearch)
+			if *withTag != "" {
+				ids = tasklist.tagIds(ids, *withTag)
+			}
+			if flagPassed("waiting") {
+				ids = tasklist.waitingIds(ids)
+			}
+			if flagPassed("stale") {
+				ids = tasklist.staleIds(ids, staleAge, opts.now)
+				// Each task shows its age instead, all of them stale.
+				opts.age, opts.staleAfter = true, 0
+			}
+			if *dueSoon {
+				ids = tasklist.dueSoonIds(ids, opts.now)
+			}
+			untimed := 0
+			if *since != "" {
+				ids, untimed = tasklist.sinceIds(ids, sinceTime)
+			}
+			if *countOnly {
+				fmt.Println(len(ids))
+				return
+			}
+			if *idsOnly {
+				for _, taskId := range ids {
+					if prefix, ok := opts.idPrefixes[tasklist.Tasks[taskId]]; ok {
+						fmt.Println(prefix)
+					} else {
+						fmt.Println(displayId(taskId))
+					}
+				}
+				return
+			}
+			if *promptLine {
+				var done int
+				if *grep == "" {
+					done, err = cachedDoneToday(donePath(taskFilePath), opts.now)
+				} else {
+					done, err = doneToday(donePath(taskFilePath), *grep, *regexpSearch, opts.now)
+				}
+				if err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					os.Exit(1)
+				}
+				format, ok := config.Get("prompt_format")
+				if !ok {
+					format = defaultPromptFormat
+				}
+				fmt.Print(formatPrompt(format, len(ids), done))
+				return
+			}
+			if *summary {
+				done, err := doneToday(donePath(taskFilePath), *grep, *regexpSearch, opts.now)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					os.Exit(1)
+				}
+				fmt.Println(formatSummary(len(ids), done))
+				return
+			}
+			// Listings show the most important tasks first, and
+			// otherwise keep the order of the list.
+			order := "priority"
+			if *dueSoon {
+				order = "due"
+			}
+			if flagPassed("stale") {
+				order = "age"
 			}
+			if *sortBy != "" {
+				order = *sortBy
+			}
+			sorted, err := tasklist.orderIds(ids, order)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(2)
+			}
+			ids = sorted
+			if *shuffle {
+				if *seed == 0 {
+					*seed = time.Now().UnixNano()
+				}
+				shuffleIds(ids, *seed)
+			}
+			if *limit > 0 && len(ids) > *limit {
+				ids = ids[:*limit]
+			}
+			timings.phase("filter", "")
+			if *porcelain {
+				for _, taskId := range ids {
+					id := strconv.Itoa(displayId(taskId))
+					if prefix, ok := opts.idPrefixes[tasklist.Tasks[taskId]]; ok {
+						id = prefix
+					}
+					fmt.Println(porcelainLine(id, tasklist.Tasks[taskId]))
+				}
+				timings.phase("render", "")
+				timings.skip("write")
+				return
+			}
+			if opts.json {
+				if err := writeJSONTasks(os.Stdout, tasklist.jsonTasks(ids, "")); err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					os.Exit(1)
+				}
+				timings.phase("render", "")
+				timings.skip("write")
+				return
+			}
+			for _, taskId := range ids {
+				fmt.Println(formatTask(taskId, tasklist.Tasks[taskId], opts))
+				for _, line := range formatChildren(taskId, tasklist.Tasks[taskId], opts) {
+					fmt.Println(line)
+				}
+			}
+			if untimed > 0 && !opts.plain {
+				fmt.Printf("(%d tasks without a creation time not shown)\n", untimed)
+			}
+			timings.phase("render", "")
+			timings.skip("write")
 		}
 	}
 }
 
-func (t *TaskList) write(deleteIfEmpty bool) error {
-	marshaledList, _ := tasklist.MarshalText()
-	err := ioutil.WriteFile(taskFilePath, marshaledList, 0644)
+// mutatingFlags are the flags that change a task list.
+var mutatingFlags = map[string]bool{
+	"e": true, "f": true, "fix": true, "due": true, "bump": true, "set-due": true, "dedupe": true,
+	"save-order": true, "move-to": true, "copy-to": true, "in": true,
+	"process": true, "pomodoro": true, "attach": true, "link": true,
+	"undo": true, "redo": true, "archive": true, "import": true,
+	"inject": true, "json-in": true, "finish-matching": true,
+	"checkpoint": true, "restore": true, "apply": true, "P": true, "edit-file": true, "vacuum": true, "meta": true, "prune": true, "triage": true, "stdin": true, "import-reminders": true, "import-bookmarks": true, "import-todotxt": true, "import-tw": true, "mute": true, "move": true, "unwait": true, "d": true, "delete": true, "clear": true, "purge-done": true, "unbundle": true, "append": true, "prepend": true,
+	"unmute": true, "every": true, "finish-match": true, "edit-match": true, "archive-done": true, "i": true, "note": true, "snooze": true, "bulk": true, "sub": true, "sync": true,
+}
+
+// isMutating reports whether the command line changes a task list,
+// either through a mutating flag or by adding a task.
+func isMutating() bool {
+	// The words of --fuzzy are what it looks for.
+	mutating := flag.NArg() > 0 && !flagPassed("fuzzy")
+	flag.Visit(func(f *flag.Flag) {
+		// --waiting without an id lists the waiting tasks.
+		if mutatingFlags[f.Name] || (f.Name == "waiting" && f.Value.String() != "") {
+			mutating = true
+		}
+	})
+	return mutating
+}
+
+// oldest returns the id of the task added longest ago, by creation
+// time. Tasks from files older than creation times count as older than
+// any that has one, and ties go to the first in the list. Waiting tasks
+// only count if all tasks are waiting.
+func (t *TaskList) oldest() int {
+	oldest := -1
+	for i, task := range t.Tasks {
+		if !task.Waiting && (oldest == -1 || task.CreatedAt.Before(t.Tasks[oldest].CreatedAt)) {
+			oldest = i
+		}
+	}
+	if oldest == -1 {
+		return 0
+	}
+	return oldest
+}
+
+// idKeywords name tasks by their place in the list rather than by id.
+var idKeywords = map[string]func(t *TaskList) int{
+	"first":  func(t *TaskList) int { return 0 },
+	"oldest": func(t *TaskList) int { return t.oldest() },
+	"last":   func(t *TaskList) int { return len(t.Tasks) - 1 },
+}
+
+// displayId returns the id t shows for the task at an index.
+func displayId(taskId int) int {
+	return taskId + core.IndexBase
+}
+
+// parseId turns a numeric task id given by the user into the task's
+// index.
+func parseId(s string) (int, error) {
+	id, err := strconv.Atoi(s)
+	if err != nil {
+		return -1, err
+	}
+	return id - core.IndexBase, nil
+}
+
+// parseIndexBase checks a T_INDEX_BASE or index_base value.
+func parseIndexBase(s string) (int, error) {
+	switch s {
+	case "0":
+		return 0, nil
+	case "1":
+		return 1, nil
+	}
+	return 0, fmt.Errorf("invalid index base %q, expected 0 or 1", s)
+}
+
+// resolveId turns a task id given on the command line into the task's
+// index. Keywords like last are only tried once the id isn't a number,
+// and prefixes of stable ids, as shown with ids = hash, after that.
+// Strict mode takes neither, see resolveStrictId.
+func (t *TaskList) resolveId(s string) (int, error) {
+	if strictMode {
+		return t.resolveStrictId(s)
+	}
+	if taskId, err := parseId(s); err == nil {
+		return taskId, nil
+	}
+	if keyword, ok := idKeywords[s]; ok {
+		if len(t.Tasks) == 0 {
+			return -1, errors.New("No tasks found")
+		}
+		return keyword(t), nil
+	}
+	if prefix := strings.ToLower(s); isIdPrefix(prefix) {
+		return t.resolvePrefix(prefix)
+	}
+	return -1, fmt.Errorf("invalid task id %q", s)
+}
+
+// checkFile handles --check and --fsck: it reports the problems in the
+// tasks file and, with fix, repairs what it safely can after
+// confirmation, keeping the file as it was in corruptPath. It returns
+// the exit status, 1 if there were problems and 2 if the file couldn't
+// be read.
+func checkFile(fix bool, yes bool) int {
+	text, err := ioutil.ReadFile(taskFilePath)
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if fix {
+		fixed, fixes := fixTasks(text)
+		if fixes > 0 && (yes || confirm(fmt.Sprintf("Apply %d fixes to %s?", fixes, taskFilePath))) {
+			if err := ioutil.WriteFile(corruptPath(taskFilePath), text, 0600); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 2
+			}
+			if err := core.WriteAtomic(taskFilePath, fixed); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 2
+			}
+			fmt.Printf("applied %d fixes\n", fixes)
+			text = fixed
+		}
+	}
+	problems := checkTasks(text)
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	if len(problems) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// linkTasks handles --link: it links the task target to the task in args.
+func linkTasks(target string, args []string) error {
+	if len(args) != 1 {
+		return errors.New("Usage: t --link <id> <other id>")
+	}
+	a, err := tasklist.resolveId(target)
+	if err != nil {
+		return err
+	}
+	b, err := tasklist.resolveId(args[0])
+	if err != nil {
+		return err
+	}
+	if err := tasklist.Link(a, b); err != nil {
+		return err
+	}
+	return tasklist.write(true)
+}
+
+// bump handles --bump: it pushes the due date of a single task, or with
+// "overdue" of every overdue task, forward by the offset in args.
+func bump(target string, args []string, now time.Time) error {
+	if len(args) != 1 {
+		return errors.New("Usage: t --bump <id|overdue> <+Nd|+Nw>")
+	}
+	days, err := parseOffset(args[0])
+	if err != nil {
+		return err
+	}
+	if target != "overdue" {
+		taskId, err := tasklist.resolveId(target)
+		if err != nil {
+			return err
+		}
+		return tasklist.Bump(taskId, days, now)
+	}
+	for _, taskId := range tasklist.Overdue(now) {
+		task := tasklist.Tasks[taskId]
+		from := task.DueAt
+		tasklist.Bump(taskId, days, now)
+		fmt.Printf("%d - %s: due %s -> %s\n", displayId(taskId), task.Description,
+			displayDate(from), displayDate(task.DueAt))
+	}
+	return nil
+}
+
+// setDueMatching handles --set-due: it gives every listed task matching
+// query, or with all every listed task, the due date in args and prints
+// the tasks it changed. The caller writes the list once for all of them.
+func setDueMatching(query string, all bool, args []string, now time.Time) error {
+	if len(args) != 1 || (query == "") == !all {
+		return errors.New("Usage: t --set-due <query> <date>, or t --set-due --all <date> to change every task")
+	}
+	dueAt, err := parseDue(args[0], now)
 	if err != nil {
 		return err
 	}
+	ids := tasklist.Search(query)
+	if len(ids) == 0 {
+		return errNoMatch
+	}
+	for _, taskId := range ids {
+		task := tasklist.Tasks[taskId]
+		task.DueAt = dueAt
+		fmt.Println(formatTask(taskId, task, formatOptions{plain: true}))
+	}
+	return nil
+}
+
+// removeDuplicates handles --dedupe: it deletes all but the oldest task
+// of every group of duplicates and prints what it removed. Duplicates
+// weren't done, so they don't go to the done file, and the audit log
+// records them as removed.
+func removeDuplicates(fuzzy bool, dryRun bool) error {
+	verb := "removed"
+	if dryRun {
+		verb = "would remove"
+	}
+	removed := make([]int, 0)
+	for _, group := range tasklist.Duplicates(fuzzy) {
+		for _, taskId := range group[1:] {
+			fmt.Printf("%s %d - %s (duplicate of %d)\n", verb, displayId(taskId),
+				tasklist.Tasks[taskId].Description, displayId(group[0]))
+			removed = append(removed, taskId)
+		}
+	}
+	fmt.Printf("%s %d duplicates\n", verb, len(removed))
+	if dryRun {
+		return nil
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(removed)))
+	for _, taskId := range removed {
+		task, err := tasklist.Remove(taskId)
+		if err != nil {
+			return err
+		}
+		// Tasks read from a file may share an id with the one kept.
+		if tasklist.IndexOf(task.Id) == -1 {
+			tasklist.Unlink(task.Id)
+		}
+	}
 	return nil
 }
 
-func getTaskFilePath() string {
-	tasksFilePath := os.Getenv("T_TASKS_FILE")
-	if tasksFilePath == "" {
-		user, _ := user.Current()
-		tasksFilePath = user.HomeDir + "/tasks"
+// write writes the tasks file and the files it includes, removing the
+// tasks file if the list is empty and deleteIfEmpty is set.
+func (t *TaskList) write(deleteIfEmpty bool) error {
+	return t.commit(newTransaction(journalPath(taskFilePath)), deleteIfEmpty)
+}
+
+// commit writes the list like write does, in the transaction tx along
+// with whatever the caller staged in it, so that either all of the files
+// change or none do.
+func (t *TaskList) commit(tx *transaction, deleteIfEmpty bool) error {
+	if inHook {
+		tx.abort()
+		return errInHook
+	}
+	if t.ReadOnly && !ignoreReadOnly {
+		tx.abort()
+		return readOnlyError(taskFilePath)
+	}
+	if err := t.checkIncludedReadOnly(); err != nil {
+		tx.abort()
+		return err
+	}
+	before, _ := tx.read(taskFilePath)
+	after, _ := t.MarshalText()
+	if string(before) != string(after) {
+		depth, err := backupDepth()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v, keeping %d backups\n", err, defaultBackups)
+			depth = defaultBackups
+		}
+		if err := stageBackups(tx, taskFilePath, before, depth); err != nil {
+			tx.abort()
+			return err
+		}
+	}
+	if err := t.stageTo(tx, taskFilePath, deleteIfEmpty); err != nil {
+		tx.abort()
+		return err
+	}
+	if err := t.stageIncluded(tx); err != nil {
+		tx.abort()
+		return err
+	}
+	stageHistory(tx, taskFilePath, string(before), string(after))
+	if err := tx.commit(); err != nil {
+		return err
+	}
+	recordAudit(taskFilePath, string(before), string(after), t.Finished)
+	recordEvents(string(before), string(after), t.Finished)
+	recordStatus(t)
+	runHook()
+	commitToGit(taskFilePath, string(before), string(after), t.Finished)
+	return nil
+}
+
+// stageTo stages the list as the new contents of the file at path in
+// tx, or removing the file if the list is empty and deleteIfEmpty is set.
+func (t *TaskList) stageTo(tx *transaction, path string, deleteIfEmpty bool) error {
+	marshaledList, _ := t.MarshalText()
+	if deleteIfEmpty && len(marshaledList) == 0 {
+		tx.remove(path)
+		// What --changed last saw of a list that is gone goes with it.
+		tx.remove(seenPath(path))
+		return nil
+	}
+	// Only writing creates the directory; reading a missing file is
+	// just an empty list.
+	return tx.write(path, marshaledList, 0644)
+}
+
+// readTaskList loads the tasks file at path. A missing file is an empty
+// list.
+func readTaskList(path string) (*TaskList, error) {
+	list, err := core.Load(path)
+	return &TaskList{TaskList: *list}, err
+}
+
+// expandPath expands $VAR and ${VAR} references and a leading ~ in a
+// path from the environment, a flag or the config. setting names where
+// the path came from for the error if nothing is left of it.
+func expandPath(path string, setting string) (string, error) {
+	expanded := core.ExpandHome(os.ExpandEnv(path))
+	if strings.TrimSpace(expanded) == "" {
+		return "", fmt.Errorf("%s %q expands to an empty path", setting, path)
+	}
+	return expanded, nil
+}
+
+// getTaskFilePath returns the tasks file: that of the named list if
+// list isn't empty, file if --file was given, T_TASKS_FILE if set, and
+// the default tasks file otherwise.
+func getTaskFilePath(file string, list string) (string, error) {
+	if list != "" {
+		return listPath(list)
+	}
+	if file != "" {
+		return expandPath(file, "--file")
+	}
+	if tasksFilePath := os.Getenv("T_TASKS_FILE"); tasksFilePath != "" {
+		return expandPath(tasksFilePath, "T_TASKS_FILE")
+	}
+	return defaultTaskFilePath()
+}
+
+// defaultTaskFilePath returns ~/tasks if t kept its tasks there, as it
+// used to, and the tasks file in t's data directory otherwise. An empty
+// list has no tasks file, so a done file of ~/tasks also keeps the tasks
+// there.
+func defaultTaskFilePath() (string, error) {
+	if home, err := os.UserHomeDir(); err == nil {
+		legacy := filepath.Join(home, "tasks")
+		for _, path := range []string{legacy, donePath(legacy)} {
+			if _, err := os.Stat(path); err == nil {
+				return legacy, nil
+			}
+		}
+	}
+	dir, err := dataDir()
+	if err != nil {
+		return "", fmt.Errorf("no place for the tasks file: %v; set T_TASKS_FILE or use --file", err)
+	}
+	return filepath.Join(dir, "tasks"), nil
+}
+
+// dataDir returns t's directory in the user's data directory,
+// $XDG_DATA_HOME/t or ~/.local/share/t, and %APPDATA%\t on Windows.
+// It is created when the tasks file is first written.
+func dataDir() (string, error) {
+	if runtime.GOOS == "windows" {
+		dir := os.Getenv("APPDATA")
+		if dir == "" {
+			return "", errors.New("%APPDATA% is not defined")
+		}
+		return filepath.Join(dir, "t"), nil
+	}
+	// The XDG spec has relative paths ignored.
+	if dir := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "t"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
 	}
-	return tasksFilePath
+	return filepath.Join(home, ".local", "share", "t"), nil
 }
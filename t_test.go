@@ -1,19 +1,56 @@
 package main
 
 import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
+
+	core "github.com/t-900/t/tasklist"
 )
 
+// tBinary is the t binary built once for all CLI tests.
+var tBinary string
+
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "t-test")
+	if err != nil {
+		panic(err)
+	}
+	tBinary = filepath.Join(dir, "t")
+	build := exec.Command("go", "build", "-o", tBinary, ".")
+	build.Stderr = os.Stderr
+	if err := build.Run(); err != nil {
+		panic(err)
+	}
+	// The tests and the t they run keep away from the user's own
+	// config, schedule, lists and t --serve.
+	for _, name := range []string{"HOME", "XDG_CONFIG_HOME", "XDG_DATA_HOME", "XDG_CACHE_HOME", "XDG_RUNTIME_DIR"} {
+		home := filepath.Join(dir, name)
+		if err := os.Mkdir(home, 0700); err != nil {
+			panic(err)
+		}
+		os.Setenv(name, home)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 func TestCliAddTask(t *testing.T) {
 	withCliSetup(t, func() {
-		cmd := exec.Command("go", "run", "t.go", "foo")
+		cmd := exec.Command(tBinary, "foo")
 		err := cmd.Run()
 		if err != nil {
 			t.Fatal(err)
 		}
-		listCmd := exec.Command("go", "run", "t.go")
+		listCmd := exec.Command(tBinary)
 		out, err := listCmd.Output()
 		if err != nil {
 			t.Fatal(err)
@@ -28,17 +65,17 @@ func TestCliAddTask(t *testing.T) {
 
 func TestCliFinishTask(t *testing.T) {
 	withCliSetup(t, func() {
-		cmd := exec.Command("go", "run", "t.go", "foo")
+		cmd := exec.Command(tBinary, "foo")
 		err := cmd.Run()
 		if err != nil {
 			t.Fatal(err)
 		}
-		finishCmd := exec.Command("go", "run", "t.go", "-f", "0")
+		finishCmd := exec.Command(tBinary, "-f", "0")
 		err = finishCmd.Run()
 		if err != nil {
 			t.Fatal(err)
 		}
-		listCmd := exec.Command("go", "run", "t.go")
+		listCmd := exec.Command(tBinary)
 		out, err := listCmd.Output()
 		if err != nil {
 			t.Fatal(err)
@@ -52,17 +89,17 @@ func TestCliFinishTask(t *testing.T) {
 
 func TestCliEditTask(t *testing.T) {
 	withCliSetup(t, func() {
-		cmd := exec.Command("go", "run", "t.go", "foo")
+		cmd := exec.Command(tBinary, "foo")
 		err := cmd.Run()
 		if err != nil {
 			t.Fatal(err)
 		}
-		editCmd := exec.Command("go", "run", "t.go", "-e", "0", "bar")
+		editCmd := exec.Command(tBinary, "-e", "0", "bar")
 		err = editCmd.Run()
 		if err != nil {
 			t.Fatal(err)
 		}
-		listCmd := exec.Command("go", "run", "t.go")
+		listCmd := exec.Command(tBinary)
 		out, err := listCmd.Output()
 		if err != nil {
 			t.Fatal(err)
@@ -75,6 +112,32 @@ func TestCliEditTask(t *testing.T) {
 	})
 }
 
+func TestCliEditBlank(t *testing.T) {
+	for _, args := range [][]string{{" "}, {" ", "\t"}} {
+		withCliSetup(t, func() {
+			ioutil.WriteFile("/tmp/tasks", []byte("foo\n"), 0644)
+			out, err := exec.Command(tBinary, append([]string{"-e", "0"}, args...)...).CombinedOutput()
+			if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 || !strings.Contains(string(out), "wasn't changed") {
+				t.Fatalf("Expected editing to %q to fail, got %v: %s", args, err, out)
+			}
+			if text, _ := ioutil.ReadFile("/tmp/tasks"); string(text) != "foo\n" {
+				t.Fatalf("Expected editing to %q to leave the task, got '%s'", args, text)
+			}
+		})
+	}
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("foo\n"), 0644)
+		cmd := exec.Command(tBinary, "-e", "0")
+		cmd.Env = append(cmd.Environ(), "VISUAL=", "EDITOR=true")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("Expected -e without text to open the editor, got %v: %s", err, out)
+		}
+		if text, _ := ioutil.ReadFile("/tmp/tasks"); !strings.HasPrefix(string(text), "foo") {
+			t.Fatalf("Expected an untouched description to be kept, got '%s'", text)
+		}
+	})
+}
+
 func withCliSetup(t *testing.T, testFunc func()) {
 	origTaskFilePath := os.Getenv("T_TASKS_FILE")
 	err := os.Setenv("T_TASKS_FILE", "/tmp/tasks")
@@ -83,71 +146,1357 @@ func withCliSetup(t *testing.T, testFunc func()) {
 	}
 	defer func() {
 		os.Remove("/tmp/tasks")
+		os.Remove("/tmp/tasks.history")
+		os.Remove("/tmp/tasks.done")
+		os.Remove("/tmp/tasks.log")
+		os.Remove("/tmp/tasks.lock")
+		os.Remove("/tmp/tasks.seen")
+		os.Remove("/tmp/tasks.done.today")
+		os.Remove("/tmp/tasks.journal")
+		os.Remove("/tmp/tasks.done.corrupt")
+		os.Remove("/tmp/tasks.corrupt")
+		for n := 1; n <= defaultBackups; n++ {
+			os.Remove(backupPath("/tmp/tasks", n))
+		}
 		os.Setenv("T_TASKS_FILE", origTaskFilePath)
 	}()
 	testFunc()
 }
 
-func TestAddTask(t *testing.T) {
+func TestCliAppendPrepend(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("call bob"), 0644)
+		for _, args := range [][]string{{"--append", "0", "before", "Friday"}, {"--prepend", "0", "URGENT:"}} {
+			if err := exec.Command(tBinary, args...).Run(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if out, _ := exec.Command(tBinary).Output(); string(out) != "0 - URGENT: call bob before Friday\n" {
+			t.Fatalf("Expected the task amended on both ends, got '%s'", out)
+		}
+		for _, args := range [][]string{{"--append", "0"}, {"--prepend", "0", " "}, {"--append", "0", "--prepend", "0", "x"}} {
+			err := exec.Command(tBinary, args...).Run()
+			if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 2 {
+				t.Fatalf("t %s: expected a usage error, got %v", strings.Join(args, " "), err)
+			}
+		}
+	})
+}
+
+func TestFormatTaskLineBreaks(t *testing.T) {
+	task := &Task{Description: "first line\nsecond\r\nthird"}
+	if line := formatTask(0, task, formatOptions{plain: true}); line != "0 - first line⏎second⏎third" {
+		t.Fatalf("Expected line breaks shown as ⏎, got %q", line)
+	}
+}
+
+func TestBumpTask(t *testing.T) {
+	now := time.Date(2024, 6, 10, 9, 0, 0, 0, time.Local)
 	tasklist := TaskList{}
-	tasklist.Add("foo")
-	if len(tasklist.tasks) != 1 {
-		t.Fatalf("Expected list to have one element, got %d", len(tasklist.tasks))
+	tasklist.Add("no due date")
+	overdue, _ := tasklist.Add("overdue")
+	overdue.DueAt = time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)
+
+	tasklist.Bump(0, 2, now)
+	if tasklist.Tasks[0].DueAt.Format(dateLayout) != "2024-06-12" {
+		t.Fatalf("Expected bump to count from today, got %v", tasklist.Tasks[0].DueAt)
+	}
+	ids := tasklist.Overdue(now)
+	if len(ids) != 1 || ids[0] != 1 {
+		t.Fatalf("Expected only task 1 to be overdue, got %v", ids)
+	}
+	tasklist.Bump(1, 7, now)
+	if overdue.DueAt.Format(dateLayout) != "2024-06-08" {
+		t.Fatalf("Expected bump to count from the due date, got %v", overdue.DueAt)
+	}
+	if err := tasklist.Bump(2, 1, now); err == nil {
+		t.Fatal("Expected bumping a missing task to fail")
+	}
+}
+
+func TestCliBumpOverdue(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("foo | due:2000-01-01\nbar"), 0644)
+		out, err := exec.Command(tBinary, "--bump", "overdue", "+1w").Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := "0 - foo: due 2000-01-01 -> 2000-01-08\n"
+		if string(out) != expected {
+			t.Fatalf("Expected output to be '%s', got '%s'", expected, out)
+		}
+		out, _ = exec.Command(tBinary).Output()
+		expected = "0 - foo (due 2000-01-08)\n1 - bar\n"
+		if string(out) != expected {
+			t.Fatalf("Expected output to be '%s', got '%s'", expected, out)
+		}
+	})
+}
+
+func TestCliSetDue(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("fix login +sprint12\nwrite docs\nreview +sprint12 | due:2024-06-01"), 0644)
+		if err := exec.Command(tBinary, "--set-due", "2024-06-14").Run(); err == nil {
+			t.Fatal("Expected --set-due without a query or --all to fail")
+		}
+		out, err := exec.Command(tBinary, "--set-due", "+sprint12", "2024-06-14").Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := "0 - fix login +sprint12 (due 2024-06-14)\n2 - review +sprint12 (due 2024-06-14)\n"
+		if string(out) != expected {
+			t.Fatalf("Expected output to be '%s', got '%s'", expected, out)
+		}
+		if err := exec.Command(tBinary, "--set-due", "--all", "2024-07-01").Run(); err != nil {
+			t.Fatal(err)
+		}
+		text, _ := ioutil.ReadFile("/tmp/tasks")
+		if strings.Count(string(text), "due:2024-07-01") != 3 {
+			t.Fatalf("Expected --all to change every task, got '%s'", text)
+		}
+	})
+}
+
+func TestCliDedupe(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("foo\nbar\nFoo "), 0644)
+		out, err := exec.Command(tBinary, "--dedupe", "--dry-run").Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := "would remove 2 - Foo  (duplicate of 0)\nwould remove 1 duplicates\n"
+		if string(out) != expected {
+			t.Fatalf("Expected output to be '%s', got '%s'", expected, out)
+		}
+		exec.Command(tBinary, "--dedupe").Run()
+		out, _ = exec.Command(tBinary).Output()
+		expected = "0 - foo\n1 - bar\n"
+		if string(out) != expected {
+			t.Fatalf("Expected output to be '%s', got '%s'", expected, out)
+		}
+		// A duplicate isn't done: it is removed, not finished.
+		if done, _ := ioutil.ReadFile("/tmp/tasks.done"); len(done) != 0 {
+			t.Fatalf("Expected nothing in the done file, got '%s'", done)
+		}
+		events := loadAudit("/tmp/tasks")
+		if len(events) != 1 || events[0].Id != core.Hash("Foo ") || events[0].Event != "removed" {
+			t.Fatalf("Expected the duplicate's removal in the audit log, got %+v", events)
+		}
+	})
+}
+
+func TestCliSaveOrder(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("b\na"), 0644)
+		out, _ := exec.Command(tBinary, "--sort", "alpha").Output()
+		expected := "1 - a\n0 - b\n"
+		if string(out) != expected {
+			t.Fatalf("Expected output to be '%s', got '%s'", expected, out)
+		}
+		err := exec.Command(tBinary, "--sort", "size").Run()
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 2 {
+			t.Fatalf("Expected an unknown sort key to be a usage error, got %v", err)
+		}
+		cmd := exec.Command(tBinary, "--sort", "alpha", "--save-order")
+		if err := cmd.Run(); err == nil {
+			t.Fatal("Expected unconfirmed --save-order to fail")
+		}
+		if err := exec.Command(tBinary, "--sort", "alpha", "--save-order", "-y").Run(); err != nil {
+			t.Fatal(err)
+		}
+		out, _ = exec.Command(tBinary).Output()
+		expected = "0 - a\n1 - b\n"
+		if string(out) != expected {
+			t.Fatalf("Expected output to be '%s', got '%s'", expected, out)
+		}
+	})
+}
+
+func TestCliMoveToList(t *testing.T) {
+	withCliSetup(t, func() {
+		dir, _ := ioutil.TempDir("", "t-lists")
+		defer os.RemoveAll(dir)
+		os.Setenv("T_TASKS_DIR", dir)
+		defer os.Unsetenv("T_TASKS_DIR")
+
+		exec.Command(tBinary, "-l", "home", "water plants").Run()
+		ioutil.WriteFile("/tmp/tasks", []byte("foo\nbar | due:2024-06-01"), 0644)
+		out, err := exec.Command(tBinary, "--move-to", "home", "1").Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := "home/1 - bar\n"
+		if string(out) != expected {
+			t.Fatalf("Expected output to be '%s', got '%s'", expected, out)
+		}
+		out, _ = exec.Command(tBinary, "-l", "home").Output()
+		expected = "0 - water plants\n1 - bar (due 2024-06-01)\n"
+		if string(out) != expected {
+			t.Fatalf("Expected output to be '%s', got '%s'", expected, out)
+		}
+		out, _ = exec.Command(tBinary).Output()
+		if string(out) != "0 - foo\n" {
+			t.Fatalf("Expected output to be '0 - foo\n', got '%s'", out)
+		}
+	})
+}
+
+func TestCliMoveToListDropsLinks(t *testing.T) {
+	withCliSetup(t, func() {
+		dir := t.TempDir()
+		os.Setenv("T_TASKS_DIR", dir)
+		defer os.Unsetenv("T_TASKS_DIR")
+
+		ioutil.WriteFile("/tmp/tasks", []byte("foo | link:"+core.Hash("bar")+"\nbar | link:"+core.Hash("foo")), 0644)
+		if out, err := exec.Command(tBinary, "--move-to", "home", "1").CombinedOutput(); err != nil {
+			t.Fatalf("%v: %s", err, out)
+		}
+		if text, _ := ioutil.ReadFile("/tmp/tasks"); string(text) != "foo" {
+			t.Fatalf("Expected the link to the moved task to go, got '%s'", text)
+		}
+		if text, _ := ioutil.ReadFile(filepath.Join(dir, "home")); string(text) != "bar" {
+			t.Fatalf("Expected the moved task without its link, got '%s'", text)
+		}
+	})
+}
+
+func TestCliMoveToLockedList(t *testing.T) {
+	withCliSetup(t, func() {
+		dir := t.TempDir()
+		os.Setenv("T_TASKS_DIR", dir)
+		defer os.Unsetenv("T_TASKS_DIR")
+
+		home := filepath.Join(dir, "home")
+		ioutil.WriteFile(home, []byte("water plants"), 0644)
+		ioutil.WriteFile("/tmp/tasks", []byte("foo"), 0644)
+		lock, err := acquireLock(home)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := exec.Command(tBinary, "--move-to", "home", "0").CombinedOutput()
+		lock.Close()
+		if err == nil || !strings.Contains(string(out), errLocked.Error()) {
+			t.Fatalf("Expected the move refused while home is locked, got '%s' (%v)", out, err)
+		}
+		if text, _ := ioutil.ReadFile(home); string(text) != "water plants" {
+			t.Fatalf("Expected home left alone, got '%s'", text)
+		}
+		if text, _ := ioutil.ReadFile("/tmp/tasks"); string(text) != "foo" {
+			t.Fatalf("Expected the task left on the list, got '%s'", text)
+		}
+	})
+}
+
+func TestCliCopyToList(t *testing.T) {
+	withCliSetup(t, func() {
+		dir, _ := ioutil.TempDir("", "t-lists")
+		defer os.RemoveAll(dir)
+		os.Setenv("T_TASKS_DIR", dir)
+		defer os.Unsetenv("T_TASKS_DIR")
+
+		ioutil.WriteFile("/tmp/tasks", []byte("foo | pomodoros:3"), 0644)
+		cmd := exec.Command(tBinary, "--copy-to", "home", "0")
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != "home/0 - foo\n" {
+			t.Fatalf("Expected output to be 'home/0 - foo\n', got '%s'", out)
+		}
+		for _, args := range [][]string{{}, {"-l", "home"}} {
+			out, _ = exec.Command(tBinary, args...).Output()
+			if string(out) != "0 - foo\n" {
+				t.Fatalf("Expected %v to list '0 - foo\n', got '%s'", args, out)
+			}
+		}
+		original, _ := readTaskList("/tmp/tasks")
+		copied, _ := readTaskList(filepath.Join(dir, "home"))
+		if copied.Tasks[0].Id == original.Tasks[0].Id || copied.Tasks[0].Pomodoros != 0 || original.Tasks[0].Pomodoros != 3 {
+			t.Fatalf("Expected the copy to get an id of its own and no tracked time, got %+v", copied.Tasks[0])
+		}
+	})
+}
+
+func TestSearch(t *testing.T) {
+	tasklist := TaskList{}
+	tasklist.Add("Deploy the thing")
+	tasklist.Add("buy milk")
+	tasklist.Add("redeploy")
+	ids := tasklist.Search("deploy")
+	if len(ids) != 2 || ids[0] != 0 || ids[1] != 2 {
+		t.Fatalf("Expected tasks 0 and 2 to match, got %v", ids)
 	}
+}
 
-	actualTaskDescription := tasklist.tasks[0].description
-	if actualTaskDescription != "foo" {
-		t.Fatalf("expected tasklist to contain 'foo', got '%v'", actualTaskDescription)
+func TestSearchFold(t *testing.T) {
+	defer func() { foldSearch = false }()
+	tasklist := TaskList{}
+	tasklist.Add("Café com João")
+	tasklist.Add("cafeteria")
+	if ids := tasklist.Search("cafe"); len(ids) != 1 {
+		t.Fatalf("Expected only cafeteria to match without folding, got %v", ids)
+	}
+	foldSearch = true
+	if ids := tasklist.Search("CAFE COM JOAO"); len(ids) != 1 || ids[0] != 0 {
+		t.Fatalf("Expected the folded search to ignore case and accents, got %v", ids)
 	}
+	if tasklist.Tasks[0].Description != "Café com João" {
+		t.Fatal("Expected the description to be left untouched")
+	}
+}
+
+func TestCliSearchAllLists(t *testing.T) {
+	withCliSetup(t, func() {
+		dir, _ := ioutil.TempDir("", "t-lists")
+		defer os.RemoveAll(dir)
+		os.Setenv("T_TASKS_DIR", dir)
+		defer os.Unsetenv("T_TASKS_DIR")
+		ioutil.WriteFile(filepath.Join(dir, "work"), []byte("write docs\ndeploy the thing"), 0644)
+		ioutil.WriteFile(filepath.Join(dir, "home"), []byte("deploy shelves"), 0644)
+		os.Mkdir(filepath.Join(dir, "unreadable"), 0700)
+
+		out, err := exec.Command(tBinary, "--all-lists", "-g", "deploy").Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := "home/0 - deploy shelves\nwork/1 - deploy the thing\n"
+		if string(out) != expected {
+			t.Fatalf("Expected output to be '%s', got '%s'", expected, out)
+		}
+
+		if err := exec.Command(tBinary, "-f", "work/1").Run(); err != nil {
+			t.Fatal(err)
+		}
+		out, _ = exec.Command(tBinary, "-l", "work").Output()
+		if string(out) != "0 - write docs\n" {
+			t.Fatalf("Expected output to be '0 - write docs\n', got '%s'", out)
+		}
+	})
+}
+
+func TestCliShowSeveralLists(t *testing.T) {
+	withCliSetup(t, func() {
+		dir, _ := ioutil.TempDir("", "t-lists")
+		defer os.RemoveAll(dir)
+		os.Setenv("T_TASKS_DIR", dir)
+		defer os.Unsetenv("T_TASKS_DIR")
+		ioutil.WriteFile(filepath.Join(dir, "work"), []byte("write docs"), 0644)
+		ioutil.WriteFile(filepath.Join(dir, "home"), []byte("water plants"), 0644)
+
+		out, _ := exec.Command(tBinary, "-l", "work", "-l", "home").Output()
+		expected := "work:\n0 - write docs\n\nhome:\n0 - water plants\n"
+		if string(out) != expected {
+			t.Fatalf("Expected output to be '%s', got '%s'", expected, out)
+		}
+		out, _ = exec.Command(tBinary, "-l", "work", "-l", "home", "--plain").Output()
+		expected = "work/0 - write docs\nhome/0 - water plants\n"
+		if string(out) != expected {
+			t.Fatalf("Expected output to be '%s', got '%s'", expected, out)
+		}
+		if err := exec.Command(tBinary, "-l", "work", "-l", "home", "-f", "0").Run(); err == nil {
+			t.Fatal("Expected finishing with several lists to fail")
+		}
+	})
+}
+
+func TestCliProcessInbox(t *testing.T) {
+	withCliSetup(t, func() {
+		dir, _ := ioutil.TempDir("", "t-lists")
+		defer os.RemoveAll(dir)
+		os.Setenv("T_TASKS_DIR", dir)
+		defer os.Unsetenv("T_TASKS_DIR")
+
+		for _, idea := range []string{"call bob", "old idea", "read book", "someday maybe"} {
+			if err := exec.Command(tBinary, "--in", idea).Run(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		process := exec.Command(tBinary, "--process")
+		process.Stdin = strings.NewReader("t\nphone\nm\nwork\nx\nd\n+3d\ns\n")
+		if err := process.Run(); err != nil {
+			t.Fatal(err)
+		}
+		out, _ := exec.Command(tBinary, "-l", "work").Output()
+		if string(out) != "0 - call bob +phone\n" {
+			t.Fatalf("Expected work list to be '0 - call bob +phone\n', got '%s'", out)
+		}
+		out, _ = exec.Command(tBinary, "-l", "inbox").Output()
+		if string(out) != "1 - someday maybe\n" {
+			t.Fatalf("Expected inbox to be '1 - someday maybe\n', got '%s'", out)
+		}
+		inbox, _ := ioutil.ReadFile(filepath.Join(dir, "inbox"))
+		if !strings.HasPrefix(string(inbox), "read book | snooze:") {
+			t.Fatalf("Expected deferred task to be snoozed, got '%s'", inbox)
+		}
+	})
+}
+
+func TestCliProcessToSomeday(t *testing.T) {
+	withCliSetup(t, func() {
+		dir := t.TempDir()
+		os.Setenv("T_TASKS_DIR", dir)
+		defer os.Unsetenv("T_TASKS_DIR")
+
+		created := time.Now().AddDate(-1, 0, 0).UTC().Format(time.RFC3339)
+		ioutil.WriteFile(filepath.Join(dir, "inbox"), []byte("learn the cello | created:"+created), 0644)
+		if out, _ := exec.Command(tBinary, "-l", "inbox").Output(); string(out) != "0 - learn the cello (stale)\n" {
+			t.Fatalf("Expected the idea to be stale in the inbox, got '%s'", out)
+		}
+		process := exec.Command(tBinary, "--process")
+		process.Stdin = strings.NewReader("m\nsomeday\n")
+		if out, err := process.CombinedOutput(); err != nil {
+			t.Fatalf("%v: %s", err, out)
+		}
+		if out, _ := exec.Command(tBinary, "-l", "someday").Output(); string(out) != "0 - learn the cello\n" {
+			t.Fatalf("Expected a task on the someday list not to go stale, got '%s'", out)
+		}
+		if out, _ := exec.Command(tBinary, "-l", "someday", "--stale").Output(); len(out) != 0 {
+			t.Fatalf("Expected no stale tasks on the someday list, got '%s'", out)
+		}
+		onSomedayList = true
+		defer func() { onSomedayList = false }()
+		list, _ := readTaskList(filepath.Join(dir, "someday"))
+		if line := nagLine(list, time.Now()); line != "" {
+			t.Fatalf("Expected no nag about the someday list, got '%s'", line)
+		}
+		if task := list.Tasks[0]; effectivePriority(task, time.Now()) != storedPriority(task) {
+			t.Fatal("Expected a task on the someday list not to age")
+		}
+	})
+}
+
+func TestCliPomodoro(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("write report"), 0644)
+		cmd := exec.Command(tBinary, "--pomodoro", "0", "--pomodoro-length", "10ms")
+		if err := cmd.Run(); err != nil {
+			t.Fatal(err)
+		}
+		out, _ := exec.Command(tBinary, "--show", "0").Output()
+		expected := "id: 0\nstable id: " + core.Hash("write report") + "\ndescription: write report\npomodoros: 1\n"
+		if string(out) != expected {
+			t.Fatalf("Expected output to be '%s', got '%s'", expected, out)
+		}
+	})
 }
 
-func TestListTasks(t *testing.T) {
+func TestResolveId(t *testing.T) {
 	tasklist := TaskList{}
-	tasks := tasklist.List()
+	if _, err := tasklist.resolveId("last"); err == nil {
+		t.Fatal("Expected keyword on an empty list to fail")
+	}
+	tasklist.Add("foo")
+	tasklist.Add("bar")
+	tasklist.Add("baz")
+	cases := map[string]int{"1": 1, "first": 0, "oldest": 0, "last": 2}
+	for in, expected := range cases {
+		taskId, err := tasklist.resolveId(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if taskId != expected {
+			t.Fatalf("resolveId(%q): expected %d, got %d", in, expected, taskId)
+		}
+	}
+	if _, err := tasklist.resolveId("latest"); err == nil {
+		t.Fatal("Expected unknown keyword to fail")
+	}
+}
 
-	if len(tasks) != 0 {
-		t.Fatalf("Expected tasklist to contain no element, got %d", len(tasks))
+func TestCliFinishLast(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("foo\nbar"), 0644)
+		if err := exec.Command(tBinary, "-f", "last").Run(); err != nil {
+			t.Fatal(err)
+		}
+		out, _ := exec.Command(tBinary).Output()
+		if string(out) != "0 - foo\n" {
+			t.Fatalf("Expected output to be '0 - foo\n', got '%s'", out)
+		}
+	})
+}
+
+func TestResolveIdIndexBase(t *testing.T) {
+	defer func() { core.IndexBase = 0 }()
+	core.IndexBase = 1
+	tasklist := TaskList{}
+	tasklist.Add("foo")
+	tasklist.Add("bar")
+	cases := map[string]int{"1": 0, "2": 1, "first": 0, "last": 1}
+	for in, expected := range cases {
+		taskId, err := tasklist.resolveId(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if taskId != expected {
+			t.Fatalf("resolveId(%q): expected %d, got %d", in, expected, taskId)
+		}
+	}
+	if displayId(0) != 1 {
+		t.Fatalf("Expected index 0 to show as 1, got %d", displayId(0))
+	}
+	if _, err := parseIndexBase("2"); err == nil {
+		t.Fatal("Expected an index base of 2 to be rejected")
 	}
+}
 
-	tasklist.Add("Foo")
-	tasks = tasklist.List()
+func TestCliIndexBase(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("foo\nbar"), 0644)
+		env := append(os.Environ(), "T_INDEX_BASE=1")
+		cmd := exec.Command(tBinary, "-f", "1")
+		cmd.Env = env
+		if err := cmd.Run(); err != nil {
+			t.Fatal(err)
+		}
+		cmd = exec.Command(tBinary)
+		cmd.Env = env
+		out, _ := cmd.Output()
+		if string(out) != "1 - bar\n" {
+			t.Fatalf("Expected output to be '1 - bar\n', got '%s'", out)
+		}
+	})
+}
+
+func TestCliAttach(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("read spec"), 0644)
+		if err := exec.Command(tBinary, "--attach", "0", "/tmp/t-missing.pdf").Run(); err != nil {
+			t.Fatal(err)
+		}
+		out, _ := exec.Command(tBinary, "--show", "0").Output()
+		expected := "id: 0\nstable id: " + core.Hash("read spec") + "\ndescription: read spec\nattachment: /tmp/t-missing.pdf\n"
+		if string(out) != expected {
+			t.Fatalf("Expected output to be '%s', got '%s'", expected, out)
+		}
+		if err := exec.Command(tBinary, "--open-attachment", "0").Run(); err == nil {
+			t.Fatal("Expected opening a missing attachment to fail")
+		}
+	})
+}
+
+func TestStableIds(t *testing.T) {
+	tasklist := TaskList{}
+	tasklist.Add("foo")
+	id := tasklist.Tasks[0].Id
+	if id != "0beec7b5ea3f0fdbc95d0dd47f3c5bc275da8a33" {
+		t.Fatalf("Expected id to be the description's sha1, got '%s'", id)
+	}
+	text, _ := tasklist.MarshalText()
+	if strings.Contains(string(text), "id:") {
+		t.Fatalf("Expected derived ids not to be stored, got '%s'", text)
+	}
 
-	if len(tasks) != 1 {
-		t.Fatalf("Expected tasklist to have one element, got %d", len(tasks))
+	tasklist.Edit(0, "bar")
+	text, _ = tasklist.MarshalText()
+	loaded := TaskList{}
+	loaded.UnmarshalText(text)
+	if loaded.Tasks[0].Id != id {
+		t.Fatalf("Expected id to survive an edit, got '%s' in '%s'", loaded.Tasks[0].Id, text)
 	}
 }
 
-func TestFinishTask(t *testing.T) {
+func TestLinkTasks(t *testing.T) {
 	tasklist := TaskList{}
 	tasklist.Add("foo")
+	tasklist.Add("bar")
+	tasklist.Add("baz")
+	tasklist.Link(0, 2)
+	tasklist.Link(2, 0)
+	tasklist.Link(1, 1)
+	if len(tasklist.Tasks[0].Links) != 1 || len(tasklist.Tasks[2].Links) != 1 {
+		t.Fatalf("Expected exactly one link on each end, got %v and %v",
+			tasklist.Tasks[0].Links, tasklist.Tasks[2].Links)
+	}
+	if tasklist.Tasks[1].Links != nil {
+		t.Fatalf("Expected no self links, got %v", tasklist.Tasks[1].Links)
+	}
 
-	if len(tasklist.tasks) != 1 {
-		t.Fatalf("Expected tasklist to contain one element, got %d", len(tasklist.tasks))
+	tasklist.Finish(1)
+	details := tasklist.formatDetails(0, tasklist.Tasks[0])
+	if !strings.Contains(details, "see also: 1 - baz\n") {
+		t.Fatalf("Expected link to follow renumbering, got '%s'", details)
+	}
+	tasklist.Finish(1)
+	if tasklist.Tasks[0].Links != nil {
+		t.Fatalf("Expected link to be removed with its task, got %v", tasklist.Tasks[0].Links)
 	}
+}
+
+func TestCliShowTask(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("pay rent +home @desk | due:2024-06-01 priority:2"), 0644)
+		out, err := exec.Command(tBinary, "--show", "0").Output()
+		expected := "id: 0\nstable id: " + core.Hash("pay rent +home @desk") + "\ndescription: pay rent +home @desk\n" +
+			"tags: +home @desk\ndue: 2024-06-01\npriority: 2\n"
+		if err != nil || string(out) != expected {
+			t.Fatalf("Expected '%s', got '%s' (%v)", expected, out, err)
+		}
+		cmd := exec.Command(tBinary, "--show", "4")
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		err = cmd.Run()
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 || stderr.String() != "no task for id 4\n" {
+			t.Fatalf("Expected 'no task for id 4' and exit status 1, got '%s' (%v)", stderr.String(), err)
+		}
+	})
+}
 
-	tasklist.Finish(0)
+func TestCliShuffle(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("a1\nb\na2\nc\na3\na4"), 0644)
+		out, _ := exec.Command(tBinary, "--shuffle", "--seed", "7", "-g", "a", "-n", "3").Output()
+		again, _ := exec.Command(tBinary, "--shuffle", "--seed", "7", "-g", "a", "-n", "3").Output()
+		if string(out) != string(again) {
+			t.Fatalf("Expected seeded shuffles to match, got '%s' and '%s'", out, again)
+		}
+		lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("Expected three tasks, got '%s'", out)
+		}
+		listed, _ := exec.Command(tBinary).Output()
+		for _, line := range lines {
+			if !strings.Contains(string(listed), line+"\n") {
+				t.Fatalf("Expected shuffled lines to keep their ids, got '%s'", out)
+			}
+		}
+	})
+}
+
+func TestCliCheck(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("pay rent | due:2024-6-1"), 0644)
+		out, err := exec.Command(tBinary, "--check").Output()
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+			t.Fatalf("Expected --check to exit with 1, got %v", err)
+		}
+		expected := "line 1: invalid due date \"2024-6-1\"\n"
+		if string(out) != expected {
+			t.Fatalf("Expected output to be '%s', got '%s'", expected, out)
+		}
+		if err := exec.Command(tBinary, "--check", "--fix", "-y").Run(); err != nil {
+			t.Fatal(err)
+		}
+		text, _ := ioutil.ReadFile("/tmp/tasks")
+		if string(text) != "pay rent | due:2024-06-01" {
+			t.Fatalf("Expected date to be fixed, got '%s'", text)
+		}
+	})
+}
 
-	if len(tasklist.tasks) != 0 {
-		t.Fatalf("Expected tasklist to contain no element, got %d", len(tasklist.tasks))
+func TestCliInvalidMeta(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("pay rent\nShip it | due:friday"), 0644)
+		var stderr bytes.Buffer
+		cmd := exec.Command(tBinary)
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil || string(out) != "0 - pay rent\n1 - Ship it | due:friday\n" {
+			t.Fatalf("Expected the list read in spite of the line, got '%s' (%v)", out, err)
+		}
+		if !strings.Contains(stderr.String(), `line 2: invalid due date "friday"`) {
+			t.Fatalf("Expected a warning about the line, got '%s'", stderr.String())
+		}
+		if err := exec.Command(tBinary, "-f", "0").Run(); err != nil {
+			t.Fatalf("Expected the list to be changed, got %v", err)
+		}
+		if out, _ := exec.Command(tBinary).Output(); string(out) != "0 - Ship it | due:friday\n" {
+			t.Fatalf("Expected the line kept as the description, got '%s'", out)
+		}
+		// Until it is written back, --check --fix drops the value.
+		ioutil.WriteFile("/tmp/tasks", []byte("Ship it | due:friday owner:me"), 0644)
+		if out, err := exec.Command(tBinary, "--check", "--fix", "-y").Output(); err != nil || string(out) != "applied 1 fixes\n" {
+			t.Fatalf("Expected the value dropped, got '%s' (%v)", out, err)
+		}
+		if text, _ := ioutil.ReadFile("/tmp/tasks"); string(text) != "Ship it | owner:me" {
+			t.Fatalf("Expected the rest of the line kept, got '%s'", text)
+		}
+		if text, _ := ioutil.ReadFile("/tmp/tasks.corrupt"); string(text) != "Ship it | due:friday owner:me" {
+			t.Fatalf("Expected the line kept as it was, got '%s'", text)
+		}
+	})
+}
+
+func TestCliUndoRedo(t *testing.T) {
+	withCliSetup(t, func() {
+		exec.Command(tBinary, "foo").Run()
+		exec.Command(tBinary, "bar").Run()
+		exec.Command(tBinary, "-f", "0").Run()
+
+		for _, expected := range []string{"0 - foo\n1 - bar\n", "0 - foo\n"} {
+			if err := exec.Command(tBinary, "--undo").Run(); err != nil {
+				t.Fatal(err)
+			}
+			out, _ := exec.Command(tBinary).Output()
+			if string(out) != expected {
+				t.Fatalf("Expected output to be '%s', got '%s'", expected, out)
+			}
+		}
+		out, _ := exec.Command(tBinary, "--redo").Output()
+		if string(out) != "redid: t bar\nrestored task: bar\n" {
+			t.Fatalf("Expected output to be 'redid: t bar\nrestored task: bar\n', got '%s'", out)
+		}
+		out, _ = exec.Command(tBinary, "--history").Output()
+		if !strings.HasSuffix(string(out), "  t -f 0  (undone)\n") {
+			t.Fatalf("Expected history to show the undone finish, got '%s'", out)
+		}
+
+		ioutil.WriteFile("/tmp/tasks", []byte("edited by hand"), 0644)
+		if err := exec.Command(tBinary, "--undo").Run(); err == nil {
+			t.Fatal("Expected undo over an external edit to fail")
+		}
+		out, _ = exec.Command(tBinary).Output()
+		if string(out) != "0 - edited by hand\n" {
+			t.Fatalf("Expected external edit to be kept, got '%s'", out)
+		}
+	})
+}
+
+func TestCliUndoEachChange(t *testing.T) {
+	cases := []struct {
+		args     []string
+		restored string
+		listed   string
+	}{
+		{[]string{"bar"}, "undid: t bar\nremoved task: bar\n", "0 - foo\n"},
+		{[]string{"-e", "0", "baz"}, "undid: t -e 0 baz\nrestored task: foo\nremoved task: baz\n", "0 - foo\n"},
+		{[]string{"-f", "0"}, "undid: t -f 0\nrestored task: foo\n", "0 - foo\n"},
+	}
+	for _, c := range cases {
+		withCliSetup(t, func() {
+			exec.Command(tBinary, "foo").Run()
+			if err := exec.Command(tBinary, c.args...).Run(); err != nil {
+				t.Fatal(err)
+			}
+			out, _ := exec.Command(tBinary, "--undo").Output()
+			if string(out) != c.restored {
+				t.Fatalf("Expected undoing t %v to print '%s', got '%s'", c.args, c.restored, out)
+			}
+			out, _ = exec.Command(tBinary).Output()
+			if string(out) != c.listed {
+				t.Fatalf("Expected output to be '%s', got '%s'", c.listed, out)
+			}
+		})
 	}
+	withCliSetup(t, func() {
+		exec.Command(tBinary, "foo").Run()
+		exec.Command(tBinary, "--undo").Run()
+		out, err := exec.Command(tBinary, "--undo").CombinedOutput()
+		if err == nil || string(out) != "nothing to undo\n" {
+			t.Fatalf("Expected a second undo to have nothing to undo, got '%s' (%v)", out, err)
+		}
+	})
+	withCliSetup(t, func() {
+		exec.Command(tBinary, "foo").Run()
+		exec.Command(tBinary, "-f", "0").Run()
+		exec.Command(tBinary, "--undo").Run()
+		if done, _ := ioutil.ReadFile("/tmp/tasks.done"); len(done) != 0 {
+			t.Fatalf("Expected undoing a finish to take it out of the done file, got '%s'", done)
+		}
+		exec.Command(tBinary, "--redo").Run()
+		if done, _ := ioutil.ReadFile("/tmp/tasks.done"); !strings.HasPrefix(string(done), "foo | ") || strings.Contains(string(done), "\n") {
+			t.Fatalf("Expected redoing a finish to put it back in the done file, got '%s'", done)
+		}
+	})
+}
+
+func TestCliRelativeDue(t *testing.T) {
+	withCliSetup(t, func() {
+		exec.Command(tBinary, "--due", "tomorrow", "call dentist").Run()
+		out, _ := exec.Command(tBinary).Output()
+		if string(out) != "0 - call dentist (due tomorrow)\n" {
+			t.Fatalf("Expected output to be '0 - call dentist (due tomorrow)\n', got '%s'", out)
+		}
+		out, _ = exec.Command(tBinary, "--plain").Output()
+		expected := "0 - call dentist (due " + time.Now().AddDate(0, 0, 1).Format(dateLayout) + ")\n"
+		if string(out) != expected {
+			t.Fatalf("Expected output to be '%s', got '%s'", expected, out)
+		}
+	})
 }
 
-func TestEditTask(t *testing.T) {
+func TestOldest(t *testing.T) {
+	now := time.Now()
 	tasklist := TaskList{}
-	tasklist.Add("foo")
+	newer, _ := tasklist.Add("newer")
+	older, _ := tasklist.Add("older")
+	newer.CreatedAt = now.Add(-time.Hour)
+	older.CreatedAt = now.Add(-48 * time.Hour)
+	if taskId, _ := tasklist.resolveId("oldest"); taskId != 1 {
+		t.Fatalf("Expected oldest to be the task created first, got %d", taskId)
+	}
+	tasklist.Add("legacy")
+	tasklist.Tasks[2].CreatedAt = time.Time{}
+	if taskId, _ := tasklist.resolveId("oldest"); taskId != 2 {
+		t.Fatalf("Expected oldest to be the task without a creation time, got %d", taskId)
+	}
+}
+
+func TestCliAge(t *testing.T) {
+	withCliSetup(t, func() {
+		created := time.Now().Add(-50 * time.Hour).UTC().Format(time.RFC3339)
+		ioutil.WriteFile("/tmp/tasks", []byte("old | created:"+created+"\nlegacy"), 0644)
+		out, _ := exec.Command(tBinary, "--age").Output()
+		if string(out) != "0 - old (2d)\n1 - legacy\n" {
+			t.Fatalf("Expected output to be '0 - old (2d)\n1 - legacy\n', got '%s'", out)
+		}
+		out, _ = exec.Command(tBinary, "-v").Output()
+		if string(out) != "0 - old (2d)\n1 - legacy\n" {
+			t.Fatalf("Expected -v to show ages too, got '%s'", out)
+		}
+		out, _ = exec.Command(tBinary, "--age", "--plain").Output()
+		expected := "0 - old (created " + created + ")\n1 - legacy\n"
+		if string(out) != expected {
+			t.Fatalf("Expected output to be '%s', got '%s'", expected, out)
+		}
+	})
+}
+
+func TestCliArchive(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("foo\nbar"), 0644)
+		name := "tasks" + archiveSuffix + time.Now().Format(dateLayout)
+		defer os.Remove("/tmp/" + name)
+		out, err := exec.Command(tBinary, "--archive").Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != "archived 2 tasks to "+name+"\n" {
+			t.Fatalf("Expected output to be 'archived 2 tasks to %s\n', got '%s'", name, out)
+		}
+		if _, err := os.Stat("/tmp/tasks"); !os.IsNotExist(err) {
+			t.Fatal("Expected the empty tasks file to be removed")
+		}
+		out, _ = exec.Command(tBinary, "--archives").Output()
+		if string(out) != name+"\n" {
+			t.Fatalf("Expected output to be '%s\n', got '%s'", name, out)
+		}
+		exec.Command(tBinary, "baz").Run()
+		if err := exec.Command(tBinary, "--import", name).Run(); err != nil {
+			t.Fatal(err)
+		}
+		out, _ = exec.Command(tBinary).Output()
+		if string(out) != "0 - baz\n1 - foo\n2 - bar\n" {
+			t.Fatalf("Expected output to be '0 - baz\n1 - foo\n2 - bar\n', got '%s'", out)
+		}
+		if _, err := os.Stat("/tmp/" + name); !os.IsNotExist(err) {
+			t.Fatal("Expected the imported archive to be removed")
+		}
+	})
+}
+
+func TestCliImportResume(t *testing.T) {
+	withCliSetup(t, func() {
+		defer os.Remove("/tmp/tasks-big")
+		lines := make([]string, 2500)
+		for i := range lines {
+			lines[i] = fmt.Sprintf("task %d", i+1)
+		}
+		lines[2099] = "bad | due:2024-13-45"
+		ioutil.WriteFile("/tmp/tasks-big", []byte(strings.Join(lines, "\n")), 0644)
+		var stderr bytes.Buffer
+		cmd := exec.Command(tBinary, "--import", "/tmp/tasks-big")
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err == nil {
+			t.Fatal("Expected the import to stop at the bad line")
+		}
+		for _, expected := range []string{"imported 1000 tasks (", "imported 2000 tasks (", "line 2100:", "imported 2099 tasks, resume with t --import /tmp/tasks-big --resume-from 2100"} {
+			if !strings.Contains(stderr.String(), expected) {
+				t.Fatalf("Expected stderr to contain %q, got '%s'", expected, stderr.String())
+			}
+		}
+		lines[2099] = "task 2100"
+		ioutil.WriteFile("/tmp/tasks-big", []byte(strings.Join(lines, "\n")), 0644)
+		if err := exec.Command(tBinary, "--import", "/tmp/tasks-big", "--resume-from", "2100").Run(); err != nil {
+			t.Fatal(err)
+		}
+		text, _ := ioutil.ReadFile("/tmp/tasks")
+		if string(text) != strings.Join(lines, "\n") {
+			t.Fatal("Expected every task to be imported once, in order")
+		}
+	})
+}
+
+func TestCliFinishRecordsDone(t *testing.T) {
+	withCliSetup(t, func() {
+		exec.Command(tBinary, "foo").Run()
+		exec.Command(tBinary, "bar").Run()
+		exec.Command(tBinary, "-f", "0").Run()
+		exec.Command(tBinary, "-f", "0").Run()
+		text, _ := ioutil.ReadFile("/tmp/tasks.done")
+		lines := strings.Split(string(text), "\n")
+		if len(lines) != 2 || !strings.HasPrefix(lines[0], "foo | ") || !strings.HasPrefix(lines[1], "bar | ") {
+			t.Fatalf("Expected finished tasks to be appended to the done file, got '%s'", text)
+		}
+		if !strings.Contains(lines[0], " done:") {
+			t.Fatalf("Expected a completion time, got '%s'", lines[0])
+		}
+	})
+}
+
+func TestCliInject(t *testing.T) {
+	withCliSetup(t, func() {
+		dir := t.TempDir()
+		os.Mkdir(filepath.Join(dir, "t"), 0700)
+		ioutil.WriteFile(filepath.Join(dir, "t", "schedule"), []byte("daily stretch\n"), 0644)
+		env := append(os.Environ(), "XDG_CONFIG_HOME="+dir)
+		// A listing while another t holds the lock leaves the tasks for
+		// the next run instead of writing behind its back.
+		lock, err := acquireLock("/tmp/tasks")
+		if err != nil {
+			t.Fatal(err)
+		}
+		cmd := exec.Command(tBinary, "--count")
+		cmd.Env = env
+		out, err := cmd.Output()
+		lock.Close()
+		if _, statErr := os.Stat("/tmp/tasks"); err != nil || statErr == nil {
+			t.Fatalf("Expected the listing to leave the list alone, got '%s' (%v)", out, err)
+		}
+		for i := 0; i < 3; i++ {
+			cmd := exec.Command(tBinary)
+			cmd.Env = env
+			out, _ := cmd.Output()
+			if string(out) != "0 - stretch\n" {
+				t.Fatalf("Expected output to be '0 - stretch\n', got '%s'", out)
+			}
+		}
+		cmd = exec.Command(tBinary, "--inject")
+		cmd.Env = env
+		out, _ = cmd.Output()
+		if string(out) != "added 0 scheduled tasks\n" {
+			t.Fatalf("Expected output to be 'added 0 scheduled tasks\n', got '%s'", out)
+		}
+	})
+}
+
+func TestCliLog(t *testing.T) {
+	withCliSetup(t, func() {
+		exec.Command(tBinary, "foo").Run()
+		exec.Command(tBinary, "bar").Run()
+		exec.Command(tBinary, "-e", "0", "baz").Run()
+		out, _ := exec.Command(tBinary, "--log", "0").Output()
+		lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+		if len(lines) != 2 || !strings.HasSuffix(lines[0], "  created: foo") || !strings.HasSuffix(lines[1], "  edited: foo → baz") {
+			t.Fatalf("Expected the creation and the edit to be logged, got '%s'", out)
+		}
+	})
+}
+
+func TestCliJSONIn(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("a\nb"), 0644)
+		cmd := exec.Command(tBinary, "--json-in")
+		cmd.Stdin = strings.NewReader(`[{"op":"add","description":"c"},{"op":"finish","id":9}]`)
+		out, err := cmd.Output()
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+			t.Fatalf("Expected a failed batch to exit with 1, got %v", err)
+		}
+		if !strings.Contains(string(out), `"ok":false`) {
+			t.Fatalf("Expected a result for the failed operation, got '%s'", out)
+		}
+		text, _ := ioutil.ReadFile("/tmp/tasks")
+		if string(text) != "a\nb" {
+			t.Fatalf("Expected nothing to be written, got '%s'", text)
+		}
+
+		cmd = exec.Command(tBinary, "--json-in")
+		cmd.Stdin = strings.NewReader(`[{"op":"finish","id":0},{"op":"add","description":"c"}]`)
+		if err := cmd.Run(); err != nil {
+			t.Fatal(err)
+		}
+		out, _ = exec.Command(tBinary).Output()
+		if string(out) != "0 - b\n1 - c\n" {
+			t.Fatalf("Expected output to be '0 - b\n1 - c\n', got '%s'", out)
+		}
+	})
+}
 
-	if len(tasklist.tasks) != 1 {
-		t.Fatalf("Expected tasklist to contain one element, got %d", len(tasklist.tasks))
+func TestCliFinishWithoutIdNeedsTerminal(t *testing.T) {
+	withCliSetup(t, func() {
+		exec.Command(tBinary, "foo").Run()
+		cmd := exec.Command(tBinary, "-f")
+		cmd.Stdin = strings.NewReader("0\n")
+		err := cmd.Run()
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 2 {
+			t.Fatalf("Expected t -f without a terminal to exit with 2, got %v", err)
+		}
+		out, _ := exec.Command(tBinary).Output()
+		if string(out) != "0 - foo\n" {
+			t.Fatalf("Expected nothing to be finished, got '%s'", out)
+		}
+	})
+}
+
+func TestCliFinishMatching(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("bug 1 WONTFIX\nbug 2\nbug 3 wontfix"), 0644)
+		out, err := exec.Command(tBinary, "-f", "--match", "WONTFIX", "-y").Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := "0 - bug 1 WONTFIX\n2 - bug 3 wontfix\nfinished 2 tasks\n"
+		if string(out) != expected {
+			t.Fatalf("Expected output to be '%s', got '%s'", expected, out)
+		}
+		out, _ = exec.Command(tBinary).Output()
+		if string(out) != "0 - bug 2\n" {
+			t.Fatalf("Expected output to be '0 - bug 2\n', got '%s'", out)
+		}
+		err = exec.Command(tBinary, "--finish-matching", "WONTFIX", "-y").Run()
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+			t.Fatalf("Expected no matches to exit with 1, got %v", err)
+		}
+	})
+}
+
+func TestGetTaskFilePathExpansion(t *testing.T) {
+	defer os.Setenv("T_TASKS_FILE", os.Getenv("T_TASKS_FILE"))
+	os.Setenv("T_SYNC_DIR", "/sync")
+	defer os.Unsetenv("T_SYNC_DIR")
+	home, _ := os.UserHomeDir()
+	cases := map[string]string{
+		"~/sync/tasks":         filepath.Join(home, "sync/tasks"),
+		"$T_SYNC_DIR/tasks":    "/sync/tasks",
+		"${T_SYNC_DIR}/tasks2": "/sync/tasks2",
+	}
+	for value, expected := range cases {
+		os.Setenv("T_TASKS_FILE", value)
+		if path, err := getTaskFilePath("", ""); err != nil || path != expected {
+			t.Fatalf("T_TASKS_FILE=%s: expected %s, got %s (%v)", value, expected, path, err)
+		}
+		if path, err := getTaskFilePath(value, ""); err != nil || path != expected {
+			t.Fatalf("--file %s: expected %s, got %s (%v)", value, expected, path, err)
+		}
 	}
-	actualTaskDescription := tasklist.tasks[0].description
-	if actualTaskDescription != "foo" {
-		t.Fatalf("expected tasklist to contain 'foo', got '%v'", actualTaskDescription)
+	os.Setenv("T_TASKS_FILE", "$T_UNSET_VARIABLE")
+	if _, err := getTaskFilePath("", ""); err == nil {
+		t.Fatal("Expected a path expanding to nothing to be rejected")
 	}
+}
 
-	tasklist.Edit(0, "bar")
+func TestGetTaskFilePathDefault(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the default is in %APPDATA% on Windows")
+	}
+	home, data := t.TempDir(), t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_DATA_HOME", data)
+	t.Setenv("T_TASKS_FILE", "")
+	// Without ~/tasks, the tasks file is in the data directory.
+	if path, err := getTaskFilePath("", ""); err != nil || path != filepath.Join(data, "t", "tasks") {
+		t.Fatalf("Expected the tasks file in $XDG_DATA_HOME, got %s (%v)", path, err)
+	}
+	t.Setenv("XDG_DATA_HOME", "relative")
+	if path, err := getTaskFilePath("", ""); err != nil || path != filepath.Join(home, ".local", "share", "t", "tasks") {
+		t.Fatalf("Expected a relative $XDG_DATA_HOME ignored, got %s (%v)", path, err)
+	}
+	// A done file of ~/tasks keeps the tasks there, and so does ~/tasks.
+	legacy := filepath.Join(home, "tasks")
+	ioutil.WriteFile(legacy+".done", []byte("pay rent\n"), 0600)
+	if path, err := getTaskFilePath("", ""); err != nil || path != legacy {
+		t.Fatalf("Expected ~/tasks kept for its done file, got %s (%v)", path, err)
+	}
+	os.Remove(legacy + ".done")
+	ioutil.WriteFile(legacy, []byte("pay rent\n"), 0600)
+	if path, err := getTaskFilePath("", ""); err != nil || path != legacy {
+		t.Fatalf("Expected ~/tasks kept, got %s (%v)", path, err)
+	}
+	// T_TASKS_FILE comes first.
+	t.Setenv("T_TASKS_FILE", filepath.Join(data, "mine"))
+	if path, err := getTaskFilePath("", ""); err != nil || path != filepath.Join(data, "mine") {
+		t.Fatalf("Expected T_TASKS_FILE to win, got %s (%v)", path, err)
+	}
+	// Without a home or a data directory, there is no tasks file.
+	t.Setenv("T_TASKS_FILE", "")
+	t.Setenv("HOME", "")
+	if _, err := getTaskFilePath("", ""); err == nil || !strings.Contains(err.Error(), "T_TASKS_FILE") {
+		t.Fatalf("Expected an error pointing at T_TASKS_FILE, got %v", err)
+	}
+}
+
+func TestCliNoHome(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the default is in %APPDATA% on Windows")
+	}
+	env := make([]string, 0)
+	for _, v := range os.Environ() {
+		if !strings.HasPrefix(v, "HOME=") && !strings.HasPrefix(v, "XDG_DATA_HOME=") && !strings.HasPrefix(v, "T_TASKS_FILE=") {
+			env = append(env, v)
+		}
+	}
+	cmd := exec.Command(tBinary, "pay rent")
+	cmd.Env = env
+	out, err := cmd.CombinedOutput()
+	if err == nil || !strings.Contains(string(out), "no place for the tasks file") {
+		t.Fatalf("Expected t to fail without a home, got '%s' (%v)", out, err)
+	}
+	// An XDG_DATA_HOME is enough, and the directory is made for the file.
+	data := t.TempDir()
+	cmd = exec.Command(tBinary, "-q", "pay rent")
+	cmd.Env = append(env, "XDG_DATA_HOME="+data)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Expected the task added, got '%s' (%v)", out, err)
+	}
+	if text, err := ioutil.ReadFile(filepath.Join(data, "t", "tasks")); err != nil || !strings.HasPrefix(string(text), "pay rent") {
+		t.Fatalf("Expected the tasks file in $XDG_DATA_HOME/t, got '%s' (%v)", text, err)
+	}
+}
+
+func TestCliCreatesTasksDirectory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes", "t", "tasks")
+	cmd := exec.Command(tBinary)
+	cmd.Env = append(os.Environ(), "T_TASKS_FILE="+path)
+	if out, err := cmd.Output(); err != nil || string(out) != "" {
+		t.Fatalf("Expected listing a missing directory to be an empty list, got '%s' (%v)", out, err)
+	}
+	if _, err := os.Stat(filepath.Dir(path)); !os.IsNotExist(err) {
+		t.Fatal("Expected listing not to create the directory")
+	}
+	cmd = exec.Command(tBinary, "foo")
+	cmd.Env = append(os.Environ(), "T_TASKS_FILE="+path)
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(filepath.Dir(path)); err != nil || info.Mode().Perm() != 0700 {
+		t.Fatalf("Expected the directory to be created with 0700, got %v (%v)", info, err)
+	}
+
+	blocked := filepath.Join(filepath.Dir(path), "tasks", "nested")
+	cmd = exec.Command(tBinary, "foo")
+	cmd.Env = append(os.Environ(), "T_TASKS_FILE="+blocked)
+	out, err := cmd.CombinedOutput()
+	if err == nil || !strings.Contains(string(out), blocked) {
+		t.Fatalf("Expected the failed write to be reported with the path, got '%s' (%v)", out, err)
+	}
+}
+
+func TestCliStrictWip(t *testing.T) {
+	withCliSetup(t, func() {
+		dir := t.TempDir()
+		os.Mkdir(filepath.Join(dir, "t"), 0700)
+		ioutil.WriteFile(filepath.Join(dir, "t", "config"), []byte("wip_limit = 1\n"), 0644)
+		env := append(os.Environ(), "XDG_CONFIG_HOME="+dir)
+		for _, args := range [][]string{{"foo"}, {"bar"}} {
+			cmd := exec.Command(tBinary, args...)
+			cmd.Env = env
+			cmd.Run()
+		}
+		cmd := exec.Command(tBinary, "--strict-wip", "baz")
+		cmd.Env = env
+		out, err := cmd.CombinedOutput()
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+			t.Fatalf("Expected --strict-wip to refuse the add, got %v", err)
+		}
+		if !strings.Contains(string(out), "3 tasks, over the WIP limit of 1") {
+			t.Fatalf("Expected a WIP warning, got '%s'", out)
+		}
+		out, _ = exec.Command(tBinary).Output()
+		if string(out) != "0 - foo\n1 - bar\n" {
+			t.Fatalf("Expected output to be '0 - foo\n1 - bar\n', got '%s'", out)
+		}
+	})
+}
 
-	actualTaskDescription = tasklist.tasks[0].description
-	if actualTaskDescription != "bar" {
-		t.Fatalf("expected tasklist to contain 'bar', got '%v'", actualTaskDescription)
+func TestShellQuote(t *testing.T) {
+	cases := map[string]string{
+		"":                       "''",
+		"plain words":            "'plain words'",
+		"it's":                   `'it'\''s'`,
+		"$(rm -rf ~) `id` $HOME": "'$(rm -rf ~) `id` $HOME'",
+		"''":                     `''\'''\'''`,
+	}
+	for in, expected := range cases {
+		if quoted := shellQuote(in); quoted != expected {
+			t.Fatalf("shellQuote(%q): expected %s, got %s", in, expected, quoted)
+		}
+		out, err := exec.Command("sh", "-c", "printf %s "+shellQuote(in)).Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != in {
+			t.Fatalf("Expected the shell to read %s back as %q, got %q", shellQuote(in), in, out)
+		}
 	}
 }
+
+func TestCliQuote(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("don't run $(id)"), 0644)
+		out, _ := exec.Command(tBinary, "--plain", "--quote").Output()
+		expected := "0 - 'don'\\''t run $(id)'\n"
+		if string(out) != expected {
+			t.Fatalf("Expected output to be %q, got %q", expected, out)
+		}
+	})
+}
+
+func TestCliExitCodes(t *testing.T) {
+	cases := []struct {
+		tasks string
+		args  []string
+		code  int
+	}{
+		{"a\nb\nc", []string{"-f", "99"}, 1},
+		{"a\nb\nc", []string{"-e", "99", "x"}, 1},
+		{"a\nb\nc", []string{"-e", "-1", "x"}, 1},
+		{"a\nb\nc", []string{"-e", "nope", "x"}, 2},
+		{"pay rent\ncaf\xe9", []string{}, 1},
+		{"pay rent\ncaf\xe9", []string{"new task"}, 1},
+		{"a", []string{strings.Repeat("x", core.MaxDescriptionLength+1)}, 1},
+	}
+	for _, c := range cases {
+		withCliSetup(t, func() {
+			ioutil.WriteFile("/tmp/tasks", []byte(c.tasks), 0644)
+			var stderr bytes.Buffer
+			cmd := exec.Command(tBinary, c.args...)
+			cmd.Stderr = &stderr
+			err := cmd.Run()
+			if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != c.code {
+				t.Fatalf("Expected t %v to exit with %d, got %v", c.args, c.code, err)
+			}
+			if stderr.Len() == 0 {
+				t.Fatalf("Expected t %v to explain the error on stderr", c.args)
+			}
+			text, _ := ioutil.ReadFile("/tmp/tasks")
+			if string(text) != c.tasks {
+				t.Fatalf("Expected t %v to leave the tasks alone, got '%s'", c.args, text)
+			}
+		})
+	}
+}
+
+func TestCliHasAndEmpty(t *testing.T) {
+	withCliSetup(t, func() {
+		cases := []struct {
+			tasks string
+			args  []string
+			code  int
+		}{
+			{"", []string{"--empty"}, 0},
+			{"", []string{"--has", "+urgent"}, 1},
+			{"fix bug +urgent\nwater plants", []string{"--has", "+urgent"}, 0},
+			{"fix bug +urgent\nwater plants", []string{"--has", "+later"}, 1},
+			{"fix bug +urgent\nwater plants", []string{"--empty"}, 1},
+			{"pay rent\ncaf\xe9", []string{"--empty"}, 2},
+		}
+		for _, c := range cases {
+			os.Remove("/tmp/tasks")
+			if c.tasks != "" {
+				ioutil.WriteFile("/tmp/tasks", []byte(c.tasks), 0644)
+			}
+			out, err := exec.Command(tBinary, c.args...).Output()
+			code := 0
+			if exitErr, ok := err.(*exec.ExitError); ok {
+				code = exitErr.ExitCode()
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if code != c.code || len(out) != 0 {
+				t.Fatalf("Expected t %v to exit with %d silently, got %d and '%s'", c.args, c.code, code, out)
+			}
+			if _, err := os.Stat("/tmp/tasks"); c.tasks == "" && err == nil {
+				t.Fatalf("Expected t %v not to create the tasks file", c.args)
+			}
+		}
+	})
+}
+
+func TestCliTaskDir(t *testing.T) {
+	withCliSetup(t, func() {
+		dir := filepath.Join(t.TempDir(), "lists")
+		out, err := exec.Command(tBinary, "--task-dir", dir, "-l", "work").Output()
+		if err != nil || len(out) != 0 {
+			t.Fatalf("Expected a missing list to list nothing, got '%s' (%v)", out, err)
+		}
+		if err := exec.Command(tBinary, "--task-dir", dir, "-l", "work", "buy standing desk").Run(); err != nil {
+			t.Fatal(err)
+		}
+		text, _ := ioutil.ReadFile(filepath.Join(dir, "work"))
+		if !strings.HasPrefix(string(text), "buy standing desk") {
+			t.Fatalf("Expected the task in the work list, got '%s'", text)
+		}
+		cmd := exec.Command(tBinary, "-l", "work")
+		cmd.Env = append(os.Environ(), "T_TASK_DIR="+dir)
+		out, _ = cmd.Output()
+		if string(out) != "0 - buy standing desk\n" {
+			t.Fatalf("Expected T_TASK_DIR to find the list, got '%s'", out)
+		}
+	})
+}
+
+func TestFilter(t *testing.T) {
+	list := &TaskList{}
+	list.UnmarshalText([]byte("buy Groceries\ncall bob\nemail alice\ngroceries again"))
+	lines, err := list.Filter("groceries", false)
+	if err != nil || strings.Join(lines, "\n") != "0 - buy Groceries\n3 - groceries again" {
+		t.Fatalf("Expected both grocery tasks with their ids, got %v (%v)", lines, err)
+	}
+	lines, err = list.Filter("^(call|email) ", true)
+	if err != nil || strings.Join(lines, "\n") != "1 - call bob\n2 - email alice" {
+		t.Fatalf("Expected the tasks matching the regexp, got %v (%v)", lines, err)
+	}
+	if _, err := list.Filter("(", true); err == nil {
+		t.Fatal("Expected an invalid regexp to fail")
+	}
+}
+
+func TestCliRegexpFilter(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("buy milk\ncall bob\nemail alice"), 0644)
+		out, _ := exec.Command(tBinary, "-E", "-g", "^(call|email) ").Output()
+		if string(out) != "1 - call bob\n2 - email alice\n" {
+			t.Fatalf("Expected the matching tasks with their ids, got '%s'", out)
+		}
+		err := exec.Command(tBinary, "-E", "-g", "(").Run()
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 2 {
+			t.Fatalf("Expected an invalid pattern to exit with 2, got %v", err)
+		}
+	})
+}
+
+func TestCliReadOnlyDirectory(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can write to read-only directories")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tasks")
+	ioutil.WriteFile(path, []byte("water plants\npay rent"), 0644)
+	os.Chmod(dir, 0555)
+	defer os.Chmod(dir, 0755)
+	cmd := exec.Command(tBinary, "-f", "0")
+	cmd.Env = append(os.Environ(), "T_TASKS_FILE="+path)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 || stderr.Len() == 0 {
+		t.Fatalf("Expected the failed write to exit with 1 and say why, got %v", err)
+	}
+	text, _ := ioutil.ReadFile(path)
+	if string(text) != "water plants\npay rent" {
+		t.Fatalf("Expected the tasks file to be untouched, got '%s'", text)
+	}
+}
+
+func TestCliClear(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("a\nb"), 0644)
+		err := exec.Command(tBinary, "--clear").Run()
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+			t.Fatalf("Expected --clear without a terminal or --yes to exit with 1, got %v", err)
+		}
+		if text, _ := ioutil.ReadFile("/tmp/tasks"); string(text) != "a\nb" {
+			t.Fatalf("Expected the tasks to be left alone, got '%s'", text)
+		}
+		out, err := exec.Command(tBinary, "--clear", "--yes").Output()
+		if err != nil || string(out) != "deleted 2 tasks\n" {
+			t.Fatalf("Expected both tasks deleted, got '%s' (%v)", out, err)
+		}
+		if out, _ := exec.Command(tBinary).Output(); string(out) != "" {
+			t.Fatalf("Expected no tasks left, got '%s'", out)
+		}
+		if _, err := os.Stat("/tmp/tasks.done"); !os.IsNotExist(err) {
+			t.Fatal("Expected cleared tasks not to go to the done file")
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// tagCount is a tag or context and the number of tasks using it.
+type tagCount struct {
+	tag   string
+	count int
+}
+
+func (c tagCount) String() string {
+	return fmt.Sprintf("%s %d", c.tag, c.count)
+}
+
+// isTag reports whether a word of a description is a +tag, a #tag or
+// an @context.
+func isTag(word string) bool {
+	return len(word) > 1 && (word[0] == '+' || word[0] == '#' || word[0] == '@')
+}
+
+// Tags returns the tags and contexts of the list's tasks, most used
+// first.
+func (t *TaskList) Tags() []string {
+	tags := make([]string, 0)
+	for _, count := range tagCounts(t) {
+		tags = append(tags, count.tag)
+	}
+	return tags
+}
+
+// tagIds returns the ids of the tasks with the tag, given as home, +home
+// or #home, which match both +home and #home, or as the @context. Tags
+// match whatever their case.
+func (t *TaskList) tagIds(ids []int, tag string) []int {
+	tagged := make([]int, 0)
+	for _, taskId := range ids {
+		task := t.Tasks[taskId]
+		if (strings.HasPrefix(tag, "@") && hasWord(task, tag)) || hasTag(task, strings.TrimLeft(tag, "+#")) {
+			tagged = append(tagged, taskId)
+		}
+	}
+	return tagged
+}
+
+// tagCounts counts the tasks using each tag and @context, most used
+// first. Tags differing only in case, or in being a +tag or a #tag,
+// count as one, spelled the way they were first seen.
+func tagCounts(lists ...*TaskList) []tagCount {
+	counts := make([]tagCount, 0)
+	index := make(map[string]int)
+	for _, list := range lists {
+		for _, task := range list.Tasks {
+			seen := make(map[string]bool)
+			for _, word := range strings.Fields(task.Description) {
+				key := strings.ToLower(word)
+				if strings.HasPrefix(key, "#") {
+					key = "+" + key[1:]
+				}
+				if !isTag(word) || seen[key] {
+					continue
+				}
+				seen[key] = true
+				i, ok := index[key]
+				if !ok {
+					i = len(counts)
+					index[key] = i
+					counts = append(counts, tagCount{tag: word})
+				}
+				counts[i].count++
+			}
+		}
+	}
+	sort.SliceStable(counts, func(i, j int) bool {
+		if counts[i].count != counts[j].count {
+			return counts[i].count > counts[j].count
+		}
+		return strings.ToLower(counts[i].tag) < strings.ToLower(counts[j].tag)
+	})
+	return counts
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+func TestTagCounts(t *testing.T) {
+	tasklist := TaskList{}
+	tasklist.UnmarshalText([]byte("buy milk +Errands @town\npost letter +errands +errands\ncall bob @phone\nfix + and @"))
+	done := TaskList{}
+	done.UnmarshalText([]byte("pick up parcel @town"))
+	expected := []tagCount{{"+Errands", 2}, {"@town", 2}, {"@phone", 1}}
+	if counts := tagCounts(&tasklist, &done); !reflect.DeepEqual(counts, expected) {
+		t.Fatalf("Expected %v, got %v", expected, counts)
+	}
+}
+
+func TestTagIds(t *testing.T) {
+	tasklist := TaskList{}
+	tasklist.UnmarshalText([]byte("fix the deck #Home\npaint fence +home\ncall bob @home\nhomework"))
+	ids := []int{0, 1, 2, 3}
+	cases := map[string][]int{"home": {0, 1}, "#HOME": {0, 1}, "+home": {0, 1}, "@home": {2}, "work": {}}
+	for tag, expected := range cases {
+		if tagged := tasklist.tagIds(ids, tag); !reflect.DeepEqual(tagged, expected) {
+			t.Fatalf("tagIds(%q): expected %v, got %v", tag, expected, tagged)
+		}
+	}
+	if tags := tasklist.Tags(); !reflect.DeepEqual(tags, []string{"#Home", "@home"}) {
+		t.Fatalf("Expected #Home and +home to count as one tag, got %v", tags)
+	}
+}
+
+func TestCliTag(t *testing.T) {
+	withCliSetup(t, func() {
+		exec.Command(tBinary, "buy milk").Run()
+		exec.Command(tBinary, "fix the deck #home").Run()
+		out, _ := exec.Command(tBinary, "--tag", "home").Output()
+		if string(out) != "1 - fix the deck #home\n" {
+			t.Fatalf("Expected output to be '1 - fix the deck #home\n', got '%s'", out)
+		}
+		if err := exec.Command(tBinary, "-f", "1").Run(); err != nil {
+			t.Fatal(err)
+		}
+		out, _ = exec.Command(tBinary).Output()
+		if string(out) != "0 - buy milk\n" {
+			t.Fatalf("Expected output to be '0 - buy milk\n', got '%s'", out)
+		}
+	})
+}
@@ -0,0 +1,297 @@
+package tasklist
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// IndexBase is the id of the first task, 0 unless the user numbers tasks
+// from 1. Tasks are numbered from 0 everywhere but in what t reads from
+// and shows to the user, like the errors of Get.
+var IndexBase = 0
+
+// ErrEmptyDescription refuses a task with nothing but whitespace to it,
+// which couldn't be told from a blank line in the tasks file.
+var ErrEmptyDescription = errors.New("description is empty")
+
+// ErrNoAmendment is returned for text to append or prepend that has
+// nothing but spaces.
+var ErrNoAmendment = errors.New("nothing to add to the description")
+
+// LimitDescription checks a description given to Add or Edit against
+// DescriptionLimit, truncating it if truncate is set. A blank one is
+// refused.
+func LimitDescription(description string, truncate bool) (string, error) {
+	if strings.TrimSpace(description) == "" {
+		return "", ErrEmptyDescription
+	}
+	if len(description) <= DescriptionLimit {
+		return description, nil
+	}
+	if truncate {
+		return TruncateDescription(description, DescriptionLimit), nil
+	}
+	return "", fmt.Errorf("description is %d bytes, over the limit of %d (--truncate shortens it)", len(description), DescriptionLimit)
+}
+
+// Add adds a task to the end of the list and returns it.
+func (t *TaskList) Add(taskDescription string) (*Task, error) {
+	task, err := t.NewTask(taskDescription)
+	if err != nil {
+		return nil, err
+	}
+	if t.Tasks == nil {
+		t.Tasks = make([]*Task, 0)
+	}
+	t.Tasks = append(t.Tasks, task)
+	return task, nil
+}
+
+// NewTask returns a task to add to the list, its description normalized
+// unless the list is Raw, created now.
+func (t *TaskList) NewTask(taskDescription string) (*Task, error) {
+	if !t.Raw {
+		taskDescription = NormalizeText(taskDescription)
+	}
+	taskDescription, err := LimitDescription(taskDescription, t.Truncate)
+	if err != nil {
+		return nil, err
+	}
+	task := Task{
+		Description: taskDescription,
+		Id:          t.NewId(taskDescription),
+		CreatedAt:   time.Now().Truncate(time.Second),
+	}
+	return &task, nil
+}
+
+// NewId returns a stable id for a task added now, the hash of its
+// description unless another task on the list, or on one of others,
+// already has that id, as it does when the same task is added twice.
+func (t *TaskList) NewId(description string, others ...*TaskList) string {
+	taken := func(id string) bool {
+		for _, list := range append([]*TaskList{t}, others...) {
+			if list.IndexOf(id) != -1 {
+				return true
+			}
+		}
+		return false
+	}
+	id := Hash(description)
+	for n := 2; taken(id); n++ {
+		id = Hash(fmt.Sprintf("%s\n%d", description, n))
+	}
+	return id
+}
+
+// List returns the ids of the tasks listed at now, leaving out those
+// Snoozed hides.
+func (t *TaskList) List(now time.Time) []int {
+	ids := make([]int, 0, len(t.Tasks))
+	for i, task := range t.Tasks {
+		if !Snoozed(task, now) {
+			ids = append(ids, i)
+		}
+	}
+	return ids
+}
+
+// IndexOf returns the index of the task with the given stable id, or -1.
+func (t *TaskList) IndexOf(id string) int {
+	for i, task := range t.Tasks {
+		if task.Id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Get returns the task with the given id, or an error naming the id if
+// there is none.
+func (t *TaskList) Get(taskId int) (*Task, error) {
+	if taskId < 0 || len(t.Tasks) <= taskId {
+		return nil, fmt.Errorf("no task for id %d", taskId+IndexBase)
+	}
+	return t.Tasks[taskId], nil
+}
+
+// Edit replaces the description of a task, normalized unless the list
+// is Raw. A task that was waiting on someone isn't anymore. A list that
+// keeps tombstones records when the task was edited, for merges.
+func (t *TaskList) Edit(taskId int, newDescription string) error {
+	task, err := t.Get(taskId)
+	if err != nil {
+		return err
+	}
+	if !t.Raw {
+		newDescription = NormalizeText(newDescription)
+	}
+	if newDescription, err = LimitDescription(newDescription, t.Truncate); err != nil {
+		return err
+	}
+	task.Description = newDescription
+	task.Waiting, task.WaitingFor = false, ""
+	if t.KeepTombstones {
+		task.EditedAt = time.Now().Truncate(time.Second)
+	}
+	return nil
+}
+
+// Append adds text to the end of a task's description, after a space,
+// as an edit of it.
+func (t *TaskList) Append(taskId int, text string) error {
+	task, err := t.Get(taskId)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(text) == "" {
+		return ErrNoAmendment
+	}
+	return t.Edit(taskId, task.Description+" "+text)
+}
+
+// Prepend adds text to the front of a task's description, before a
+// space, as an edit of it.
+func (t *TaskList) Prepend(taskId int, text string) error {
+	task, err := t.Get(taskId)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(text) == "" {
+		return ErrNoAmendment
+	}
+	return t.Edit(taskId, text+" "+task.Description)
+}
+
+// Finish takes a task off the list and returns it, for the done file.
+// A recurring task stays, due again its interval later, and a copy of it
+// is returned instead. Open subtasks are finished with the task, or stay
+// on the list in its place with KeepChildren.
+func (t *TaskList) Finish(taskId int) (*Task, error) {
+	if task, err := t.Get(taskId); err == nil && task.RecurEvery > 0 {
+		finished := task.Clone()
+		if t.KeepChildren {
+			finished.Children = nil
+		} else {
+			task.Children = nil
+		}
+		Reschedule(task, time.Now())
+		return finished, nil
+	}
+	task, err := t.Remove(taskId)
+	if err != nil {
+		return nil, err
+	}
+	if t.KeepChildren {
+		t.promoteChildren(taskId, task)
+	}
+	t.Unlink(task.Id)
+	if t.Finished == nil {
+		t.Finished = make(map[string]bool)
+	}
+	t.Finished[task.Id] = true
+	return task, nil
+}
+
+// Delete takes a task off the list without finishing it, for a task
+// that shouldn't have been there, so it never goes to the done file.
+func (t *TaskList) Delete(taskId int) error {
+	task, err := t.Remove(taskId)
+	if err != nil {
+		return err
+	}
+	t.Unlink(task.Id)
+	return nil
+}
+
+// Clear takes every task off the list without finishing them, and
+// returns how many there were.
+func (t *TaskList) Clear() int {
+	n := len(t.Tasks)
+	for _, task := range t.Tasks {
+		t.Bury(task)
+	}
+	t.Tasks = make([]*Task, 0)
+	return n
+}
+
+// Remove takes a task off the list and returns it, leaving its links
+// to the caller.
+func (t *TaskList) Remove(taskId int) (*Task, error) {
+	removed, err := t.Get(taskId)
+	if err != nil {
+		return nil, err
+	}
+	newTasks := make([]*Task, 0)
+	for i, task := range t.Tasks {
+		if i != taskId {
+			newTasks = append(newTasks, task)
+		}
+	}
+	t.Tasks = newTasks
+	t.Bury(removed)
+	return removed, nil
+}
+
+// Bury leaves a tombstone for a task taken off the list, if the list
+// keeps them. The tombstone is a copy, so the task itself can still go
+// to the done file as it was, and leaves its subtasks out.
+func (t *TaskList) Bury(task *Task) {
+	if !t.KeepTombstones {
+		return
+	}
+	tombstone := task.Clone()
+	tombstone.Children = nil
+	tombstone.DeletedAt = time.Now().Truncate(time.Second)
+	t.Tombstones = append(t.Tombstones, tombstone)
+}
+
+// promoteChildren puts the subtasks of a task taken off the list at
+// index at on the list in its place.
+func (t *TaskList) promoteChildren(at int, task *Task) {
+	tasks := append(append([]*Task(nil), t.Tasks[:at]...), task.Children...)
+	t.Tasks = append(tasks, t.Tasks[at:]...)
+	task.Children = nil
+}
+
+// Link records that two tasks are related, on both of them. Linking a
+// task to itself or linking two tasks twice changes nothing.
+func (t *TaskList) Link(a, b int) error {
+	taskA, err := t.Get(a)
+	if err != nil {
+		return err
+	}
+	taskB, err := t.Get(b)
+	if err != nil {
+		return err
+	}
+	if taskA.Id == taskB.Id {
+		return nil
+	}
+	for _, id := range taskA.Links {
+		if id == taskB.Id {
+			return nil
+		}
+	}
+	taskA.Links = append(taskA.Links, taskB.Id)
+	taskB.Links = append(taskB.Links, taskA.Id)
+	return nil
+}
+
+// Unlink removes all links to the task with the given stable id.
+func (t *TaskList) Unlink(id string) {
+	for _, task := range t.Tasks {
+		links := make([]string, 0)
+		for _, link := range task.Links {
+			if link != id {
+				links = append(links, link)
+			}
+		}
+		if len(links) == 0 {
+			links = nil
+		}
+		task.Links = links
+	}
+}
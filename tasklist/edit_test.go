@@ -0,0 +1,220 @@
+package tasklist
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAddTask(t *testing.T) {
+	tasklist := TaskList{}
+	tasklist.Add("foo")
+	if len(tasklist.Tasks) != 1 {
+		t.Fatalf("Expected list to have one element, got %d", len(tasklist.Tasks))
+	}
+
+	actualTaskDescription := tasklist.Tasks[0].Description
+	if actualTaskDescription != "foo" {
+		t.Fatalf("expected tasklist to contain 'foo', got '%v'", actualTaskDescription)
+	}
+}
+
+func TestListTasks(t *testing.T) {
+	tasklist := TaskList{}
+	tasks := tasklist.List(time.Now())
+
+	if len(tasks) != 0 {
+		t.Fatalf("Expected tasklist to contain no element, got %d", len(tasks))
+	}
+
+	tasklist.Add("Foo")
+	tasks = tasklist.List(time.Now())
+
+	if len(tasks) != 1 {
+		t.Fatalf("Expected tasklist to have one element, got %d", len(tasks))
+	}
+
+	snoozed, _ := tasklist.Add("Bar")
+	snoozed.SnoozedUntil = time.Now().Add(time.Hour)
+	if tasks = tasklist.List(time.Now()); len(tasks) != 1 || tasks[0] != 0 {
+		t.Fatalf("Expected the snoozed task to be left out, got %v", tasks)
+	}
+}
+
+func TestFinishTask(t *testing.T) {
+	tasklist := TaskList{}
+	tasklist.Add("foo")
+
+	if len(tasklist.Tasks) != 1 {
+		t.Fatalf("Expected tasklist to contain one element, got %d", len(tasklist.Tasks))
+	}
+
+	tasklist.Finish(0)
+
+	if len(tasklist.Tasks) != 0 {
+		t.Fatalf("Expected tasklist to contain no element, got %d", len(tasklist.Tasks))
+	}
+}
+
+func TestEditTask(t *testing.T) {
+	tasklist := TaskList{}
+	tasklist.Add("foo")
+
+	if len(tasklist.Tasks) != 1 {
+		t.Fatalf("Expected tasklist to contain one element, got %d", len(tasklist.Tasks))
+	}
+	actualTaskDescription := tasklist.Tasks[0].Description
+	if actualTaskDescription != "foo" {
+		t.Fatalf("expected tasklist to contain 'foo', got '%v'", actualTaskDescription)
+	}
+
+	tasklist.Edit(0, "bar")
+
+	actualTaskDescription = tasklist.Tasks[0].Description
+	if actualTaskDescription != "bar" {
+		t.Fatalf("expected tasklist to contain 'bar', got '%v'", actualTaskDescription)
+	}
+}
+
+func TestAppendPrepend(t *testing.T) {
+	tasklist := TaskList{}
+	task, _ := tasklist.Add("call bob")
+	task.Waiting, task.WaitingFor = true, "bob"
+	if err := tasklist.Append(0, "before Friday"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tasklist.Prepend(0, "URGENT:"); err != nil {
+		t.Fatal(err)
+	}
+	if task.Description != "URGENT: call bob before Friday" {
+		t.Fatalf("Expected 'URGENT: call bob before Friday', got '%s'", task.Description)
+	}
+	if task.Waiting {
+		t.Fatal("Expected an amended task to stop waiting, like an edited one")
+	}
+	if err := tasklist.Append(0, "  "); err != ErrNoAmendment {
+		t.Fatalf("Expected blank text to be refused, got %v", err)
+	}
+	if err := tasklist.Prepend(1, "x"); err == nil || err.Error() != "no task for id 1" {
+		t.Fatalf("Expected 'no task for id 1', got %v", err)
+	}
+	DescriptionLimit = 30
+	defer func() { DescriptionLimit = MaxDescriptionLength }()
+	if err := tasklist.Append(0, "and more"); err == nil || task.Description != "URGENT: call bob before Friday" {
+		t.Fatalf("Expected the limit to apply as for edits, got %v and '%s'", err, task.Description)
+	}
+}
+
+func TestAddDuplicateGetsOwnId(t *testing.T) {
+	list := &TaskList{}
+	first, _ := list.Add("buy milk")
+	second, _ := list.Add("buy milk")
+	if first.Id == second.Id {
+		t.Fatalf("Expected tasks added twice to get different ids, both got %s", first.Id)
+	}
+	text, _ := list.MarshalText()
+	loaded := &TaskList{}
+	loaded.UnmarshalText(text)
+	if loaded.Tasks[0].Id != first.Id || loaded.Tasks[1].Id != second.Id {
+		t.Fatalf("Expected ids to survive a round trip, got %s and %s", loaded.Tasks[0].Id, loaded.Tasks[1].Id)
+	}
+}
+
+func TestAddNormalizesDescription(t *testing.T) {
+	tasklist := TaskList{}
+	tasklist.Add("\u00a0buy milk\t ")
+	if tasklist.Tasks[0].Description != "buy milk" {
+		t.Fatalf("Expected description to be normalized, got %q", tasklist.Tasks[0].Description)
+	}
+	tasklist.Edit(0, "buy  oat\u200bmilk")
+	if tasklist.Tasks[0].Description != "buy oatmilk" {
+		t.Fatalf("Expected edited description to be normalized, got %q", tasklist.Tasks[0].Description)
+	}
+	tasklist.Raw = true
+	tasklist.Add("a\tb")
+	if tasklist.Tasks[1].Description != "a\tb" {
+		t.Fatalf("Expected raw description to be kept, got %q", tasklist.Tasks[1].Description)
+	}
+}
+
+func TestAddSetsCreationTime(t *testing.T) {
+	tasklist := TaskList{}
+	before := time.Now().Add(-time.Second)
+	task, _ := tasklist.Add("foo")
+	if task.CreatedAt.Before(before) || task.CreatedAt.After(time.Now()) {
+		t.Fatalf("Expected creation time to be now, got %v", task.CreatedAt)
+	}
+	legacy := Task{}
+	legacy.UnmarshalText([]byte("foo"))
+	if !legacy.CreatedAt.IsZero() {
+		t.Fatalf("Expected old lines to have no creation time, got %v", legacy.CreatedAt)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	list := &TaskList{}
+	list.UnmarshalText([]byte("a | link:" + Hash("b") + "\nb\nc"))
+	if err := list.Delete(1); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Tasks) != 2 || list.Tasks[0].Description != "a" || list.Tasks[1].Description != "c" {
+		t.Fatalf("Expected a and c to be left, got %v", list.Tasks)
+	}
+	if len(list.Tasks[0].Links) != 0 {
+		t.Fatalf("Expected the link to the deleted task to go, got %v", list.Tasks[0].Links)
+	}
+	if len(list.Finished) != 0 {
+		t.Fatal("Expected a deleted task not to count as finished")
+	}
+	if err := list.Delete(5); err == nil {
+		t.Fatal("Expected deleting a missing task to fail")
+	}
+}
+
+func TestClear(t *testing.T) {
+	tasklist := TaskList{KeepTombstones: true}
+	tasklist.Add("a")
+	tasklist.Add("b")
+	if n := tasklist.Clear(); n != 2 || len(tasklist.Tasks) != 0 {
+		t.Fatalf("Expected 2 tasks cleared and none left, got %d and %d", n, len(tasklist.Tasks))
+	}
+	if len(tasklist.Tombstones) != 2 {
+		t.Fatalf("Expected a tombstone for each task, got %d", len(tasklist.Tombstones))
+	}
+}
+
+func TestReschedule(t *testing.T) {
+	now := time.Date(2024, 6, 10, 15, 0, 0, 0, time.Local)
+	cases := []struct {
+		due, expected time.Time
+	}{
+		// Finished late, the next one counts from today.
+		{time.Date(2024, 6, 5, 0, 0, 0, 0, time.Local), time.Date(2024, 6, 13, 0, 0, 0, 0, time.Local)},
+		{time.Date(2024, 6, 10, 0, 0, 0, 0, time.Local), time.Date(2024, 6, 13, 0, 0, 0, 0, time.Local)},
+		// Finished early, from the due date.
+		{time.Date(2024, 6, 12, 0, 0, 0, 0, time.Local), time.Date(2024, 6, 15, 0, 0, 0, 0, time.Local)},
+	}
+	for _, c := range cases {
+		task := Task{DueAt: c.due, RecurEvery: 3 * EveryUnit}
+		Reschedule(&task, now)
+		if !task.DueAt.Equal(c.expected) {
+			t.Errorf("Due %s: expected %s, got %s", c.due, c.expected, task.DueAt)
+		}
+	}
+}
+
+func TestEditKeepsTimeWithTombstones(t *testing.T) {
+	tasklist := TaskList{}
+	tasklist.Add("foo")
+	tasklist.Edit(0, "bar")
+	if !tasklist.Tasks[0].EditedAt.IsZero() {
+		t.Fatal("Expected no edit time without tombstones")
+	}
+	tasklist.KeepTombstones = true
+	tasklist.Edit(0, "baz")
+	text, _ := tasklist.MarshalText()
+	loaded := TaskList{}
+	loaded.UnmarshalText(text)
+	if edited := loaded.Tasks[0].EditedAt; edited.IsZero() || time.Since(edited) > time.Minute {
+		t.Fatalf("Expected the edit time to be kept, got %q", text)
+	}
+}
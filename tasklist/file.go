@@ -0,0 +1,99 @@
+package tasklist
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Load reads the tasks file at path and the files it includes. A
+// missing file is an empty list. On an error, the list holds the tasks
+// read before it.
+func Load(path string) (*TaskList, error) {
+	list := &TaskList{}
+	text, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return list, nil
+		}
+		return list, err
+	}
+	if err := list.UnmarshalText(text); err != nil {
+		return list, err
+	}
+	list.loadIncludes(path, list.Includes, 1)
+	return list, nil
+}
+
+// Save writes the list's own tasks to the file at path with
+// WriteAtomic. The included files are left as they are.
+func (t *TaskList) Save(path string) error {
+	text, err := t.MarshalText()
+	if err != nil {
+		return err
+	}
+	return WriteAtomic(path, text)
+}
+
+// WriteAtomic replaces the file at path with data by renaming a
+// temporary file in the same directory over it, once the data is safely
+// on disk. Readers see either the old or the new contents, and a crash
+// or a full disk leaves the old file as it was. The file keeps its
+// permissions, and a new one gets 0644.
+func WriteAtomic(path string, data []byte) error {
+	temp, err := StageFile(path, data, 0644)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(temp, path); err != nil {
+		os.Remove(temp)
+		return err
+	}
+	return nil
+}
+
+// StageFile writes data to a temporary file next to path, synced and
+// with the permissions of the file at path or perm if there is none, and
+// returns its name, for renaming over the file.
+func StageFile(path string, data []byte, perm os.FileMode) (string, error) {
+	mode := perm
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return "", err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return "", err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	if err := os.Chmod(file.Name(), mode); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+// ExpandHome expands a leading ~ in path to the user's home directory.
+func ExpandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
@@ -0,0 +1,78 @@
+package tasklist
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadSave(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tasks")
+	ioutil.WriteFile(path, []byte("#include team\nmine | due:2024-06-01"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "team"), []byte("#readonly\nshared"), 0644)
+	list, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Tasks) != 2 || list.Tasks[0].Description != "shared" || list.Tasks[0].Source != filepath.Join(dir, "team") ||
+		!list.ReadOnlyIncluded[filepath.Join(dir, "team")] || list.Tasks[1].DueAt.IsZero() {
+		t.Fatalf("Expected the included task before the file's own, got %+v", list.Tasks)
+	}
+	list.Tasks = append(list.Tasks, &Task{Description: "new", Id: Hash("new")})
+	if err := list.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if text, _ := ioutil.ReadFile(path); string(text) != "#include team\nmine | due:2024-06-01\nnew" {
+		t.Fatalf("Expected the list's own tasks written, got '%s'", text)
+	}
+	if text, _ := ioutil.ReadFile(filepath.Join(dir, "team")); string(text) != "#readonly\nshared" {
+		t.Fatalf("Expected the included file left alone, got '%s'", text)
+	}
+	if list, err := Load(filepath.Join(dir, "missing")); err != nil || len(list.Tasks) != 0 {
+		t.Fatalf("Expected a missing file to be an empty list, got %v", err)
+	}
+}
+
+func TestWriteAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tasks")
+	ioutil.WriteFile(path, []byte("old"), 0600)
+	if err := WriteAtomic(path, []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	text, _ := ioutil.ReadFile(path)
+	info, _ := os.Stat(path)
+	if string(text) != "new" || info.Mode().Perm() != 0600 {
+		t.Fatalf("Expected new contents with the old permissions, got '%s' and %v", text, info.Mode())
+	}
+	if err := WriteAtomic(filepath.Join(dir, "fresh"), []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(filepath.Join(dir, "fresh")); info.Mode().Perm() != 0644 {
+		t.Fatalf("Expected a new file to get 0644, got %v", info.Mode())
+	}
+	entries, _ := ioutil.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Fatalf("Expected no temporary files to be left behind, got %d files", len(entries))
+	}
+}
+
+func TestWriteAtomicFailure(t *testing.T) {
+	dir := t.TempDir()
+	// Renaming a file over a directory that isn't empty fails, as late
+	// as a write can fail.
+	path := filepath.Join(dir, "tasks")
+	os.MkdirAll(filepath.Join(path, "kept"), 0755)
+	if err := WriteAtomic(path, []byte("new")); err == nil {
+		t.Fatal("Expected the write to fail")
+	}
+	entries, _ := ioutil.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("Expected no temporary files to be left behind, got %d files", len(entries))
+	}
+	if _, err := os.Stat(filepath.Join(path, "kept")); err != nil {
+		t.Fatal("Expected the target to be left alone")
+	}
+}
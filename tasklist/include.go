@@ -0,0 +1,76 @@
+package tasklist
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// IncludeDirective starts a line of a tasks file naming another file
+// whose tasks are listed along with the file's own.
+const IncludeDirective = "#include "
+
+// maxIncludeDepth is how deep includes may nest.
+const maxIncludeDepth = 4
+
+// includePath resolves an #include line of the file at path. Relative
+// names are relative to the including file's directory.
+func includePath(path string, include string) string {
+	include = ExpandHome(os.ExpandEnv(include))
+	if filepath.IsAbs(include) {
+		return include
+	}
+	return filepath.Join(filepath.Dir(path), include)
+}
+
+// loadIncludes reads the files included by the file at path and puts
+// their tasks before the list's own, remembering where each task came
+// from. Files that can't be read, are included twice or nest too deep
+// are skipped with a warning.
+func (t *TaskList) loadIncludes(path string, includes []string, depth int) {
+	if t.Included == nil {
+		t.Included = make(map[string][]string)
+		t.ReadOnlyIncluded = make(map[string]bool)
+	}
+	visited := map[string]bool{path: true}
+	t.Tasks = append(t.readIncludes(path, includes, depth, visited), t.Tasks...)
+}
+
+func (t *TaskList) readIncludes(path string, includes []string, depth int, visited map[string]bool) []*Task {
+	tasks := make([]*Task, 0)
+	for _, include := range includes {
+		included := includePath(path, include)
+		if visited[included] {
+			fmt.Fprintf(Warnings, "warning: %s is included more than once\n", include)
+			continue
+		}
+		if depth > maxIncludeDepth {
+			fmt.Fprintf(Warnings, "warning: not including %s, includes nest too deep\n", include)
+			continue
+		}
+		text, err := ioutil.ReadFile(included)
+		if err != nil {
+			fmt.Fprintf(Warnings, "warning: can't include %s: %v\n", include, err)
+			continue
+		}
+		list := &TaskList{}
+		if err := list.UnmarshalText(text); err != nil {
+			fmt.Fprintf(Warnings, "warning: can't include %s: %v\n", include, err)
+			continue
+		}
+		for _, task := range list.Tasks {
+			task.Source = included
+		}
+		for _, tombstone := range list.Tombstones {
+			tombstone.Source = included
+			t.Tombstones = append(t.Tombstones, tombstone)
+		}
+		visited[included] = true
+		t.Included[included] = list.Includes
+		t.ReadOnlyIncluded[included] = list.ReadOnly
+		tasks = append(tasks, t.readIncludes(included, list.Includes, depth+1, visited)...)
+		tasks = append(tasks, list.Tasks...)
+	}
+	return tasks
+}
@@ -0,0 +1,104 @@
+package tasklist
+
+import (
+	"strings"
+	"unicode"
+)
+
+// combiningMarksStart and combiningMarksEnd delimit the Combining
+// Diacritical Marks block, the accents of Latin letters.
+const (
+	combiningMarksStart = 0x0300
+	combiningMarksEnd   = 0x036F
+)
+
+// latinCompositions is the inverse of latinDecompositions.
+var latinCompositions = make(map[[2]rune]rune, len(latinDecompositions))
+
+func init() {
+	for composed, pair := range latinDecompositions {
+		latinCompositions[pair] = composed
+	}
+}
+
+// zeroWidth are the invisible characters dropped from descriptions. The
+// zero width joiner is kept since emoji sequences depend on it.
+var zeroWidth = map[rune]bool{
+	'\u00ad': true, // soft hyphen
+	'\u200b': true, // zero width space
+	'\u200c': true, // zero width non-joiner
+	'\u2060': true, // word joiner
+	'\ufeff': true, // zero width no-break space
+}
+
+// NormalizeText cleans up a description pasted from elsewhere: tabs,
+// no-break and other Unicode spaces become plain spaces, control and
+// zero width characters are dropped, runs of spaces collapse into one
+// and the ends are trimmed. Latin letters followed by combining marks
+// are composed into their precomposed form, which is what Unicode NFC
+// does for Latin text. Other scripts are left as they are, since the
+// standard library has no full normalization tables.
+func NormalizeText(s string) string {
+	var b strings.Builder
+	space := false
+	var last rune = -1
+	flush := func() {
+		if last != -1 {
+			b.WriteRune(last)
+			last = -1
+		}
+	}
+	for _, r := range s {
+		switch {
+		case unicode.IsSpace(r):
+			space = true
+			continue
+		case unicode.IsControl(r) || zeroWidth[r]:
+			continue
+		}
+		if composed, ok := latinCompositions[[2]rune{last, r}]; ok && !space {
+			last = composed
+			continue
+		}
+		flush()
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		last = r
+	}
+	flush()
+	return b.String()
+}
+
+// FoldAccents strips the diacritics of Latin letters, the way Unicode
+// NFD followed by dropping the combining marks would: é becomes e and ǘ
+// becomes u. Letters of other scripts are left alone, so they only
+// match as they are.
+func FoldAccents(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		for {
+			pair, ok := latinDecompositions[r]
+			if !ok {
+				break
+			}
+			r = pair[0]
+		}
+		if r >= combiningMarksStart && r <= combiningMarksEnd {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Fold is what searches compare: the text in lower case, and with
+// accents also without the accents of its Latin letters.
+func Fold(s string, accents bool) string {
+	s = strings.ToLower(s)
+	if accents {
+		s = FoldAccents(s)
+	}
+	return s
+}
@@ -0,0 +1,61 @@
+package tasklist
+
+import (
+	"testing"
+)
+
+func TestNormalizeText(t *testing.T) {
+	cases := []struct {
+		in, expected string
+	}{
+		{"buy milk", "buy milk"},
+		{"  buy \t milk  ", "buy milk"},
+		{"buy\u00a0milk", "buy milk"},
+		{"buy \u2003 milk", "buy milk"},
+		{"line\nbreak\r\n", "line break"},
+		{"zero\u200bwidth\ufeff", "zerowidth"},
+		{"bell\a and null\x00", "bell and null"},
+		{"Cafe\u0301", "Caf\u00e9"},
+		{"e\u0302\u0301", "\u1ebf"},
+		{"Caf\u00e9", "Caf\u00e9"},
+		{"a \u0301", "a \u0301"},
+		{"family \U0001f468\u200d\U0001f469", "family \U0001f468\u200d\U0001f469"},
+		{"\u65e5\u672c\u3000\u8a9e", "\u65e5\u672c \u8a9e"},
+		{"", ""},
+	}
+	for _, c := range cases {
+		if normalized := NormalizeText(c.in); normalized != c.expected {
+			t.Fatalf("NormalizeText(%q): expected %q, got %q", c.in, c.expected, normalized)
+		}
+	}
+}
+
+func TestNormalizeTextIsIdempotent(t *testing.T) {
+	for _, in := range []string{" a\tb ", "Cafe\u0301", "x\u200by  z"} {
+		once := NormalizeText(in)
+		if twice := NormalizeText(once); twice != once {
+			t.Fatalf("Expected normalizing %q twice to change nothing, got %q and %q", in, once, twice)
+		}
+	}
+}
+
+func TestFoldAccents(t *testing.T) {
+	cases := []struct {
+		in       string
+		expected string
+	}{
+		{"Café com João", "Cafe com Joao"},
+		{"Crème brûlée", "Creme brulee"},
+		{"Zürich, Łódź, Ærø", "Zurich, Łodz, Ærø"},
+		{"Việt Nam", "Viet Nam"},
+		{"ǘ", "u"},
+		{"Cafe\u0301", "Cafe"},
+		{"Ελληνικά и русский", "Ελληνικά и русский"},
+		{"日本語", "日本語"},
+	}
+	for _, c := range cases {
+		if folded := FoldAccents(c.in); folded != c.expected {
+			t.Fatalf("FoldAccents(%q): expected %q, got %q", c.in, c.expected, folded)
+		}
+	}
+}
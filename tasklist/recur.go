@@ -0,0 +1,42 @@
+package tasklist
+
+import "time"
+
+// Snoozed reports whether the task is hidden from listings at now,
+// snoozed or recurring and not due yet.
+func Snoozed(task *Task, now time.Time) bool {
+	return now.Before(task.SnoozedUntil) || NotDue(task, now)
+}
+
+// NotDue reports whether the task recurs and its next due day hasn't
+// come yet at now. Listings leave it out until then.
+func NotDue(task *Task, now time.Time) bool {
+	return task.RecurEvery > 0 && !task.DueAt.IsZero() && DaysBetween(now, task.DueAt) > 0
+}
+
+// Reschedule moves a recurring task finished at now on to its next due
+// day: its interval after the due date, or after today if that passed.
+func Reschedule(task *Task, now time.Time) {
+	from := StartOfDay(now)
+	if task.DueAt.After(from) {
+		from = task.DueAt
+	}
+	task.DueAt = from.AddDate(0, 0, int(task.RecurEvery/EveryUnit))
+}
+
+// StartOfDay returns midnight of the day of t, in its location.
+func StartOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// DaysBetween returns the number of calendar days from a to b, going by
+// their dates alone so that neither the time of day nor DST shifts
+// matter.
+func DaysBetween(a, b time.Time) int {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.In(a.Location()).Date()
+	from := time.Date(ay, am, ad, 0, 0, 0, 0, time.UTC)
+	to := time.Date(by, bm, bd, 0, 0, 0, 0, time.UTC)
+	return int(to.Sub(from).Hours() / 24)
+}
@@ -0,0 +1,419 @@
+package tasklist
+
+import (
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DateLayout is the layout dates are stored in.
+const DateLayout = "2006-01-02"
+
+// HighestPriority and LowestPriority bound task priorities; lower
+// numbers are more important.
+const (
+	HighestPriority = 1
+	LowestPriority  = 9
+)
+
+// EveryUnit is the unit recurring tasks come back in; due dates have no
+// time of day.
+const EveryUnit = 24 * time.Hour
+
+// Task is a task of a list, as it is stored on a line of the tasks file:
+// its description, then its metadata as key:value pairs.
+type Task struct {
+	Description string
+	// Id is the task's stable id. It is the hash of the description the
+	// task was created with and doesn't change when the task is edited.
+	Id string
+	// CreatedAt is when the task was added. Tasks from older files don't
+	// have one.
+	CreatedAt time.Time
+	// DoneAt is when the task was finished, for tasks in the done file.
+	DoneAt time.Time
+	DueAt  time.Time
+	// RecurEvery makes the task come back that long after it is
+	// finished, in whole days, instead of going off the list.
+	RecurEvery time.Duration
+	// SnoozedUntil hides the task from listings until that day.
+	SnoozedUntil time.Time
+	// MutedUntil keeps --notify quiet about the task until then.
+	MutedUntil time.Time
+	// Waiting is set while the task waits for someone else, for the
+	// reason in WaitingFor if one was given. Waiting tasks don't age.
+	Waiting    bool
+	WaitingFor string
+	// DeletedAt is when the task was finished or removed, for the
+	// tombstones a list keeps with tombstones = true.
+	DeletedAt time.Time
+	// EditedAt is when the description was last edited, kept along with
+	// tombstones so that a merge can tell an edit from a deletion.
+	EditedAt time.Time
+	// Priority goes from 1, the highest, to 9. Zero means the task
+	// wasn't given one.
+	Priority    int
+	Pomodoros   int
+	Attachments []string
+	// Links are the stable ids of related tasks.
+	Links []string
+	// Notes are the annotations added with --note, each of any number of
+	// lines.
+	Notes []string
+	// Children are the subtasks added with --sub, listed under the task
+	// and not on the list themselves.
+	Children []*Task
+	// Source is the included file the task came from, "" for the tasks
+	// file itself.
+	Source string
+	Meta   map[string]string
+}
+
+// MetaSeparator separates a task's description from its metadata on a
+// line of the tasks file, e.g. "pay rent | due:2024-06-01".
+const MetaSeparator = " | "
+
+// Clone returns a copy of the task that shares no state with it.
+func (task *Task) Clone() *Task {
+	copied := *task
+	if task.Attachments != nil {
+		copied.Attachments = append([]string(nil), task.Attachments...)
+	}
+	if task.Links != nil {
+		copied.Links = append([]string(nil), task.Links...)
+	}
+	if task.Notes != nil {
+		copied.Notes = append([]string(nil), task.Notes...)
+	}
+	if task.Meta != nil {
+		copied.Meta = make(map[string]string, len(task.Meta))
+		for key, value := range task.Meta {
+			copied.Meta[key] = value
+		}
+	}
+	if task.Children != nil {
+		copied.Children = make([]*Task, len(task.Children))
+		for i, child := range task.Children {
+			copied.Children[i] = child.Clone()
+		}
+	}
+	return &copied
+}
+
+func (task *Task) MarshalText() ([]byte, error) {
+	line := escapeDescription(task.Description)
+	meta := make([]string, 0)
+	if task.Id != "" && task.Id != Hash(task.Description) {
+		meta = append(meta, "id:"+task.Id)
+	}
+	if !task.DueAt.IsZero() {
+		meta = append(meta, "due:"+task.DueAt.Format(DateLayout))
+	}
+	if task.RecurEvery > 0 {
+		meta = append(meta, "every:"+FormatEvery(task.RecurEvery))
+	}
+	if !task.SnoozedUntil.IsZero() {
+		meta = append(meta, "snooze:"+task.SnoozedUntil.Format(DateLayout))
+	}
+	if !task.MutedUntil.IsZero() {
+		meta = append(meta, "mute:"+task.MutedUntil.UTC().Format(time.RFC3339))
+	}
+	if task.Waiting {
+		meta = append(meta, "waiting:"+EscapeMetaValue(task.WaitingFor))
+	}
+	if task.Priority != 0 {
+		meta = append(meta, "priority:"+strconv.Itoa(task.Priority))
+	}
+	if task.Pomodoros > 0 {
+		meta = append(meta, "pomodoros:"+strconv.Itoa(task.Pomodoros))
+	}
+	for _, path := range task.Attachments {
+		meta = append(meta, "attach:"+EscapeMetaValue(path))
+	}
+	for _, id := range task.Links {
+		meta = append(meta, "link:"+id)
+	}
+	for _, note := range task.Notes {
+		meta = append(meta, "note:"+EscapeMetaValue(note))
+	}
+	if !task.CreatedAt.IsZero() {
+		meta = append(meta, "created:"+task.CreatedAt.UTC().Format(time.RFC3339))
+	}
+	if !task.DoneAt.IsZero() {
+		meta = append(meta, "done:"+task.DoneAt.UTC().Format(time.RFC3339))
+	}
+	if !task.DeletedAt.IsZero() {
+		meta = append(meta, "deleted:"+task.DeletedAt.UTC().Format(time.RFC3339))
+	}
+	if !task.EditedAt.IsZero() {
+		meta = append(meta, "edited:"+task.EditedAt.UTC().Format(time.RFC3339))
+	}
+	keys := make([]string, 0, len(task.Meta))
+	for key := range task.Meta {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		meta = append(meta, key+":"+EscapeMetaValue(task.Meta[key]))
+	}
+	if len(meta) > 0 {
+		line += MetaSeparator + strings.Join(meta, " ")
+	}
+	return []byte(line), nil
+}
+
+func (task *Task) UnmarshalText(text []byte) error {
+	if !utf8.Valid(text) {
+		return ErrInvalidUTF8
+	}
+	line := string(text)
+	task.Description = unescapeDescription(line)
+	if i := strings.LastIndex(line, MetaSeparator); i != -1 {
+		meta, ok := ParseMeta(line[i+len(MetaSeparator):])
+		if ok {
+			task.Description = unescapeDescription(line[:i])
+			if err := task.SetMeta(meta); err != nil {
+				return err
+			}
+		} else {
+			// Not metadata after all, just a description containing " | ".
+			task.Description = line
+		}
+	}
+	if task.Id == "" {
+		task.Id = Hash(task.Description)
+	}
+	return nil
+}
+
+// ErrInvalidUTF8 refuses a line of a tasks file that isn't UTF-8,
+// likely not a task at all.
+var ErrInvalidUTF8 = errors.New("invalid UTF-8")
+
+// ParseMeta splits the metadata part of a line into its key:value pairs.
+// It reports false if the text isn't metadata at all.
+func ParseMeta(text string) ([][2]string, bool) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return nil, false
+	}
+	meta := make([][2]string, 0, len(fields))
+	for _, field := range fields {
+		sep := strings.Index(field, ":")
+		if sep < 1 {
+			return nil, false
+		}
+		meta = append(meta, [2]string{field[:sep], UnescapeMetaValue(field[sep+1:])})
+	}
+	return meta, true
+}
+
+// SetMeta sets the task's fields from parsed metadata. Keys t doesn't
+// know are kept as they are.
+func (task *Task) SetMeta(meta [][2]string) error {
+	for _, pair := range meta {
+		key, value := pair[0], pair[1]
+		switch key {
+		case "id":
+			task.Id = value
+		case "due":
+			dueAt, err := time.ParseInLocation(DateLayout, value, time.Local)
+			if err != nil {
+				return fmt.Errorf("invalid due date %q", value)
+			}
+			task.DueAt = dueAt
+		case "every":
+			every, err := ParseEvery(value)
+			if err != nil {
+				return err
+			}
+			task.RecurEvery = every
+		case "snooze":
+			snoozedUntil, err := time.ParseInLocation(DateLayout, value, time.Local)
+			if err != nil {
+				return fmt.Errorf("invalid snooze date %q", value)
+			}
+			task.SnoozedUntil = snoozedUntil
+		case "mute":
+			mutedUntil, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return fmt.Errorf("invalid mute time %q", value)
+			}
+			task.MutedUntil = mutedUntil
+		case "waiting":
+			task.Waiting, task.WaitingFor = true, value
+		case "priority":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < HighestPriority || n > LowestPriority {
+				return fmt.Errorf("invalid priority %q", value)
+			}
+			task.Priority = n
+		case "pomodoros":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid pomodoro count %q", value)
+			}
+			task.Pomodoros = n
+		case "attach":
+			task.Attachments = append(task.Attachments, value)
+		case "link":
+			task.Links = append(task.Links, value)
+		case "note":
+			task.Notes = append(task.Notes, value)
+		case "created":
+			createdAt, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return fmt.Errorf("invalid creation time %q", value)
+			}
+			task.CreatedAt = createdAt
+		case "done":
+			doneAt, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return fmt.Errorf("invalid completion time %q", value)
+			}
+			task.DoneAt = doneAt
+		case "deleted":
+			deletedAt, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return fmt.Errorf("invalid deletion time %q", value)
+			}
+			task.DeletedAt = deletedAt
+		case "edited":
+			editedAt, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return fmt.Errorf("invalid edit time %q", value)
+			}
+			task.EditedAt = editedAt
+		default:
+			if task.Meta == nil {
+				task.Meta = make(map[string]string)
+			}
+			task.Meta[key] = value
+		}
+	}
+	return nil
+}
+
+// Hash derives a task's stable id from its description.
+func Hash(description string) string {
+	return fmt.Sprintf("%x", sha1.Sum([]byte(description)))
+}
+
+// Descriptions are escaped on a line of the tasks file so that one with
+// " | " isn't taken for metadata and one with a line break stays on one
+// line. Lines without any of the escapes are read as they are, which
+// keeps older files with backslashes in them readable.
+var descriptionEscaper = strings.NewReplacer(`\`, `\\`, "|", `\|`, "\n", `\n`, "\r", `\r`)
+var descriptionUnescaper = strings.NewReplacer(`\\`, `\`, `\|`, "|", `\n`, "\n", `\r`, "\r")
+
+// descriptionEscapes are the escapes unescapeDescription reads.
+var descriptionEscapes = []string{`\\`, `\|`, `\n`, `\r`}
+
+// hasEscape reports whether s contains one of the descriptionEscapes.
+func hasEscape(s string) bool {
+	for _, escape := range descriptionEscapes {
+		if strings.Contains(s, escape) {
+			return true
+		}
+	}
+	return false
+}
+
+func escapeDescription(description string) string {
+	if !strings.ContainsAny(description, "|\n\r") && !hasEscape(description) {
+		return description
+	}
+	return descriptionEscaper.Replace(description)
+}
+
+func unescapeDescription(description string) string {
+	if !hasEscape(description) {
+		return description
+	}
+	return descriptionUnescaper.Replace(description)
+}
+
+// EscapeMetaValue escapes what would end a metadata value or the line:
+// '%', '|' and whitespace of any kind, as %XX of their UTF-8 bytes.
+func EscapeMetaValue(value string) string {
+	var escaped strings.Builder
+	for _, r := range value {
+		if r != '%' && r != '|' && !unicode.IsSpace(r) {
+			escaped.WriteRune(r)
+			continue
+		}
+		for _, b := range []byte(string(r)) {
+			fmt.Fprintf(&escaped, "%%%02X", b)
+		}
+	}
+	return escaped.String()
+}
+
+// UnescapeMetaValue undoes EscapeMetaValue. A '%' not followed by two
+// hex digits is kept as it is.
+func UnescapeMetaValue(value string) string {
+	if !strings.Contains(value, "%") {
+		return value
+	}
+	unescaped := make([]byte, 0, len(value))
+	for i := 0; i < len(value); i++ {
+		if value[i] == '%' && i+2 < len(value) {
+			if b, err := strconv.ParseUint(value[i+1:i+3], 16, 8); err == nil {
+				unescaped = append(unescaped, byte(b))
+				i += 2
+				continue
+			}
+		}
+		unescaped = append(unescaped, value[i])
+	}
+	return string(unescaped)
+}
+
+// ParseEvery parses the interval of a recurring task, like 3d, 2w or
+// 30: a number of days, days or weeks, or a Go duration like 48h, in
+// whole days.
+func ParseEvery(s string) (time.Duration, error) {
+	invalid := fmt.Errorf("invalid interval %q, expected whole days like 3d or 2w", s)
+	d, err := parseDuration(s)
+	if err != nil || d < EveryUnit || d%EveryUnit != 0 {
+		return 0, invalid
+	}
+	return d, nil
+}
+
+// parseDuration parses a number of days like 30, days or weeks like 30d
+// or 2w, or a Go duration.
+func parseDuration(s string) (time.Duration, error) {
+	unit := 0
+	switch {
+	case strings.HasSuffix(s, "d"):
+		unit = 1
+	case strings.HasSuffix(s, "w"):
+		unit = 7
+	}
+	if unit != 0 {
+		if n, err := strconv.Atoi(s[:len(s)-1]); err == nil && n >= 0 && s[0] != '+' {
+			return time.Duration(n*unit) * EveryUnit, nil
+		}
+	}
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 {
+		return time.Duration(n) * EveryUnit, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// FormatEvery renders the interval of a recurring task as it is stored,
+// a number of days like 3d.
+func FormatEvery(d time.Duration) string {
+	return fmt.Sprintf("%dd", d/EveryUnit)
+}
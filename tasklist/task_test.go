@@ -0,0 +1,184 @@
+package tasklist
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMarshalTaskMetadata(t *testing.T) {
+	task := &Task{Description: "pay rent | or not", Id: Hash("pay rent | or not")}
+	task.DueAt = time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)
+	task.CreatedAt = time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	tasklist := TaskList{Tasks: []*Task{task, {Description: "foo", Id: Hash("foo")}}}
+
+	text, _ := tasklist.MarshalText()
+	expected := "pay rent \\| or not | due:2024-06-01 created:2024-05-01T10:00:00Z\nfoo"
+	if string(text) != expected {
+		t.Fatalf("Expected marshaled list to be '%s', got '%s'", expected, text)
+	}
+
+	loaded := TaskList{}
+	if err := loaded.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Tasks[0].Description != "pay rent | or not" {
+		t.Fatalf("Expected description to round-trip, got '%s'", loaded.Tasks[0].Description)
+	}
+	if !loaded.Tasks[0].DueAt.Equal(task.DueAt) {
+		t.Fatalf("Expected due date to round-trip, got '%v'", loaded.Tasks[0].DueAt)
+	}
+}
+
+func TestUnmarshalLegacyLineWithSeparator(t *testing.T) {
+	tasklist := TaskList{}
+	tasklist.UnmarshalText([]byte("compare a | b"))
+	if tasklist.Tasks[0].Description != "compare a | b" {
+		t.Fatalf("Expected legacy description to be kept, got '%s'", tasklist.Tasks[0].Description)
+	}
+}
+
+// descriptionPieces are what TestDescriptionRoundTrip makes up
+// descriptions from: the characters that need escaping, things that look
+// like escapes or metadata, whitespace and Unicode.
+var descriptionPieces = []string{
+	"a", "word", "é", "日本", "🙂", " ", "  ", "\t", "\n", "\r\n", "\\", `\n`, `\|`, "|", " | ", "due:2024-06-01", "%20",
+}
+
+func TestDescriptionRoundTrip(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for round := 0; round < 200; round++ {
+		list := TaskList{}
+		descriptions := make([]string, 0)
+		for n := random.Intn(5) + 1; len(descriptions) < n; {
+			var b strings.Builder
+			for pieces := random.Intn(8) + 1; pieces > 0; pieces-- {
+				b.WriteString(descriptionPieces[random.Intn(len(descriptionPieces))])
+			}
+			description := b.String()
+			if strings.TrimSpace(description) == "" {
+				continue
+			}
+			task := &Task{Description: description, Id: Hash(description)}
+			if random.Intn(2) == 0 {
+				task.DueAt = time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)
+			}
+			list.Tasks = append(list.Tasks, task)
+			descriptions = append(descriptions, description)
+		}
+		text, _ := list.MarshalText()
+		loaded := TaskList{}
+		if err := loaded.UnmarshalText(text); err != nil {
+			t.Fatal(err)
+		}
+		if len(loaded.Tasks) != len(list.Tasks) {
+			t.Fatalf("Expected %d tasks from %q, got %d", len(list.Tasks), text, len(loaded.Tasks))
+		}
+		for i, task := range loaded.Tasks {
+			if !reflect.DeepEqual(task, list.Tasks[i]) {
+				t.Fatalf("Expected %+v back from %q, got %+v", list.Tasks[i], text, task)
+			}
+		}
+	}
+}
+
+func TestUnmarshalLegacyBackslashes(t *testing.T) {
+	tasklist := TaskList{}
+	tasklist.UnmarshalText([]byte(`copy C:\temp\data`))
+	if tasklist.Tasks[0].Description != `copy C:\temp\data` {
+		t.Fatalf("Expected a line without escapes to be read as it is, got '%s'", tasklist.Tasks[0].Description)
+	}
+}
+
+func TestMarshalAttachments(t *testing.T) {
+	task := Task{Description: "read spec", Attachments: []string{"/tmp/spec v2.pdf", "notes.txt"}}
+	text, _ := task.MarshalText()
+	expected := "read spec | attach:/tmp/spec%20v2.pdf attach:notes.txt"
+	if string(text) != expected {
+		t.Fatalf("Expected marshaled task to be '%s', got '%s'", expected, text)
+	}
+	loaded := Task{}
+	loaded.UnmarshalText(text)
+	if len(loaded.Attachments) != 2 || loaded.Attachments[0] != "/tmp/spec v2.pdf" {
+		t.Fatalf("Expected attachments to round-trip, got %v", loaded.Attachments)
+	}
+}
+
+func TestParseEvery(t *testing.T) {
+	cases := map[string]time.Duration{"3d": 3 * EveryUnit, "2w": 14 * EveryUnit, "30": 30 * EveryUnit, "48h": 2 * EveryUnit}
+	for s, expected := range cases {
+		if every, err := ParseEvery(s); err != nil || every != expected {
+			t.Errorf("%s: expected %v, got %v (%v)", s, expected, every, err)
+		}
+	}
+	for _, s := range []string{"", "0", "36h", "12h", "soon", "-3d"} {
+		if _, err := ParseEvery(s); err == nil {
+			t.Errorf("Expected %q to be refused", s)
+		}
+	}
+}
+
+func TestRecurMarshalRoundTrip(t *testing.T) {
+	task := Task{Description: "water plants", DueAt: time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local), RecurEvery: 3 * EveryUnit}
+	text, _ := task.MarshalText()
+	if string(text) != "water plants | due:2024-06-01 every:3d" {
+		t.Fatalf("Unexpected line '%s'", text)
+	}
+	var read Task
+	if err := read.UnmarshalText(text); err != nil || read.RecurEvery != task.RecurEvery {
+		t.Fatalf("Expected the interval back, got %v (%v)", read.RecurEvery, err)
+	}
+	if err := read.UnmarshalText([]byte("water plants | every:soon")); err == nil {
+		t.Fatal("Expected an invalid interval to be refused")
+	}
+}
+
+func TestNoteRoundTrip(t *testing.T) {
+	notes := []string{"first line\n\tsecond | line\n100% sure", "a b"}
+	list := &TaskList{Tasks: []*Task{{Description: "pay rent", Id: Hash("pay rent"), Notes: notes}}}
+	text, err := list.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(text), "\n") != 0 || strings.Count(string(text), " | ") != 1 {
+		t.Fatalf("Expected the notes escaped on the task's line, got '%s'", text)
+	}
+	read := &TaskList{}
+	if err := read.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read.Tasks[0].Notes, notes) {
+		t.Fatalf("Expected %q, got %q", notes, read.Tasks[0].Notes)
+	}
+}
+
+func TestUnmarshalInvalidUTF8(t *testing.T) {
+	list := &TaskList{}
+	if err := list.UnmarshalText([]byte("pay rent\ncaf\xe9")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("Expected the line that isn't UTF-8 refused, got %v", err)
+	}
+	if err := (&Task{}).UnmarshalText([]byte("caf\xe9")); err != ErrInvalidUTF8 {
+		t.Fatalf("Expected a task that isn't UTF-8 refused, got %v", err)
+	}
+}
+
+func TestTruncateDescription(t *testing.T) {
+	cases := []struct {
+		description string
+		limit       int
+		expected    string
+	}{
+		{"short", 10, "short"},
+		{"exactly10!", 10, "exactly10!"},
+		{"a bit too long", 10, "a bit t…"},
+		{"ééééé", 8, "éé…"},
+	}
+	for _, c := range cases {
+		truncated := TruncateDescription(c.description, c.limit)
+		if truncated != c.expected || len(truncated) > c.limit {
+			t.Fatalf("TruncateDescription(%q, %d): expected %q, got %q", c.description, c.limit, c.expected, truncated)
+		}
+	}
+}
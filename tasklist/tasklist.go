@@ -0,0 +1,214 @@
+// Package tasklist reads and writes t's tasks file: the tasks on it,
+// their metadata, the files it includes, and the way it is written back
+// without losing anything to a crash. It also adds, edits and finishes
+// the tasks, the way t does.
+package tasklist
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// TaskList is a tasks file with the files it includes.
+type TaskList struct {
+	Tasks []*Task
+	// Tombstones are the finished and removed tasks kept, hidden, so
+	// that a sync can tell them from tasks another copy never had.
+	Tombstones []*Task
+	// Includes are the files the tasks file includes, as written in its
+	// #include lines, and Included the files that were read with the
+	// includes of each.
+	Includes []string
+	Included map[string][]string
+	// ReadOnly is set when the tasks file has a #readonly line, and
+	// ReadOnlyIncluded for the included files that have one.
+	ReadOnly         bool
+	ReadOnlyIncluded map[string]bool
+	// Raw keeps added and edited descriptions exactly as given instead
+	// of normalizing them.
+	Raw bool
+	// Truncate shortens added and edited descriptions over
+	// DescriptionLimit instead of refusing them.
+	Truncate bool
+	// KeepTombstones leaves a tombstone behind when a task is taken off
+	// the list.
+	KeepTombstones bool
+	// KeepChildren makes Finish put the open subtasks of a task on the
+	// list in its place instead of finishing them with it.
+	KeepChildren bool
+	// Finished holds the stable ids of the tasks finished since the list
+	// was read.
+	Finished map[string]bool
+}
+
+// ReadOnlyDirective is a header line of a tasks file that t must not
+// change, like a list shared with others.
+const ReadOnlyDirective = "#readonly"
+
+// ParentKey is the metadata key a subtask's line in the tasks file
+// names its task with, by stable id. Subtasks come right after their
+// task, so a version of t that doesn't know them reads them as tasks of
+// their own, the key kept with the rest of their metadata.
+const ParentKey = "parent"
+
+// MaxDescriptionLength is the longest description t considers sane,
+// and takes unless description_limit says otherwise.
+const MaxDescriptionLength = 10 * 1024
+
+// DescriptionLimit is the longest description, in bytes, t adds, edits
+// or reads.
+var DescriptionLimit = MaxDescriptionLength
+
+// Warnings is where reading a tasks file warns about what it had to
+// skip or cut.
+var Warnings io.Writer = os.Stderr
+
+// MarshalText marshals the list's own tasks file, leaving out the
+// tasks of included files.
+func (t *TaskList) MarshalText() ([]byte, error) {
+	return t.MarshalFile("")
+}
+
+// MarshalFile marshals the tasks that came from the given file, ""
+// being the list's own, after its #include lines.
+func (t *TaskList) MarshalFile(source string) ([]byte, error) {
+	list := make([]string, 0)
+	includes := t.Includes
+	if source != "" {
+		includes = t.Included[source]
+	}
+	if (source == "" && t.ReadOnly) || (source != "" && t.ReadOnlyIncluded[source]) {
+		list = append(list, ReadOnlyDirective)
+	}
+	for _, include := range includes {
+		list = append(list, IncludeDirective+include)
+	}
+	for _, task := range append(append([]*Task(nil), t.Tasks...), t.Tombstones...) {
+		if task.Source != source {
+			continue
+		}
+		line, err := task.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		// A blank line would be skipped when the list is read back.
+		if strings.TrimSpace(string(line)) == "" {
+			continue
+		}
+		list = append(list, string(line))
+		children, err := marshalChildren(task)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, children...)
+	}
+	return []byte(strings.Join(list, "\n")), nil
+}
+
+// UnmarshalText reads a tasks file into the list, subtasks under their
+// task and tombstones apart. Its #include lines are kept but not read;
+// Load reads them.
+func (t *TaskList) UnmarshalText(text []byte) error {
+	in := string(text)
+	list := strings.Split(in, "\n")
+
+	t.Tasks = make([]*Task, 0)
+	t.Tombstones = nil
+	t.Includes = nil
+	t.ReadOnly = false
+	for i, line := range list {
+		if strings.TrimSpace(line) == ReadOnlyDirective {
+			t.ReadOnly = true
+		} else if strings.HasPrefix(line, IncludeDirective) {
+			t.Includes = append(t.Includes, strings.TrimSpace(line[len(IncludeDirective):]))
+		} else if strings.TrimSpace(line) != "" {
+			if !utf8.ValidString(line) {
+				return fmt.Errorf("line %d isn't valid UTF-8, t --fsck --fix repairs it", i+1)
+			}
+			task := Task{}
+			if err := task.UnmarshalText([]byte(line)); err != nil {
+				// A value t can't read, like due:friday written by hand,
+				// mustn't keep the rest of the list from being read. The
+				// line is read as the description, so nothing is lost.
+				fmt.Fprintf(Warnings, "warning: line %d: %v, the whole line is read as the description (t --check --fix drops it)\n", i+1, err)
+				task = Task{Description: line, Id: Hash(line)}
+			}
+			// Cut descriptions no one could have meant, like a whole log
+			// file, so they don't slow down everything that follows.
+			if len(task.Description) > DescriptionLimit {
+				fmt.Fprintf(Warnings, "warning: line %d is %d bytes long, its description is cut to %d\n", i+1, len(line), DescriptionLimit)
+				task.Description = strings.Clone(TruncateDescription(task.Description, DescriptionLimit))
+			}
+			if parent := t.parentOf(&task); parent != nil {
+				parent.Children = append(parent.Children, &task)
+			} else if task.DeletedAt.IsZero() {
+				t.Tasks = append(t.Tasks, &task)
+			} else {
+				t.Tombstones = append(t.Tombstones, &task)
+			}
+		}
+	}
+	return nil
+}
+
+// TruncateDescription cuts a description down to limit bytes, ending in
+// an ellipsis and never in the middle of a character.
+func TruncateDescription(description string, limit int) string {
+	if len(description) <= limit {
+		return description
+	}
+	cut := limit - len(ellipsis)
+	for cut > 0 && !utf8.RuneStart(description[cut]) {
+		cut--
+	}
+	if cut < 0 {
+		cut = 0
+	}
+	return description[:cut] + ellipsis
+}
+
+// ellipsis marks a truncated description.
+const ellipsis = "…"
+
+// marshalChildren marshals the subtasks of a task for the tasks file,
+// each naming the task with ParentKey.
+func marshalChildren(task *Task) ([]string, error) {
+	lines := make([]string, 0, len(task.Children))
+	for _, child := range task.Children {
+		marked := child.Clone()
+		if marked.Meta == nil {
+			marked.Meta = make(map[string]string)
+		}
+		marked.Meta[ParentKey] = task.Id
+		line, err := marked.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, string(line))
+	}
+	return lines, nil
+}
+
+// parentOf returns the task a task read from the tasks file is a
+// subtask of, the last one read so far with the stable id in its
+// ParentKey, and takes the key off it. It returns nil for a task of its
+// own, and for a subtask whose task isn't there, which is kept as one.
+func (t *TaskList) parentOf(task *Task) *Task {
+	id, ok := task.Meta[ParentKey]
+	if !ok {
+		return nil
+	}
+	for i := len(t.Tasks) - 1; i >= 0; i-- {
+		if t.Tasks[i].Id == id {
+			delete(task.Meta, ParentKey)
+			if len(task.Meta) == 0 {
+				task.Meta = nil
+			}
+			return t.Tasks[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package tasklist
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// joinDescriptions joins the descriptions of tasks with spaces.
+func joinDescriptions(tasks []*Task) string {
+	descriptions := make([]string, len(tasks))
+	for i, task := range tasks {
+		descriptions[i] = task.Description
+	}
+	return strings.Join(descriptions, " ")
+}
+
+func TestSubtasksRoundTrip(t *testing.T) {
+	created := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	list := &TaskList{Tasks: []*Task{
+		{Description: "ship the release", Id: Hash("ship the release"), CreatedAt: created, Children: []*Task{
+			{Description: "write tests", Id: Hash("write tests"), CreatedAt: created},
+			{Description: "tag the commit", Id: Hash("tag the commit"), CreatedAt: created, Notes: []string{"after the tests"}},
+		}},
+		{Description: "pay rent", Id: Hash("pay rent"), CreatedAt: created},
+	}}
+	text, err := list.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(text), "\n")
+	if len(lines) != 4 || lines[0] != "ship the release | created:"+list.Tasks[0].CreatedAt.UTC().Format("2006-01-02T15:04:05Z07:00") ||
+		!strings.HasPrefix(lines[1], "write tests | ") || !strings.HasSuffix(lines[1], " parent:"+list.Tasks[0].Id) ||
+		!strings.HasPrefix(lines[3], "pay rent | ") {
+		t.Fatalf("Expected the subtasks right after their task, got '%s'", text)
+	}
+	read := &TaskList{}
+	if err := read.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+	if joinDescriptions(read.Tasks) != "ship the release pay rent" || joinDescriptions(read.Tasks[0].Children) != "write tests tag the commit" {
+		t.Fatalf("Expected the subtasks back under their task, got %s and %s", joinDescriptions(read.Tasks), joinDescriptions(read.Tasks[0].Children))
+	}
+	child := read.Tasks[0].Children[1]
+	if child.Meta != nil || len(child.Notes) != 1 || child.Id != list.Tasks[0].Children[1].Id {
+		t.Fatalf("Expected the subtask as it was, got %+v", child)
+	}
+	again, _ := read.MarshalText()
+	if string(again) != string(text) {
+		t.Fatalf("Expected the same file written back, got '%s'", again)
+	}
+	// A subtask whose task is gone is a task of its own.
+	orphan := &TaskList{}
+	if err := orphan.UnmarshalText([]byte("pay rent\nwrite tests | parent:1234\n")); err != nil {
+		t.Fatal(err)
+	}
+	if joinDescriptions(orphan.Tasks) != "pay rent write tests" || orphan.Tasks[1].Meta[ParentKey] != "1234" {
+		t.Fatalf("Expected the orphan kept with its parent, got %s", joinDescriptions(orphan.Tasks))
+	}
+}
+
+func TestUnmarshalInvalidMeta(t *testing.T) {
+	var warnings strings.Builder
+	Warnings = &warnings
+	defer func() { Warnings = os.Stderr }()
+	list := &TaskList{}
+	if err := list.UnmarshalText([]byte("pay rent | due:2024-06-01\nShip it | due:friday\nx | created:yesterday")); err != nil {
+		t.Fatal(err)
+	}
+	if joinDescriptions(list.Tasks) != "pay rent Ship it | due:friday x | created:yesterday" || list.Tasks[0].DueAt.IsZero() {
+		t.Fatalf("Expected the lines t can't read kept as descriptions, got %s", joinDescriptions(list.Tasks))
+	}
+	if !strings.Contains(warnings.String(), `line 2: invalid due date "friday"`) || !strings.Contains(warnings.String(), "line 3: ") {
+		t.Fatalf("Expected a warning for each line, got '%s'", warnings.String())
+	}
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+// twTimeLayout is Taskwarrior's compact UTC time format, like
+// 20240601T090000Z.
+const twTimeLayout = "20060102T150405Z"
+
+// twKey is the metadata key keeping the Taskwarrior UUID of an imported
+// task, so that exporting it gives it back.
+const twKey = "uuid"
+
+// twTask is a task in Taskwarrior's JSON export format, with the fields
+// t knows. The others are ignored.
+type twTask struct {
+	UUID        string   `json:"uuid,omitempty"`
+	Description string   `json:"description"`
+	Status      string   `json:"status"`
+	Entry       string   `json:"entry,omitempty"`
+	Due         string   `json:"due,omitempty"`
+	End         string   `json:"end,omitempty"`
+	Priority    string   `json:"priority,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// twEntries is an error listing the entries of a Taskwarrior export
+// that ImportTaskwarrior skipped because it couldn't read them,
+// numbered from 1. The other entries were imported.
+type twEntries []int
+
+func (entries twEntries) Error() string {
+	numbers := make([]string, 0, len(entries))
+	for _, n := range entries {
+		numbers = append(numbers, fmt.Sprint(n))
+	}
+	return fmt.Sprintf("skipped %d malformed entries: %s", len(entries), strings.Join(numbers, ", "))
+}
+
+// twPriorities map Taskwarrior's priorities to t's, and back for the
+// priorities up to 3, 6 and 9.
+var twPriorities = map[string]int{"H": 1, "M": 5, "L": 9}
+
+func twPriority(priority int) string {
+	switch {
+	case priority == 0:
+		return ""
+	case priority <= 3:
+		return "H"
+	case priority <= 6:
+		return "M"
+	}
+	return "L"
+}
+
+// parseTwTime reads a time in twTimeLayout; an empty one is the zero
+// time.
+func parseTwTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(twTimeLayout, s)
+}
+
+func formatTwTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(twTimeLayout)
+}
+
+// twUUID returns the UUID a task is exported with: the one it was
+// imported with, or one made from its stable id, which stays the same
+// from one export to the next.
+func twUUID(task *Task) string {
+	if uuid := task.Meta[twKey]; uuid != "" {
+		return uuid
+	}
+	sum := sha1.Sum([]byte("t:" + task.Id))
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// parseTw turns an entry of a Taskwarrior export into a task, its tags
+// as #tags after the description. It returns nil for a deleted task or
+// the template of a recurring one, which have no place in t.
+func parseTw(entry twTask) (*Task, error) {
+	switch entry.Status {
+	case "deleted", "recurring":
+		return nil, nil
+	case "pending", "waiting", "completed":
+	default:
+		return nil, fmt.Errorf("invalid status %q", entry.Status)
+	}
+	words := strings.Fields(entry.Description)
+	if len(words) == 0 {
+		return nil, fmt.Errorf("no description")
+	}
+	task := &Task{}
+	for _, tag := range entry.Tags {
+		if tag == "" || strings.ContainsAny(tag, " \t\r\n") {
+			return nil, fmt.Errorf("invalid tag %q", tag)
+		}
+		if !containsWord(words, "#"+tag) {
+			words = append(words, "#"+tag)
+		}
+	}
+	task.Description = strings.Join(words, " ")
+	if entry.Priority != "" {
+		priority, ok := twPriorities[entry.Priority]
+		if !ok {
+			return nil, fmt.Errorf("invalid priority %q", entry.Priority)
+		}
+		task.Priority = priority
+	}
+	var err error
+	if task.CreatedAt, err = parseTwTime(entry.Entry); err != nil {
+		return nil, fmt.Errorf("invalid entry %q", entry.Entry)
+	}
+	due, err := parseTwTime(entry.Due)
+	if err != nil {
+		return nil, fmt.Errorf("invalid due %q", entry.Due)
+	}
+	if !due.IsZero() {
+		task.DueAt = startOfDay(due.In(time.Local))
+	}
+	if entry.Status == "completed" {
+		if task.DoneAt, err = parseTwTime(entry.End); err != nil {
+			return nil, fmt.Errorf("invalid end %q", entry.End)
+		}
+		if task.DoneAt.IsZero() {
+			task.DoneAt = time.Now()
+		}
+	}
+	if entry.UUID != "" {
+		task.Meta = map[string]string{twKey: entry.UUID}
+	}
+	return task, nil
+}
+
+func containsWord(words []string, word string) bool {
+	for _, w := range words {
+		if w == word {
+			return true
+		}
+	}
+	return false
+}
+
+// ImportTaskwarrior adds the pending tasks of a Taskwarrior JSON export
+// to the list and returns the completed ones, for the done file.
+// Entries it can't read are skipped and returned as a twEntries error,
+// the others imported all the same.
+func (t *TaskList) ImportTaskwarrior(data []byte) ([]*Task, error) {
+	var entries []json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("not a Taskwarrior export: %v", err)
+	}
+	completed := make([]*Task, 0)
+	skipped := make(twEntries, 0)
+	for i, raw := range entries {
+		var entry twTask
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			skipped = append(skipped, i+1)
+			continue
+		}
+		parsed, err := parseTw(entry)
+		if err != nil {
+			skipped = append(skipped, i+1)
+			continue
+		}
+		if parsed == nil {
+			continue
+		}
+		task, err := t.Add(parsed.Description)
+		if err != nil {
+			skipped = append(skipped, i+1)
+			continue
+		}
+		task.Priority, task.DueAt, task.DoneAt, task.Meta = parsed.Priority, parsed.DueAt, parsed.DoneAt, parsed.Meta
+		if !parsed.CreatedAt.IsZero() {
+			task.CreatedAt = parsed.CreatedAt
+		}
+		if entry.Status == "completed" {
+			// Completed tasks go to the done file only.
+			t.Tasks = t.Tasks[:len(t.Tasks)-1]
+			completed = append(completed, task)
+		}
+	}
+	if len(skipped) > 0 {
+		return completed, skipped
+	}
+	return completed, nil
+}
+
+// ExportTaskwarrior renders the tasks of list as pending and those of
+// done as completed in Taskwarrior's JSON export format, an object per
+// line. The tags at the end of a description go to the tags of the
+// task, without their # (or + or @), and the others stay where they are.
+func ExportTaskwarrior(list, done *TaskList) ([]byte, error) {
+	var b strings.Builder
+	b.WriteString("[\n")
+	all := append(append([]*Task(nil), list.Tasks...), done.Tasks...)
+	for i, task := range all {
+		words := strings.Fields(task.Description)
+		tags := make([]string, 0)
+		for _, word := range words {
+			if isTag(word) {
+				tags = append(tags, word[1:])
+			}
+		}
+		for len(words) > 1 && isTag(words[len(words)-1]) {
+			words = words[:len(words)-1]
+		}
+		entry := twTask{
+			UUID:        twUUID(task),
+			Description: strings.Join(words, " "),
+			Status:      "pending",
+			Entry:       formatTwTime(task.CreatedAt),
+			Due:         formatTwTime(task.DueAt),
+			Priority:    twPriority(task.Priority),
+			Tags:        tags,
+		}
+		if i >= len(list.Tasks) {
+			entry.Status, entry.End = "completed", formatTwTime(task.DoneAt)
+		}
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(line)
+		if i < len(all)-1 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("]\n")
+	return []byte(b.String()), nil
+}
+
+// importTaskwarrior handles --import-tw: it imports the Taskwarrior
+// export at path, writing the pending tasks to the list and the
+// completed ones to the done file at once, and says how many there
+// were on out. Entries it can't read are reported on stderr.
+func importTaskwarrior(path string, out io.Writer) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	limit, err := doneLimit()
+	if err != nil {
+		return err
+	}
+	count := len(tasklist.Tasks)
+	completed, err := tasklist.ImportTaskwarrior(data)
+	if err != nil {
+		if _, ok := err.(twEntries); !ok {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "warning:", err)
+	}
+	tx := newTransaction(journalPath(taskFilePath))
+	for _, task := range completed {
+		if err := stageDone(tx, donePath(taskFilePath), []*Task{task}, task.DoneAt, limit); err != nil {
+			tx.abort()
+			return err
+		}
+	}
+	if err := tasklist.commit(tx, true); err != nil {
+		return err
+	}
+	added := len(tasklist.Tasks) - count
+	fmt.Fprintf(out, "imported %d tasks, %d of them completed\n", added+len(completed), len(completed))
+	return nil
+}
+
+// exportTaskwarrior handles --export-tw: it prints the list and the
+// tasks finished so far in Taskwarrior's JSON export format.
+func exportTaskwarrior(out io.Writer) error {
+	done, err := readDoneHistory(donePath(taskFilePath))
+	if err != nil {
+		return err
+	}
+	text, err := ExportTaskwarrior(tasklist, done)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(text)
+	return err
+}
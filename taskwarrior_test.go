@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+// twMidnight is a due date as Taskwarrior exports it when it was set
+// to a day, local midnight in UTC.
+var twMidnight = time.Date(2024, 6, 14, 0, 0, 0, 0, time.Local).UTC().Format(twTimeLayout)
+
+// twSample is an export like task export prints, with entries t can't
+// read at 5 to 8.
+var twSample = `[
+{"id":1,"description":"Call mom","entry":"20240601T090000Z","modified":"20240601T090000Z","status":"pending","uuid":"5c9b1a3e-2f1d-4e8a-9c7b-1d2e3f4a5b6c","tags":["family"],"priority":"H","due":"` + twMidnight + `","urgency":8.9},
+{"id":2,"description":"Renew passport","entry":"20240602T100000Z","status":"waiting","wait":"20240701T000000Z","uuid":"0d4c6b8a-1e2f-4a3b-8c9d-0e1f2a3b4c5d","priority":"L"},
+{"id":0,"description":"Pay #taxes early","entry":"20240301T080000Z","end":"20240415T170000Z","status":"completed","uuid":"7a8b9c0d-1e2f-4a3b-9c4d-5e6f7a8b9c0d","priority":"M","tags":["taxes"]},
+{"id":0,"description":"Old idea","entry":"20240101T080000Z","status":"deleted","uuid":"1a2b3c4d-5e6f-4a7b-8c9d-0e1f2a3b4c5d"},
+{"id":3,"description":"  ","entry":"20240601T090000Z","status":"pending"},
+{"id":4,"description":"Bad date","entry":"yesterday","status":"pending"},
+42,
+{"id":5,"description":"Odd priority","status":"pending","priority":"X"}
+]`
+
+func TestImportTaskwarrior(t *testing.T) {
+	list := &TaskList{}
+	completed, err := list.ImportTaskwarrior([]byte(twSample))
+	skipped, ok := err.(twEntries)
+	if !ok || !reflect.DeepEqual(skipped, twEntries{5, 6, 7, 8}) {
+		t.Fatalf("Expected entries 5 to 8 skipped, got %v", err)
+	}
+	if err.Error() != "skipped 4 malformed entries: 5, 6, 7, 8" {
+		t.Fatalf("Unexpected message: %v", err)
+	}
+	if joinDescriptions(list.Tasks) != "Call mom #family Renew passport" {
+		t.Fatalf("Unexpected tasks: %s", joinDescriptions(list.Tasks))
+	}
+	mom := list.Tasks[0]
+	if mom.Priority != 1 || list.Tasks[1].Priority != 9 {
+		t.Fatalf("Unexpected priorities: %d, %d", mom.Priority, list.Tasks[1].Priority)
+	}
+	if !mom.CreatedAt.Equal(time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Expected the entry time kept, got %v", mom.CreatedAt)
+	}
+	if !mom.DueAt.Equal(time.Date(2024, 6, 14, 0, 0, 0, 0, time.Local)) {
+		t.Fatalf("Expected the due date kept, got %v", mom.DueAt)
+	}
+	if mom.Meta[twKey] != "5c9b1a3e-2f1d-4e8a-9c7b-1d2e3f4a5b6c" {
+		t.Fatalf("Expected the UUID kept, got %v", mom.Meta)
+	}
+	if len(completed) != 1 || completed[0].Description != "Pay #taxes early" || completed[0].Priority != 5 ||
+		!completed[0].DoneAt.Equal(time.Date(2024, 4, 15, 17, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Expected the completed task with its end time, got %v", completed)
+	}
+	if _, err := list.ImportTaskwarrior([]byte(`{"description":"not an array"}`)); err == nil {
+		t.Fatal("Expected something other than an export refused")
+	}
+}
+
+func TestTaskwarriorRoundTrip(t *testing.T) {
+	list := &TaskList{}
+	completed, _ := list.ImportTaskwarrior([]byte(twSample))
+	text, err := ExportTaskwarrior(list, &TaskList{TaskList: core.TaskList{Tasks: completed}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exported, sample []map[string]interface{}
+	if err := json.Unmarshal(text, &exported); err != nil {
+		t.Fatalf("Expected a JSON array, got '%s' (%v)", text, err)
+	}
+	json.Unmarshal([]byte(strings.Replace(twSample, "42,\n", "", 1)), &sample)
+	// What t keeps of the tasks it imports, waiting tasks being
+	// pending ones in t.
+	kept := []string{"uuid", "description", "entry", "due", "end", "priority", "tags"}
+	for i, n := range []int{0, 1, 2} {
+		for _, key := range kept {
+			if fmt.Sprint(exported[i][key]) != fmt.Sprint(sample[n][key]) {
+				t.Errorf("task %d: expected %s %v, got %v", i, key, sample[n][key], exported[i][key])
+			}
+		}
+	}
+	if len(exported) != 3 || exported[0]["status"] != "pending" || exported[1]["status"] != "pending" || exported[2]["status"] != "completed" {
+		t.Fatalf("Unexpected export: %s", text)
+	}
+	// And t's tasks come back from an export as they were.
+	again := &TaskList{}
+	done, err := again.ImportTaskwarrior(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	originals := append(append([]*Task(nil), list.Tasks...), completed...)
+	for i, task := range append(again.Tasks, done...) {
+		original := originals[i]
+		if task.Description != original.Description || task.Priority != original.Priority || !task.DueAt.Equal(original.DueAt) ||
+			!task.CreatedAt.Equal(original.CreatedAt) || !task.DoneAt.Equal(original.DoneAt) || task.Meta[twKey] != original.Meta[twKey] {
+			t.Errorf("Expected %v back, got %v", original, task)
+		}
+	}
+}
+
+func TestTwUUID(t *testing.T) {
+	list := &TaskList{}
+	task, _ := list.Add("pay rent")
+	uuid := twUUID(task)
+	if len(uuid) != 36 || uuid[14] != '5' || !strings.ContainsAny(uuid[19:20], "89ab") {
+		t.Fatalf("Expected a version 5 UUID, got %s", uuid)
+	}
+	task.Description = "pay the rent"
+	if twUUID(task) != uuid {
+		t.Fatal("Expected the UUID to follow the stable id")
+	}
+	other, _ := list.Add("call bob")
+	if twUUID(other) == uuid {
+		t.Fatal("Expected another task to get another UUID")
+	}
+	task.Meta = map[string]string{twKey: "imported"}
+	if twUUID(task) != "imported" {
+		t.Fatal("Expected an imported UUID kept")
+	}
+}
+
+func TestCliTaskwarrior(t *testing.T) {
+	withCliSetup(t, func() {
+		path := filepath.Join(t.TempDir(), "export.json")
+		if err := ioutil.WriteFile(path, []byte(twSample), 0644); err != nil {
+			t.Fatal(err)
+		}
+		out, err := exec.Command(tBinary, "--import-tw", path).CombinedOutput()
+		if err != nil || string(out) != "warning: skipped 4 malformed entries: 5, 6, 7, 8\nimported 3 tasks, 1 of them completed\n" {
+			t.Fatalf("Expected a warning and a count, got '%s' (%v)", out, err)
+		}
+		if out, _ := exec.Command(tBinary).Output(); string(out) != "0 - Call mom #family (P1) (due 2024-06-14) (stale)\n1 - Renew passport (P9) (stale)\n" {
+			t.Fatalf("Expected the pending tasks on the list, got '%s'", out)
+		}
+		if text, _ := ioutil.ReadFile("/tmp/tasks.done"); !strings.HasPrefix(string(text), "Pay #taxes early | ") || !strings.Contains(string(text), "done:2024-04-15T17:00:00Z") {
+			t.Fatalf("Expected the completed task in the done file, got '%s'", text)
+		}
+		out, err = exec.Command(tBinary, "--export-tw").Output()
+		if err != nil || !strings.HasPrefix(string(out), "[\n{") || strings.Count(string(out), "\n") != 5 ||
+			!strings.Contains(string(out), `"description":"Call mom","status":"pending","entry":"20240601T090000Z"`) {
+			t.Fatalf("Expected the tasks exported, got '%s' (%v)", out, err)
+		}
+		if err := exec.Command(tBinary, "--import-tw", "/nonexistent.json").Run(); err == nil {
+			t.Fatal("Expected a missing file to fail")
+		}
+	})
+}
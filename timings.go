@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// metrics times the phases of a run of t for --timings. Each phase ends
+// where the next one starts, so together they add up to the whole run.
+// A nil *metrics records nothing, which keeps timing free when it is
+// off.
+type metrics struct {
+	clock  clock
+	start  time.Time
+	last   time.Time
+	phases []phaseTiming
+}
+
+// phaseTiming is how long one phase took, with a note like the number of
+// tasks it dealt with.
+type phaseTiming struct {
+	name     string
+	duration time.Duration
+	note     string
+	skipped  bool
+}
+
+func newMetrics(c clock) *metrics {
+	now := c.Now()
+	return &metrics{clock: c, start: now, last: now}
+}
+
+// phase ends the phase running since the last one, under the given name.
+func (m *metrics) phase(name string, note string) {
+	if m == nil {
+		return
+	}
+	now := m.clock.Now()
+	m.phases = append(m.phases, phaseTiming{name: name, duration: now.Sub(m.last), note: note})
+	m.last = now
+}
+
+// skip records a phase the run didn't need.
+func (m *metrics) skip(name string) {
+	if m == nil {
+		return
+	}
+	m.phases = append(m.phases, phaseTiming{name: name, skipped: true})
+}
+
+// total ends the run, putting the time since the last phase under
+// other, and returns how long the run took.
+func (m *metrics) total() time.Duration {
+	if m.last.Before(m.clock.Now()) {
+		m.phase("other", "")
+	}
+	return m.last.Sub(m.start)
+}
+
+// report ends the run and writes the phases and the total to w, like
+// "load: 3.2ms (8,412 tasks), render: 1.1ms, write: skipped, total: 4.3ms".
+func (m *metrics) report(w io.Writer) {
+	if m == nil {
+		return
+	}
+	total := m.total()
+	parts := make([]string, 0, len(m.phases)+1)
+	for _, p := range m.phases {
+		switch {
+		case p.skipped:
+			parts = append(parts, p.name+": skipped")
+		case p.note != "":
+			parts = append(parts, fmt.Sprintf("%s: %s (%s)", p.name, formatMillis(p.duration), p.note))
+		default:
+			parts = append(parts, fmt.Sprintf("%s: %s", p.name, formatMillis(p.duration)))
+		}
+	}
+	parts = append(parts, "total: "+formatMillis(total))
+	fmt.Fprintln(w, strings.Join(parts, ", "))
+}
+
+// formatMillis renders a duration in milliseconds, like 3.2ms.
+func formatMillis(d time.Duration) string {
+	return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond))
+}
+
+// formatCount renders a count with thousands separators, like 8,412.
+func formatCount(n int) string {
+	s := strconv.Itoa(n)
+	for i := len(s) - 3; i > 0 && s[i-1] != '-'; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
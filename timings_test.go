@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsAddUp(t *testing.T) {
+	c := &fakeClock{now: time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)}
+	m := newMetrics(c)
+	c.now = c.now.Add(3200 * time.Microsecond)
+	m.phase("load", formatCount(8412)+" tasks")
+	c.now = c.now.Add(400 * time.Microsecond)
+	m.phase("filter", "")
+	c.now = c.now.Add(1100 * time.Microsecond)
+	m.phase("render", "")
+	m.skip("write")
+	c.now = c.now.Add(300 * time.Microsecond)
+
+	var out bytes.Buffer
+	m.report(&out)
+	expected := "load: 3.2ms (8,412 tasks), filter: 0.4ms, render: 1.1ms, write: skipped, other: 0.3ms, total: 5.0ms\n"
+	if out.String() != expected {
+		t.Fatalf("Expected report %q, got %q", expected, out.String())
+	}
+	sum := time.Duration(0)
+	for _, p := range m.phases {
+		sum += p.duration
+	}
+	if sum != m.last.Sub(m.start) || sum != 5*time.Millisecond {
+		t.Fatalf("Expected the phases to add up to the total of 5ms, got %v", sum)
+	}
+}
+
+func TestMetricsOff(t *testing.T) {
+	var m *metrics
+	m.phase("load", "")
+	m.skip("write")
+	m.report(nil)
+}
+
+func TestFormatCount(t *testing.T) {
+	cases := map[int]string{0: "0", 999: "999", 1000: "1,000", 8412: "8,412", 1234567: "1,234,567", -1234: "-1,234"}
+	for n, expected := range cases {
+		if formatted := formatCount(n); formatted != expected {
+			t.Fatalf("formatCount(%d): expected %s, got %s", n, expected, formatted)
+		}
+	}
+}
+
+func TestCliTimings(t *testing.T) {
+	withCliSetup(t, func() {
+		var stderr bytes.Buffer
+		cmd := exec.Command(tBinary, "--timings")
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			t.Fatal(err)
+		}
+		report := stderr.String()
+		for _, phase := range []string{"load: ", "filter: ", "render: ", "write: skipped", "total: "} {
+			if !strings.Contains(report, phase) {
+				t.Fatalf("Expected %q in the timings, got %q", phase, report)
+			}
+		}
+	})
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+// writeToday writes the --today report: the tasks of done finished
+// since since, in the order they were finished, and the tasks of list
+// added since then, each under a heading, with "(none)" for an empty
+// one. since is the start of the day, local midnight for --today.
+func writeToday(w io.Writer, list *TaskList, done []*Task, since time.Time, opts formatOptions) {
+	fmt.Fprintln(w, "Finished today:")
+	finished, _ := (&TaskList{TaskList: core.TaskList{Tasks: done}}).doneSince(since)
+	sort.SliceStable(finished, func(i, j int) bool {
+		return finished[i].DoneAt.Before(finished[j].DoneAt)
+	})
+	for _, task := range finished {
+		fmt.Fprintf(w, "  %s %s\n", task.DoneAt.In(since.Location()).Format("15:04"), lineBreaks.Replace(task.Description))
+	}
+	if len(finished) == 0 {
+		fmt.Fprintln(w, "  (none)")
+	}
+	fmt.Fprintln(w, "Added today:")
+	all := make([]int, len(list.Tasks))
+	for i := range all {
+		all[i] = i
+	}
+	added, _ := list.sinceIds(all, since)
+	for _, taskId := range added {
+		fmt.Fprintf(w, "  %s\n", formatTask(taskId, list.Tasks[taskId], opts))
+	}
+	if len(added) == 0 {
+		fmt.Fprintln(w, "  (none)")
+	}
+}
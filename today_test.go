@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"os/exec"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestWriteToday(t *testing.T) {
+	loc := time.FixedZone("", 2*3600)
+	midnight := time.Date(2024, 6, 3, 0, 0, 0, 0, loc)
+	done := []*Task{
+		{Description: "pay rent", DoneAt: time.Date(2024, 6, 3, 0, 15, 0, 0, time.UTC)},
+		{Description: "ship it", DoneAt: time.Date(2024, 6, 2, 23, 30, 0, 0, time.UTC)},
+		{Description: "yesterday", DoneAt: time.Date(2024, 6, 2, 21, 30, 0, 0, time.UTC)},
+		{Description: "untimed"},
+	}
+	list := &TaskList{}
+	list.Add("old")
+	list.Tasks[0].CreatedAt = midnight.Add(-time.Minute)
+	list.Add("call bob")
+	list.Tasks[1].CreatedAt = midnight.Add(9 * time.Hour)
+	var out bytes.Buffer
+	writeToday(&out, list, done, midnight, formatOptions{now: midnight.Add(10 * time.Hour)})
+	expected := "Finished today:\n  01:30 ship it\n  02:15 pay rent\nAdded today:\n  1 - call bob\n"
+	if out.String() != expected {
+		t.Fatalf("Expected\n%s\ngot\n%s", expected, out.String())
+	}
+	out.Reset()
+	writeToday(&out, &TaskList{}, nil, midnight, formatOptions{now: midnight})
+	if out.String() != "Finished today:\n  (none)\nAdded today:\n  (none)\n" {
+		t.Fatalf("Expected empty sections to say so, got\n%s", out.String())
+	}
+}
+
+func TestCliToday(t *testing.T) {
+	withCliSetup(t, func() {
+		exec.Command(tBinary, "pay rent").Run()
+		exec.Command(tBinary, "call bob").Run()
+		exec.Command(tBinary, "-f", "0").Run()
+		out, err := exec.Command(tBinary, "--today").Output()
+		if err != nil || !regexp.MustCompile(`^Finished today:\n  \d\d:\d\d pay rent\nAdded today:\n  0 - call bob\n$`).Match(out) {
+			t.Fatalf("Expected the finished and the added task, got '%s' (%v)", out, err)
+		}
+	})
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+// todoTxtLines is an error listing the lines of a todo.txt file that
+// ImportTodoTxt skipped because it couldn't read them. The other lines
+// were imported.
+type todoTxtLines []int
+
+func (lines todoTxtLines) Error() string {
+	numbers := make([]string, 0, len(lines))
+	for _, n := range lines {
+		numbers = append(numbers, fmt.Sprint(n))
+	}
+	return fmt.Sprintf("skipped %d malformed lines: %s", len(lines), strings.Join(numbers, ", "))
+}
+
+// todoTxtPriority returns the todo.txt priority of a task, (A) for 1 to
+// (I) for 9, or "" for a task without one.
+func todoTxtPriority(task *Task) string {
+	if task.Priority == 0 {
+		return ""
+	}
+	return fmt.Sprintf("(%c)", 'A'+task.Priority-1)
+}
+
+// ExportTodoTxt renders the list in todo.txt syntax: each task with its
+// priority and creation date in front, its #tags as +projects, and its
+// due date, notes and metadata of its own as key:value pairs.
+func (t *TaskList) ExportTodoTxt() []byte {
+	var b strings.Builder
+	for _, task := range t.Tasks {
+		fields := make([]string, 0)
+		if priority := todoTxtPriority(task); priority != "" {
+			fields = append(fields, priority)
+		}
+		if !task.CreatedAt.IsZero() {
+			fields = append(fields, task.CreatedAt.Local().Format(dateLayout))
+		}
+		for _, word := range strings.Fields(task.Description) {
+			if len(word) > 1 && word[0] == '#' {
+				word = "+" + word[1:]
+			}
+			fields = append(fields, word)
+		}
+		if !task.DueAt.IsZero() {
+			fields = append(fields, "due:"+task.DueAt.Format(dateLayout))
+		}
+		for _, note := range task.Notes {
+			fields = append(fields, "note:"+core.EscapeMetaValue(note))
+		}
+		keys := make([]string, 0, len(task.Meta))
+		for key := range task.Meta {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fields = append(fields, key+":"+core.EscapeMetaValue(task.Meta[key]))
+		}
+		b.WriteString(strings.Join(fields, " ") + "\n")
+	}
+	return []byte(b.String())
+}
+
+// parseTodoTxt reads a line of a todo.txt file into a task. It returns
+// nil for a completed task, which has no place on the list.
+func parseTodoTxt(line string) (*Task, error) {
+	fields := strings.Fields(line)
+	if len(fields) > 0 && fields[0] == "x" {
+		return nil, nil
+	}
+	task := &Task{}
+	if len(fields) > 0 && len(fields[0]) == 3 && fields[0][0] == '(' && fields[0][2] == ')' &&
+		fields[0][1] >= 'A' && fields[0][1] <= 'Z' {
+		task.Priority = int(fields[0][1]-'A') + 1
+		if task.Priority > lowestPriority {
+			task.Priority = lowestPriority
+		}
+		fields = fields[1:]
+	}
+	if len(fields) > 0 && len(fields[0]) == len(dateLayout) && fields[0][4] == '-' {
+		created, err := time.ParseInLocation(dateLayout, fields[0], time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("invalid creation date %s", fields[0])
+		}
+		task.CreatedAt = created
+		fields = fields[1:]
+	}
+	words := make([]string, 0, len(fields))
+	pairs := make([][2]string, 0)
+	for _, word := range fields {
+		if key, value, ok := todoTxtPair(word); ok {
+			pairs = append(pairs, [2]string{key, core.UnescapeMetaValue(value)})
+			continue
+		}
+		words = append(words, word)
+	}
+	if err := task.SetMeta(pairs); err != nil {
+		return nil, err
+	}
+	if len(words) == 0 {
+		return nil, fmt.Errorf("no description")
+	}
+	task.Description = strings.Join(words, " ")
+	return task, nil
+}
+
+// todoTxtPair splits a key:value word of a todo.txt line. A key is
+// letters only and the value can't start with a slash, so times like
+// 10:30 and links stay part of the description.
+func todoTxtPair(word string) (string, string, bool) {
+	sep := strings.Index(word, ":")
+	if sep < 1 || sep == len(word)-1 || word[sep+1] == '/' {
+		return "", "", false
+	}
+	for _, r := range word[:sep] {
+		if !unicode.IsLetter(r) {
+			return "", "", false
+		}
+	}
+	return word[:sep], word[sep+1:], true
+}
+
+// ImportTodoTxt adds the tasks of a todo.txt file to the list, leaving
+// out the completed ones. Lines it can't read are skipped and returned
+// as a todoTxtLines error, the others imported all the same.
+func (t *TaskList) ImportTodoTxt(data []byte) error {
+	skipped := make(todoTxtLines, 0)
+	for i, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		parsed, err := parseTodoTxt(line)
+		if err != nil {
+			skipped = append(skipped, i+1)
+			continue
+		}
+		if parsed == nil {
+			continue
+		}
+		task, err := t.Add(parsed.Description)
+		if err != nil {
+			skipped = append(skipped, i+1)
+			continue
+		}
+		task.Priority, task.DueAt, task.Notes, task.Meta = parsed.Priority, parsed.DueAt, parsed.Notes, parsed.Meta
+		if !parsed.CreatedAt.IsZero() {
+			task.CreatedAt = parsed.CreatedAt
+		}
+	}
+	if len(skipped) > 0 {
+		return skipped
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTodoTxtRoundTrip(t *testing.T) {
+	list := &TaskList{}
+	for _, description := range []string{"Call mom #family @phone", "Fix the build +work", "Meet at 10:30 about https://example.com"} {
+		if _, err := list.Add(description); err != nil {
+			t.Fatal(err)
+		}
+	}
+	created := time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)
+	list.Tasks[0].Priority = 1
+	list.Tasks[0].CreatedAt = created
+	list.Tasks[1].Priority = 9
+	list.Tasks[1].DueAt = time.Date(2024, 6, 14, 0, 0, 0, 0, time.Local)
+	list.Tasks[1].Notes = []string{"two words"}
+	list.Tasks[1].Meta = map[string]string{"owner": "alice"}
+	list.Tasks[2].CreatedAt = time.Time{}
+
+	exported := list.ExportTodoTxt()
+	expected := "(A) 2024-05-01 Call mom +family @phone\n" +
+		"(I) " + list.Tasks[1].CreatedAt.Local().Format(dateLayout) + " Fix the build +work due:2024-06-14 note:two%20words owner:alice\n" +
+		"Meet at 10:30 about https://example.com\n"
+	if string(exported) != expected {
+		t.Fatalf("Expected\n%s\ngot\n%s", expected, exported)
+	}
+
+	imported := &TaskList{}
+	if err := imported.ImportTodoTxt(exported); err != nil {
+		t.Fatal(err)
+	}
+	if len(imported.Tasks) != 3 {
+		t.Fatalf("Expected 3 tasks, got %d", len(imported.Tasks))
+	}
+	if joinDescriptions(imported.Tasks) != "Call mom +family @phone Fix the build +work Meet at 10:30 about https://example.com" {
+		t.Fatalf("Unexpected descriptions: %s", joinDescriptions(imported.Tasks))
+	}
+	for i, task := range imported.Tasks {
+		original := list.Tasks[i]
+		if task.Priority != original.Priority || !task.DueAt.Equal(original.DueAt) || !reflect.DeepEqual(task.Meta, original.Meta) || !reflect.DeepEqual(task.Notes, original.Notes) {
+			t.Fatalf("Task %d changed on the way: %+v, was %+v", i, task, original)
+		}
+	}
+	if !imported.Tasks[0].CreatedAt.Equal(created) {
+		t.Fatalf("Expected the creation date kept, got %v", imported.Tasks[0].CreatedAt)
+	}
+	// The task without a creation date gets one on import, the others
+	// come out as they went in.
+	again := strings.SplitAfter(string(imported.ExportTodoTxt()), "\n")
+	if strings.Join(again[:2], "") != strings.Join(strings.SplitAfter(expected, "\n")[:2], "") {
+		t.Fatalf("Expected a second export to be the same, got\n%s", strings.Join(again, ""))
+	}
+}
+
+func TestImportTodoTxtSkipsMalformedLines(t *testing.T) {
+	data := strings.Join([]string{
+		"(B) Pay rent @home",
+		"x 2024-05-02 2024-05-01 Done already",
+		"(a) lowercase priority is just text",
+		"2024-02-30 Bad creation date",
+		"Due never due:2024-13-45",
+		"(C) 2024-05-01",
+		"",
+		"(Q) Low priority",
+	}, "\n")
+	list := &TaskList{}
+	err := list.ImportTodoTxt([]byte(data))
+	skipped, ok := err.(todoTxtLines)
+	if !ok || !reflect.DeepEqual(skipped, todoTxtLines{4, 5, 6}) {
+		t.Fatalf("Expected lines 4 to 6 skipped, got %v", err)
+	}
+	if err.Error() != "skipped 3 malformed lines: 4, 5, 6" {
+		t.Fatalf("Unexpected message: %v", err)
+	}
+	if joinDescriptions(list.Tasks) != "Pay rent @home (a) lowercase priority is just text Low priority" {
+		t.Fatalf("Unexpected tasks: %s", joinDescriptions(list.Tasks))
+	}
+	if list.Tasks[0].Priority != 2 || list.Tasks[1].Priority != 0 || list.Tasks[2].Priority != lowestPriority {
+		t.Fatalf("Unexpected priorities: %d, %d, %d", list.Tasks[0].Priority, list.Tasks[1].Priority, list.Tasks[2].Priority)
+	}
+}
+
+func TestCliTodoTxt(t *testing.T) {
+	withCliSetup(t, func() {
+		path := "/tmp/todo.txt"
+		defer exec.Command("rm", path).Run()
+		if err := ioutil.WriteFile(path, []byte("(A) Call mom +family due:2024-06-14\nnot a date due:soon\nx Done\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		out, err := exec.Command(tBinary, "--import-todotxt", path).CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != "warning: skipped 1 malformed lines: 2\nimported 1 tasks\n" {
+			t.Fatalf("Expected a warning and a count, got '%s'", out)
+		}
+		out, _ = exec.Command(tBinary, "--export-todotxt").Output()
+		if !strings.HasPrefix(string(out), "(A) ") || !strings.HasSuffix(string(out), " Call mom +family due:2024-06-14\n") {
+			t.Fatalf("Expected the task in todo.txt syntax, got '%s'", out)
+		}
+	})
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// defaultTombstoneWindow is how long --vacuum keeps tombstones unless
+// tombstone_window says otherwise.
+const defaultTombstoneWindow = 30 * 24 * time.Hour
+
+// tombstoneSettings reads whether the list keeps tombstones, from the
+// tombstones setting, and how long --vacuum keeps them, from
+// tombstone_window.
+func tombstoneSettings(config Config) (bool, time.Duration, error) {
+	keep := false
+	if value, ok := config.Get("tombstones"); ok {
+		switch value {
+		case "true":
+			keep = true
+		case "false":
+		default:
+			return false, 0, fmt.Errorf("invalid tombstones = %s, expected true or false", value)
+		}
+	}
+	window := defaultTombstoneWindow
+	if value, ok := config.Get("tombstone_window"); ok {
+		d, err := parseDuration(value)
+		if err != nil {
+			return false, 0, fmt.Errorf("invalid tombstone_window = %s, expected e.g. 30d or 2w", value)
+		}
+		window = d
+	}
+	return keep, window, nil
+}
+
+// vacuum drops the tombstones older than window and returns how many
+// it dropped.
+func (t *TaskList) vacuum(window time.Duration, now time.Time) int {
+	cutoff := now.Add(-window)
+	kept := make([]*Task, 0, len(t.Tombstones))
+	for _, tombstone := range t.Tombstones {
+		if tombstone.DeletedAt.After(cutoff) {
+			kept = append(kept, tombstone)
+		}
+	}
+	dropped := len(t.Tombstones) - len(kept)
+	t.Tombstones = kept
+	return dropped
+}
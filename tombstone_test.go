@@ -0,0 +1,93 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+func TestTombstones(t *testing.T) {
+	list := &TaskList{TaskList: core.TaskList{KeepTombstones: true}}
+	list.UnmarshalText([]byte("water plants\npay rent"))
+	finished, err := list.Finish(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !finished.DeletedAt.IsZero() {
+		t.Fatal("Expected the finished task itself not to be marked deleted")
+	}
+	if len(list.Tasks) != 1 || len(list.Tombstones) != 1 || list.Tombstones[0].DeletedAt.IsZero() {
+		t.Fatalf("Expected one task and one tombstone, got %d and %d", len(list.Tasks), len(list.Tombstones))
+	}
+	text, _ := list.MarshalText()
+	if !strings.HasPrefix(string(text), "pay rent") || !strings.Contains(string(text), "water plants | deleted:") {
+		t.Fatalf("Expected the tombstone after the tasks, got '%s'", text)
+	}
+
+	loaded := &TaskList{}
+	loaded.UnmarshalText(text)
+	if len(loaded.Tasks) != 1 || loaded.Tasks[0].Description != "pay rent" || len(loaded.Tombstones) != 1 {
+		t.Fatalf("Expected the tombstone to be read back hidden, got %v", loaded.Tasks)
+	}
+	if problems := checkTasks(append(text, []byte("\nwater plants")...)); len(problems) != 0 {
+		t.Fatalf("Expected a task added again not to clash with its tombstone, got %v", problems)
+	}
+
+	plain := &TaskList{}
+	plain.UnmarshalText([]byte("water plants"))
+	plain.Finish(0)
+	if len(plain.Tombstones) != 0 {
+		t.Fatal("Expected no tombstones unless the list keeps them")
+	}
+}
+
+func TestVacuum(t *testing.T) {
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+	list := &TaskList{}
+	list.UnmarshalText([]byte("open\nold | deleted:2024-05-01T09:00:00Z\nrecent | deleted:2024-06-20T09:00:00Z"))
+	if dropped := list.vacuum(30*24*time.Hour, now); dropped != 1 {
+		t.Fatalf("Expected one tombstone to be dropped, got %d", dropped)
+	}
+	if len(list.Tombstones) != 1 || list.Tombstones[0].Description != "recent" || len(list.Tasks) != 1 {
+		t.Fatalf("Expected the recent tombstone and the open task to be kept, got %v", list.Tombstones)
+	}
+}
+
+func TestCliTombstones(t *testing.T) {
+	withCliSetup(t, func() {
+		dir := t.TempDir()
+		os.MkdirAll(filepath.Join(dir, "t"), 0755)
+		ioutil.WriteFile(filepath.Join(dir, "t", "config"), []byte("tombstones = true\ntombstone_window = 0\n"), 0644)
+		ioutil.WriteFile("/tmp/tasks", []byte("water plants"), 0644)
+		env := append(os.Environ(), "XDG_CONFIG_HOME="+dir)
+		cmd := exec.Command(tBinary, "-f", "0")
+		cmd.Env = env
+		if err := cmd.Run(); err != nil {
+			t.Fatal(err)
+		}
+		text, _ := ioutil.ReadFile("/tmp/tasks")
+		if !strings.HasPrefix(string(text), "water plants | ") || !strings.Contains(string(text), " deleted:") {
+			t.Fatalf("Expected a tombstone in the tasks file, got '%s'", text)
+		}
+		cmd = exec.Command(tBinary)
+		cmd.Env = env
+		if out, _ := cmd.Output(); len(out) != 0 {
+			t.Fatalf("Expected the tombstone to be hidden, got '%s'", out)
+		}
+		cmd = exec.Command(tBinary, "--vacuum")
+		cmd.Env = env
+		out, _ := cmd.Output()
+		if string(out) != "dropped 1 tombstones\n" {
+			t.Fatalf("Expected one tombstone to be dropped, got '%s'", out)
+		}
+		if _, err := os.Stat("/tmp/tasks"); !os.IsNotExist(err) {
+			t.Fatal("Expected the emptied tasks file to be removed")
+		}
+	})
+}
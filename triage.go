@@ -0,0 +1,231 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+)
+
+// triageInput is where --triage reads its single-key actions from, and
+// the lines some of them ask for, so that tests can script them.
+type triageInput interface {
+	Key() (byte, error)
+	Line(prompt string) (string, error)
+}
+
+// triageSummary counts what --triage did.
+type triageSummary struct {
+	finished, deleted, edited, prioritized, skipped int
+}
+
+func (s triageSummary) handled() int {
+	return s.finished + s.deleted + s.edited + s.prioritized + s.skipped
+}
+
+func (s triageSummary) String() string {
+	return fmt.Sprintf("handled %d: finished %d, deleted %d, edited %d, prioritized %d, skipped %d",
+		s.handled(), s.finished, s.deleted, s.edited, s.prioritized, s.skipped)
+}
+
+// triageOrder returns the listed tasks, the oldest first. Tasks without
+// a creation time come before the others, in list order.
+func (t *TaskList) triageOrder() []*Task {
+	tasks := make([]*Task, 0)
+	for _, taskId := range t.Search("") {
+		tasks = append(tasks, t.Tasks[taskId])
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].CreatedAt.Before(tasks[j].CreatedAt)
+	})
+	return tasks
+}
+
+// triage goes through the tasks one at a time, reading an action for
+// each from in: f finishes, d deletes, e edits, p and a digit sets the
+// priority, s skips and q quits. save is called after every change with
+// the finished task, if any, so that quitting halfway loses nothing.
+// Another t may change the list while in waits for a key, so the task
+// is looked up again, by stable id, before its action is applied.
+func (t *TaskList) triage(tasks []*Task, in triageInput, out io.Writer, save func(finished *Task) error) (triageSummary, error) {
+	var summary triageSummary
+	for _, task := range tasks {
+		taskId := t.IndexOf(task.Id)
+		if taskId == -1 {
+			continue
+		}
+		fmt.Fprintln(out, formatTask(taskId, t.Tasks[taskId], formatOptions{age: true, now: time.Now()}))
+		var finished *Task
+		changed := true
+	actions:
+		for {
+			fmt.Fprint(out, "[f]inish, [d]elete, [e]dit, [p]riority, [s]kip, [q]uit? ")
+			key, err := in.Key()
+			if err != nil {
+				return summary, endOfInput(err)
+			}
+			fmt.Fprintln(out, string(key))
+			var description string
+			priority := 0
+			switch key {
+			case 'e':
+				description, err = in.Line("new description:")
+				if err != nil && err != io.EOF {
+					return summary, err
+				}
+				if err != nil || description == "" {
+					continue
+				}
+			case 'p':
+				fmt.Fprint(out, "priority (1-9)? ")
+				digit, err := in.Key()
+				if err != nil {
+					return summary, endOfInput(err)
+				}
+				fmt.Fprintln(out, string(digit))
+				if priority, err = parsePriority(string(digit)); err != nil {
+					fmt.Fprintln(out, err)
+					continue
+				}
+			}
+			if taskId = t.IndexOf(task.Id); taskId == -1 {
+				fmt.Fprintln(out, "the task was finished or deleted meanwhile")
+				changed = false
+				break actions
+			}
+			switch key {
+			case 'f':
+				finished, _ = t.Finish(taskId)
+				summary.finished++
+			case 'd':
+				t.Remove(taskId)
+				summary.deleted++
+			case 'e':
+				if err := t.Edit(taskId, description); err != nil {
+					fmt.Fprintln(out, err)
+					continue
+				}
+				summary.edited++
+			case 'p':
+				t.Tasks[taskId].Priority = priority
+				summary.prioritized++
+			case 's':
+				changed = false
+				summary.skipped++
+			case 'q':
+				return summary, nil
+			default:
+				continue
+			}
+			break actions
+		}
+		if changed {
+			if err := save(finished); err != nil {
+				return summary, err
+			}
+		}
+	}
+	return summary, nil
+}
+
+// endOfInput returns nil for the end of the input, which ends --triage
+// like q, and other errors, like failing to take the lock on the tasks
+// file again, as they are.
+func endOfInput(err error) error {
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+// terminalInput reads keys from a terminal as soon as they are typed,
+// with stty switching off line editing and echo, and lines as usual.
+type terminalInput struct {
+	saved string
+}
+
+// stty runs stty on the terminal t runs on and returns what it printed.
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// newTerminalInput saves the state of the terminal and puts it in the
+// mode for reading single keys. restore must be called to put it back.
+func newTerminalInput() (*terminalInput, error) {
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("can't read the terminal state: %v", err)
+	}
+	in := &terminalInput{saved: saved}
+	if _, err := stty("-icanon", "-echo", "min", "1"); err != nil {
+		return nil, fmt.Errorf("can't set up the terminal: %v", err)
+	}
+	return in, nil
+}
+
+func (in *terminalInput) restore() {
+	stty(in.saved)
+}
+
+// Key and Line wait for the user without holding the lock on the tasks
+// file, like a prompt.
+func (in *terminalInput) Key() (key byte, err error) {
+	if _, lockErr := whileUnlocked(func() { key, err = stdin.ReadByte() }); lockErr != nil {
+		return 0, lockErr
+	}
+	return key, err
+}
+
+func (in *terminalInput) Line(prompt string) (line string, err error) {
+	in.restore()
+	defer stty("-icanon", "-echo", "min", "1")
+	fmt.Printf("%s ", prompt)
+	if _, lockErr := whileUnlocked(func() { line, err = stdin.ReadString('\n') }); lockErr != nil {
+		return "", lockErr
+	}
+	return strings.TrimSpace(line), err
+}
+
+// triageTasks handles --triage: it goes through the listed tasks, the
+// oldest first, writing the list after each change, and prints what was
+// done. The terminal is put back the way it was however triage ends,
+// Ctrl-C and panics included.
+func triageTasks() error {
+	if !stdinIsTerminal() {
+		return errors.New("--triage reads keys from a terminal")
+	}
+	limit, err := doneLimit()
+	if err != nil {
+		return err
+	}
+	in, err := newTerminalInput()
+	if err != nil {
+		return err
+	}
+	// Deferred calls run on panics too; Ctrl-C needs catching.
+	defer in.restore()
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	go func() {
+		<-interrupts
+		in.restore()
+		fmt.Println()
+		os.Exit(130)
+	}()
+	summary, err := tasklist.triage(tasklist.triageOrder(), in, os.Stdout, func(finished *Task) error {
+		if finished == nil {
+			return tasklist.write(true)
+		}
+		return tasklist.writeDone([]*Task{finished}, time.Now(), limit)
+	})
+	fmt.Println(summary)
+	return err
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scriptedInput plays back keys and lines for --triage.
+type scriptedInput struct {
+	keys  string
+	lines []string
+}
+
+func (in *scriptedInput) Key() (byte, error) {
+	if in.keys == "" {
+		return 0, io.EOF
+	}
+	key := in.keys[0]
+	in.keys = in.keys[1:]
+	return key, nil
+}
+
+func (in *scriptedInput) Line(prompt string) (string, error) {
+	if len(in.lines) == 0 {
+		return "", io.EOF
+	}
+	line := in.lines[0]
+	in.lines = in.lines[1:]
+	return line, nil
+}
+
+func triageList() *TaskList {
+	tasklist := &TaskList{}
+	now := time.Now()
+	for i, description := range []string{"newest", "middle", "oldest", "older"} {
+		task, _ := tasklist.Add(description)
+		task.CreatedAt = now.Add(-time.Duration([]int{1, 10, 40, 30}[i]) * 24 * time.Hour)
+	}
+	return tasklist
+}
+
+func TestTriage(t *testing.T) {
+	tasklist := triageList()
+	in := &scriptedInput{keys: "fxep3sq", lines: []string{"older, edited"}}
+	saves := make([]string, 0)
+	summary, err := tasklist.triage(tasklist.triageOrder(), in, &bytes.Buffer{}, func(finished *Task) error {
+		if finished != nil {
+			saves = append(saves, "finished "+finished.Description)
+		} else {
+			saves = append(saves, "saved")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(saves, []string{"finished oldest", "saved", "saved"}) {
+		t.Fatalf("Expected a save after each change, got %v", saves)
+	}
+	if summary != (triageSummary{finished: 1, edited: 1, prioritized: 1, skipped: 1}) || summary.handled() != 4 {
+		t.Fatalf("Expected one of each but deleted, got %v", summary)
+	}
+	if got := joinDescriptions(tasklist.Tasks); got != "newest middle older, edited" {
+		t.Fatalf("Expected the finished task gone and the edit made, got '%s'", got)
+	}
+	if tasklist.Tasks[1].Priority != 3 {
+		t.Fatalf("Expected middle to get priority 3, got %d", tasklist.Tasks[1].Priority)
+	}
+}
+
+func TestTriageDeleteAndQuit(t *testing.T) {
+	tasklist := triageList()
+	var out bytes.Buffer
+	summary, _ := tasklist.triage(tasklist.triageOrder(), &scriptedInput{keys: "dq"}, &out, func(*Task) error { return nil })
+	if summary.deleted != 1 || summary.handled() != 1 {
+		t.Fatalf("Expected one task deleted before quitting, got %v", summary)
+	}
+	if got := joinDescriptions(tasklist.Tasks); got != "newest middle older" {
+		t.Fatalf("Expected the oldest task deleted, got '%s'", got)
+	}
+	if !strings.Contains(out.String(), "oldest") || strings.Contains(out.String(), "middle") {
+		t.Fatalf("Expected only the first two tasks shown, got '%s'", out.String())
+	}
+}
+
+func TestTriageSaveError(t *testing.T) {
+	tasklist := triageList()
+	failed := errors.New("disk full")
+	_, err := tasklist.triage(tasklist.triageOrder(), &scriptedInput{keys: "ff"}, &bytes.Buffer{}, func(*Task) error { return failed })
+	if err != failed {
+		t.Fatalf("Expected the save error, got %v", err)
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+// journalPath returns the path of the journal of the transactions on
+// the tasks file at path, which only exists while one is being put in
+// place or after a crash interrupted it.
+func journalPath(path string) string {
+	return path + ".journal"
+}
+
+// stagedFile is a file a transaction replaces with the temporary file
+// next to it, or removes if there is none.
+type stagedFile struct {
+	Path string `json:"path"`
+	Temp string `json:"temp,omitempty"`
+}
+
+// transaction writes several files as one: each is staged to a
+// temporary file next to it, and only once they all are does commit
+// record them in the journal and rename them in place. If a crash
+// interrupts the renames, recoverJournal finishes them on the next run;
+// if it comes before the journal is written, no file has changed.
+type transaction struct {
+	journal string
+	// copies are the journals of other tasks files the transaction
+	// changes, where it is recorded too, so that a crash is recovered
+	// from whichever of them t reads next.
+	copies []string
+	files  []stagedFile
+}
+
+// newTransaction starts a transaction recorded in the journal at path.
+func newTransaction(journal string) *transaction {
+	return &transaction{journal: journal}
+}
+
+// journalUnder records the transaction in the journal of the tasks file
+// at path as well as in its own.
+func (tx *transaction) journalUnder(path string) {
+	tx.copies = append(tx.copies, journalPath(path))
+}
+
+// staged returns the index of the file at path among those staged, or
+// -1 if it isn't.
+func (tx *transaction) staged(path string) int {
+	for i, file := range tx.files {
+		if file.Path == path {
+			return i
+		}
+	}
+	return -1
+}
+
+// write stages data as the new contents of the file at path, created
+// with perm if it doesn't exist, replacing whatever was staged for it
+// before.
+func (tx *transaction) write(path string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("can't write %s: %v", path, err)
+	}
+	temp, err := core.StageFile(path, data, perm)
+	if err != nil {
+		return fmt.Errorf("can't write %s: %v", path, err)
+	}
+	tx.set(stagedFile{path, temp})
+	return nil
+}
+
+// remove stages removing the file at path, if there is one by then.
+func (tx *transaction) remove(path string) {
+	tx.set(stagedFile{Path: path})
+}
+
+func (tx *transaction) set(file stagedFile) {
+	if i := tx.staged(file.Path); i != -1 {
+		if tx.files[i].Temp != "" {
+			os.Remove(tx.files[i].Temp)
+		}
+		tx.files[i] = file
+		return
+	}
+	tx.files = append(tx.files, file)
+}
+
+// read returns the contents of the file at path as the transaction
+// leaves it: what was staged for it, or what it holds now. A file that
+// doesn't exist or is staged to be removed reads as os.ErrNotExist.
+func (tx *transaction) read(path string) ([]byte, error) {
+	if i := tx.staged(path); i != -1 {
+		if tx.files[i].Temp == "" {
+			return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+		}
+		return ioutil.ReadFile(tx.files[i].Temp)
+	}
+	return ioutil.ReadFile(path)
+}
+
+// abort drops the staged files, leaving every file as it was.
+func (tx *transaction) abort() {
+	for _, file := range tx.files {
+		if file.Temp != "" {
+			os.Remove(file.Temp)
+		}
+	}
+	tx.files = nil
+}
+
+// commit puts the staged files in place: it records them in the
+// journal, renames them over the files they replace in the order they
+// were staged, and removes the journal. A failure after the journal is
+// written leaves it for recoverJournal.
+func (tx *transaction) commit() error {
+	if len(tx.files) == 0 {
+		return nil
+	}
+	if err := tx.writeJournal(); err != nil {
+		tx.abort()
+		return err
+	}
+	if err := applyStaged(tx.files); err != nil {
+		return err
+	}
+	tx.files = nil
+	for _, journal := range tx.copies {
+		if err := os.Remove(journal); err != nil {
+			return err
+		}
+	}
+	return os.Remove(tx.journal)
+}
+
+func (tx *transaction) writeJournal() error {
+	text, err := json.Marshal(tx.files)
+	if err != nil {
+		return err
+	}
+	for _, journal := range append([]string{tx.journal}, tx.copies...) {
+		if err := core.WriteAtomic(journal, text); err != nil {
+			return fmt.Errorf("can't write %s: %v", journal, err)
+		}
+	}
+	return nil
+}
+
+// applyStaged renames the staged files in place and removes those staged
+// for removal. A temporary file that is gone was renamed already, so
+// applying the same files again finishes what a crash interrupted.
+func applyStaged(files []stagedFile) error {
+	for _, file := range files {
+		if file.Temp == "" {
+			if err := os.Remove(file.Path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("can't remove %s: %v", file.Path, err)
+			}
+			continue
+		}
+		if err := os.Rename(file.Temp, file.Path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("can't write %s: %v", file.Path, err)
+		}
+	}
+	return nil
+}
+
+// recoverJournal finishes the transaction a crash left in the journal at
+// path, if there is one, and returns the paths of the files it covered.
+// The copies of the journal under the other files it covered go with it,
+// so that they aren't applied again over later changes.
+func recoverJournal(path string) ([]string, error) {
+	text, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var files []stagedFile
+	if err := json.Unmarshal(text, &files); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", path, err)
+	}
+	if err := applyStaged(files); err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(files))
+	for _, file := range files {
+		paths = append(paths, file.Path)
+		journal := journalPath(file.Path)
+		if other, err := ioutil.ReadFile(journal); err == nil && journal != path && bytes.Equal(other, text) {
+			if err := os.Remove(journal); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return paths, os.Remove(path)
+}
+
+// recoverWrites finishes the transactions a crash interrupted on the
+// tasks file at path and its done file before anything reads them,
+// taking the lock for it unless locked says it is held already.
+func recoverWrites(path string, locked bool) error {
+	journals := []string{journalPath(path), journalPath(donePath(path))}
+	for _, journal := range journals {
+		if _, err := os.Stat(journal); err != nil {
+			continue
+		}
+		if !locked && !inHook {
+			if err := lockTasksFile(path); err != nil {
+				return err
+			}
+			locked = true
+		}
+		recovered, err := recoverJournal(journal)
+		if err != nil {
+			return fmt.Errorf("can't finish an interrupted write: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "finished an interrupted write of %s\n", strings.Join(recovered, ", "))
+	}
+	return nil
+}
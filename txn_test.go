@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// stagedFiles returns the names of the temporary files left in dir.
+func stagedFiles(dir string) []string {
+	names, _ := filepath.Glob(filepath.Join(dir, ".*.tmp*"))
+	return names
+}
+
+func TestTransactionCommit(t *testing.T) {
+	dir := t.TempDir()
+	a, b, gone := filepath.Join(dir, "a"), filepath.Join(dir, "sub", "b"), filepath.Join(dir, "gone")
+	ioutil.WriteFile(a, []byte("old a"), 0600)
+	ioutil.WriteFile(gone, []byte("gone"), 0644)
+	tx := newTransaction(filepath.Join(dir, "journal"))
+	tx.write(a, []byte("first a"), 0644)
+	tx.write(b, []byte("new b"), 0644)
+	tx.write(a, []byte("new a"), 0644)
+	tx.remove(gone)
+	if text, _ := tx.read(a); string(text) != "new a" {
+		t.Fatalf("Expected to read what was staged last, got '%s'", text)
+	}
+	if _, err := tx.read(gone); !os.IsNotExist(err) {
+		t.Fatalf("Expected a file staged for removal to read as missing, got %v", err)
+	}
+	if text, _ := ioutil.ReadFile(a); string(text) != "old a" {
+		t.Fatalf("Expected nothing to change before the commit, got '%s'", text)
+	}
+	if err := tx.commit(); err != nil {
+		t.Fatal(err)
+	}
+	for path, expected := range map[string]string{a: "new a", b: "new b"} {
+		if text, _ := ioutil.ReadFile(path); string(text) != expected {
+			t.Errorf("Expected %s to be '%s', got '%s'", filepath.Base(path), expected, text)
+		}
+	}
+	if info, _ := os.Stat(a); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected a to keep its permissions, got %v", info.Mode().Perm())
+	}
+	for _, path := range []string{gone, filepath.Join(dir, "journal")} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be gone", filepath.Base(path))
+		}
+	}
+	if left := append(stagedFiles(dir), stagedFiles(filepath.Join(dir, "sub"))...); len(left) > 0 {
+		t.Errorf("Expected no temporary files left, got %v", left)
+	}
+}
+
+func TestTransactionAbort(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a")
+	ioutil.WriteFile(a, []byte("old a"), 0644)
+	tx := newTransaction(filepath.Join(dir, "journal"))
+	tx.write(a, []byte("new a"), 0644)
+	tx.abort()
+	if err := tx.commit(); err != nil {
+		t.Fatal(err)
+	}
+	if text, _ := ioutil.ReadFile(a); string(text) != "old a" {
+		t.Fatalf("Expected an aborted write to change nothing, got '%s'", text)
+	}
+	if left := stagedFiles(dir); len(left) > 0 {
+		t.Fatalf("Expected no temporary files left, got %v", left)
+	}
+}
+
+func TestRecoverJournalAfterCrash(t *testing.T) {
+	dir := t.TempDir()
+	journal := filepath.Join(dir, "journal")
+	tasks, done, gone := filepath.Join(dir, "tasks"), filepath.Join(dir, "tasks.done"), filepath.Join(dir, "tasks.seen")
+	ioutil.WriteFile(tasks, []byte("pay rent\ncall bob"), 0644)
+	ioutil.WriteFile(done, []byte("buy milk"), 0644)
+	ioutil.WriteFile(gone, []byte("{}"), 0644)
+	tx := newTransaction(journal)
+	tx.write(tasks, []byte("call bob"), 0644)
+	tx.write(done, []byte("buy milk\npay rent"), 0644)
+	tx.remove(gone)
+	// The crash comes after the journal is written and the first file
+	// is renamed in place.
+	if err := tx.writeJournal(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tx.files[0].Temp, tasks); err != nil {
+		t.Fatal(err)
+	}
+	if text, _ := ioutil.ReadFile(done); string(text) != "buy milk" {
+		t.Fatalf("Expected the done file not written yet, got '%s'", text)
+	}
+
+	recovered, err := recoverJournal(journal)
+	if err != nil || strings.Join(recovered, " ") != strings.Join([]string{tasks, done, gone}, " ") {
+		t.Fatalf("Expected all three files recovered, got %v (%v)", recovered, err)
+	}
+	for path, expected := range map[string]string{tasks: "call bob", done: "buy milk\npay rent"} {
+		if text, _ := ioutil.ReadFile(path); string(text) != expected {
+			t.Errorf("Expected %s to be '%s', got '%s'", filepath.Base(path), expected, text)
+		}
+	}
+	if _, err := os.Stat(gone); !os.IsNotExist(err) {
+		t.Error("Expected the removal rolled forward")
+	}
+	if _, err := os.Stat(journal); !os.IsNotExist(err) {
+		t.Error("Expected the journal removed")
+	}
+	if recovered, err := recoverJournal(journal); err != nil || recovered != nil {
+		t.Fatalf("Expected nothing left to recover, got %v (%v)", recovered, err)
+	}
+}
+
+func TestRecoverJournalUnderEitherList(t *testing.T) {
+	dir := t.TempDir()
+	tasks, home := filepath.Join(dir, "tasks"), filepath.Join(dir, "home")
+	ioutil.WriteFile(tasks, []byte("pay rent\ncall bob"), 0644)
+	ioutil.WriteFile(home, []byte("water plants"), 0644)
+	tx := newTransaction(journalPath(tasks))
+	tx.journalUnder(home)
+	tx.write(home, []byte("water plants\ncall bob"), 0644)
+	tx.write(tasks, []byte("pay rent"), 0644)
+	// The crash comes right after the journals are written.
+	if err := tx.writeJournal(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := recoverJournal(journalPath(home)); err != nil {
+		t.Fatal(err)
+	}
+	for path, expected := range map[string]string{tasks: "pay rent", home: "water plants\ncall bob"} {
+		if text, _ := ioutil.ReadFile(path); string(text) != expected {
+			t.Errorf("Expected %s to be '%s', got '%s'", filepath.Base(path), expected, text)
+		}
+	}
+	for _, journal := range []string{journalPath(tasks), journalPath(home)} {
+		if _, err := os.Stat(journal); !os.IsNotExist(err) {
+			t.Errorf("Expected %s removed along with the one recovered", filepath.Base(journal))
+		}
+	}
+}
+
+func TestCliRecoversInterruptedFinish(t *testing.T) {
+	withCliSetup(t, func() {
+		ioutil.WriteFile("/tmp/tasks", []byte("pay rent\ncall bob"), 0644)
+		ioutil.WriteFile("/tmp/.tasks.tmp1", []byte("call bob"), 0644)
+		ioutil.WriteFile("/tmp/.tasks.done.tmp1", []byte("pay rent | done:2024-06-01T09:00:00Z"), 0644)
+		ioutil.WriteFile("/tmp/tasks.journal", []byte(`[{"path":"/tmp/tasks","temp":"/tmp/.tasks.tmp1"},{"path":"/tmp/tasks.done","temp":"/tmp/.tasks.done.tmp1"}]`), 0644)
+		// The tasks file was renamed in place before the crash.
+		os.Rename("/tmp/.tasks.tmp1", "/tmp/tasks")
+		cmd := exec.Command(tBinary)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil || string(out) != "0 - call bob\n" {
+			t.Fatalf("Expected the list as written, got '%s' (%v)", out, err)
+		}
+		if !strings.Contains(stderr.String(), "interrupted write") {
+			t.Fatalf("Expected the recovery reported, got '%s'", stderr.String())
+		}
+		if text, _ := ioutil.ReadFile("/tmp/tasks.done"); !strings.HasPrefix(string(text), "pay rent") {
+			t.Fatalf("Expected the done file written, got '%s'", text)
+		}
+		if _, err := os.Stat("/tmp/tasks.journal"); !os.IsNotExist(err) {
+			t.Fatal("Expected the journal removed")
+		}
+	})
+}
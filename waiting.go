@@ -0,0 +1,59 @@
+package main
+
+import "fmt"
+
+// waitingIds returns the ids of the tasks waiting for someone else.
+func (t *TaskList) waitingIds(ids []int) []int {
+	waiting := make([]int, 0)
+	for _, taskId := range ids {
+		if t.Tasks[taskId].Waiting {
+			waiting = append(waiting, taskId)
+		}
+	}
+	return waiting
+}
+
+// formatWaiting renders what a task is waiting for in listings, like
+// "⏳ waiting: Bob's reply", or "(waiting: Bob's reply)" in plain ones.
+func formatWaiting(task *Task, plain bool) string {
+	text := "waiting"
+	if task.WaitingFor != "" {
+		text += ": " + task.WaitingFor
+	}
+	if plain {
+		return "(" + text + ")"
+	}
+	return "⏳ " + text
+}
+
+// waitTask handles --waiting with an id: it marks the task as waiting
+// for someone else, for the given reason if there is one.
+func waitTask(target string, reason string) error {
+	taskId, err := tasklist.resolveId(target)
+	if err != nil {
+		return err
+	}
+	task, err := tasklist.Get(taskId)
+	if err != nil {
+		return err
+	}
+	task.Waiting, task.WaitingFor = true, reason
+	return tasklist.write(true)
+}
+
+// unwaitTask handles --unwait: it takes a task off waiting.
+func unwaitTask(target string) error {
+	taskId, err := tasklist.resolveId(target)
+	if err != nil {
+		return err
+	}
+	task, err := tasklist.Get(taskId)
+	if err != nil {
+		return err
+	}
+	if !task.Waiting {
+		return fmt.Errorf("task %s isn't waiting", target)
+	}
+	task.Waiting, task.WaitingFor = false, ""
+	return tasklist.write(true)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os/exec"
+	"reflect"
+	"testing"
+	"time"
+
+	core "github.com/t-900/t/tasklist"
+)
+
+func TestWaitingRoundTrip(t *testing.T) {
+	for _, line := range []string{"ask bob | waiting:Bob's%20reply", "ask bob | waiting:"} {
+		task := &Task{}
+		if err := task.UnmarshalText([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+		if !task.Waiting || task.Description != "ask bob" {
+			t.Fatalf("Expected %q to be waiting, got %+v", line, task)
+		}
+		text, _ := task.MarshalText()
+		if string(text) != line {
+			t.Fatalf("Expected %q to be written back as it was, got %q", line, text)
+		}
+	}
+}
+
+func TestWaitingDoesNotAge(t *testing.T) {
+	defer func(saved priorityAging) { aging = saved }(aging)
+	aging, _ = parseAging("2w")
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.Local)
+	tasklist := TaskList{TaskList: core.TaskList{Tasks: []*Task{
+		{Description: "waiting", CreatedAt: now.AddDate(0, 0, -60), Waiting: true},
+		{Description: "old", CreatedAt: now.AddDate(0, 0, -40)},
+	}}}
+	if stale := tasklist.staleIds([]int{0, 1}, 30*24*time.Hour, now); !reflect.DeepEqual(stale, []int{1}) {
+		t.Fatalf("Expected only [1] to be stale, got %v", stale)
+	}
+	if oldest := tasklist.oldest(); oldest != 1 {
+		t.Fatalf("Expected the oldest task not waiting, got %d", oldest)
+	}
+	if priority := effectivePriority(tasklist.Tasks[0], now); priority != defaultPriority {
+		t.Fatalf("Expected a waiting task not to age, got priority %d", priority)
+	}
+	tasklist.Tasks[1].Waiting = true
+	if oldest := tasklist.oldest(); oldest != 0 {
+		t.Fatalf("Expected the oldest of all when all are waiting, got %d", oldest)
+	}
+	tasklist.Edit(0, "no longer waiting")
+	if tasklist.Tasks[0].Waiting {
+		t.Fatal("Expected an edit to take the task off waiting")
+	}
+}
+
+func TestCliWaiting(t *testing.T) {
+	withCliSetup(t, func() {
+		exec.Command(tBinary, "ask bob").Run()
+		exec.Command(tBinary, "write report").Run()
+		if out, err := exec.Command(tBinary, "--waiting", "0", "Bob's", "reply").CombinedOutput(); err != nil {
+			t.Fatalf("Expected --waiting to succeed, got %v: %s", err, out)
+		}
+		out, _ := exec.Command(tBinary, "--plain").Output()
+		if string(out) != "0 - ask bob (waiting: Bob's reply)\n1 - write report\n" {
+			t.Fatalf("Expected the waiting task marked, got '%s'", out)
+		}
+		out, _ = exec.Command(tBinary, "--waiting").Output()
+		if string(out) != "0 - ask bob ⏳ waiting: Bob's reply\n" {
+			t.Fatalf("Expected only the waiting task listed, got '%s'", out)
+		}
+		if err := exec.Command(tBinary, "--unwait", "0").Run(); err != nil {
+			t.Fatal(err)
+		}
+		out, _ = exec.Command(tBinary, "--waiting").Output()
+		if string(out) != "" {
+			t.Fatalf("Expected no waiting tasks after --unwait, got '%s'", out)
+		}
+		if err := exec.Command(tBinary, "--unwait", "0").Run(); err == nil {
+			t.Fatal("Expected --unwait of a task that isn't waiting to fail")
+		}
+	})
+}
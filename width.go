@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// defaultWidth is the width of a terminal that doesn't tell its own and
+// has no $COLUMNS either.
+const defaultWidth = 80
+
+// listingWidth returns how many columns a listing line may take: the
+// width of the terminal stdout is, or failing that $COLUMNS or
+// defaultWidth. It is 0, for no limit, when stdout isn't a terminal, so
+// that whatever reads the output gets it whole.
+func listingWidth() int {
+	info, err := os.Stdout.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return 0
+	}
+	if width := terminalWidth(os.Stdout); width > 0 {
+		return width
+	}
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && width > 0 {
+		return width
+	}
+	return defaultWidth
+}
+
+// truncate cuts s down to width runes, the last of them an ellipsis.
+// Escape sequences like colors don't count and are kept, and a line
+// cut short ends with colorReset so that its color doesn't spill over.
+// A width of 0 or less leaves s as it is.
+func truncate(s string, width int) string {
+	if width <= 0 || visibleRunes(s) <= width {
+		return s
+	}
+	var b strings.Builder
+	shown, colored := 0, false
+	for i := 0; i < len(s); {
+		if n := escapeLength(s[i:]); n > 0 {
+			b.WriteString(s[i : i+n])
+			i += n
+			colored = true
+			continue
+		}
+		if shown == width-1 {
+			break
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		b.WriteString(s[i : i+size])
+		i += size
+		shown++
+	}
+	b.WriteString(ellipsis)
+	if colored {
+		b.WriteString(colorReset)
+	}
+	return b.String()
+}
+
+// visibleRunes counts the runes of s but those of escape sequences.
+func visibleRunes(s string) int {
+	n := 0
+	for i := 0; i < len(s); {
+		if length := escapeLength(s[i:]); length > 0 {
+			i += length
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		n++
+	}
+	return n
+}
+
+// escapeLength returns the length of the escape sequence s starts with,
+// like "\x1b[1;31m", or 0 if it doesn't start with one.
+func escapeLength(s string) int {
+	if len(s) < 2 || s[0] != '\x1b' || s[1] != '[' {
+		return 0
+	}
+	for i := 2; i < len(s); i++ {
+		if s[i] >= 0x40 && s[i] <= 0x7e {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// ellipsis marks where text was cut.
+const ellipsis = "…"
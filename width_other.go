@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly && !windows
+
+package main
+
+import "os"
+
+// terminalWidth can't tell the width of a terminal here, leaving it to
+// $COLUMNS.
+func terminalWidth(f *os.File) int {
+	return 0
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestTruncate(t *testing.T) {
+	red, dim := "\x1b[31m", "\x1b[2m"
+	cases := []struct {
+		s        string
+		width    int
+		expected string
+	}{
+		{"pay rent", 20, "pay rent"},
+		{"pay rent", 8, "pay rent"},
+		{"pay rent", 7, "pay re…"},
+		{"pay rent", 1, "…"},
+		{"pay rent", 0, "pay rent"},
+		{"café crème brûlée", 10, "café crèm…"},
+		{"ünïcödé", 7, "ünïcödé"},
+		{"🎉 party 🎂 cake", 10, "🎉 party 🎂…"},
+		{"👍🏽👍🏽👍🏽", 3, "👍🏽…"},
+		// Colors don't take up room, and are reset once cut.
+		{red + "pay rent" + colorReset, 8, red + "pay rent" + colorReset},
+		{red + "pay rent" + colorReset, 5, red + "pay …" + colorReset},
+		{dim + "0" + colorReset + red + " - pay rent" + colorReset, 6, dim + "0" + colorReset + red + " - p…" + colorReset},
+	}
+	for _, c := range cases {
+		if got := truncate(c.s, c.width); got != c.expected {
+			t.Errorf("truncate(%q, %d): expected %q, got %q", c.s, c.width, c.expected, got)
+		}
+	}
+}
+
+func TestFormatTaskWidth(t *testing.T) {
+	task := &Task{Description: "water the plants in the garden", Priority: 2}
+	if line := formatTask(0, task, formatOptions{width: 20}); line != "0 - water the plant…" {
+		t.Fatalf("Expected the line cut at 20 runes, got '%s'", line)
+	}
+	if line := formatTask(0, task, formatOptions{}); line != "0 - water the plants in the garden (P2)" {
+		t.Fatalf("Expected the line whole without a width, got '%s'", line)
+	}
+}
+
+func TestCliWidePipe(t *testing.T) {
+	withCliSetup(t, func() {
+		long := strings.Repeat("very long task ", 20)
+		exec.Command(tBinary, long).Run()
+		cmd := exec.Command(tBinary)
+		cmd.Env = append(cmd.Environ(), "COLUMNS=40")
+		if out, _ := cmd.Output(); string(out) != "0 - "+strings.TrimSpace(long)+"\n" {
+			t.Fatalf("Expected the task whole through a pipe, got '%s'", out)
+		}
+	})
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// wipLimits reads the work in progress limits from the config:
+// wip_limit for the whole list, keyed "", and wip_limit.+tag or
+// wip_limit.@context for the tasks with a tag, keyed by the lowercased
+// tag.
+func wipLimits(config Config) (map[string]int, error) {
+	limits := make(map[string]int)
+	settings := config.withPrefix("wip_limit.")
+	if value, ok := config.Get("wip_limit"); ok {
+		settings[""] = value
+	}
+	for tag, value := range settings {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || (tag != "" && tag[0] != '+' && tag[0] != '@') {
+			setting := "wip_limit"
+			if tag != "" {
+				setting += "." + tag
+			}
+			return nil, fmt.Errorf("invalid %s = %s, expected a +tag or @context and a positive number", setting, value)
+		}
+		limits[strings.ToLower(tag)] = limit
+	}
+	return limits, nil
+}
+
+// wipExceeded checks the limits against the list, which already holds
+// task, and describes each limit the task takes the list over. Only the
+// tasks listed right now count, and a tag's limit only applies to tasks
+// with the tag.
+func (t *TaskList) wipExceeded(task *Task, limits map[string]int) []string {
+	tags := make([]string, 0, len(limits))
+	for tag := range limits {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	exceeded := make([]string, 0)
+	for _, tag := range tags {
+		limit := limits[tag]
+		if tag != "" && !hasWord(task, tag) {
+			continue
+		}
+		count := 0
+		for _, taskId := range t.Search("") {
+			if tag == "" || hasWord(t.Tasks[taskId], tag) {
+				count++
+			}
+		}
+		if count > limit {
+			what := "tasks"
+			if tag != "" {
+				what += " " + tag
+			}
+			exceeded = append(exceeded, fmt.Sprintf("%d %s, over the WIP limit of %d", count, what, limit))
+		}
+	}
+	return exceeded
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWipExceeded(t *testing.T) {
+	config := Config{values: map[string]string{"wip_limit": "3", "wip_limit.+Errands": "1"}}
+	limits, err := wipLimits(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasklist := TaskList{}
+	tasklist.Add("buy milk +errands")
+	tasklist.Add("write report")
+	task, _ := tasklist.Add("post letter +errands")
+	expected := []string{"2 tasks +errands, over the WIP limit of 1"}
+	if exceeded := tasklist.wipExceeded(task, limits); !reflect.DeepEqual(exceeded, expected) {
+		t.Fatalf("Expected %q, got %q", expected, exceeded)
+	}
+	task, _ = tasklist.Add("call bob")
+	expected = []string{"4 tasks, over the WIP limit of 3"}
+	if exceeded := tasklist.wipExceeded(task, limits); !reflect.DeepEqual(exceeded, expected) {
+		t.Fatalf("Expected %q, got %q", expected, exceeded)
+	}
+	if exceeded := tasklist.wipExceeded(task, map[string]int{}); len(exceeded) != 0 {
+		t.Fatalf("Expected no limits without config, got %q", exceeded)
+	}
+	for _, bad := range []map[string]string{{"wip_limit": "many"}, {"wip_limit.errands": "2"}} {
+		if _, err := wipLimits(Config{values: bad}); err == nil {
+			t.Fatalf("Expected %v to be rejected", bad)
+		}
+	}
+}